@@ -7,6 +7,7 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -15,26 +16,64 @@ import (
 	transactionPreOrderRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/transaction_pre_order"
 	transactionPreOrderUserRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/transaction_pre_order_user"
 	transactionPreOrderUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/transaction_pre_order"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
 
+	productPriceRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_price"
+	productRevisionRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_revision"
+	productRevisionUserRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_revision_user"
 	productUserRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_user"
 
 	"bitbucket.org/bridce/ms-pari-web/docs"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/config"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/credential"
+	accessTokenHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/access_token"
 	authHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/auth"
+	commodityHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/commodity"
 	companyHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/company"
+	giroHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/giro"
+	invitationHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/invitation"
+	onboardingHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/onboarding"
+	passwordHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/password"
 	productHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/product"
 	roleHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/role"
+	ssoHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/sso"
+	trashHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/trash"
+	twoFactorHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/two_factor"
 	userHandler "bitbucket.org/bridce/ms-pari-web/internal/pkg/handler/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/mailer"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/middleware"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/oidc"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	accessTokenRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/access_token"
+	commodityRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/commodity"
 	companyRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/company"
 	giroRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/giro"
+	invitationRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/invitation"
+	loginAttemptRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/login_attempt"
+	onboardingStepRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/onboarding_step"
+	passwordHistoryRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/password_history"
+	passwordResetRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/password_reset"
 	productRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product"
 	roleRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	ssoRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/sso"
+	trashRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/trash"
+	twoFactorRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/two_factor"
 	userRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/search"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	accessTokenUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/access_token"
 	authUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/auth"
+	commodityUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/commodity"
 	companyUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/company"
+	giroUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/giro"
+	invitationUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/invitation"
+	onboardingUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/onboarding"
+	passwordUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/password"
 	productUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/product"
 	roleUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/role"
+	ssoUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/sso"
+	trashUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/trash"
+	twoFactorUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/two_factor"
 	userUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/user"
 	"github.com/casbin/casbin"
 	gormadapter "github.com/casbin/gorm-adapter"
@@ -44,6 +83,8 @@ import (
 	"github.com/spf13/viper"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // @title PARI Korporat
@@ -62,6 +103,12 @@ func main() {
 		return
 	}
 
+	err = helper.InitLogger(viper.GetString("LOG_LEVEL"), viper.GetString("LOG_PATH"))
+	if err != nil {
+		helper.CommonLogger().Error(err)
+		return
+	}
+
 	port := viper.Get("PORT").(string)
 	dbUser := viper.Get("DB_USER").(string)
 	dbPass := viper.Get("DB_PASSWORD").(string)
@@ -69,6 +116,19 @@ func main() {
 	dbPort := viper.Get("DB_PORT").(string)
 	dbName := viper.Get("DB_NAME").(string)
 
+	exporter, err := tracing.NewOTLPExporter(context.Background(), viper.GetString("OTEL_EXPORTER_OTLP_ENDPOINT"))
+	if err != nil {
+		helper.CommonLogger().Error(err)
+		return
+	}
+	tp := tracing.NewProvider(viper.GetString("OTEL_SERVICE_NAME"), exporter)
+	defer func(tp *sdktrace.TracerProvider) {
+		err := tp.Shutdown(context.Background())
+		if err != nil {
+			helper.CommonLogger().Error(err)
+		}
+	}(tp)
+
 	db := config.DbConnect(dbUser, dbPass, dbHost, dbPort, dbName)
 	defer func(db *gorm.DB) {
 		err := db.Close()
@@ -82,20 +142,28 @@ func main() {
 	adapter := gormadapter.NewAdapterByDB(db)
 
 	// Load model configuration file and policy store adapter
-	enforcer := casbin.NewEnforcer("./internal/pkg/config/rbac_model.conf", adapter)
+	enforcer := casbin.NewSyncedEnforcer("./internal/pkg/config/rbac_model.conf", adapter)
 
-	//add policy
-	if hasPolicy := enforcer.HasPolicy("superadmin", "report", "read"); !hasPolicy {
-		enforcer.AddPolicy("supeardmin", "report", "read")
-	}
-	if hasPolicy := enforcer.HasPolicy("verificator", "report", "read"); !hasPolicy {
-		enforcer.AddPolicy("verificator", "report", "read")
+	// reload the policy changed by other instances, every POLICY_RELOAD_INTERVAL
+	policyReloadInterval := 10 * time.Second
+	if d := viper.GetDuration("POLICY_RELOAD_INTERVAL"); d > 0 {
+		policyReloadInterval = d
 	}
-	if hasPolicy := enforcer.HasPolicy("user", "report", "read"); !hasPolicy {
-		enforcer.AddPolicy("user", "report", "read")
+	watcher, err := permission.NewWatcher(db, policyReloadInterval)
+	if err != nil {
+		helper.CommonLogger().Fatal(err)
 	}
+	defer watcher.Close()
+	enforcer.SetWatcher(watcher)
+
+	//add policy
+	permission.Seed(enforcer)
 
-	router := gin.Default()
+	validation.Register()
+
+	router := gin.New()
+	// tracing wraps the error handler, so spans end with the status of rendered errors
+	router.Use(gin.Recovery(), middleware.RequestID(), middleware.Logger(), middleware.Tracing(), middleware.ErrorHandler())
 	docs.SwaggerInfo.BasePath = "/api/v1"
 	err = router.SetTrustedProxies(nil)
 	if err != nil {
@@ -106,8 +174,8 @@ func main() {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3003", viper.Get("ALLOW_ORIGIN").(string)},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With", "If-Match", middleware.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "ETag", middleware.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -120,36 +188,232 @@ func main() {
 
 	// init repositories
 	userRepo := userRepository.NewRepository(db)
+	accessTokenRepo := accessTokenRepository.NewRepository(db)
 	roleRepo := roleRepository.NewRepository(db)
 	companyRepo := companyRepository.NewRepository(db)
+	commodityRepo := commodityRepository.NewRepository(db)
 	giroRepo := giroRepository.NewRepository(db)
+	invitationRepo := invitationRepository.NewRepository(db)
+	loginAttemptRepo := loginAttemptRepository.NewRepository(db)
+	onboardingStepRepo := onboardingStepRepository.NewRepository(db)
+	passwordHistoryRepo := passwordHistoryRepository.NewRepository(db)
+	passwordResetRepo := passwordResetRepository.NewRepository(db)
 	productRepo := productRepository.NewRepository(db)
 	productUserRepo := productUserRepository.NewRepository(db)
+	productPriceRepo := productPriceRepository.NewRepository(db)
+	productRevisionRepo := productRevisionRepository.NewRepository(db)
+	productRevisionUserRepo := productRevisionUserRepository.NewRepository(db)
 	transactionPreOrderRepo := transactionPreOrderRepository.NewRepository(db)
 	transactionPreOrderUserRepo := transactionPreOrderUserRepository.NewRepository(db)
+	ssoRepo := ssoRepository.NewRepository(db)
+	trashRepo := trashRepository.NewRepository(db)
+	twoFactorRepo := twoFactorRepository.NewRepository(db)
+
+	// init product search
+	synonyms := search.DefaultSynonyms
+	if s := viper.GetString("SEARCH_SYNONYMS"); s != "" {
+		synonyms = search.ParseSynonyms(s)
+	}
+	searchIndex := search.NewIndex(synonyms)
+
+	// init mailer
+	mail, err := mailer.New(mailer.Config{
+		Driver:   viper.GetString("MAILER"),
+		From:     viper.GetString("MAIL_FROM"),
+		Host:     viper.GetString("SMTP_HOST"),
+		Port:     viper.GetString("SMTP_PORT"),
+		Username: viper.GetString("SMTP_USERNAME"),
+		Password: viper.GetString("SMTP_PASSWORD"),
+		Dir:      viper.GetString("MAIL_DIR"),
+	})
+	if err != nil {
+		helper.CommonLogger().Fatal(err)
+	}
+	invitationTTL := 72 * time.Hour
+	if viper.IsSet("INVITATION_TTL_HOURS") {
+		invitationTTL = time.Duration(viper.GetInt("INVITATION_TTL_HOURS")) * time.Hour
+	}
+	passwordResetTTL := time.Hour
+	if viper.IsSet("PASSWORD_RESET_TTL_MINUTES") {
+		passwordResetTTL = time.Duration(viper.GetInt("PASSWORD_RESET_TTL_MINUTES")) * time.Minute
+	}
+
+	// init credential service, every PASSWORD_* setting defaulting to credential.DefaultPolicy
+	passwordPolicy := credential.DefaultPolicy
+	if viper.IsSet("PASSWORD_MIN_LENGTH") {
+		passwordPolicy.MinLength = viper.GetInt("PASSWORD_MIN_LENGTH")
+	}
+	if viper.IsSet("PASSWORD_MIN_CLASSES") {
+		passwordPolicy.MinClasses = viper.GetInt("PASSWORD_MIN_CLASSES")
+	}
+	if viper.IsSet("PASSWORD_HISTORY") {
+		passwordPolicy.History = viper.GetInt("PASSWORD_HISTORY")
+	}
+	if path := viper.GetString("PASSWORD_BREACHED_FILE"); path != "" {
+		passwordPolicy.Breached, err = credential.LoadBreached(path)
+		if err != nil {
+			helper.CommonLogger().Fatal(err)
+		}
+	}
+	bcryptCost := bcrypt.DefaultCost
+	if viper.IsSet("PASSWORD_BCRYPT_COST") {
+		bcryptCost = viper.GetInt("PASSWORD_BCRYPT_COST")
+	}
+	credentialService, err := credential.NewService(passwordPolicy, bcryptCost, userRepo, passwordHistoryRepo)
+	if err != nil {
+		helper.CommonLogger().Fatal(err)
+	}
+
+	// init login lockout, every LOGIN_* setting defaulting to credential.DefaultLockout
+	lockout := credential.DefaultLockout
+	if viper.IsSet("LOGIN_FREE_ATTEMPTS") {
+		lockout.Free = viper.GetInt("LOGIN_FREE_ATTEMPTS")
+	}
+	if d := viper.GetDuration("LOGIN_DELAY"); d > 0 {
+		lockout.Delay = d
+	}
+	if viper.IsSet("LOGIN_LOCKOUT_THRESHOLD") {
+		lockout.Threshold = viper.GetInt("LOGIN_LOCKOUT_THRESHOLD")
+	}
+	if d := viper.GetDuration("LOGIN_LOCKOUT_DURATION"); d > 0 {
+		lockout.Duration = d
+	}
+	if viper.IsSet("LOGIN_IP_FAILURES") {
+		lockout.IPFailures = viper.GetInt("LOGIN_IP_FAILURES")
+	}
+	if d := viper.GetDuration("LOGIN_IP_WINDOW"); d > 0 {
+		lockout.IPWindow = d
+	}
+
+	// init two-factor authentication
+	twoFactorIssuer := "PARI Korporat"
+	if s := viper.GetString("TWO_FACTOR_ISSUER"); s != "" {
+		twoFactorIssuer = s
+	}
+	twoFactorChallengeTTL := 5 * time.Minute
+	if d := viper.GetDuration("TWO_FACTOR_CHALLENGE_TTL"); d > 0 {
+		twoFactorChallengeTTL = d
+	}
+
+	// init single sign-on, users having SSO_LOGIN_TTL to come back from their provider
+	ssoLoginTTL := 10 * time.Minute
+	if d := viper.GetDuration("SSO_LOGIN_TTL"); d > 0 {
+		ssoLoginTTL = d
+	}
+	oidcClient := oidc.NewClient(&http.Client{Timeout: 10 * time.Second})
+
+	// init personal access tokens, valid for ACCESS_TOKEN_MAX_DAYS at most
+	accessTokenMaxDays := 365
+	if viper.IsSet("ACCESS_TOKEN_MAX_DAYS") {
+		accessTokenMaxDays = viper.GetInt("ACCESS_TOKEN_MAX_DAYS")
+	}
 
 	// init usecases
-	userUC := userUsecase.NewUsecase(userRepo)
-	authUC := authUsecase.NewUsecase(userRepo, giroRepo, roleRepo, companyRepo)
-	roleUC := roleUsecase.NewUsecase(roleRepo)
-	companyUC := companyUsecase.NewUsecase(companyRepo)
-	productUC := productUsecase.NewUsecase(productRepo, productUserRepo, userRepo, roleRepo)
-	transactionPreOrderUC := transactionPreOrderUsecase.NewUsecase(transactionPreOrderRepo, transactionPreOrderUserRepo, userRepo, roleRepo)
+	userUC := userUsecase.NewUsecase(userRepo, roleRepo, credentialService, enforcer)
+	twoFactorUC := twoFactorUsecase.NewUsecase(twoFactorRepo, userRepo, roleRepo, companyRepo, loginAttemptRepo, enforcer, lockout, twoFactorIssuer, twoFactorChallengeTTL)
+	authUC := authUsecase.NewUsecase(userRepo, giroRepo, roleRepo, companyRepo, loginAttemptRepo, credentialService, lockout, twoFactorUC)
+	roleUC := roleUsecase.NewUsecase(roleRepo, userRepo, companyRepo, enforcer)
+	ssoUC := ssoUsecase.NewUsecase(ssoRepo, userRepo, roleRepo, companyRepo, loginAttemptRepo, oidcClient, twoFactorUC, enforcer, viper.GetString("SSO_REDIRECT_URL"), ssoLoginTTL)
+	accessTokenUC := accessTokenUsecase.NewUsecase(accessTokenRepo, userRepo, roleRepo, enforcer, time.Duration(accessTokenMaxDays)*24*time.Hour)
+	companyUC := companyUsecase.NewUsecase(companyRepo, giroRepo)
+	giroUC := giroUsecase.NewUsecase(giroRepo, companyRepo)
+	invitationUC := invitationUsecase.NewUsecase(invitationRepo, userRepo, roleRepo, companyRepo, credentialService, mail, invitationTTL, viper.GetString("INVITATION_URL"))
+	passwordUC := passwordUsecase.NewUsecase(passwordResetRepo, userRepo, credentialService, mail, passwordResetTTL, viper.GetString("PASSWORD_RESET_URL"))
+	onboardingUC := onboardingUsecase.NewUsecase(giroRepo, companyRepo, userRepo, roleRepo, onboardingStepRepo, credentialService)
+	commodityUC := commodityUsecase.NewUsecase(commodityRepo, productRepo)
+	productUC := productUsecase.NewUsecase(productRepo, productUserRepo, productPriceRepo, productRevisionRepo, productRevisionUserRepo, userRepo, commodityRepo, searchIndex)
+	transactionPreOrderUC := transactionPreOrderUsecase.NewUsecase(transactionPreOrderRepo, transactionPreOrderUserRepo, userRepo, productRepo)
+
+	if err = productUC.RebuildSearchIndex(context.Background()); err != nil {
+		helper.CommonLogger().Error(err)
+	}
+	if err = userUC.SyncRoles(context.Background()); err != nil {
+		helper.CommonLogger().Error(err)
+	}
+
+	// init trash, purging what was deleted longer than TRASH_RETENTION_DAYS ago
+	retentionDays := 30
+	if viper.IsSet("TRASH_RETENTION_DAYS") {
+		retentionDays = viper.GetInt("TRASH_RETENTION_DAYS")
+	}
+	purgeInterval := 24 * time.Hour
+	if d := viper.GetDuration("TRASH_PURGE_INTERVAL"); d > 0 {
+		purgeInterval = d
+	}
+	trashUC := trashUsecase.NewUsecase(trashRepo, productRepo, searchIndex, time.Duration(retentionDays)*24*time.Hour)
+	go trashUC.Schedule(context.Background(), purgeInterval)
 
 	// init handlers
 	userH := userHandler.NewHandler(userUC)
 	authH := authHandler.NewHandler(authUC)
 	roleH := roleHandler.NewHandler(roleUC)
 	companyH := companyHandler.NewHandler(companyUC)
+	giroH := giroHandler.NewHandler(giroUC)
+	invitationH := invitationHandler.NewHandler(invitationUC)
+	onboardingH := onboardingHandler.NewHandler(onboardingUC)
+	passwordH := passwordHandler.NewHandler(passwordUC)
+	commodityH := commodityHandler.NewHandler(commodityUC)
 	productH := productHandler.NewHandler(productUC)
 	transactionPreOrderH := transactionPreOrderHandler.NewHandler(transactionPreOrderUC)
+	trashH := trashHandler.NewHandler(trashUC)
+	twoFactorH := twoFactorHandler.NewHandler(twoFactorUC)
+	ssoH := ssoHandler.NewHandler(ssoUC)
+	accessTokenH := accessTokenHandler.NewHandler(accessTokenUC)
+
+	// password routes are public, so each client is limited to PASSWORD_RATE_LIMIT
+	// requests every PASSWORD_RATE_WINDOW
+	passwordRateLimit := 5
+	if viper.IsSet("PASSWORD_RATE_LIMIT") {
+		passwordRateLimit = viper.GetInt("PASSWORD_RATE_LIMIT")
+	}
+	passwordRateWindow := 15 * time.Minute
+	if d := viper.GetDuration("PASSWORD_RATE_WINDOW"); d > 0 {
+		passwordRateWindow = d
+	}
+	passwordLimiter := middleware.NewRateLimiter(passwordRateLimit, passwordRateWindow)
+
+	// personal access tokens authenticate where a permission is required, granted their
+	// scopes only, but not on the routes about the current user
+	authorize := middleware.AuthorizeToken(userRepo, accessTokenRepo)
 
 	v1 := router.Group("/api/v1")
 	{
-		v1.POST("/register", authH.Register(enforcer))
-		v1.POST("/register/bulk", authH.BulkRegister(enforcer))
+		v1.POST("/register", authorize, middleware.Authorize(permission.UserManage, enforcer), authH.Register(enforcer))
+		v1.POST("/register/bulk", authorize, middleware.Authorize(permission.UserManage, enforcer), authH.BulkRegister(enforcer))
 		v1.POST("/login", authH.Login)
+		v1.GET("/login/attempts", authorize, middleware.Authorize(permission.LoginManage, enforcer), authH.ViewLoginAttempts)
+		v1.POST("/login/2fa", twoFactorH.LoginTwoFactor)
+		v1.POST("/login/2fa/enrollment", twoFactorH.LoginEnrollmentTwoFactor)
+		v1.POST("/login/sso/authorization", ssoH.AuthorizationSSO)
+		v1.POST("/login/sso", ssoH.LoginSSO)
 		v1.GET("/validate_giro/:code", authH.ValidateGiro)
+		v1.POST("/onboarding", onboardingH.Onboard(enforcer))
+
+		// init password routes
+		password := v1.Group("/password", middleware.RateLimit(passwordLimiter))
+		{
+			password.POST("/forgot", passwordH.ForgotPassword)
+			password.POST("/reset", passwordH.ResetPassword)
+		}
+
+		v1.GET("/invitation/acceptance", invitationH.ViewInvitationByToken)
+		v1.POST("/invitation/acceptance", invitationH.AcceptanceInvitation(enforcer))
+
+		// init invitation routes
+		invitation := v1.Group("/invitation", authorize, middleware.Authorize(permission.InvitationManage, enforcer))
+		{
+			invitation.GET("", invitationH.ViewInvitations)
+			invitation.POST("", invitationH.AddInvitation)
+			invitation.POST("/:id/revocation", invitationH.RevocationInvitation)
+		}
+
+		// init onboarding routes
+		onboarding := v1.Group("/onboarding", authorize, middleware.Authorize(permission.OnboardingManage, enforcer))
+		{
+			onboarding.GET("/:company_id/steps", onboardingH.ViewOnboardingSteps)
+			onboarding.POST("/:company_id/approval", onboardingH.ApprovalCompany)
+			onboarding.POST("/:company_id/rejection", onboardingH.RejectionCompany)
+		}
 
 		// init open api
 		v1.GET("/token", authH.GetToken)
@@ -158,59 +422,139 @@ func main() {
 		v1.POST("/product/transaction", middleware.AuthorizeAPI(), productH.PariProductTransaction)
 
 		// init user routes
-		user := v1.Group("/user", middleware.AuthorizeJWT())
+		user := v1.Group("/user", authorize)
+		{
+			user.GET("", middleware.Authorize(permission.UserRead, enforcer), userH.ViewUsers)
+			user.POST("", middleware.Authorize(permission.UserManage, enforcer), userH.AddUser)
+			user.POST("/service_account", middleware.Authorize(permission.UserManage, enforcer), userH.AddServiceAccount)
+			user.PUT("/change_password/:id", middleware.SessionOnly(), userH.ChangePassword)
+			user.POST("/:id/unlock", middleware.Authorize(permission.LoginManage, enforcer), authH.UnlockUser)
+			user.POST("/:id/2fa/reset", middleware.Authorize(permission.LoginManage, enforcer), twoFactorH.ResetTwoFactor)
+			user.GET("/:id", middleware.Authorize(permission.UserRead, enforcer), userH.ViewUserId)
+			user.PUT("/:id", middleware.Authorize(permission.UserManage, enforcer), userH.EditUser)
+			user.PATCH("/:id", middleware.Authorize(permission.UserManage, enforcer), userH.PatchUser)
+			user.DELETE("/:id", middleware.Authorize(permission.UserManage, enforcer), userH.DeleteUser)
+			user.PUT("/:id/role", middleware.Authorize(permission.UserManage, enforcer), userH.AssignRole)
+			user.DELETE("/:id/role", middleware.Authorize(permission.UserManage, enforcer), userH.RevokeRole)
+			user.GET("/:id/permissions", middleware.Authorize(permission.UserRead, enforcer), userH.ViewPermissions)
+			user.GET("/:id/access_token", middleware.Authorize(permission.UserManage, enforcer), accessTokenH.ViewUserAccessTokens)
+			user.POST("/:id/access_token", middleware.SessionOnly(), middleware.Authorize(permission.UserManage, enforcer), accessTokenH.AddUserAccessToken)
+			user.DELETE("/:id/access_token/:token_id", middleware.Authorize(permission.UserManage, enforcer), accessTokenH.RevokeUserAccessToken)
+		}
+
+		// init two-factor routes
+		twoFactor := v1.Group("/2fa", middleware.AuthorizeJWT(userRepo))
+		{
+			twoFactor.GET("", twoFactorH.ViewTwoFactor)
+			twoFactor.POST("/enrollment", twoFactorH.EnrollmentTwoFactor)
+			twoFactor.POST("/enrollment/confirmation", twoFactorH.ConfirmationTwoFactor)
+			twoFactor.POST("/deactivation", twoFactorH.DeactivationTwoFactor)
+			twoFactor.POST("/recovery_codes", twoFactorH.RecoveryCodesTwoFactor)
+			twoFactor.PUT("/policy/company/:company_id", middleware.Authorize(permission.LoginManage, enforcer), twoFactorH.CompanyPolicyTwoFactor)
+			twoFactor.PUT("/policy/role/:role_id", middleware.Authorize(permission.LoginManage, enforcer), twoFactorH.RolePolicyTwoFactor)
+		}
+
+		// init access token routes, for which logging in is required
+		accessToken := v1.Group("/access_token", middleware.AuthorizeJWT(userRepo))
+		{
+			accessToken.GET("", accessTokenH.ViewAccessTokens)
+			accessToken.POST("", accessTokenH.AddAccessToken)
+			accessToken.DELETE("/:id", accessTokenH.RevokeAccessToken)
+		}
+
+		// init single sign-on routes
+		sso := v1.Group("/sso", authorize, middleware.Authorize(permission.LoginManage, enforcer))
 		{
-			user.GET("", middleware.Authorize("report", "read", enforcer), userH.ViewUsers)
-			user.POST("", middleware.Authorize("report", "write", enforcer), userH.AddUser)
-			user.PUT("/change_password/:id", userH.ChangePassword)
-			user.GET("/:id", middleware.Authorize("report", "read", enforcer), userH.ViewUserId)
-			user.PUT("/:id", middleware.Authorize("report", "write", enforcer), userH.EditUser)
-			user.DELETE("/:id", middleware.Authorize("report", "write", enforcer), userH.DeleteUser)
+			sso.GET("/company/:company_id", ssoH.ViewProvider)
+			sso.PUT("/company/:company_id", ssoH.EditProvider)
+			sso.DELETE("/company/:company_id", ssoH.DeleteProvider)
 		}
 
 		// init role routes
-		role := v1.Group("/role")
+		role := v1.Group("/role", authorize)
 		{
-			role.GET("", roleH.ViewRoles)
-			role.POST("", roleH.AddRole(enforcer))
-			role.GET("/:id", roleH.ViewRoleId)
-			role.PUT("/:id", roleH.EditRole)
-			role.DELETE("/:id", roleH.DeleteRole)
+			role.GET("", middleware.Authorize(permission.RoleRead, enforcer), roleH.ViewRoles)
+			role.POST("", middleware.Authorize(permission.RoleManage, enforcer), roleH.AddRole)
+			role.GET("/:id", middleware.Authorize(permission.RoleRead, enforcer), roleH.ViewRoleId)
+			role.PUT("/:id", middleware.Authorize(permission.RoleManage, enforcer), roleH.EditRole)
+			role.DELETE("/:id", middleware.Authorize(permission.RoleManage, enforcer), roleH.DeleteRole)
+			role.GET("/:id/permissions", middleware.Authorize(permission.RoleRead, enforcer), roleH.ViewPermissions)
+			role.PUT("/:id/permissions", middleware.Authorize(permission.RoleManage, enforcer), roleH.EditPermissions)
 		}
 
+		// init permission routes
+		v1.GET("/permission", authorize, middleware.Authorize(permission.RoleRead, enforcer), roleH.ViewCatalog)
+
 		// init company routes
-		company := v1.Group("/company")
+		company := v1.Group("/company", authorize)
+		{
+			company.GET("", middleware.Authorize(permission.CompanyRead, enforcer), companyH.ViewCompanies)
+			company.POST("", middleware.Authorize(permission.CompanyManage, enforcer), companyH.AddCompany)
+			company.PUT("/:id", middleware.Authorize(permission.CompanyManage, enforcer), companyH.EditCompany)
+			company.PATCH("/:id", middleware.Authorize(permission.CompanyManage, enforcer), companyH.PatchCompany)
+			company.DELETE("/:id", middleware.Authorize(permission.CompanyManage, enforcer), companyH.DeleteCompany)
+		}
+
+		// init giro routes
+		giro := v1.Group("/giro", authorize, middleware.Authorize(permission.GiroManage, enforcer))
 		{
-			company.GET("", companyH.ViewCompanies)
-			company.POST("", companyH.AddCompany)
-			company.PUT("/:id", companyH.EditCompany)
-			company.DELETE("/:id", companyH.DeleteCompany)
+			giro.GET("", giroH.ViewGiros)
+			giro.POST("", giroH.AddGiro)
+			giro.POST("/import", giroH.ImportGiros)
+			giro.GET("/:id", giroH.ViewGiroId)
+			giro.PUT("/:id", giroH.EditGiro)
+			giro.DELETE("/:id", giroH.DeleteGiro)
+		}
+
+		// init commodity routes
+		commodity := v1.Group("/commodity", authorize)
+		{
+			commodity.GET("", middleware.Authorize(permission.CommodityRead, enforcer), commodityH.ViewCommodities)
+			commodity.POST("", middleware.Authorize(permission.CommodityManage, enforcer), commodityH.AddCommodity)
+			commodity.GET("/:id", middleware.Authorize(permission.CommodityRead, enforcer), commodityH.ViewCommodityId)
+			commodity.PUT("/:id", middleware.Authorize(permission.CommodityManage, enforcer), commodityH.EditCommodity)
+			commodity.DELETE("/:id", middleware.Authorize(permission.CommodityManage, enforcer), commodityH.DeleteCommodity)
 		}
 
 		// init product routes
-		product := v1.Group("/product", middleware.AuthorizeJWT())
+		product := v1.Group("/product", authorize)
 		{
-			product.GET("", productH.ViewProducts)
-			product.GET("/company/:company_id", productH.ViewProductsBy)
-			product.POST("", productH.AddProduct)
-			product.GET("/:id", productH.ViewProductId)
-			product.GET("/summary/:company_id", productH.SummaryProduct)
-			product.PUT("/:id", productH.EditProduct)
-			product.DELETE("/:id", productH.DeleteProduct)
-			product.POST("/verification", productH.VerificationProduct)
+			product.GET("", middleware.Authorize(permission.ProductRead, enforcer), productH.ViewProducts)
+			product.GET("/company/:company_id", middleware.Authorize(permission.ProductRead, enforcer), productH.ViewProductsBy)
+			product.GET("/search", middleware.Authorize(permission.ProductRead, enforcer), productH.SearchProducts)
+			product.POST("", middleware.Authorize(permission.ProductCreate, enforcer), productH.AddProduct)
+			product.GET("/:id", middleware.Authorize(permission.ProductRead, enforcer), productH.ViewProductId)
+			product.GET("/summary/:company_id", middleware.Authorize(permission.ProductRead, enforcer), productH.SummaryProduct)
+			product.PUT("/:id", middleware.Authorize(permission.ProductUpdate, enforcer), productH.EditProduct)
+			product.PATCH("/:id", middleware.Authorize(permission.ProductUpdate, enforcer), productH.PatchProduct)
+			product.DELETE("/:id", middleware.Authorize(permission.ProductDelete, enforcer), productH.DeleteProduct)
+			product.POST("/verification", middleware.Authorize(permission.ProductVerify, enforcer), productH.VerificationProduct)
+			product.GET("/:id/prices", middleware.Authorize(permission.ProductRead, enforcer), productH.ViewProductPrices)
+			product.GET("/:id/revisions", middleware.Authorize(permission.ProductRead, enforcer), productH.ViewProductRevisions)
+			product.POST("/revision/verification", middleware.Authorize(permission.ProductVerify, enforcer), productH.VerificationProductRevision)
+			product.POST("/revision/rejection", middleware.Authorize(permission.ProductVerify, enforcer), productH.RejectionProductRevision)
 		}
 
 		// init transaction pre order routes
-		tpo := v1.Group("/transaction/preorder", middleware.AuthorizeJWT())
+		tpo := v1.Group("/transaction/preorder", authorize)
 		{
-			tpo.GET("", transactionPreOrderH.ViewTransactionPreOrders)
-			tpo.GET("/company/:company_id", transactionPreOrderH.ViewTransactionPreOrdersBy)
-			tpo.POST("", transactionPreOrderH.AddTransactionPreOrder)
-			tpo.GET("/:id", transactionPreOrderH.ViewTransactionPreOrderId)
-			tpo.GET("/summary/:company_id", transactionPreOrderH.SummaryTransactionPreOrder)
-			tpo.PUT("/:id", transactionPreOrderH.EditTransactionPreOrder)
-			tpo.DELETE("/:id", transactionPreOrderH.DeleteTransactionPreOrder)
-			tpo.POST("/verification", transactionPreOrderH.VerificationTransactionPreOrder)
+			tpo.GET("", middleware.Authorize(permission.PreorderRead, enforcer), transactionPreOrderH.ViewTransactionPreOrders)
+			tpo.GET("/company/:company_id", middleware.Authorize(permission.PreorderRead, enforcer), transactionPreOrderH.ViewTransactionPreOrdersBy)
+			tpo.POST("", middleware.Authorize(permission.PreorderCreate, enforcer), transactionPreOrderH.AddTransactionPreOrder)
+			tpo.GET("/:id", middleware.Authorize(permission.PreorderRead, enforcer), transactionPreOrderH.ViewTransactionPreOrderId)
+			tpo.GET("/summary/:company_id", middleware.Authorize(permission.PreorderRead, enforcer), transactionPreOrderH.SummaryTransactionPreOrder)
+			tpo.PUT("/:id", middleware.Authorize(permission.PreorderUpdate, enforcer), transactionPreOrderH.EditTransactionPreOrder)
+			tpo.PATCH("/:id", middleware.Authorize(permission.PreorderUpdate, enforcer), transactionPreOrderH.PatchTransactionPreOrder)
+			tpo.DELETE("/:id", middleware.Authorize(permission.PreorderDelete, enforcer), transactionPreOrderH.DeleteTransactionPreOrder)
+			tpo.POST("/verification", middleware.Authorize(permission.PreorderVerify, enforcer), transactionPreOrderH.VerificationTransactionPreOrder)
+		}
+
+		// init trash routes
+		trash := v1.Group("/trash", authorize, middleware.Authorize(permission.TrashManage, enforcer))
+		{
+			trash.GET("/:resource", trashH.ViewTrash)
+			trash.POST("/:resource/:id/restore", trashH.RestoreTrash)
+			trash.DELETE("/:resource/:id", trashH.PurgeTrash)
 		}
 	}
 
@@ -220,6 +564,5 @@ func main() {
 	err = router.Run(":" + port)
 	if err != nil {
 		helper.CommonLogger().Error(err)
-		helper.CommonLogger().Error(err)
 	}
 }
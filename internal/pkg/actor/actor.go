@@ -0,0 +1,41 @@
+// Package actor loads the users acting as admins on users, companies and roles.
+package actor
+
+import (
+	"context"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+)
+
+// Actor is a user acting as an admin. Superadmins act on every company, other admins on
+// their own.
+type Actor struct {
+	*model.User
+	Superadmin bool
+}
+
+// Load loads the user id acting as an admin along with their role.
+func Load(ctx context.Context, users user.Repository, roles role.Repository, id int) (*Actor, error) {
+	u, err := users.ReadById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ro, err := roles.ReadById(ctx, u.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	return &Actor{u, ro.IsSuperadmin()}, nil
+}
+
+// Sees tells whether the actor acts on the company.
+func (a *Actor) Sees(companyID int) bool {
+	return a.Superadmin || companyID == a.CompanyID
+}
+
+// Manages tells whether the actor manages the role: superadmins every role, other
+// admins the roles of their company but not those shared by all companies.
+func (a *Actor) Manages(r *model.Role) bool {
+	return a.Superadmin || r.CompanyID == a.CompanyID
+}
@@ -0,0 +1,43 @@
+package actor
+
+import (
+	"context"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	mock "bitbucket.org/bridce/ms-pari-web/internal/pkg/mock/repository"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// roles are the superadmin role and a role of company 7 that took its name.
+type roles struct {
+	role.Repository
+}
+
+func (roles) ReadById(_ context.Context, id int) (*model.Role, error) {
+	if id == 1 {
+		return &model.Role{ID: 1, Name: enum.RoleSuperadmin}, nil
+	}
+	return &model.Role{ID: id, Name: enum.RoleSuperadmin, CompanyID: 7}, nil
+}
+
+func TestLoad(t *testing.T) {
+	users := mock.NewMockRepository(gomock.NewController(t))
+	users.EXPECT().ReadById(gomock.Any(), 3).Return(&model.User{ID: 3, RoleID: 1}, nil)
+	users.EXPECT().ReadById(gomock.Any(), 4).Return(&model.User{ID: 4, RoleID: 2, CompanyID: 7}, nil)
+
+	a, err := Load(context.Background(), users, roles{}, 3)
+	require.NoError(t, err)
+	require.True(t, a.Superadmin)
+	require.True(t, a.Sees(8))
+
+	// a company role named superadmin is not the superadmin role
+	a, err = Load(context.Background(), users, roles{}, 4)
+	require.NoError(t, err)
+	require.False(t, a.Superadmin)
+	require.True(t, a.Sees(7))
+	require.False(t, a.Sees(8))
+}
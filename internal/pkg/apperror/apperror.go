@@ -0,0 +1,185 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+)
+
+type Kind string
+
+const (
+	KindBadRequest   Kind = "bad_request"
+	KindValidation   Kind = "validation"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+	KindNotFound     Kind = "not_found"
+	KindConflict     Kind = "conflict"
+	KindPrecondition Kind = "precondition"
+	KindRateLimited  Kind = "rate_limited"
+	KindUpstream     Kind = "upstream"
+	KindInternal     Kind = "internal"
+)
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is the domain error returned by repositories and usecases. Code is a stable,
+// machine readable identifier; Message is safe to show to the client.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Details []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap keeps err as the cause of e for logging; the cause is never sent to the client.
+func (e *Error) Wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// WithDetails attaches field level details to e.
+func (e *Error) WithDetails(details ...FieldError) *Error {
+	e.Details = append(e.Details, details...)
+	return e
+}
+
+// StatusCode returns the HTTP status code for the kind of e.
+func (e *Error) StatusCode() int {
+	switch e.Kind {
+	case KindBadRequest:
+		return http.StatusBadRequest
+	case KindValidation:
+		return http.StatusUnprocessableEntity
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindPrecondition:
+		return http.StatusPreconditionFailed
+	case KindRateLimited:
+		return http.StatusTooManyRequests
+	case KindUpstream:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func newError(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func BadRequest(code, message string) *Error {
+	return newError(KindBadRequest, code, message)
+}
+
+func Validation(code, message string) *Error {
+	return newError(KindValidation, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return newError(KindUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return newError(KindForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return newError(KindNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return newError(KindConflict, code, message)
+}
+
+// PreconditionFailed reports that the resource changed since the version given in If-Match.
+func PreconditionFailed(code, message string) *Error {
+	return newError(KindPrecondition, code, message)
+}
+
+// VersionMismatch reports that entity was modified after the client read the version it sent.
+func VersionMismatch(entity string) *Error {
+	return PreconditionFailed("version_mismatch", fmt.Sprintf("%s has been modified, reload it and try again", entity))
+}
+
+// RateLimited reports that the client sent too many requests and should retry later.
+func RateLimited(code, message string) *Error {
+	return newError(KindRateLimited, code, message)
+}
+
+func Upstream(code, message string) *Error {
+	return newError(KindUpstream, code, message)
+}
+
+func Internal(code, message string) *Error {
+	return newError(KindInternal, code, message)
+}
+
+// From returns err as a domain error, treating unknown errors as internal ones.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal("internal_error", "internal server error").Wrap(err)
+}
+
+// Is reports whether err is a domain error of the given kind.
+func Is(err error, kind Kind) bool {
+	var appErr *Error
+	return errors.As(err, &appErr) && appErr.Kind == kind
+}
+
+func IsNotFound(err error) bool {
+	return Is(err, KindNotFound)
+}
+
+// FromDB translates a database error about entity into a domain error. message is
+// used for failures that are not caused by the caller, e.g. "failed insert data".
+// Domain errors, e.g. returned from a transaction, are kept as they are.
+func FromDB(err error, entity, message string) error {
+	if err == nil {
+		return nil
+	}
+
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	if gorm.IsRecordNotFoundError(err) {
+		return NotFound(entity+"_not_found", fmt.Sprintf("%s is not exists", entity)).Wrap(err)
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return Conflict(entity+"_already_exists", fmt.Sprintf("%s already exists", entity)).Wrap(err)
+	}
+
+	return Internal("database_error", message).Wrap(err)
+}
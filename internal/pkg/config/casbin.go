@@ -0,0 +1,28 @@
+package config
+
+import "github.com/jinzhu/gorm"
+
+// migrateCasbinDomains scopes the casbin rules stored before roles were scoped per
+// company: policies are granted in every company, and users are grouped into their
+// role in the domain of their company. It has to run before the enforcer loads the
+// rules, which it cannot with fewer fields than the model has.
+func migrateCasbinDomains(db *gorm.DB) error {
+	if !db.HasTable("casbin_rule") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		// MySQL assigns from left to right, each column reading the one before it moved
+		err := tx.Exec("UPDATE casbin_rule SET v3 = v2, v2 = v1, v1 = '*' " +
+			"WHERE p_type = 'p' AND (v3 = '' OR v3 IS NULL)").Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec("UPDATE casbin_rule r JOIN users u ON r.v0 = CAST(u.id AS CHAR) " +
+			"SET r.v2 = CASE WHEN COALESCE(u.company_id, 0) = 0 THEN '*' ELSE CAST(u.company_id AS CHAR) END " +
+			"WHERE r.p_type = 'g' AND (r.v2 = '' OR r.v2 IS NULL)").Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec("DELETE FROM casbin_rule WHERE p_type = 'g' AND (v2 = '' OR v2 IS NULL)").Error
+	})
+}
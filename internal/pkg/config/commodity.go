@@ -0,0 +1,83 @@
+package config
+
+import (
+	"strings"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"github.com/jinzhu/gorm"
+)
+
+// defaultCommodities is the catalog a new database starts with.
+var defaultCommodities = []model.Commodity{
+	{Code: "PADI", Name: "padi", Category: enum.Pangan, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "BERAS", Name: "beras", Category: enum.Pangan, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "JAGUNG", Name: "jagung", Category: enum.Pangan, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "KEDELAI", Name: "kedelai", Category: enum.Pangan, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "CABAI", Name: "cabai", Category: enum.Hortikultura, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "BAWANG-MERAH", Name: "bawang merah", Category: enum.Hortikultura, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "BAWANG-PUTIH", Name: "bawang putih", Category: enum.Hortikultura, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "TEBU", Name: "tebu", Category: enum.Perkebunan, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "KOPI", Name: "kopi", Category: enum.Perkebunan, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "KAKAO", Name: "kakao", Category: enum.Perkebunan, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "SAWIT", Name: "sawit", Category: enum.Perkebunan, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "KARET", Name: "karet", Category: enum.Perkebunan, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "SAPI", Name: "sapi", Category: enum.Peternakan, UnitQuantity: "ekor", UnitPrice: "ekor"},
+	{Code: "AYAM", Name: "ayam", Category: enum.Peternakan, UnitQuantity: "ekor", UnitPrice: "ekor"},
+	{Code: "IKAN", Name: "ikan", Category: enum.Perikanan, UnitQuantity: "kg", UnitPrice: "kg"},
+	{Code: "UDANG", Name: "udang", Category: enum.Perikanan, UnitQuantity: "kg", UnitPrice: "kg"},
+}
+
+// migrateCommodities seeds the commodity catalog of an empty database and links the
+// products created before the catalog existed to it by their free-text commodity.
+// Values that match no name or code become new pangan commodities sold by the kg, for
+// an admin to review. Only products without a commodity_id are touched, deleted ones
+// included, so running it again does nothing.
+func migrateCommodities(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int
+		if err := tx.Model(&model.Commodity{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			for i := range defaultCommodities {
+				c := defaultCommodities[i]
+				if err := tx.Create(&c).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		var names []string
+		err := tx.Unscoped().Model(&model.Product{}).Where("commodity_id = 0 AND commodity <> ''").
+			Pluck("DISTINCT LOWER(TRIM(commodity))", &names).Error
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			var c model.Commodity
+			err := tx.Where("LOWER(name) = ? OR LOWER(code) = ?", name, name).First(&c).Error
+			if gorm.IsRecordNotFoundError(err) {
+				c = model.Commodity{
+					Code:         strings.ToUpper(strings.Join(strings.Fields(name), "-")),
+					Name:         name,
+					Category:     enum.Pangan,
+					UnitQuantity: "kg",
+					UnitPrice:    "kg",
+				}
+				err = tx.Create(&c).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			err = tx.Unscoped().Model(&model.Product{}).Where("commodity_id = 0 AND LOWER(TRIM(commodity)) = ?", name).
+				UpdateColumns(map[string]interface{}{"commodity_id": c.ID, "commodity": c.Name}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -12,6 +12,8 @@ import (
 
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/search"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jinzhu/gorm"
 )
@@ -31,15 +33,51 @@ func DbConnect(dbUser, dbPass, dbHost, dbPort, dbName string) *gorm.DB {
 		return nil
 	}
 
+	tracing.RegisterCallbacks(db)
+
 	db.Debug().AutoMigrate(
 		model.User{},
 		model.Role{},
 		model.Company{},
+		model.Commodity{},
 		model.Giro{},
+		model.OnboardingStep{},
+		model.Invitation{},
+		model.PasswordReset{},
+		model.PasswordHistory{},
+		model.LoginAttempt{},
+		model.TwoFactor{},
+		model.RecoveryCode{},
+		model.PolicyRevision{},
+		model.SSOProvider{},
+		model.SSOLogin{},
+		model.UserIdentity{},
+		model.AccessToken{},
 		model.Product{},
 		model.ProductUser{},
+		model.ProductPrice{},
+		model.ProductRevision{},
+		model.ProductRevisionUser{},
 		model.TransactionPreOrder{},
 		model.TransactionPreOrderUser{},
 	)
+	if err := migrateCommodities(db); err != nil {
+		helper.CommonLogger().WithError(err).Error("failed migrating product commodities")
+	}
+	if err := migratePricing(db); err != nil {
+		helper.CommonLogger().WithError(err).Error("failed migrating product prices and units")
+	}
+	if err := migrateRoles(db); err != nil {
+		helper.CommonLogger().WithError(err).Error("failed migrating roles per company")
+	}
+	if err := migrateCasbinDomains(db); err != nil {
+		helper.CommonLogger().WithError(err).Error("failed migrating casbin rules per company")
+	}
+	if err := migrateOnboarding(db); err != nil {
+		helper.CommonLogger().WithError(err).Error("failed migrating company onboarding")
+	}
+	if err := search.EnsureIndex(db); err != nil {
+		helper.CommonLogger().WithError(err).Error("failed creating product search index")
+	}
 	return db
 }
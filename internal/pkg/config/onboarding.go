@@ -0,0 +1,37 @@
+package config
+
+import (
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"github.com/jinzhu/gorm"
+)
+
+// migrateOnboarding creates the role of company admins and links the companies created
+// before onboarding existed to the giro account with their giro number, if there is
+// one. A giro shared by several companies is linked to the oldest. Only companies
+// without a giro_id are touched, so running it again does nothing.
+func migrateOnboarding(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(model.Role{Name: enum.RoleCompanyAdmin}).FirstOrCreate(&model.Role{}).Error; err != nil {
+			return err
+		}
+
+		var links []struct {
+			CompanyID int
+			GiroID    int
+		}
+		err := tx.Raw("SELECT MIN(c.id) AS company_id, g.id AS giro_id FROM companies c " +
+			"JOIN giros g ON g.code = c.giro AND g.deleted_at IS NULL " +
+			"WHERE c.giro_id IS NULL AND NOT EXISTS (SELECT 1 FROM companies l WHERE l.giro_id = g.id) " +
+			"GROUP BY g.id").Scan(&links).Error
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			if err := tx.Exec("UPDATE companies SET giro_id = ? WHERE id = ?", link.GiroID, link.CompanyID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"math"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/money"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/unit"
+	"github.com/jinzhu/gorm"
+)
+
+// migratePricing moves the floating point prices of the products and pre-orders made
+// before amounts were kept in minor units to the *_amount columns, turns free-text
+// units into unit codes and fills the price per base unit and pre-order totals. Every
+// step only touches rows it has not migrated yet.
+func migratePricing(db *gorm.DB) error {
+	scale := math.Pow10(money.Exponent(money.DefaultCurrency))
+
+	if db.Dialect().HasColumn("products", "price") {
+		err := db.Exec("UPDATE products SET price_amount = ROUND(price * ?), min_price_amount = ROUND(min_price * ?), max_price_amount = ROUND(max_price * ?)",
+			scale, scale, scale).Error
+		if err != nil {
+			return err
+		}
+		for _, column := range []string{"price", "min_price", "max_price"} {
+			if err := db.Model(&model.Product{}).DropColumn(column).Error; err != nil {
+				return err
+			}
+		}
+	}
+	if db.Dialect().HasColumn("transaction_pre_orders", "actual_price") {
+		err := db.Exec("UPDATE transaction_pre_orders SET actual_price_amount = ROUND(actual_price * ?)", scale).Error
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&model.TransactionPreOrder{}).DropColumn("actual_price").Error; err != nil {
+			return err
+		}
+	}
+
+	if err := migrateCommodityUnits(db); err != nil {
+		return err
+	}
+	if err := migrateProductUnits(db); err != nil {
+		return err
+	}
+	if err := migratePreOrderTotals(db); err != nil {
+		return err
+	}
+	return migratePriceHistory(db)
+}
+
+// migratePriceHistory starts the price history of the products that have none with
+// their current price, taken as synced when the product is on PARI.
+func migratePriceHistory(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO product_prices (product_id, price_amount, min_price_amount, max_price_amount, currency, unit_price, price_per_base_unit, effective_at, synced_at, created_at, updated_at)
+		SELECT p.id, p.price_amount, p.min_price_amount, p.max_price_amount, p.currency, p.unit_price, p.price_per_base_unit, p.updated_at,
+			CASE WHEN p.pari_product_id <> '' THEN p.updated_at END, NOW(), NOW()
+		FROM products p
+		WHERE NOT EXISTS (SELECT 1 FROM product_prices pp WHERE pp.product_id = p.id)`).Error
+}
+
+func migrateCommodityUnits(db *gorm.DB) error {
+	var commodities []model.Commodity
+	if err := db.Unscoped().Find(&commodities).Error; err != nil {
+		return err
+	}
+	for _, c := range commodities {
+		quantityUnit, known := unit.Parse(c.UnitQuantity)
+		priceUnit, priceKnown := unit.Parse(c.UnitPrice)
+		if !known || !priceKnown || (quantityUnit.Code == c.UnitQuantity && priceUnit.Code == c.UnitPrice) {
+			continue
+		}
+		err := db.Unscoped().Model(&c).UpdateColumns(map[string]interface{}{
+			"unit_quantity": quantityUnit.Code,
+			"unit_price":    priceUnit.Code,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateProductUnits(db *gorm.DB) error {
+	var products []model.Product
+	if err := db.Unscoped().Where("base_unit = ''").Find(&products).Error; err != nil {
+		return err
+	}
+	for _, p := range products {
+		quantityUnit, known := unit.Parse(p.UnitQuantity)
+		priceUnit, priceKnown := unit.Parse(p.UnitPrice)
+		if !known || !priceKnown || quantityUnit.Dimension != priceUnit.Dimension {
+			helper.CommonLogger().WithField("product_id", p.ID).Warnf("product units %q and %q are not known, fix them by hand", p.UnitQuantity, p.UnitPrice)
+			continue
+		}
+		base := priceUnit.Base()
+		amount, err := unit.PricePer(p.PriceAmount, priceUnit, base)
+		if err != nil {
+			return err
+		}
+		err = db.Unscoped().Model(&p).UpdateColumns(map[string]interface{}{
+			"unit_quantity":       quantityUnit.Code,
+			"unit_price":          priceUnit.Code,
+			"base_unit":           base.Code,
+			"price_per_base_unit": amount,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migratePreOrderTotals(db *gorm.DB) error {
+	var rows []struct {
+		ID                int
+		Quantity          int
+		ActualPriceAmount int64
+		UnitQuantity      string
+		UnitPrice         string
+		Currency          string
+	}
+	err := db.Table("transaction_pre_orders").
+		Select("transaction_pre_orders.id, transaction_pre_orders.quantity, transaction_pre_orders.actual_price_amount, p.unit_quantity, p.unit_price, p.currency").
+		Joins("JOIN products p ON p.id = transaction_pre_orders.product_id").
+		Where("transaction_pre_orders.total_amount = 0 AND transaction_pre_orders.actual_price_amount > 0 AND p.base_unit <> ''").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		quantityUnit, _ := unit.Lookup(row.UnitQuantity)
+		priceUnit, _ := unit.Lookup(row.UnitPrice)
+		total, err := unit.Total(row.ActualPriceAmount, priceUnit, row.Quantity, quantityUnit)
+		if err != nil {
+			return err
+		}
+		err = db.Table("transaction_pre_orders").Where("id = ?", row.ID).
+			UpdateColumns(map[string]interface{}{"total_amount": total, "currency": row.Currency}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
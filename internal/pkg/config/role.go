@@ -0,0 +1,15 @@
+package config
+
+import "github.com/jinzhu/gorm"
+
+// migrateRoles drops the unique index on roles.name, the name of a role being unique
+// within its company only since companies define their own roles.
+func migrateRoles(db *gorm.DB) error {
+	var count int
+	err := db.Raw("SELECT COUNT(*) FROM information_schema.statistics " +
+		"WHERE table_schema = DATABASE() AND table_name = 'roles' AND index_name = 'name'").Row().Scan(&count)
+	if err != nil || count == 0 {
+		return err
+	}
+	return db.Exec("ALTER TABLE roles DROP INDEX name").Error
+}
@@ -0,0 +1,38 @@
+package credential
+
+import "time"
+
+// Lockout is how long an account waits after failed logins. The first Free failures
+// cost nothing, the next ones a delay doubling from Delay, and Threshold of them lock
+// the account for Duration, until it logs in or an admin unlocks it. Whatever the
+// accounts, an IP failing IPFailures logins within IPWindow waits for them to age.
+type Lockout struct {
+	Free       int
+	Delay      time.Duration
+	Threshold  int
+	Duration   time.Duration
+	IPFailures int
+	IPWindow   time.Duration
+}
+
+// DefaultLockout is the lockout when none is configured.
+var DefaultLockout = Lockout{Free: 3, Delay: time.Second, Threshold: 10, Duration: 15 * time.Minute, IPFailures: 50, IPWindow: 15 * time.Minute}
+
+// Wait is how long an account that failed failures logins in a row waits before the
+// next try.
+func (l Lockout) Wait(failures int) time.Duration {
+	switch {
+	case failures >= l.Threshold:
+		return l.Duration
+	case failures <= l.Free:
+		return 0
+	}
+	wait := l.Delay
+	for i := l.Free + 1; i < failures && wait < l.Duration; i++ {
+		wait *= 2
+	}
+	if wait > l.Duration {
+		return l.Duration
+	}
+	return wait
+}
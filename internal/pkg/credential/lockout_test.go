@@ -0,0 +1,18 @@
+package credential
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockoutWait(t *testing.T) {
+	l := Lockout{Free: 2, Delay: time.Second, Threshold: 6, Duration: 5 * time.Second}
+
+	waits := make([]time.Duration, 0, 8)
+	for failures := 0; failures < 8; failures++ {
+		waits = append(waits, l.Wait(failures))
+	}
+	require.Equal(t, []time.Duration{0, 0, 0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, waits)
+}
@@ -0,0 +1,96 @@
+package credential
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+)
+
+// maxBytes is the most bcrypt hashes, anything after is ignored.
+const maxBytes = 72
+
+// Policy is what a new password must satisfy.
+type Policy struct {
+	// MinLength is the least number of characters.
+	MinLength int
+	// MinClasses is the least number of classes, among lowercase, uppercase, digits and
+	// symbols, the characters are of.
+	MinClasses int
+	// History is how many of the last passwords of a user cannot be used again.
+	History int
+	// Breached are known leaked passwords, lowercased.
+	Breached map[string]struct{}
+}
+
+// DefaultPolicy is the policy when none is configured.
+var DefaultPolicy = Policy{MinLength: 8, MinClasses: 2, History: 5}
+
+// Check tells why password does not satisfy the policy, the reuse of previous passwords
+// aside.
+func (p Policy) Check(password string) error {
+	switch {
+	case utf8.RuneCountInString(password) < p.MinLength:
+		return weak(fmt.Sprintf("must be at least %d characters", p.MinLength))
+	case len(password) > maxBytes:
+		return weak(fmt.Sprintf("must be at most %d bytes", maxBytes))
+	case classes(password) < p.MinClasses:
+		return weak(fmt.Sprintf("must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses))
+	}
+	if _, ok := p.Breached[strings.ToLower(password)]; ok {
+		return weak("is a known leaked password, choose another one")
+	}
+	return nil
+}
+
+func classes(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+func weak(message string) error {
+	return apperror.Validation("weak_password", "password does not meet the password policy").WithDetails(apperror.FieldError{
+		Field:   "password",
+		Message: message,
+	})
+}
+
+// LoadBreached reads a list of leaked passwords, one a line. Blank lines and lines
+// starting with # are skipped.
+func LoadBreached(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	breached := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breached[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return breached, nil
+}
@@ -0,0 +1,50 @@
+package credential
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	p := Policy{MinLength: 8, MinClasses: 3, Breached: map[string]struct{}{"passw0rd!": {}}}
+
+	tests := []struct {
+		password string
+		message  string
+	}{
+		{"Sh0rt!", "must be at least 8 characters"},
+		{strings.Repeat("Aa1!", 19), "must be at most 72 bytes"},
+		{"lowercase1", "must mix at least 3 of lowercase letters, uppercase letters, digits and symbols"},
+		{"PASSW0RD!", "is a known leaked password, choose another one"},
+		{"Lowercase1", ""},
+		{"kata sandi 1", ""},
+	}
+	for _, tt := range tests {
+		err := p.Check(tt.password)
+		if tt.message == "" {
+			require.NoError(t, err, tt.password)
+			continue
+		}
+		var appErr *apperror.Error
+		require.ErrorAs(t, err, &appErr, tt.password)
+		require.Equal(t, "weak_password", appErr.Code)
+		require.Equal(t, []apperror.FieldError{{Field: "password", Message: tt.message}}, appErr.Details)
+	}
+}
+
+func TestLoadBreached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# leaked\nPassword1\n\n  qwerty123 \n"), 0o600))
+
+	breached, err := LoadBreached(path)
+	require.NoError(t, err)
+	require.Equal(t, map[string]struct{}{"password1": {}, "qwerty123": {}}, breached)
+
+	_, err = LoadBreached(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
@@ -0,0 +1,114 @@
+// Package credential is the one place passwords are checked, hashed and verified.
+package credential
+
+import (
+	"context"
+	"fmt"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/password_history"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Service interface {
+	// Hash checks the password of a new user against the policy and hashes it.
+	Hash(password string) (string, error)
+	// Change checks the new password of u against the policy and the passwords u had,
+	// and hashes it.
+	Change(ctx context.Context, u *model.User, password string) (string, error)
+	// Verify tells whether password is the one of u. The stored hash is upgraded when it
+	// was made with other parameters than the current ones. A nil u, e.g. for an unknown
+	// email, is never verified but takes as long.
+	Verify(ctx context.Context, u *model.User, password string) bool
+}
+
+type service struct {
+	policy                    Policy
+	cost                      int
+	userRepository            user.Repository
+	passwordHistoryRepository password_history.Repository
+	// dummy is compared against when there is no user to verify
+	dummy []byte
+}
+
+// NewService hashes passwords with bcrypt at cost, which can be raised at any time:
+// hashes are upgraded as users log in.
+func NewService(policy Policy, cost int, userRepository user.Repository, passwordHistoryRepository password_history.Repository) (Service, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cost)
+	}
+	dummy, err := bcrypt.GenerateFromPassword([]byte("dummy password"), cost)
+	if err != nil {
+		return nil, err
+	}
+	return &service{policy, cost, userRepository, passwordHistoryRepository, dummy}, nil
+}
+
+func (e *service) Hash(password string) (string, error) {
+	if err := e.policy.Check(password); err != nil {
+		return "", err
+	}
+	return e.hash(password)
+}
+
+func (e *service) hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), e.cost)
+	if err != nil {
+		return "", apperror.Internal("hash_error", "failed hashing password").Wrap(err)
+	}
+	return string(hash), nil
+}
+
+func (e *service) Change(ctx context.Context, u *model.User, password string) (string, error) {
+	if err := e.policy.Check(password); err != nil {
+		return "", err
+	}
+	if e.policy.History > 0 {
+		histories, err := e.passwordHistoryRepository.ReadLatest(ctx, u.ID, e.policy.History)
+		if err != nil {
+			return "", err
+		}
+		// users from before the history was kept only have their current password
+		previous := []string{u.Password}
+		for _, h := range histories {
+			if h.Password != u.Password {
+				previous = append(previous, h.Password)
+			}
+		}
+		if len(previous) > e.policy.History {
+			previous = previous[:e.policy.History]
+		}
+		for _, hash := range previous {
+			if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+				return "", weak(fmt.Sprintf("must not be one of your last %d passwords", e.policy.History))
+			}
+		}
+	}
+	return e.hash(password)
+}
+
+func (e *service) Verify(ctx context.Context, u *model.User, password string) bool {
+	if u == nil {
+		_ = bcrypt.CompareHashAndPassword(e.dummy, []byte(password))
+		return false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil {
+		return false
+	}
+	if cost, err := bcrypt.Cost([]byte(u.Password)); err == nil && cost != e.cost {
+		hash, err := e.hash(password)
+		if err == nil {
+			err = e.userRepository.UpdatePasswordHash(ctx, u.ID, u.Password, hash)
+		}
+		if err != nil {
+			// the old hash still works, the upgrade is tried again on the next login
+			helper.Logger(ctx).WithError(err).Warn("[credentialService.Verify] failed upgrading password hash")
+		} else {
+			u.Password = hash
+		}
+	}
+	return true
+}
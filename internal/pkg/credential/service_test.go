@@ -0,0 +1,80 @@
+package credential
+
+import (
+	"context"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	mock "bitbucket.org/bridce/ms-pari-web/internal/pkg/mock/repository"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// histories is a password history, latest first.
+type histories []model.PasswordHistory
+
+func (h histories) ReadLatest(_ context.Context, _, limit int) ([]model.PasswordHistory, error) {
+	if len(h) > limit {
+		return h[:limit], nil
+	}
+	return h, nil
+}
+
+func hash(t *testing.T, password string) string {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	return string(h)
+}
+
+func TestNewServiceCost(t *testing.T) {
+	_, err := NewService(DefaultPolicy, bcrypt.MaxCost+1, nil, nil)
+	require.Error(t, err)
+}
+
+func TestChangeReuse(t *testing.T) {
+	current := hash(t, "current-1")
+	s, err := NewService(Policy{MinLength: 8, History: 3}, bcrypt.MinCost, nil, histories{
+		{Password: current},
+		{Password: hash(t, "previous-1")},
+		{Password: hash(t, "previous-2")},
+		{Password: hash(t, "previous-3")},
+	})
+	require.NoError(t, err)
+	u := &model.User{ID: 7, Password: current}
+
+	for _, password := range []string{"current-1", "previous-1", "previous-2"} {
+		_, err := s.Change(context.Background(), u, password)
+		var appErr *apperror.Error
+		require.ErrorAs(t, err, &appErr, password)
+		require.Equal(t, "must not be one of your last 3 passwords", appErr.Details[0].Message)
+	}
+
+	// older than the last 3
+	h, err := s.Change(context.Background(), u, "previous-3")
+	require.NoError(t, err)
+	require.NoError(t, bcrypt.CompareHashAndPassword([]byte(h), []byte("previous-3")))
+}
+
+func TestVerifyUpgradesCost(t *testing.T) {
+	users := mock.NewMockRepository(gomock.NewController(t))
+	s, err := NewService(DefaultPolicy, bcrypt.MinCost+1, users, nil)
+	require.NoError(t, err)
+	old := hash(t, "secret-pass")
+	u := &model.User{ID: 7, Password: old}
+
+	require.False(t, s.Verify(context.Background(), u, "wrong-pass"))
+	require.Equal(t, old, u.Password)
+
+	users.EXPECT().UpdatePasswordHash(gomock.Any(), 7, old, gomock.Any()).Return(nil)
+	require.True(t, s.Verify(context.Background(), u, "secret-pass"))
+	cost, err := bcrypt.Cost([]byte(u.Password))
+	require.NoError(t, err)
+	require.Equal(t, bcrypt.MinCost+1, cost)
+
+	// already at the current cost
+	require.True(t, s.Verify(context.Background(), u, "secret-pass"))
+
+	require.False(t, s.Verify(context.Background(), nil, "secret-pass"))
+}
@@ -5,11 +5,12 @@ type PARI int
 const (
 	CreateProduct PARI = iota + 1
 	DetailProduct
+	UpdateProduct
 )
 
 // String - Creating common behavior - give the type a String function
 func (p PARI) String() string {
-	return [...]string{"/product/create", "/product/detail"}[p-1]
+	return [...]string{"/product/create", "/product/detail", "/product/update"}[p-1]
 }
 
 // EnumIndex - Creating common behavior - give the type a EnumIndex function
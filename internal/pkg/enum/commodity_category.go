@@ -0,0 +1,11 @@
+package enum
+
+type CommodityCategory string
+
+const (
+	Pangan       CommodityCategory = "pangan"
+	Hortikultura CommodityCategory = "hortikultura"
+	Perkebunan   CommodityCategory = "perkebunan"
+	Peternakan   CommodityCategory = "peternakan"
+	Perikanan    CommodityCategory = "perikanan"
+)
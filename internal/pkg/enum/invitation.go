@@ -0,0 +1,9 @@
+package enum
+
+type StatusInvitation string
+
+const (
+	InvitationPending  StatusInvitation = "pending"
+	InvitationAccepted StatusInvitation = "accepted"
+	InvitationRevoked  StatusInvitation = "revoked"
+)
@@ -0,0 +1,20 @@
+package enum
+
+// LoginResult is the outcome of a login attempt, as audited.
+type LoginResult string
+
+const (
+	LoginSucceeded       LoginResult = "succeeded"
+	LoginUnknownEmail    LoginResult = "unknown_email"
+	LoginWrongPassword   LoginResult = "wrong_password"
+	LoginLocked          LoginResult = "locked"
+	LoginIPThrottled     LoginResult = "ip_throttled"
+	LoginCompanyPending  LoginResult = "company_pending"
+	LoginCompanyRejected LoginResult = "company_rejected"
+	// the password was right, a TOTP code is awaited
+	LoginTwoFactorPending LoginResult = "two_factor_pending"
+	LoginTwoFactorFailed  LoginResult = "two_factor_failed"
+	// logins with the OpenID Connect provider of the company
+	LoginSSOSucceeded LoginResult = "sso_succeeded"
+	LoginSSORejected  LoginResult = "sso_rejected"
+)
@@ -0,0 +1,19 @@
+package enum
+
+type StatusCompany string
+
+const (
+	CompanyPending  StatusCompany = "pending"
+	CompanyApproved StatusCompany = "approved"
+	CompanyRejected StatusCompany = "rejected"
+)
+
+type OnboardingStep string
+
+const (
+	StepGiroValidated   OnboardingStep = "giro_validated"
+	StepCompanyCreated  OnboardingStep = "company_created"
+	StepAdminRegistered OnboardingStep = "admin_registered"
+	StepCompanyApproved OnboardingStep = "company_approved"
+	StepCompanyRejected OnboardingStep = "company_rejected"
+)
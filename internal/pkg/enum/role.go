@@ -0,0 +1,8 @@
+package enum
+
+const (
+	// RoleSuperadmin is the role of the administrators of the whole platform.
+	RoleSuperadmin = "superadmin"
+	// RoleCompanyAdmin is the role of the user who onboarded a company.
+	RoleCompanyAdmin = "admin"
+)
@@ -0,0 +1,200 @@
+package access_token
+
+import (
+	"strconv"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/access_token"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler interface {
+	ViewAccessTokens(c *gin.Context)
+	AddAccessToken(c *gin.Context)
+	RevokeAccessToken(c *gin.Context)
+	ViewUserAccessTokens(c *gin.Context)
+	AddUserAccessToken(c *gin.Context)
+	RevokeUserAccessToken(c *gin.Context)
+}
+
+type handler struct {
+	usecase access_token.Usecase
+}
+
+func NewHandler(uc access_token.Usecase) Handler {
+	return &handler{uc}
+}
+
+// ViewAccessTokens godoc
+// @Summary Find All own access tokens
+// @Schemes
+// @Description list the personal access tokens of the current user, revoked and expired ones included, newest first.
+// @Tags Access Token
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} helper.Response{data=[]response.AccessToken}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 401 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /access_token [get]
+func (e *handler) ViewAccessTokens(c *gin.Context) {
+	e.view(c, c.GetInt("userID"))
+}
+
+// AddAccessToken godoc
+// @Summary Add own access token
+// @Schemes
+// @Description create a personal access token of the current user, for scripts to send as the Authorization header instead of a JWT. The token is only returned now.
+// @Description It is granted no more than its scopes, which have to be permissions the user holds, until it expires after expires_in_days (ACCESS_TOKEN_MAX_DAYS at most) or is revoked.
+// @Tags Access Token
+// @Accept  json
+// @Produce  json
+// @Param        token  body      request.AccessToken  true  "Add access token"
+// @Success 200 {object} helper.Response{data=response.AccessToken}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 401 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /access_token [post]
+func (e *handler) AddAccessToken(c *gin.Context) {
+	e.add(c, c.GetInt("userID"))
+}
+
+// RevokeAccessToken godoc
+// @Summary Revoke own access token
+// @Schemes
+// @Description stop a personal access token of the current user authenticating. It is kept listed.
+// @Tags Access Token
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Access token ID"
+// @Success 200 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 401 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /access_token/{id} [delete]
+func (e *handler) RevokeAccessToken(c *gin.Context) {
+	e.revoke(c, c.GetInt("userID"), c.Param("id"))
+}
+
+// ViewUserAccessTokens godoc
+// @Summary Find All access tokens of a user
+// @Schemes
+// @Description list the personal access tokens of a user, revoked and expired ones included, newest first. Admins other than superadmins list those of the users of their own company.
+// @Tags Access Token
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Success 200 {object} helper.Response{data=[]response.AccessToken}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /user/{id}/access_token [get]
+func (e *handler) ViewUserAccessTokens(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	e.view(c, id)
+}
+
+// AddUserAccessToken godoc
+// @Summary Add access token of a service account
+// @Schemes
+// @Description create a personal access token of a service account, which is only returned now. Its scopes have to be permissions the service account holds.
+// @Description Admins other than superadmins create those of the service accounts of their own company, scoped to permissions they hold too, and not those of superadmin service accounts. Access tokens cannot create tokens, log in to do it.
+// @Tags Access Token
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param        token  body      request.AccessToken  true  "Add access token"
+// @Success 200 {object} helper.Response{data=response.AccessToken}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /user/{id}/access_token [post]
+func (e *handler) AddUserAccessToken(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	e.add(c, id)
+}
+
+// RevokeUserAccessToken godoc
+// @Summary Revoke access token of a user
+// @Schemes
+// @Description stop a personal access token of a user authenticating, e.g. one that leaked. Admins other than superadmins revoke those of the users of their own company.
+// @Tags Access Token
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param token_id path string true "Access token ID"
+// @Success 200 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /user/{id}/access_token/{token_id} [delete]
+func (e *handler) RevokeUserAccessToken(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	e.revoke(c, id, c.Param("token_id"))
+}
+
+func (e *handler) view(c *gin.Context, userID int) {
+	tokens, err := e.usecase.ReadAll(c.Request.Context(), c.GetInt("userID"), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewAccessTokens(*tokens))
+}
+
+func (e *handler) add(c *gin.Context, userID int) {
+	var r request.AccessToken
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	token, raw, err := e.usecase.Create(c.Request.Context(), c.GetInt("userID"), userID, r)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	res := response.NewAccessToken(token)
+	res.Token = raw
+	helper.HandleSuccess(c, res)
+}
+
+func (e *handler) revoke(c *gin.Context, userID int, param string) {
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+
+	if err := e.usecase.Revoke(c.Request.Context(), c.GetInt("userID"), userID, id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, nil)
+}
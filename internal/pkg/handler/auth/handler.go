@@ -7,21 +7,26 @@
 package auth
 
 import (
-	"fmt"
-	"net/http"
+	"strconv"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/auth"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
 	"github.com/casbin/casbin"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler interface {
-	Register(enforcer *casbin.Enforcer) gin.HandlerFunc
-	BulkRegister(enforcer *casbin.Enforcer) gin.HandlerFunc
+	Register(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc
+	BulkRegister(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc
 	Login(c *gin.Context)
+	ViewLoginAttempts(c *gin.Context)
+	UnlockUser(c *gin.Context)
 	ValidateGiro(c *gin.Context)
 	GetToken(c *gin.Context)
 }
@@ -42,35 +47,30 @@ func NewHandler(uc auth.Usecase) Handler {
 // @Accept json
 // @Produce json
 // @Param        user  body      request.User  true  "Register"
-// @Success 201 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 201 {object} helper.Response{data=response.User}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Security BearerAuth
 // @Router /register [post]
-func (e *handler) Register(enforcer *casbin.Enforcer) gin.HandlerFunc {
+func (e *handler) Register(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var user request.User
-		err := c.Bind(&user)
+		err := c.ShouldBind(&user)
 		if err != nil {
-			helper.CommonLogger().Error(err)
-			helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+			_ = c.Error(validation.FromBind(err))
 			return
 		}
 
-		if user.Email == "" {
-			helper.HandleError(c, http.StatusBadRequest, "column cannot be empty")
-			return
-		}
-
-		newUser, err := e.usecase.Register(user)
+		newUser, err := e.usecase.Register(c.Request.Context(), user)
 		if err != nil {
-			helper.CommonLogger().Error(err)
-			helper.HandleError(c, http.StatusInternalServerError, err.Error())
+			_ = c.Error(err)
 			return
 		}
 
-		enforcer.AddGroupingPolicy(fmt.Sprint(newUser.ID), newUser.RoleName)
-		newUser.Password = ""
-		helper.HandleSuccess(c, newUser)
+		permission.Assign(enforcer, newUser.ID, newUser.RoleName, permission.Domain(newUser.CompanyID))
+		helper.HandleSuccess(c, response.NewUser(newUser))
 	}
 }
 
@@ -82,96 +82,173 @@ func (e *handler) Register(enforcer *casbin.Enforcer) gin.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param        users  body      request.Users  true  "Register"
-// @Success 201 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 201 {object} helper.Response{data=[]response.User}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Security BearerAuth
 // @Router /register/bulk [post]
-func (e *handler) BulkRegister(enforcer *casbin.Enforcer) gin.HandlerFunc {
+func (e *handler) BulkRegister(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var users request.Users
-		err := c.Bind(&users)
+		err := c.ShouldBind(&users)
 		if err != nil {
-			helper.CommonLogger().Error(err)
-			helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+			_ = c.Error(validation.FromBind(err))
 			return
 		}
 
-		newUsers, err := e.usecase.BulkRegister(users)
+		newUsers, err := e.usecase.BulkRegister(c.Request.Context(), users)
 		if err != nil {
-			helper.CommonLogger().Error(err)
-			helper.HandleError(c, http.StatusInternalServerError, err.Error())
+			_ = c.Error(err)
 			return
 		}
 
 		for _, newUser := range newUsers {
-			enforcer.AddGroupingPolicy(fmt.Sprint(newUser.ID), newUser.RoleName)
+			permission.Assign(enforcer, newUser.ID, newUser.RoleName, permission.Domain(newUser.CompanyID))
 		}
 
-		helper.HandleSuccess(c, newUsers)
+		result := make([]*response.User, 0, len(newUsers))
+		for _, newUser := range newUsers {
+			result = append(result, response.NewUser(newUser))
+		}
+
+		helper.HandleSuccess(c, result)
 	}
 }
 
 // Login godoc
 // @Summary Login
 // @Schemes
-// @Description login
+// @Description login. An unknown email and a wrong password fail the same way. Accounts failing in a row wait longer and longer before the next try, then are locked for a while,
+// @Description failing meanwhile like a wrong password so their emails cannot be found out. IPs failing too often are throttled with 429 login_throttled.
+// @Description Users logging in with a second factor get a challenge_token instead of a token, to send along with their code to /login/2fa,
+// @Description after enrolling at /login/2fa/enrollment first when two_factor_enrollment.
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param        login  body      request.Login  true  "Login"
-// @Success 201 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 201 {object} helper.Response{data=response.Token}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 401 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 429 {object} helper.ErrorResponse
 // @Router /login [post]
 func (e *handler) Login(c *gin.Context) {
-	var user = model.User{}
-	err := c.Bind(&user)
+	var login request.Login
+	err := c.ShouldBind(&login)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
+	login.IP = c.ClientIP()
+	login.UserAgent = c.Request.UserAgent()
 
-	dbUser, err := e.usecase.Login(&user)
+	dbUser, challenge, err := e.usecase.Login(c.Request.Context(), login)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-
-	isTrue := helper.ComparePassword(dbUser.Password, user.Password)
-	if !isTrue {
-		helper.HandleError(c, http.StatusInternalServerError, "Password not matched")
+	if challenge != nil {
+		helper.HandleSuccess(c, response.Token{
+			MustChangePassword:  dbUser.MustChangePassword,
+			TwoFactorRequired:   true,
+			TwoFactorEnrollment: challenge.Enroll,
+			ChallengeToken:      challenge.Token,
+		})
 		return
 	}
 
 	token := helper.GenerateToken(dbUser)
-	result := map[string]interface{}{"token": token, "must_change_password": dbUser.MustChangePassword}
-	helper.HandleSuccess(c, result)
+	helper.HandleSuccess(c, response.Token{Token: token, MustChangePassword: dbUser.MustChangePassword})
+}
+
+// ViewLoginAttempts godoc
+// @Summary Find All login attempts
+// @Schemes
+// @Description find the audited login attempts, those of the users of their own company for admins other than superadmins.
+// @Description Filterable fields: email, user_id, company_id, ip, success, result, created_at.
+// @Tags Auth
+// @Accept  json
+// @Produce  json
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. -created_at"
+// @Param   search    query    string  false        "Prefix of email"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.LoginAttempt}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /login/attempts [get]
+func (e *handler) ViewLoginAttempts(c *gin.Context) {
+	q, err := query.Parse(c.Request.URL.Query(), request.LoginAttemptQuery)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	attempts, page, err := e.usecase.ReadLoginAttempts(c.Request.Context(), c.GetInt("userID"), q)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandlePagedSuccess(c, response.NewLoginAttempts(*attempts), page)
+}
+
+// UnlockUser godoc
+// @Summary Unlock user
+// @Schemes
+// @Description forget the failed logins of a user, who can log in again right away. Admins other than superadmins unlock the users of their own company.
+// @Tags Auth
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Success 200 {object} helper.Response{data=response.User}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /user/{id}/unlock [post]
+func (e *handler) UnlockUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	u, err := e.usecase.Unlock(c.Request.Context(), c.GetInt("userID"), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewUser(u))
 }
 
 // ValidateGiro godoc
 // @Summary Find giro by code
 // @Schemes
-// @Description find giro by code
+// @Description find giro by code, with the id and status of the company onboarded with it if there is one
 // @Tags Auth
 // @Accept  json
 // @Produce  json
 // @Param code path string true "Giro Code"
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 200 {object} helper.Response{data=response.Giro}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
 // @Router /validate_giro/{code} [get]
 func (e *handler) ValidateGiro(c *gin.Context) {
 	code := c.Param("code")
 
-	r, err := e.usecase.ValidateGiro(code)
+	r, err := e.usecase.ValidateGiro(c.Request.Context(), code)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, r)
+	helper.HandleSuccess(c, response.NewGiro(r))
 }
 
 // GetToken godoc
@@ -182,16 +259,15 @@ func (e *handler) ValidateGiro(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
 // @Router /token [get]
 func (e *handler) GetToken(c *gin.Context) {
 	clientKey := c.GetHeader("CLIENT_KEY")
 	secretKey := c.GetHeader("SECRET_KEY")
-	r, err := e.usecase.GetToken(clientKey, secretKey)
+	r, err := e.usecase.GetToken(c.Request.Context(), clientKey, secretKey)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
 	helper.HandleSuccess(c, r)
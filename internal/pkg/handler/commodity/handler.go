@@ -0,0 +1,206 @@
+package commodity
+
+import (
+	"strconv"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/commodity"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler interface {
+	AddCommodity(c *gin.Context)
+	ViewCommodityId(c *gin.Context)
+	ViewCommodities(c *gin.Context)
+	EditCommodity(c *gin.Context)
+	DeleteCommodity(c *gin.Context)
+}
+
+type handler struct {
+	usecase commodity.Usecase
+}
+
+func NewHandler(uc commodity.Usecase) Handler {
+	return &handler{uc}
+}
+
+// AddCommodity godoc
+// @Summary Add new commodity
+// @Schemes
+// @Description add a commodity to the catalog
+// @Tags Commodity
+// @Accept json
+// @Produce json
+// @Param        commodity  body      request.Commodity  true  "Add commodity"
+// @Success 201 {object} helper.Response{data=response.Commodity}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /commodity [post]
+func (e *handler) AddCommodity(c *gin.Context) {
+	var r request.Commodity
+	err := c.ShouldBind(&r)
+	if err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	newCommodity, err := e.usecase.Create(c.Request.Context(), &r)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.SetETag(c, newCommodity.Version)
+	helper.HandleSuccess(c, response.NewCommodity(newCommodity))
+}
+
+// ViewCommodities godoc
+// @Summary Find All commodity
+// @Schemes
+// @Description find commodities
+// @Description Filters are field=value or field[op]=value with op one of in, gte, lte and between, e.g. category[in]=pangan,hortikultura.
+// @Description Filterable fields: code, name, category, is_active, created_at.
+// @Tags Commodity
+// @Accept  json
+// @Produce  json
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. name"
+// @Param   search    query    string  false        "Prefix of name or code"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.Commodity}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /commodity [get]
+func (e *handler) ViewCommodities(c *gin.Context) {
+	q, err := query.Parse(c.Request.URL.Query(), request.CommodityQuery)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	commodities, page, err := e.usecase.ReadAllBy(c.Request.Context(), q)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandlePagedSuccess(c, response.NewCommodities(*commodities), page)
+}
+
+// ViewCommodityId godoc
+// @Summary Find commodity by id
+// @Schemes
+// @Description find commodity by id
+// @Tags Commodity
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Commodity ID"
+// @Success 200 {object} helper.Response{data=response.Commodity}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /commodity/{id} [get]
+func (e *handler) ViewCommodityId(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	commodityModel, err := e.usecase.ReadById(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.SetETag(c, commodityModel.Version)
+	helper.HandleSuccess(c, response.NewCommodity(commodityModel))
+}
+
+// EditCommodity godoc
+// @Summary update commodity by id
+// @Schemes
+// @Description update commodity by id. Products of the commodity follow a change of its name.
+// @Tags Commodity
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Commodity ID"
+// @Param If-Match header string false "ETag of the commodity"
+// @Param        commodity  body      request.Commodity  true  "Update commodity"
+// @Success 200 {object} helper.Response{data=response.Commodity}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 412 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /commodity/{id} [put]
+func (e *handler) EditCommodity(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	version, err := helper.IfMatchVersion(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var r request.Commodity
+	err = c.ShouldBind(&r)
+	if err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	updatedCommodity, err := e.usecase.Update(c.Request.Context(), id, version, &r)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.SetETag(c, updatedCommodity.Version)
+	helper.HandleSuccess(c, response.NewCommodity(updatedCommodity))
+}
+
+// DeleteCommodity godoc
+// @Summary Delete commodity by id
+// @Schemes
+// @Description delete a commodity no product refers to
+// @Tags Commodity
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Commodity ID"
+// @Success 200 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /commodity/{id} [delete]
+func (e *handler) DeleteCommodity(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	err = e.usecase.Delete(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, "success delete data")
+}
@@ -1,12 +1,16 @@
 package company
 
 import (
-	"net/http"
 	"strconv"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/patch"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/company"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,6 +19,7 @@ type Handler interface {
 	ViewCompanyId(c *gin.Context)
 	ViewCompanies(c *gin.Context)
 	EditCompany(c *gin.Context)
+	PatchCompany(c *gin.Context)
 	DeleteCompany(c *gin.Context)
 }
 
@@ -34,59 +39,62 @@ func NewHandler(uc company.Usecase) Handler {
 // @Accept json
 // @Produce json
 // @Param        company  body      request.Company  true  "Add company"
-// @Success 201 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 201 {object} helper.Response{data=response.Company}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Security BearerAuth
 // @Router /company [post]
 func (e *handler) AddCompany(c *gin.Context) {
-	var companyModel = model.Company{}
-	err := c.Bind(&companyModel)
+	var r request.Company
+	err := c.ShouldBind(&r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
-		return
-	}
-	if companyModel.ID != 0 {
-		helper.HandleError(c, http.StatusBadRequest, "input not permitted")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
 
-	if companyModel.Name == "" {
-		helper.HandleError(c, http.StatusBadRequest, "column cannot be empty")
-		return
-	}
-	newCompany, err := e.usecase.Create(&companyModel)
+	newCompany, err := e.usecase.Create(c.Request.Context(), &r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, newCompany)
+	helper.HandleSuccess(c, response.NewCompany(newCompany))
 }
 
 // ViewCompanies godoc
 // @Summary Find All company
 // @Schemes
-// @Description find all company
+// @Description find companies
+// @Description Filters are field=value or field[op]=value with op one of in, gte, lte and between, e.g. created_at[between]=2022-06-01,2022-06-30.
+// @Description Filterable fields: name, code, giro, created_at.
 // @Tags Company
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. name"
+// @Param   search    query    string  false        "Prefix of name, code or alias"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.Company}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Security BearerAuth
 // @Router /company [get]
 func (e *handler) ViewCompanies(c *gin.Context) {
-	companys, err := e.usecase.ReadAll()
+	q, err := query.Parse(c.Request.URL.Query(), request.CompanyQuery)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	if len(*companys) == 0 {
-		helper.HandleError(c, http.StatusNotFound, "list company is empty")
+	companies, page, err := e.usecase.ReadAllBy(c.Request.Context(), q)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, companys)
+	helper.HandlePagedSuccess(c, response.NewCompanies(*companies), page)
 }
 
 // ViewCompanyId FindCompany godoc
@@ -97,25 +105,24 @@ func (e *handler) ViewCompanies(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Company ID"
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 200 {object} helper.Response{data=response.Company}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
 // @Router /company/{id} [get]
 func (e *handler) ViewCompanyId(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	companyModel, err := e.usecase.ReadById(id)
+	companyModel, err := e.usecase.ReadById(c.Request.Context(), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, companyModel)
+	helper.SetETag(c, companyModel.Version)
+	helper.HandleSuccess(c, response.NewCompany(companyModel))
 }
 
 // EditCompany UpdateCompany godoc
@@ -126,47 +133,91 @@ func (e *handler) ViewCompanyId(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Company ID"
+// @Param If-Match header string false "ETag of the company"
 // @Param        company  body      request.Company  true  "Update company"
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 200 {object} helper.Response{data=response.Company}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 412 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Security BearerAuth
 // @Router /company/{id} [put]
 func (e *handler) EditCompany(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	current, err := e.usecase.ReadById(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if err = helper.CheckIfMatch(c, current.Version); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var r request.Company
+	err = c.ShouldBind(&r)
+	if err != nil {
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
-	_, err = e.usecase.ReadById(id)
+
+	updatedCompany, err := e.usecase.Update(c.Request.Context(), id, &r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	var tempCompany = model.Company{}
-	err = c.Bind(&tempCompany)
+	helper.SetETag(c, updatedCompany.Version)
+	helper.HandleSuccess(c, response.NewCompany(updatedCompany))
+}
+
+// PatchCompany godoc
+// @Summary partially update company by id
+// @Schemes
+// @Description apply a JSON Merge Patch (RFC 7396) to a company: only the given fields change and null resets a field.
+// @Description Send the ETag of the company in If-Match to get 412 when it was modified since it was read.
+// @Tags Company
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Company ID"
+// @Param If-Match header string false "ETag of the company"
+// @Param        patch  body      request.Company  true  "Merge patch"
+// @Success 200 {object} helper.Response{data=response.Company}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 412 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /company/{id} [patch]
+func (e *handler) PatchCompany(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	if tempCompany.ID != 0 {
-		helper.HandleError(c, http.StatusBadRequest, "input not permitted")
+	version, err := helper.IfMatchVersion(c)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	if tempCompany.Name == "" {
-		helper.HandleError(c, http.StatusBadRequest, "column cannot be empty")
+	doc, err := patch.Parse(c.Request.Body)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	updatedCompany, err := e.usecase.Update(id, &tempCompany)
+	patched, err := e.usecase.Patch(c.Request.Context(), id, version, doc)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, updatedCompany)
+	helper.SetETag(c, patched.Version)
+	helper.HandleSuccess(c, response.NewCompany(patched))
 }
 
 // DeleteCompany godoc
@@ -178,21 +229,21 @@ func (e *handler) EditCompany(c *gin.Context) {
 // @Produce  json
 // @Param id path string true "Company ID"
 // @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Security BearerAuth
 // @Router /company/{id} [delete]
 func (e *handler) DeleteCompany(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	err = e.usecase.Delete(id)
+	err = e.usecase.Delete(c.Request.Context(), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
 	helper.HandleSuccess(c, "success delete data")
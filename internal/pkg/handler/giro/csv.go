@@ -0,0 +1,67 @@
+package giro
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+)
+
+// maxImportRows is the most giros a single import takes.
+const maxImportRows = 10000
+
+// readGiros reads giros from a CSV file whose header names its columns: code and
+// company_name, and optionally is_active.
+func readGiros(r io.Reader) (request.Giros, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, apperror.BadRequest("invalid_csv", "file is empty")
+	}
+	if err != nil {
+		return nil, apperror.BadRequest("invalid_csv", "file is not a valid csv").Wrap(err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range []string{"code", "company_name"} {
+		if _, ok := columns[name]; !ok {
+			return nil, apperror.BadRequest("invalid_csv", fmt.Sprintf("column %s is missing", name))
+		}
+	}
+
+	giros := make(request.Giros, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, apperror.BadRequest("invalid_csv", "file is not a valid csv").Wrap(err)
+		}
+		if len(giros) == maxImportRows {
+			return nil, apperror.BadRequest("invalid_csv", fmt.Sprintf("file has more than %d giros", maxImportRows))
+		}
+
+		giro := request.Giro{
+			Code:        strings.TrimSpace(record[columns["code"]]),
+			CompanyName: strings.TrimSpace(record[columns["company_name"]]),
+		}
+		if i, ok := columns["is_active"]; ok && strings.TrimSpace(record[i]) != "" {
+			isActive, err := strconv.ParseBool(strings.TrimSpace(record[i]))
+			if err != nil {
+				return nil, apperror.BadRequest("invalid_csv", fmt.Sprintf("line %d: is_active must be true or false", len(giros)+2))
+			}
+			giro.IsActive = &isActive
+		}
+		giros = append(giros, giro)
+	}
+	return giros, nil
+}
@@ -0,0 +1,30 @@
+package giro
+
+import (
+	"strings"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadGiros(t *testing.T) {
+	giros, err := readGiros(strings.NewReader("Company_Name,code,is_active\nPT Tani Makmur, 123456789012345,\nCV Padi,123456789012346,false\n"))
+	require.NoError(t, err)
+	require.Len(t, giros, 2)
+	require.Equal(t, "123456789012345", giros[0].Code)
+	require.Equal(t, "PT Tani Makmur", giros[0].CompanyName)
+	require.Nil(t, giros[0].IsActive)
+	require.NotNil(t, giros[1].IsActive)
+	require.False(t, *giros[1].IsActive)
+}
+
+func TestReadGirosMissingColumn(t *testing.T) {
+	_, err := readGiros(strings.NewReader("code\n123456789012345\n"))
+	require.True(t, apperror.Is(err, apperror.KindBadRequest))
+}
+
+func TestReadGirosInvalidActive(t *testing.T) {
+	_, err := readGiros(strings.NewReader("code,company_name,is_active\n123456789012345,PT Tani,ya\n"))
+	require.EqualError(t, err, "line 2: is_active must be true or false")
+}
@@ -0,0 +1,258 @@
+package giro
+
+import (
+	"strconv"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/giro"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type Handler interface {
+	AddGiro(c *gin.Context)
+	ViewGiroId(c *gin.Context)
+	ViewGiros(c *gin.Context)
+	EditGiro(c *gin.Context)
+	DeleteGiro(c *gin.Context)
+	ImportGiros(c *gin.Context)
+}
+
+type handler struct {
+	usecase giro.Usecase
+}
+
+func NewHandler(uc giro.Usecase) Handler {
+	return &handler{uc}
+}
+
+// AddGiro godoc
+// @Summary Add new giro
+// @Schemes
+// @Description add a giro account a company can onboard with
+// @Tags Giro
+// @Accept json
+// @Produce json
+// @Param        giro  body      request.Giro  true  "Add giro"
+// @Success 201 {object} helper.Response{data=response.Giro}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /giro [post]
+func (e *handler) AddGiro(c *gin.Context) {
+	var r request.Giro
+	err := c.ShouldBind(&r)
+	if err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	newGiro, err := e.usecase.Create(c.Request.Context(), &r)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.SetETag(c, newGiro.Version)
+	helper.HandleSuccess(c, response.NewGiro(newGiro))
+}
+
+// ViewGiros godoc
+// @Summary Find All giro
+// @Schemes
+// @Description find giro accounts
+// @Description Filters are field=value or field[op]=value with op one of in, gte, lte and between, e.g. is_active=false.
+// @Description Filterable fields: code, company_name, is_active, created_at.
+// @Tags Giro
+// @Accept  json
+// @Produce  json
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. company_name"
+// @Param   search    query    string  false        "Prefix of code or company_name"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.Giro}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /giro [get]
+func (e *handler) ViewGiros(c *gin.Context) {
+	q, err := query.Parse(c.Request.URL.Query(), request.GiroQuery)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	giros, page, err := e.usecase.ReadAllBy(c.Request.Context(), q)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandlePagedSuccess(c, response.NewGiros(*giros), page)
+}
+
+// ViewGiroId godoc
+// @Summary Find giro by id
+// @Schemes
+// @Description find giro by id, with the id and status of the company onboarded with it if there is one
+// @Tags Giro
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Giro ID"
+// @Success 200 {object} helper.Response{data=response.Giro}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /giro/{id} [get]
+func (e *handler) ViewGiroId(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	giroModel, err := e.usecase.ReadById(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.SetETag(c, giroModel.Version)
+	helper.HandleSuccess(c, response.NewGiro(giroModel))
+}
+
+// EditGiro godoc
+// @Summary update giro by id
+// @Schemes
+// @Description update giro by id. Set is_active to false to deactivate a giro, which then cannot onboard a company.
+// @Description The code of a giro that onboarded a company cannot change.
+// @Tags Giro
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Giro ID"
+// @Param If-Match header string false "ETag of the giro"
+// @Param        giro  body      request.Giro  true  "Update giro"
+// @Success 200 {object} helper.Response{data=response.Giro}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 412 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /giro/{id} [put]
+func (e *handler) EditGiro(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	version, err := helper.IfMatchVersion(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var r request.Giro
+	err = c.ShouldBind(&r)
+	if err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	updatedGiro, err := e.usecase.Update(c.Request.Context(), id, version, &r)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.SetETag(c, updatedGiro.Version)
+	helper.HandleSuccess(c, response.NewGiro(updatedGiro))
+}
+
+// DeleteGiro godoc
+// @Summary Delete giro by id
+// @Schemes
+// @Description delete a giro that onboarded no company
+// @Tags Giro
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Giro ID"
+// @Success 200 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /giro/{id} [delete]
+func (e *handler) DeleteGiro(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	err = e.usecase.Delete(c.Request.Context(), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, "success delete data")
+}
+
+// ImportGiros godoc
+// @Summary Import giro from CSV
+// @Schemes
+// @Description create or update giro accounts from a CSV file whose header names the columns code, company_name and optionally is_active.
+// @Description A giro whose code exists gets the company_name and is_active of the file. Nothing is imported if a row is invalid; errors refer to rows by index, the first row after the header being [0].
+// @Tags Giro
+// @Accept multipart/form-data
+// @Produce json
+// @Param   file formData file true  "CSV file"
+// @Success 200 {object} helper.Response{data=response.GiroImport}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /giro/import [post]
+func (e *handler) ImportGiros(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("missing_file", "file is required").Wrap(err))
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_file", "file cannot be read").Wrap(err))
+		return
+	}
+	defer file.Close()
+
+	giros, err := readGiros(file)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if err := binding.Validator.ValidateStruct(giros); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	created, updated, err := e.usecase.Import(c.Request.Context(), giros)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.GiroImport{Created: created, Updated: updated})
+}
@@ -0,0 +1,186 @@
+package invitation
+
+import (
+	"strconv"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/invitation"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
+	"github.com/casbin/casbin"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler interface {
+	AddInvitation(c *gin.Context)
+	ViewInvitations(c *gin.Context)
+	RevocationInvitation(c *gin.Context)
+	ViewInvitationByToken(c *gin.Context)
+	AcceptanceInvitation(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc
+}
+
+type handler struct {
+	usecase invitation.Usecase
+}
+
+func NewHandler(uc invitation.Usecase) Handler {
+	return &handler{uc}
+}
+
+// AddInvitation godoc
+// @Summary Invite user
+// @Schemes
+// @Description email a one-time link to register with a role and company, the invitee choosing their own password. The link expires after INVITATION_TTL_HOURS.
+// @Description A pending invitation to the same email is revoked. Admins other than superadmins invite to their own company.
+// @Tags Invitation
+// @Accept json
+// @Produce json
+// @Param        invitation  body      request.Invitation  true  "Invitation"
+// @Success 200 {object} helper.Response{data=response.Invitation}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 502 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /invitation [post]
+func (e *handler) AddInvitation(c *gin.Context) {
+	var r request.Invitation
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	inv, err := e.usecase.Invite(c.Request.Context(), c.GetInt("userID"), &r)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewInvitation(inv))
+}
+
+// ViewInvitations godoc
+// @Summary Find All invitation
+// @Schemes
+// @Description find invitations, those to their own company for admins other than superadmins. A pending invitation past expires_at has the status expired.
+// @Description Filterable fields: email, company_id, role_id, status, expires_at, created_at.
+// @Tags Invitation
+// @Accept  json
+// @Produce  json
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. -created_at"
+// @Param   search    query    string  false        "Prefix of email"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.Invitation}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /invitation [get]
+func (e *handler) ViewInvitations(c *gin.Context) {
+	q, err := query.Parse(c.Request.URL.Query(), request.InvitationQuery)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	invitations, page, err := e.usecase.ReadAllBy(c.Request.Context(), c.GetInt("userID"), q)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandlePagedSuccess(c, response.NewInvitations(*invitations), page)
+}
+
+// RevocationInvitation godoc
+// @Summary Revoke invitation
+// @Schemes
+// @Description revoke a pending invitation, its link can no longer be used
+// @Tags Invitation
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} helper.Response{data=response.Invitation}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /invitation/{id}/revocation [post]
+func (e *handler) RevocationInvitation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	inv, err := e.usecase.Revoke(c.Request.Context(), c.GetInt("userID"), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewInvitation(inv))
+}
+
+// ViewInvitationByToken godoc
+// @Summary Find invitation by token
+// @Schemes
+// @Description find the invitation of a link, as long as it can be accepted
+// @Tags Invitation
+// @Accept  json
+// @Produce  json
+// @Param token query string true "Token of the invitation link"
+// @Success 200 {object} helper.Response{data=response.Invitation}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Router /invitation/acceptance [get]
+func (e *handler) ViewInvitationByToken(c *gin.Context) {
+	inv, err := e.usecase.ReadByToken(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewInvitation(inv))
+}
+
+// AcceptanceInvitation godoc
+// @Summary Accept invitation
+// @Schemes
+// @Description register with the role and company of an invitation and a password of one's own. The link cannot be used again.
+// @Tags Invitation
+// @Accept json
+// @Produce json
+// @Param        acceptance  body      request.InvitationAcceptance  true  "Acceptance"
+// @Success 200 {object} helper.Response{data=response.User}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Router /invitation/acceptance [post]
+func (e *handler) AcceptanceInvitation(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var r request.InvitationAcceptance
+		if err := c.ShouldBind(&r); err != nil {
+			_ = c.Error(validation.FromBind(err))
+			return
+		}
+
+		newUser, err := e.usecase.Accept(c.Request.Context(), r)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		permission.Assign(enforcer, newUser.ID, newUser.RoleName, permission.Domain(newUser.CompanyID))
+		helper.HandleSuccess(c, response.NewUser(newUser))
+	}
+}
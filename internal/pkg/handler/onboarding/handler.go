@@ -0,0 +1,169 @@
+package onboarding
+
+import (
+	"strconv"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/onboarding"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
+	"github.com/casbin/casbin"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler interface {
+	Onboard(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc
+	ViewOnboardingSteps(c *gin.Context)
+	ApprovalCompany(c *gin.Context)
+	RejectionCompany(c *gin.Context)
+}
+
+type handler struct {
+	usecase onboarding.Usecase
+}
+
+func NewHandler(uc onboarding.Usecase) Handler {
+	return &handler{uc}
+}
+
+// Onboard godoc
+// @Summary Onboard company
+// @Schemes
+// @Description sign up the company of a giro account. The first time, a pending company is created from the giro, and the first user registered for it becomes its admin.
+// @Description Its users can log in once a superadmin approved the company.
+// @Tags Onboarding
+// @Accept json
+// @Produce json
+// @Param        onboarding  body      request.Onboarding  true  "Onboarding"
+// @Success 200 {object} helper.Response{data=response.Onboarding}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Router /onboarding [post]
+func (e *handler) Onboard(enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var o request.Onboarding
+		if err := c.ShouldBind(&o); err != nil {
+			_ = c.Error(validation.FromBind(err))
+			return
+		}
+
+		company, admin, err := e.usecase.Register(c.Request.Context(), o)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+
+		permission.Assign(enforcer, admin.ID, admin.RoleName, permission.Domain(admin.CompanyID))
+		helper.HandleSuccess(c, response.Onboarding{Company: response.NewCompany(company), User: response.NewUser(admin)})
+	}
+}
+
+// ViewOnboardingSteps godoc
+// @Summary Find onboarding steps of a company
+// @Schemes
+// @Description find the steps a company took to onboard: giro_validated, company_created, admin_registered, then company_approved or company_rejected.
+// @Description Filterable fields: step, created_at.
+// @Tags Onboarding
+// @Accept  json
+// @Produce  json
+// @Param company_id path string true "Company ID"
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. -created_at"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.OnboardingStep}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /onboarding/{company_id}/steps [get]
+func (e *handler) ViewOnboardingSteps(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("company_id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	q, err := query.Parse(c.Request.URL.Query(), request.OnboardingStepQuery)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	steps, page, err := e.usecase.Steps(c.Request.Context(), id, q)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandlePagedSuccess(c, response.NewOnboardingSteps(*steps), page)
+}
+
+// ApprovalCompany godoc
+// @Summary Approve onboarded company
+// @Schemes
+// @Description approve a pending company so its users can log in. Pending companies are found with GET /company?status=pending.
+// @Tags Onboarding
+// @Accept  json
+// @Produce  json
+// @Param company_id path string true "Company ID"
+// @Success 200 {object} helper.Response{data=response.Company}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /onboarding/{company_id}/approval [post]
+func (e *handler) ApprovalCompany(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("company_id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	company, err := e.usecase.Approve(c.Request.Context(), id, c.GetInt("userID"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewCompany(company))
+}
+
+// RejectionCompany godoc
+// @Summary Reject onboarded company
+// @Schemes
+// @Description reject a pending company; its users cannot log in and the giro cannot onboard again.
+// @Tags Onboarding
+// @Accept  json
+// @Produce  json
+// @Param company_id path string true "Company ID"
+// @Param        rejection  body      request.OnboardingRejection  true  "Rejection"
+// @Success 200 {object} helper.Response{data=response.Company}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /onboarding/{company_id}/rejection [post]
+func (e *handler) RejectionCompany(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("company_id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	var rejection request.OnboardingRejection
+	if err := c.ShouldBind(&rejection); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+	company, err := e.usecase.Reject(c.Request.Context(), id, c.GetInt("userID"), rejection.Reason)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewCompany(company))
+}
@@ -0,0 +1,75 @@
+package password
+
+import (
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/password"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler interface {
+	ForgotPassword(c *gin.Context)
+	ResetPassword(c *gin.Context)
+}
+
+type handler struct {
+	usecase password.Usecase
+}
+
+func NewHandler(uc password.Usecase) Handler {
+	return &handler{uc}
+}
+
+// ForgotPassword godoc
+// @Summary Forgot password
+// @Schemes
+// @Description email a one-time link to reset the password, expiring after PASSWORD_RESET_TTL_MINUTES. The response is the same whether the email is registered or not.
+// @Description Only the latest link sent can be used, and at most 3 links an hour are sent to a user.
+// @Tags Password
+// @Accept json
+// @Produce json
+// @Param        forgot  body      request.PasswordForgot  true  "Email"
+// @Success 200 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 429 {object} helper.ErrorResponse
+// @Router /password/forgot [post]
+func (e *handler) ForgotPassword(c *gin.Context) {
+	var r request.PasswordForgot
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	e.usecase.Forgot(c.Request.Context(), r.Email)
+	helper.HandleSuccess(c, "if the email is registered, a reset link was sent to it")
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Schemes
+// @Description set a new password with the token of a reset link. The link cannot be used again and every session of the user is revoked, they log in with the new password.
+// @Tags Password
+// @Accept json
+// @Produce json
+// @Param        reset  body      request.PasswordReset  true  "Reset"
+// @Success 200 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 429 {object} helper.ErrorResponse
+// @Router /password/reset [post]
+func (e *handler) ResetPassword(c *gin.Context) {
+	var r request.PasswordReset
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	if err := e.usecase.Reset(c.Request.Context(), r); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, "password was reset, log in with the new password")
+}
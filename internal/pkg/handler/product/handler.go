@@ -1,17 +1,19 @@
 package product
 
 import (
-	"fmt"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"time"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/patch"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/product"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,10 +22,16 @@ type Handler interface {
 	ViewProductId(c *gin.Context)
 	ViewProducts(c *gin.Context)
 	ViewProductsBy(c *gin.Context)
+	SearchProducts(c *gin.Context)
 	EditProduct(c *gin.Context)
+	PatchProduct(c *gin.Context)
 	DeleteProduct(c *gin.Context)
 	SummaryProduct(c *gin.Context)
 	VerificationProduct(c *gin.Context)
+	ViewProductPrices(c *gin.Context)
+	ViewProductRevisions(c *gin.Context)
+	VerificationProductRevision(c *gin.Context)
+	RejectionProductRevision(c *gin.Context)
 	PariProductTransaction(c *gin.Context)
 }
 
@@ -43,9 +51,11 @@ func NewHandler(uc product.Usecase) Handler {
 // @Accept multipart/form-data
 // @Param   file formData file false  "Upload Image"
 // @Param        product  formData      request.Product  true  "Add product"
-// @Success 201 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 201 {object} helper.Response{data=response.Product}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /product [post]
 func (e *handler) AddProduct(c *gin.Context) {
@@ -53,8 +63,7 @@ func (e *handler) AddProduct(c *gin.Context) {
 
 	err := c.ShouldBind(&productModel)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
 
@@ -72,113 +81,134 @@ func (e *handler) AddProduct(c *gin.Context) {
 
 	tmpFile := path + filename
 	if err = c.SaveUploadedFile(file, tmpFile); err != nil {
-		helper.HandleError(c, http.StatusBadRequest, "failed to saving image")
+		_ = c.Error(apperror.Internal("image_upload_failed", "failed to saving image").Wrap(err))
 		return
 	}
 
 	productModel.Image = "image/" + filename
 	productModel.TmpImagePath = tmpFile
+	productModel.CompanyID = c.GetInt("companyID")
 
-	if productModel.Name == "" {
-		helper.HandleError(c, http.StatusBadRequest, "column cannot be empty")
-		return
-	}
-
-	newProduct, err := e.usecase.Create(&productModel)
-	fmt.Println(err)
+	newProduct, err := e.usecase.Create(c.Request.Context(), &productModel)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
 
-	helper.HandleSuccess(c, newProduct)
+	helper.HandleSuccess(c, response.NewProduct(newProduct))
 }
 
 // ViewProducts godoc
 // @Summary Find All product
 // @Schemes
-// @Description find all product
+// @Description find products
+// @Description Filters are field=value or field[op]=value with op one of in, gte, lte and between, e.g. created_at[between]=2022-06-01,2022-06-30.
+// @Description Filterable fields: name, status, commodity, commodity_id, company_id, is_pre_order, is_active, quantity, price_amount, min_price_amount, max_price_amount, currency, unit_quantity, unit_price, base_unit, price_per_base_unit, product_created_at, expired_at, created_at, updated_at.
 // @Tags Product
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. -created_at,name"
+// @Param   search    query    string  false        "Words of name, description or commodity, or prefix of name"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.Product}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /product [get]
 func (e *handler) ViewProducts(c *gin.Context) {
-	products, err := e.usecase.ReadAll()
+	q, err := query.Parse(c.Request.URL.Query(), request.ProductQuery)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-
-	//if len(*products) == 0 {
-	//	helper.HandleError(c, http.StatusNotFound, "list product is empty")
-	//	return
-	//}
-
-	helper.HandleSuccess(c, products)
+	products, page, err := e.usecase.ReadAllBy(c.Request.Context(), q)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandlePagedSuccess(c, response.NewProducts(*products), page)
 }
 
 // ViewProductsBy godoc
 // @Summary Find All product by Company ID
 // @Schemes
-// @Description find all product by company id
-// @Param company_id path string true "Company ID"
-// @Param   page     query    int     false        "Page"
-// @Param   size      query    int     false        "Size"
-// @Param   status      query    string     false        "Status"
-// @Param   commodity      query    string     false        "Commodity"
-// @Param   search      query    string     false        "Search"
+// @Description find products of a company
+// @Description Filters are field=value or field[op]=value with op one of in, gte, lte and between, e.g. created_at[between]=2022-06-01,2022-06-30.
+// @Description Filterable fields: name, status, commodity, commodity_id, is_pre_order, is_active, quantity, price_amount, min_price_amount, max_price_amount, currency, unit_quantity, unit_price, base_unit, price_per_base_unit, product_created_at, expired_at, created_at, updated_at.
 // @Tags Product
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} helper.ResponsePaged
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Param company_id path string true "Company ID"
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. -created_at,name"
+// @Param   search    query    string  false        "Words of name, description or commodity, or prefix of name"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.Product}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /product/company/{company_id} [get]
 func (e *handler) ViewProductsBy(c *gin.Context) {
-	var req request.ProductPaged
-	var err error
-
-	err = c.ShouldBindUri(&req)
+	companyID, err := strconv.Atoi(c.Param("company_id"))
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(apperror.BadRequest("invalid_company_id", "company id has be number").Wrap(err))
 		return
 	}
-
-	err = c.ShouldBindQuery(&req)
+	q, err := query.Parse(c.Request.URL.Query(), request.ProductQuery)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(err)
 		return
 	}
-
-	companyIDStr := c.Param("company_id")
-	companyID, err := strconv.Atoi(companyIDStr)
+	q.Where("company_id", companyID)
+	products, page, err := e.usecase.ReadAllBy(c.Request.Context(), q)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "company id has be number")
+		_ = c.Error(err)
 		return
 	}
+	helper.HandlePagedSuccess(c, response.NewProducts(*products), page)
+}
 
-	req.CompanyID = companyID
-
-	products, err := e.usecase.ReadAllBy(req)
+// SearchProducts godoc
+// @Summary Search product
+// @Schemes
+// @Description full-text search on product name, description and commodity, ranked by relevance.
+// @Description Every word must match, as a prefix, a commodity synonym or, for misspelled words, a similar word.
+// @Description Accepts the filters of GET /product; facets count the matching products by commodity and status.
+// @Tags Product
+// @Accept  json
+// @Produce  json
+// @Param   q         query    string  true         "Search words"
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   sort      query    string  false        "Comma separated fields to sort by instead of relevance, e.g. -created_at"
+// @Success 200 {object} helper.ResponsePaged{data=response.ProductSearch}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /product/search [get]
+func (e *handler) SearchProducts(c *gin.Context) {
+	var req request.ProductSearch
+	if err := c.ShouldBindQuery(&req); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+	q, err := query.Parse(c.Request.URL.Query(), request.ProductQuery)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-
-	countProducts := e.usecase.Count(req)
-
-	helper.HandlePagedSuccess(c, products, req.Page, req.Size, countProducts)
+	result, page, err := e.usecase.Search(c.Request.Context(), req.Q, q)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandlePagedSuccess(c, response.NewProductSearch(result), page)
 }
 
 // ViewProductId FindProduct godoc
@@ -190,9 +220,9 @@ func (e *handler) ViewProductsBy(c *gin.Context) {
 // @Produce  json
 // @Param id path string true "Product ID"
 // @Param   user_id      query    int     false        "User ID"
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 200 {object} helper.Response{data=response.ProductDetail}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /product/{id} [get]
 func (e *handler) ViewProductId(c *gin.Context) {
@@ -201,78 +231,119 @@ func (e *handler) ViewProductId(c *gin.Context) {
 
 	err = c.ShouldBindUri(&req)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
 
 	err = c.ShouldBindQuery(&req)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
 
-	productModel, err := e.usecase.ReadBy(req)
+	productModel, err := e.usecase.ReadBy(c.Request.Context(), req)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
 
-	helper.HandleSuccess(c, productModel)
+	helper.SetETag(c, productModel.Product.Version)
+	helper.HandleSuccess(c, response.NewProductDetail(productModel))
 }
 
 // EditProduct UpdateProduct godoc
 // @Summary update product by id
 // @Schemes
 // @Description update product by id
+// @Description The edit of an approved product does not apply at once: it becomes the pending_revision of the product until verifiers approve it, see POST /product/revision/verification.
 // @Tags Product
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Product ID"
-// @Param        product  body      request.Product  true  "Update product"
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Param If-Match header string false "ETag of the product"
+// @Param        product  body      request.UpdateProduct  true  "Update product"
+// @Success 200 {object} helper.Response{data=response.Product}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
 // @Security BearerAuth
+// @Failure 412 {object} helper.ErrorResponse
 // @Router /product/{id} [put]
 func (e *handler) EditProduct(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	_, err = e.usecase.ReadById(id)
+	current, err := e.usecase.ReadById(c.Request.Context(), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	var tempProduct = model.Product{}
-	err = c.Bind(&tempProduct)
+	if err = helper.CheckIfMatch(c, current.Version); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var r request.UpdateProduct
+	err = c.ShouldBind(&r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
-	if tempProduct.ID != 0 {
-		helper.HandleError(c, http.StatusBadRequest, "input not permitted")
+
+	updatedProduct, err := e.usecase.Update(c.Request.Context(), id, &r)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	if tempProduct.Name == "" {
-		helper.HandleError(c, http.StatusBadRequest, "column cannot be empty")
+	helper.SetETag(c, updatedProduct.Version)
+	helper.HandleSuccess(c, response.NewProduct(updatedProduct))
+}
+
+// PatchProduct godoc
+// @Summary partially update product by id
+// @Schemes
+// @Description apply a JSON Merge Patch (RFC 7396) to a product: only the given fields change and null resets a field.
+// @Description The edit of an approved product does not apply at once: it becomes the pending_revision of the product until verifiers approve it, see POST /product/revision/verification.
+// @Description Send the ETag of the product in If-Match to get 412 when it was modified since it was read.
+// @Tags Product
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Product ID"
+// @Param If-Match header string false "ETag of the product"
+// @Param        patch  body      request.UpdateProduct  true  "Merge patch"
+// @Success 200 {object} helper.Response{data=response.Product}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 412 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /product/{id} [patch]
+func (e *handler) PatchProduct(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	version, err := helper.IfMatchVersion(c)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	updatedProduct, err := e.usecase.Update(id, &tempProduct)
+	doc, err := patch.Parse(c.Request.Body)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, updatedProduct)
+	patched, err := e.usecase.Patch(c.Request.Context(), id, version, doc)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.SetETag(c, patched.Version)
+	helper.HandleSuccess(c, response.NewProduct(patched))
 }
 
 // DeleteProduct godoc
@@ -284,22 +355,20 @@ func (e *handler) EditProduct(c *gin.Context) {
 // @Produce  json
 // @Param id path string true "Product ID"
 // @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /product/{id} [delete]
 func (e *handler) DeleteProduct(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	err = e.usecase.Delete(id)
+	err = e.usecase.Delete(c.Request.Context(), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
 	helper.HandleSuccess(c, "success delete data")
@@ -308,29 +377,27 @@ func (e *handler) DeleteProduct(c *gin.Context) {
 // SummaryProduct godoc
 // @Summary Find summary by Company ID
 // @Schemes
-// @Description find summary by company id
+// @Description find summary by company id: product counts by status, stock by base unit (kg, liter, pcs)
+// @Description and stock value by currency in minor units.
 // @Tags Product
 // @Accept  json
 // @Produce  json
 // @Param company_id path string true "Company ID"
 // @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /product/summary/{company_id} [get]
 func (e *handler) SummaryProduct(c *gin.Context) {
 	idStr := c.Param("company_id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Print(err)
-		helper.HandleError(c, http.StatusBadRequest, "company id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_company_id", "company id has be number").Wrap(err))
 		return
 	}
-	productModel, err := e.usecase.Summary(id)
+	productModel, err := e.usecase.Summary(c.Request.Context(), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
 	helper.HandleSuccess(c, productModel)
@@ -339,63 +406,185 @@ func (e *handler) SummaryProduct(c *gin.Context) {
 // VerificationProduct godoc
 // @Summary Verification product
 // @Schemes
-// @Description verification product
+// @Description approve a product as the signed in user. The verifiers of the company are the users sharing their role; once every one of them approved it, the product is published to PARI.
 // @Tags Product
 // @Accept json
 // @Produce json
 // @Param        productUser  body      request.ProductUser  true  "Verification Product"
-// @Success 201 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 201 {object} helper.Response{data=response.ProductDetail}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /product/verification [post]
 func (e *handler) VerificationProduct(c *gin.Context) {
 	var r = request.ProductUser{}
-	err := c.Bind(&r)
+	err := c.ShouldBind(&r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
-	newProductUser, err := e.usecase.Verification(&r)
+	newProductUser, err := e.usecase.Verification(c.Request.Context(), c.GetInt("userID"), c.GetInt("companyID"), &r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
 
-	helper.HandleSuccess(c, newProductUser)
+	helper.HandleSuccess(c, response.NewProductDetail(newProductUser))
 }
 
-func (e *handler) PariProductTransaction(c *gin.Context) {
-	var tempProduct = model.Product{}
-	err := c.ShouldBind(&tempProduct)
+// ViewProductPrices godoc
+// @Summary Find price history of a product
+// @Schemes
+// @Description find the prices a product had, each effective from effective_at. revision_id is the approved revision that set the price.
+// @Description Filterable fields: revision_id, price_amount, currency, price_per_base_unit, effective_at, created_at.
+// @Tags Product
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Product ID"
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. -effective_at"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.ProductPrice}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /product/{id}/prices [get]
+func (e *handler) ViewProductPrices(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	q, err := query.Parse(c.Request.URL.Query(), request.ProductPriceQuery)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	prices, page, err := e.usecase.Prices(c.Request.Context(), id, q)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(err)
 		return
 	}
+	helper.HandlePagedSuccess(c, response.NewProductPrices(*prices), page)
+}
 
-	currentProduct, err := e.usecase.ReadByPariProductId(tempProduct.PariProductId)
+// ViewProductRevisions godoc
+// @Summary Find revisions of a product
+// @Schemes
+// @Description find the edits proposed for a product since it was approved: processing ones wait for verifiers, approved ones were applied and rejected ones were not.
+// @Description Filterable fields: status, reviewed_at, created_at.
+// @Tags Product
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Product ID"
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. -created_at"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.ProductRevision}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /product/{id}/revisions [get]
+func (e *handler) ViewProductRevisions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	q, err := query.Parse(c.Request.URL.Query(), request.ProductRevisionQuery)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	revisions, page, err := e.usecase.Revisions(c.Request.Context(), id, q)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
+	helper.HandlePagedSuccess(c, response.NewProductRevisions(*revisions), page)
+}
+
+// VerificationProductRevision godoc
+// @Summary Verification product revision
+// @Schemes
+// @Description approve a product revision as the signed in user. The verifiers of the company are the users sharing their role; once every one of them approved it, the product takes its changes and is updated on PARI.
+// @Description Verifying an approved revision PARI did not take yet, with synced_at empty, sends it again.
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param        productRevisionUser  body      request.ProductRevisionUser  true  "Verification Product Revision"
+// @Success 200 {object} helper.Response{data=response.ProductRevision}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 412 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 502 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /product/revision/verification [post]
+func (e *handler) VerificationProductRevision(c *gin.Context) {
+	var r request.ProductRevisionUser
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+	revision, err := e.usecase.VerificationRevision(c.Request.Context(), c.GetInt("userID"), c.GetInt("companyID"), &r)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewProductRevision(revision))
+}
 
-	qty := currentProduct.Quantity - tempProduct.Quantity
-	if qty < 0 {
-		helper.HandleError(c, http.StatusInternalServerError, "stock")
+// RejectionProductRevision godoc
+// @Summary Reject product revision
+// @Schemes
+// @Description reject a product revision waiting for approval as the signed in user. The product keeps its values.
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param        rejection  body      request.ProductRevisionRejection  true  "Reject Product Revision"
+// @Success 200 {object} helper.Response{data=response.ProductRevision}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /product/revision/rejection [post]
+func (e *handler) RejectionProductRevision(c *gin.Context) {
+	var r request.ProductRevisionRejection
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
+	revision, err := e.usecase.RejectionRevision(c.Request.Context(), c.GetInt("userID"), c.GetInt("companyID"), &r)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewProductRevision(revision))
+}
 
-	calculateProduct := model.Product{Quantity: qty}
+func (e *handler) PariProductTransaction(c *gin.Context) {
+	var r request.ProductTransaction
+	err := c.ShouldBind(&r)
+	if err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
 
-	updatedProduct, err := e.usecase.Update(currentProduct.ID, &calculateProduct)
+	updatedProduct, err := e.usecase.Purchase(c.Request.Context(), &r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, updatedProduct)
+	helper.HandleSuccess(c, response.NewProduct(updatedProduct))
 }
@@ -1,22 +1,28 @@
 package role
 
 import (
-	"net/http"
 	"strconv"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/role"
-	"github.com/casbin/casbin"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler interface {
-	AddRole(enforcer *casbin.Enforcer) gin.HandlerFunc
+	AddRole(c *gin.Context)
 	ViewRoleId(c *gin.Context)
 	ViewRoles(c *gin.Context)
 	EditRole(c *gin.Context)
 	DeleteRole(c *gin.Context)
+	ViewPermissions(c *gin.Context)
+	EditPermissions(c *gin.Context)
+	ViewCatalog(c *gin.Context)
 }
 
 type handler struct {
@@ -30,75 +36,69 @@ func NewHandler(uc role.Usecase) Handler {
 // AddRole godoc
 // @Summary Add new role
 // @Schemes
-// @Description add new role
+// @Description add new role, granted the baseline permissions: user:read, role:read, company:read, commodity:read, product:read and preorder:read
+// @Description The role belongs to the company of the user, superadmins choosing company_id, 0 sharing it with all companies.
 // @Tags Role
 // @Accept json
 // @Produce json
 // @Param        role  body      request.Role  true  "Add role"
-// @Success 201 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 201 {object} helper.Response{data=response.Role}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Security BearerAuth
 // @Router /role [post]
-func (e *handler) AddRole(enforcer *casbin.Enforcer) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		var r = model.Role{}
-		err := c.Bind(&r)
-		if err != nil {
-			helper.CommonLogger().Error(err)
-			helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
-			return
-		}
-		if r.ID != 0 {
-			helper.HandleError(c, http.StatusBadRequest, "input not permitted")
-			return
-		}
-
-		if r.Name == "" {
-			helper.HandleError(c, http.StatusBadRequest, "column cannot be empty")
-			return
-		}
-		newRole, err := e.usecase.Create(&r)
-		if err != nil {
-			helper.CommonLogger().Error(err)
-			helper.HandleError(c, http.StatusInternalServerError, err.Error())
-			return
-		}
-
-		//add policy
-		if hasPolicy := enforcer.HasPolicy(newRole.Name, "report", "read"); !hasPolicy {
-			enforcer.AddPolicy(newRole.Name, "report", "read")
-		}
-		if hasPolicy := enforcer.HasPolicy(newRole.Name, "report", "write"); !hasPolicy {
-			enforcer.AddPolicy(newRole.Name, "report", "write")
-		}
-
-		helper.HandleSuccess(c, newRole)
+func (e *handler) AddRole(c *gin.Context) {
+	var r request.Role
+	err := c.ShouldBind(&r)
+	if err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
 	}
+
+	newRole, err := e.usecase.Create(c.Request.Context(), c.GetInt("userID"), &r)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	helper.HandleSuccess(c, response.NewRole(newRole))
 }
 
 // ViewRoles godoc
 // @Summary Find All role
 // @Schemes
-// @Description find all role
+// @Description find roles, those shared by all companies and those of the company of the user for users other than superadmins
+// @Description Filters are field=value or field[op]=value with op one of in, gte, lte and between, e.g. created_at[between]=2022-06-01,2022-06-30.
+// @Description Filterable fields: name, company_id, created_at.
 // @Tags Role
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. name"
+// @Param   search    query    string  false        "Prefix of name"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.Role}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Security BearerAuth
 // @Router /role [get]
 func (e *handler) ViewRoles(c *gin.Context) {
-	roles, err := e.usecase.ReadAll()
+	q, err := query.Parse(c.Request.URL.Query(), request.RoleQuery)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	if len(*roles) == 0 {
-		helper.HandleError(c, http.StatusNotFound, "list role is empty")
+	roles, page, err := e.usecase.ReadAllBy(c.Request.Context(), c.GetInt("userID"), q)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, roles)
+	helper.HandlePagedSuccess(c, response.NewRoles(*roles), page)
 }
 
 // ViewRoleId godoc
@@ -109,103 +109,181 @@ func (e *handler) ViewRoles(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Role ID"
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 200 {object} helper.Response{data=response.Role}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Security BearerAuth
 // @Router /role/{id} [get]
 func (e *handler) ViewRoleId(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	r, err := e.usecase.ReadById(id)
+	r, err := e.usecase.ReadById(c.Request.Context(), c.GetInt("userID"), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, r)
+	helper.HandleSuccess(c, response.NewRole(r))
 }
 
 // EditRole godoc
 // @Summary update role by id
 // @Schemes
-// @Description update role by id
+// @Description update role by id, moving its permissions and users to the new name. The built-in roles superadmin, admin, verificator and user cannot be renamed, and roles shared by all companies are updated by superadmins only.
 // @Tags Role
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Role ID"
 // @Param        role  body      request.Role  true  "Update role"
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 200 {object} helper.Response{data=response.Role}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Security BearerAuth
 // @Router /role/{id} [put]
 func (e *handler) EditRole(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	_, err = e.usecase.ReadById(id)
+	_, err = e.usecase.ReadById(c.Request.Context(), c.GetInt("userID"), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	var tempRole = model.Role{}
-	err = c.Bind(&tempRole)
+	var r request.Role
+	err = c.ShouldBind(&r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
-		return
-	}
-	if tempRole.ID != 0 {
-		helper.HandleError(c, http.StatusBadRequest, "input not permitted")
-		return
-	}
-	if tempRole.Name == "" {
-		helper.HandleError(c, http.StatusBadRequest, "column cannot be empty")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
-	updatedRole, err := e.usecase.Update(id, &tempRole)
+
+	updatedRole, err := e.usecase.Update(c.Request.Context(), c.GetInt("userID"), id, &r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, updatedRole)
+	helper.HandleSuccess(c, response.NewRole(updatedRole))
 }
 
 // DeleteRole godoc
 // @Summary Delete role by id
 // @Schemes
-// @Description delete role by id
+// @Description delete role by id, along with its permissions. Roles held by users and the built-in roles cannot be deleted, and roles shared by all companies are deleted by superadmins only.
 // @Tags Role
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Role ID"
 // @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Security BearerAuth
 // @Router /role/{id} [delete]
 func (e *handler) DeleteRole(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	err = e.usecase.Delete(id)
+	err = e.usecase.Delete(c.Request.Context(), c.GetInt("userID"), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
 	helper.HandleSuccess(c, "success delete data")
 }
+
+// ViewPermissions godoc
+// @Summary Find permissions of role
+// @Schemes
+// @Description find the permissions granted to the role, as object:action
+// @Tags Role
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Role ID"
+// @Success 200 {object} helper.Response{data=response.RolePermissions}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /role/{id}/permissions [get]
+func (e *handler) ViewPermissions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	r, permissions, err := e.usecase.Permissions(c.Request.Context(), c.GetInt("userID"), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.RolePermissions{RoleID: r.ID, Role: r.Name, Permissions: response.NewPermissions(permissions)})
+}
+
+// EditPermissions godoc
+// @Summary Update permissions of role
+// @Schemes
+// @Description grant the role exactly the given permissions of GET /permission, revoking the others. Users other than superadmins grant the permissions they are granted, to roles of their company only. The permissions of superadmin cannot be changed.
+// @Tags Role
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Role ID"
+// @Param        permissions  body      request.RolePermissions  true  "Permissions"
+// @Success 200 {object} helper.Response{data=response.RolePermissions}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /role/{id}/permissions [put]
+func (e *handler) EditPermissions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	var r request.RolePermissions
+	err = c.ShouldBind(&r)
+	if err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	updatedRole, permissions, err := e.usecase.SetPermissions(c.Request.Context(), c.GetInt("userID"), id, r.Permissions)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.RolePermissions{RoleID: updatedRole.ID, Role: updatedRole.Name, Permissions: response.NewPermissions(permissions)})
+}
+
+// ViewCatalog godoc
+// @Summary Find all permissions
+// @Schemes
+// @Description find the permissions roles can be granted, as object:action
+// @Tags Role
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} helper.Response{data=[]string}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /permission [get]
+func (e *handler) ViewCatalog(c *gin.Context) {
+	helper.HandleSuccess(c, response.NewPermissions(permission.Catalog))
+}
@@ -0,0 +1,199 @@
+package sso
+
+import (
+	"strconv"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/sso"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler interface {
+	ViewProvider(c *gin.Context)
+	EditProvider(c *gin.Context)
+	DeleteProvider(c *gin.Context)
+	AuthorizationSSO(c *gin.Context)
+	LoginSSO(c *gin.Context)
+}
+
+type handler struct {
+	usecase sso.Usecase
+}
+
+func NewHandler(uc sso.Usecase) Handler {
+	return &handler{uc}
+}
+
+// ViewProvider godoc
+// @Summary View the SSO provider of a company
+// @Schemes
+// @Description view the OpenID Connect provider the users of a company log in with. Admins other than superadmins view the one of their own company.
+// @Tags SSO
+// @Accept  json
+// @Produce  json
+// @Param company_id path string true "Company ID"
+// @Success 200 {object} helper.Response{data=response.SSOProvider}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /sso/company/{company_id} [get]
+func (e *handler) ViewProvider(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("company_id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+
+	p, err := e.usecase.Provider(c.Request.Context(), c.GetInt("userID"), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewSSOProvider(p))
+}
+
+// EditProvider godoc
+// @Summary Configure the SSO provider of a company
+// @Schemes
+// @Description configure the OpenID Connect provider the users of a company log in with, registering SSO_REDIRECT_URL as its redirect URI. client_secret may be left out to keep the one configured.
+// @Description Users are provisioned at their first login with the role of the first role_mapping rule matching a value of their role_claim, else default_role_id. Users of the company with the same verified email are linked instead,
+// @Description unless they are granted a permission the admin saving the provider is not, or are superadmins.
+// @Description Admins other than superadmins configure the one of their own company, mapping roles granting no permission they are not granted.
+// @Tags SSO
+// @Accept  json
+// @Produce  json
+// @Param company_id path string true "Company ID"
+// @Param        provider  body      request.SSOProvider  true  "Provider"
+// @Success 200 {object} helper.Response{data=response.SSOProvider}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /sso/company/{company_id} [put]
+func (e *handler) EditProvider(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("company_id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	var r request.SSOProvider
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	p, err := e.usecase.SaveProvider(c.Request.Context(), c.GetInt("userID"), id, r)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewSSOProvider(p))
+}
+
+// DeleteProvider godoc
+// @Summary Delete the SSO provider of a company
+// @Schemes
+// @Description stop the users of a company logging in with their OpenID Connect provider and forget the identities linked to it. The users are kept.
+// @Tags SSO
+// @Accept  json
+// @Produce  json
+// @Param company_id path string true "Company ID"
+// @Success 200 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /sso/company/{company_id} [delete]
+func (e *handler) DeleteProvider(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("company_id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+
+	if err := e.usecase.DeleteProvider(c.Request.Context(), c.GetInt("userID"), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, nil)
+}
+
+// AuthorizationSSO godoc
+// @Summary Start a login with SSO
+// @Schemes
+// @Description start a login with the OpenID Connect provider of a company. Send the user to authorization_url; the provider redirects them back to SSO_REDIRECT_URL with a code and state to post to /login/sso before expires_at.
+// @Tags SSO
+// @Accept  json
+// @Produce  json
+// @Param        authorization  body      request.SSOAuthorization  true  "Company"
+// @Success 200 {object} helper.Response{data=response.SSOAuthorization}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 502 {object} helper.ErrorResponse
+// @Router /login/sso/authorization [post]
+func (e *handler) AuthorizationSSO(c *gin.Context) {
+	var r request.SSOAuthorization
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	a, err := e.usecase.Authorize(c.Request.Context(), r.CompanyID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.SSOAuthorization{AuthorizationURL: a.URL, ExpiresAt: a.ExpiresAt})
+}
+
+// LoginSSO godoc
+// @Summary Login with SSO
+// @Schemes
+// @Description finish a login with the code and state the provider redirected the user back with. Users logging in for the first time are linked by their verified email, or provisioned.
+// @Description Users logging in with a second factor get a challenge_token to answer at /login/2fa instead of a token.
+// @Tags SSO
+// @Accept  json
+// @Produce  json
+// @Param        login  body      request.SSOLogin  true  "Code and state"
+// @Success 200 {object} helper.Response{data=response.Token}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 401 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 502 {object} helper.ErrorResponse
+// @Router /login/sso [post]
+func (e *handler) LoginSSO(c *gin.Context) {
+	var r request.SSOLogin
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+	r.IP = c.ClientIP()
+	r.UserAgent = c.Request.UserAgent()
+
+	u, challenge, err := e.usecase.Login(c.Request.Context(), r)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if challenge != nil {
+		helper.HandleSuccess(c, response.Token{
+			MustChangePassword:  u.MustChangePassword,
+			TwoFactorRequired:   true,
+			TwoFactorEnrollment: challenge.Enroll,
+			ChallengeToken:      challenge.Token,
+		})
+		return
+	}
+
+	token := helper.GenerateToken(u)
+	helper.HandleSuccess(c, response.Token{Token: token, MustChangePassword: u.MustChangePassword})
+}
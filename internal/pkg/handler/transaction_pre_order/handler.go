@@ -1,14 +1,16 @@
 package transaction_pre_order
 
 import (
-	"fmt"
-	"net/http"
 	"strconv"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/patch"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
 	transactionPreOrder "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/transaction_pre_order"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,6 +20,7 @@ type Handler interface {
 	ViewTransactionPreOrders(c *gin.Context)
 	ViewTransactionPreOrdersBy(c *gin.Context)
 	EditTransactionPreOrder(c *gin.Context)
+	PatchTransactionPreOrder(c *gin.Context)
 	DeleteTransactionPreOrder(c *gin.Context)
 	SummaryTransactionPreOrder(c *gin.Context)
 	VerificationTransactionPreOrder(c *gin.Context)
@@ -38,9 +41,10 @@ func NewHandler(uc transactionPreOrder.Usecase) Handler {
 // @Tags Transaction PreOrder
 // @Accept multipart/form-data
 // @Param        transactionPreOrder  formData      request.TransactionPreOrder  true  "Add transaction pre-order"
-// @Success 201 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 201 {object} helper.Response{data=response.TransactionPreOrder}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /transaction/preorder [post]
 func (e *handler) AddTransactionPreOrder(c *gin.Context) {
@@ -48,99 +52,91 @@ func (e *handler) AddTransactionPreOrder(c *gin.Context) {
 
 	err := c.ShouldBind(&transactionPreOrderModel)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
 
-	newProduct, err := e.usecase.Create(&transactionPreOrderModel)
+	newProduct, err := e.usecase.Create(c.Request.Context(), &transactionPreOrderModel)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, newProduct)
+	helper.HandleSuccess(c, response.NewTransactionPreOrder(newProduct))
 }
 
 // ViewTransactionPreOrders godoc
 // @Summary Find All Transaction PreOrder
 // @Schemes
-// @Description find all transaction preorder
+// @Description find transaction preorders
+// @Description Filters are field=value or field[op]=value with op one of in, gte, lte and between, e.g. created_at[between]=2022-06-01,2022-06-30.
+// @Description Filterable fields: status, company_id, product_id, commodity, buyer_name, quantity, actual_price_amount, total_amount, currency, created_at, updated_at.
 // @Tags Transaction PreOrder
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. -created_at"
+// @Param   search    query    string  false        "Words of product name, description or commodity, or prefix of product or buyer name"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.TransactionPreOrder}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /transaction/preorder [get]
 func (e *handler) ViewTransactionPreOrders(c *gin.Context) {
-	transactionPreOrders, err := e.usecase.ReadAll()
+	q, err := query.Parse(c.Request.URL.Query(), request.TransactionPreOrderQuery)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-
-	helper.HandleSuccess(c, transactionPreOrders)
+	transactionPreOrders, page, err := e.usecase.ReadAllBy(c.Request.Context(), q)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandlePagedSuccess(c, response.NewTransactionPreOrders(*transactionPreOrders), page)
 }
 
 // ViewTransactionPreOrdersBy godoc
 // @Summary Find All transaction preorder by Company ID
 // @Schemes
-// @Description find all transaction preorder by company id
-// @Param company_id path string true "Company ID"
-// @Param   page     query    int     false        "Page"
-// @Param   size      query    int     false        "Size"
-// @Param   status      query    string     false        "Status"
-// @Param   commodity      query    string     false        "Commodity"
-// @Param   search      query    string     false        "Search"
+// @Description find transaction preorders of a company
+// @Description Filters are field=value or field[op]=value with op one of in, gte, lte and between, e.g. created_at[between]=2022-06-01,2022-06-30.
+// @Description Filterable fields: status, product_id, commodity, buyer_name, quantity, actual_price_amount, total_amount, currency, created_at, updated_at.
 // @Tags Transaction PreOrder
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} helper.ResponsePaged
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Param company_id path string true "Company ID"
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. -created_at"
+// @Param   search    query    string  false        "Words of product name, description or commodity, or prefix of product or buyer name"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.TransactionPreOrder}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /transaction/preorder/company/{company_id} [get]
 func (e *handler) ViewTransactionPreOrdersBy(c *gin.Context) {
-	var req request.TransactionPreOrderPaged
-	var err error
-
-	err = c.ShouldBindUri(&req)
+	companyID, err := strconv.Atoi(c.Param("company_id"))
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(apperror.BadRequest("invalid_company_id", "company id has be number").Wrap(err))
 		return
 	}
-
-	err = c.ShouldBindQuery(&req)
+	q, err := query.Parse(c.Request.URL.Query(), request.TransactionPreOrderQuery)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(err)
 		return
 	}
-
-	companyIDStr := c.Param("company_id")
-	companyID, err := strconv.Atoi(companyIDStr)
-	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "company id has be number")
-		return
-	}
-
-	req.CompanyID = companyID
-
-	transactionPreOrders, err := e.usecase.ReadAllBy(req)
+	q.Where("transaction_pre_orders.company_id", companyID)
+	transactionPreOrders, page, err := e.usecase.ReadAllBy(c.Request.Context(), q)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-
-	countTransactionPreOrders := e.usecase.Count(req)
-
-	helper.HandlePagedSuccess(c, transactionPreOrders, req.Page, req.Size, countTransactionPreOrders)
+	helper.HandlePagedSuccess(c, response.NewTransactionPreOrders(*transactionPreOrders), page)
 }
 
 // ViewTransactionPreOrderId FindTransactionPreOrder godoc
@@ -152,9 +148,9 @@ func (e *handler) ViewTransactionPreOrdersBy(c *gin.Context) {
 // @Produce  json
 // @Param id path string true "Transaction PreOrder ID"
 // @Param   user_id      query    int     false        "User ID"
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 200 {object} helper.Response{data=response.TransactionPreOrderDetail}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /transaction/preorder/{id} [get]
 func (e *handler) ViewTransactionPreOrderId(c *gin.Context) {
@@ -163,26 +159,24 @@ func (e *handler) ViewTransactionPreOrderId(c *gin.Context) {
 
 	err = c.ShouldBindUri(&req)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
 
 	err = c.ShouldBindQuery(&req)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
 
-	transactionPreOrderModel, err := e.usecase.ReadBy(req)
+	transactionPreOrderModel, err := e.usecase.ReadBy(c.Request.Context(), req)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
 
-	helper.HandleSuccess(c, transactionPreOrderModel)
+	helper.SetETag(c, transactionPreOrderModel.TransactionPreOrder.Version)
+	helper.HandleSuccess(c, response.NewTransactionPreOrderDetail(transactionPreOrderModel))
 }
 
 // EditTransactionPreOrder UpdateTransactionPreOrder godoc
@@ -193,44 +187,89 @@ func (e *handler) ViewTransactionPreOrderId(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param id path string true "Transaction PreOrder ID"
-// @Param        product  body      request.TransactionPreOrder  true  "Update Transaction PreOrder"
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Param If-Match header string false "ETag of the transaction pre-order"
+// @Param        product  body      request.UpdateTransactionPreOrder  true  "Update Transaction PreOrder"
+// @Success 200 {object} helper.Response{data=response.TransactionPreOrder}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
 // @Security BearerAuth
+// @Failure 412 {object} helper.ErrorResponse
 // @Router /transaction/preorder/{id} [put]
 func (e *handler) EditTransactionPreOrder(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	_, err = e.usecase.ReadById(id)
+	current, err := e.usecase.ReadById(c.Request.Context(), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	var tempTransactionPreOrder = model.TransactionPreOrder{}
-	err = c.Bind(&tempTransactionPreOrder)
+	if err = helper.CheckIfMatch(c, current.Version); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var r request.UpdateTransactionPreOrder
+	err = c.ShouldBind(&r)
+	if err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	updatedTransactionPreOrder, err := e.usecase.Update(c.Request.Context(), id, &r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(err)
 		return
 	}
-	if tempTransactionPreOrder.ID != 0 {
-		helper.HandleError(c, http.StatusBadRequest, "input not permitted")
+	helper.SetETag(c, updatedTransactionPreOrder.Version)
+	helper.HandleSuccess(c, response.NewTransactionPreOrder(updatedTransactionPreOrder))
+}
+
+// PatchTransactionPreOrder godoc
+// @Summary partially update transaction pre-order by id
+// @Schemes
+// @Description apply a JSON Merge Patch (RFC 7396) to a transaction pre-order: only the given fields change and null resets a field.
+// @Description Send the ETag of the transaction pre-order in If-Match to get 412 when it was modified since it was read.
+// @Tags Transaction PreOrder
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Transaction PreOrder ID"
+// @Param If-Match header string false "ETag of the transaction pre-order"
+// @Param        patch  body      request.UpdateTransactionPreOrder  true  "Merge patch"
+// @Success 200 {object} helper.Response{data=response.TransactionPreOrder}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 412 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /transaction/preorder/{id} [patch]
+func (e *handler) PatchTransactionPreOrder(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	version, err := helper.IfMatchVersion(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	doc, err := patch.Parse(c.Request.Body)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	updatedTransactionPreOrder, err := e.usecase.Update(id, &tempTransactionPreOrder)
+	patched, err := e.usecase.Patch(c.Request.Context(), id, version, doc)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, updatedTransactionPreOrder)
+	helper.SetETag(c, patched.Version)
+	helper.HandleSuccess(c, response.NewTransactionPreOrder(patched))
 }
 
 // DeleteTransactionPreOrder godoc
@@ -242,22 +281,20 @@ func (e *handler) EditTransactionPreOrder(c *gin.Context) {
 // @Produce  json
 // @Param id path string true "Transaction PreOrder ID"
 // @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /transaction/preorder/{id} [delete]
 func (e *handler) DeleteTransactionPreOrder(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	err = e.usecase.Delete(id)
+	err = e.usecase.Delete(c.Request.Context(), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
 	helper.HandleSuccess(c, "success delete data")
@@ -266,29 +303,26 @@ func (e *handler) DeleteTransactionPreOrder(c *gin.Context) {
 // SummaryTransactionPreOrder godoc
 // @Summary Find summary by Company ID
 // @Schemes
-// @Description find summary by company id
+// @Description find summary by company id: pre-order counts per status plus quantity per unit and total_amount per currency in minor units
 // @Tags Transaction PreOrder
 // @Accept  json
 // @Produce  json
 // @Param company_id path string true "Company ID"
 // @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /transaction/preorder/summary/{company_id} [get]
 func (e *handler) SummaryTransactionPreOrder(c *gin.Context) {
 	idStr := c.Param("company_id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Print(err)
-		helper.HandleError(c, http.StatusBadRequest, "company id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_company_id", "company id has be number").Wrap(err))
 		return
 	}
-	transactionPreOrderModel, err := e.usecase.Summary(id)
+	transactionPreOrderModel, err := e.usecase.Summary(c.Request.Context(), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
 	helper.HandleSuccess(c, transactionPreOrderModel)
@@ -297,30 +331,30 @@ func (e *handler) SummaryTransactionPreOrder(c *gin.Context) {
 // VerificationTransactionPreOrder godoc
 // @Summary Verification transaction pre-order
 // @Schemes
-// @Description verification transaction pre-order
+// @Description approve a transaction pre-order as the signed in user. The verifiers of the company are the users sharing their role; once every one of them approved it, the pre-order is approved.
 // @Tags Transaction PreOrder
 // @Accept json
 // @Produce json
 // @Param        transactionPreOrderUser  body      request.TransactionPreOrderUser  true  "Verification Transaction PreOrder"
-// @Success 201 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 201 {object} helper.Response{data=response.TransactionPreOrderDetail}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /transaction/preorder/verification [post]
 func (e *handler) VerificationTransactionPreOrder(c *gin.Context) {
 	var r = request.TransactionPreOrderUser{}
-	err := c.Bind(&r)
+	err := c.ShouldBind(&r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
-	newTransactionPreOrderUser, err := e.usecase.Verification(&r)
+	newTransactionPreOrderUser, err := e.usecase.Verification(c.Request.Context(), c.GetInt("userID"), c.GetInt("companyID"), &r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
 
-	helper.HandleSuccess(c, newTransactionPreOrderUser)
+	helper.HandleSuccess(c, response.NewTransactionPreOrderDetail(newTransactionPreOrderUser))
 }
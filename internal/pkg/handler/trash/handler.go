@@ -0,0 +1,171 @@
+package trash
+
+import (
+	"strconv"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/trash"
+	trashUsecase "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/trash"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler interface {
+	ViewTrash(c *gin.Context)
+	RestoreTrash(c *gin.Context)
+	PurgeTrash(c *gin.Context)
+}
+
+type handler struct {
+	usecase trashUsecase.Usecase
+}
+
+func NewHandler(uc trashUsecase.Usecase) Handler {
+	return &handler{uc}
+}
+
+// ViewTrash godoc
+// @Summary Find deleted records
+// @Schemes
+// @Description find the deleted products, users, companies or transaction pre-orders, most recently deleted first.
+// @Description Filterable fields: deleted_at, created_at and, except for companies, company_id.
+// @Tags Trash
+// @Accept  json
+// @Produce  json
+// @Param resource path string true "Resource" Enums(product, user, company, preorder)
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. created_at"
+// @Param   search    query    string  false        "Prefix of the name, or of the email of users, the code of companies and the buyer name of pre-orders"
+// @Success 200 {object} helper.ResponsePaged{data=[]object}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /trash/{resource} [get]
+func (e *handler) ViewTrash(c *gin.Context) {
+	r, ok := trash.Lookup(c.Param("resource"))
+	if !ok {
+		_ = c.Error(unknownResource())
+		return
+	}
+	ctx := c.Request.Context()
+	values := c.Request.URL.Query()
+
+	var (
+		data interface{}
+		page *query.Page
+		q    *query.Query
+		err  error
+	)
+	switch r {
+	case trash.Products:
+		var products []model.Product
+		if q, err = query.Parse(values, request.ProductTrashQuery); err == nil {
+			page, err = e.usecase.ReadAllBy(ctx, r, q, &products)
+		}
+		data = response.NewProducts(products)
+	case trash.Users:
+		var users []model.User
+		if q, err = query.Parse(values, request.UserTrashQuery); err == nil {
+			page, err = e.usecase.ReadAllBy(ctx, r, q, &users)
+		}
+		data = response.NewUsers(users)
+	case trash.Companies:
+		var companies []model.Company
+		if q, err = query.Parse(values, request.CompanyTrashQuery); err == nil {
+			page, err = e.usecase.ReadAllBy(ctx, r, q, &companies)
+		}
+		data = response.NewCompanies(companies)
+	case trash.PreOrders:
+		var preOrders []model.TransactionPreOrder
+		if q, err = query.Parse(values, request.PreOrderTrashQuery); err == nil {
+			page, err = e.usecase.ReadAllBy(ctx, r, q, &preOrders)
+		}
+		data = response.NewTransactionPreOrders(preOrders)
+	}
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandlePagedSuccess(c, data, page)
+}
+
+// RestoreTrash godoc
+// @Summary Restore deleted record
+// @Schemes
+// @Description restore a deleted record along with the records deleted with it, e.g. the products and users of a company.
+// @Description A record whose company, product or commodity is deleted cannot be restored before it.
+// @Tags Trash
+// @Accept  json
+// @Produce  json
+// @Param resource path string true "Resource" Enums(product, user, company, preorder)
+// @Param id path string true "ID"
+// @Success 200 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /trash/{resource}/{id}/restore [post]
+func (e *handler) RestoreTrash(c *gin.Context) {
+	r, id, err := target(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if err := e.usecase.Restore(c.Request.Context(), r, id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, nil)
+}
+
+// PurgeTrash godoc
+// @Summary Purge deleted record
+// @Schemes
+// @Description delete for good a record in the trash and every record depending on it. Records are also purged once deleted longer than TRASH_RETENTION_DAYS ago.
+// @Tags Trash
+// @Accept  json
+// @Produce  json
+// @Param resource path string true "Resource" Enums(product, user, company, preorder)
+// @Param id path string true "ID"
+// @Success 200 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /trash/{resource}/{id} [delete]
+func (e *handler) PurgeTrash(c *gin.Context) {
+	r, id, err := target(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if err := e.usecase.Purge(c.Request.Context(), r, id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, nil)
+}
+
+func target(c *gin.Context) (*trash.Resource, int, error) {
+	r, ok := trash.Lookup(c.Param("resource"))
+	if !ok {
+		return nil, 0, unknownResource()
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, apperror.BadRequest("invalid_id", "id has be number").Wrap(err)
+	}
+	return r, id, nil
+}
+
+func unknownResource() error {
+	return apperror.NotFound("resource_not_found", "resource has no trash, use product, user, company or preorder")
+}
@@ -0,0 +1,337 @@
+package two_factor
+
+import (
+	"strconv"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/two_factor"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler interface {
+	ViewTwoFactor(c *gin.Context)
+	EnrollmentTwoFactor(c *gin.Context)
+	ConfirmationTwoFactor(c *gin.Context)
+	DeactivationTwoFactor(c *gin.Context)
+	RecoveryCodesTwoFactor(c *gin.Context)
+	ResetTwoFactor(c *gin.Context)
+	LoginEnrollmentTwoFactor(c *gin.Context)
+	LoginTwoFactor(c *gin.Context)
+	CompanyPolicyTwoFactor(c *gin.Context)
+	RolePolicyTwoFactor(c *gin.Context)
+}
+
+type handler struct {
+	usecase two_factor.Usecase
+}
+
+func NewHandler(uc two_factor.Usecase) Handler {
+	return &handler{uc}
+}
+
+// ViewTwoFactor godoc
+// @Summary View two-factor authentication
+// @Schemes
+// @Description tell whether the current user logs in with a TOTP code, whether their role or company requires it, and how many recovery codes they have left
+// @Tags Two Factor
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} helper.Response{data=response.TwoFactor}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 401 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /2fa [get]
+func (e *handler) ViewTwoFactor(c *gin.Context) {
+	s, err := e.usecase.Status(c.Request.Context(), c.GetInt("userID"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.TwoFactor{Enabled: s.Enabled, Required: s.Required, RecoveryCodes: s.RecoveryCodes})
+}
+
+// EnrollmentTwoFactor godoc
+// @Summary Enroll two-factor authentication
+// @Schemes
+// @Description create a new TOTP secret for the current user, to add to an authenticator app by scanning uri as a QR code. It is pending until confirmed with a code.
+// @Tags Two Factor
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} helper.Response{data=response.TwoFactorEnrollment}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 401 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /2fa/enrollment [post]
+func (e *handler) EnrollmentTwoFactor(c *gin.Context) {
+	enrollment, err := e.usecase.Enroll(c.Request.Context(), c.GetInt("userID"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.TwoFactorEnrollment{Secret: enrollment.Secret, URI: enrollment.URI})
+}
+
+// ConfirmationTwoFactor godoc
+// @Summary Confirm two-factor authentication
+// @Schemes
+// @Description enable the pending secret of the current user with a code of their app. The recovery codes returned are not shown again.
+// @Tags Two Factor
+// @Accept  json
+// @Produce  json
+// @Param        code  body      request.TwoFactorCode  true  "TOTP code"
+// @Success 200 {object} helper.Response{data=response.RecoveryCodes}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 401 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /2fa/enrollment/confirmation [post]
+func (e *handler) ConfirmationTwoFactor(c *gin.Context) {
+	var r request.TwoFactorCode
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	codes, err := e.usecase.Confirm(c.Request.Context(), c.GetInt("userID"), r.Code)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.RecoveryCodes{RecoveryCodes: codes})
+}
+
+// DeactivationTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Schemes
+// @Description turn two-factor authentication off for the current user with a TOTP code or a recovery code, unless their role or company requires it
+// @Tags Two Factor
+// @Accept  json
+// @Produce  json
+// @Param        code  body      request.TwoFactorCode  true  "TOTP code or recovery code"
+// @Success 200 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 401 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /2fa/deactivation [post]
+func (e *handler) DeactivationTwoFactor(c *gin.Context) {
+	var r request.TwoFactorCode
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	if err := e.usecase.Disable(c.Request.Context(), c.GetInt("userID"), r.Code); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, "two-factor authentication was disabled")
+}
+
+// RecoveryCodesTwoFactor godoc
+// @Summary Regenerate recovery codes
+// @Schemes
+// @Description replace the recovery codes of the current user with new ones, given a TOTP code or a recovery code. The recovery codes returned are not shown again.
+// @Tags Two Factor
+// @Accept  json
+// @Produce  json
+// @Param        code  body      request.TwoFactorCode  true  "TOTP code or recovery code"
+// @Success 200 {object} helper.Response{data=response.RecoveryCodes}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 401 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /2fa/recovery_codes [post]
+func (e *handler) RecoveryCodesTwoFactor(c *gin.Context) {
+	var r request.TwoFactorCode
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	codes, err := e.usecase.RegenerateRecoveryCodes(c.Request.Context(), c.GetInt("userID"), r.Code)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.RecoveryCodes{RecoveryCodes: codes})
+}
+
+// ResetTwoFactor godoc
+// @Summary Reset two-factor authentication
+// @Schemes
+// @Description forget the TOTP secret and recovery codes of a user who lost both. They log in with their password alone, or enroll again at their next login if they have to.
+// @Description Admins other than superadmins reset the users of their own company who are granted no permission the admin is not.
+// @Tags Two Factor
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Success 200 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /user/{id}/2fa/reset [post]
+func (e *handler) ResetTwoFactor(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	if err := e.usecase.Reset(c.Request.Context(), c.GetInt("userID"), id); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, "two-factor authentication was reset")
+}
+
+// LoginEnrollmentTwoFactor godoc
+// @Summary Enroll two-factor authentication at login
+// @Schemes
+// @Description create the TOTP secret of a user who has to enroll before logging in, given the challenge_token /login returned along with two_factor_enrollment.
+// @Description The secret is confirmed by logging in at /login/2fa with a code of the app.
+// @Tags Two Factor
+// @Accept  json
+// @Produce  json
+// @Param        challenge  body      request.TwoFactorChallenge  true  "Challenge"
+// @Success 200 {object} helper.Response{data=response.TwoFactorEnrollment}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 401 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Router /login/2fa/enrollment [post]
+func (e *handler) LoginEnrollmentTwoFactor(c *gin.Context) {
+	var r request.TwoFactorChallenge
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	enrollment, err := e.usecase.EnrollChallenge(c.Request.Context(), r.ChallengeToken)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.TwoFactorEnrollment{Secret: enrollment.Secret, URI: enrollment.URI})
+}
+
+// LoginTwoFactor godoc
+// @Summary Login with a second factor
+// @Schemes
+// @Description finish a login with the challenge_token /login returned and a TOTP code or a recovery code. Wrong codes count as failed logins of the user.
+// @Description When enrolling at login, the code confirms the secret and the recovery codes are returned, once.
+// @Tags Two Factor
+// @Accept  json
+// @Produce  json
+// @Param        login  body      request.TwoFactorLogin  true  "Challenge and code"
+// @Success 200 {object} helper.Response{data=response.Token}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 401 {object} helper.ErrorResponse
+// @Failure 409 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Failure 429 {object} helper.ErrorResponse
+// @Router /login/2fa [post]
+func (e *handler) LoginTwoFactor(c *gin.Context) {
+	var r request.TwoFactorLogin
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+	r.IP = c.ClientIP()
+	r.UserAgent = c.Request.UserAgent()
+
+	u, codes, err := e.usecase.Complete(c.Request.Context(), r)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	token := helper.GenerateToken(u)
+	helper.HandleSuccess(c, response.Token{Token: token, MustChangePassword: u.MustChangePassword, RecoveryCodes: codes})
+}
+
+// CompanyPolicyTwoFactor godoc
+// @Summary Set the two-factor policy of a company
+// @Schemes
+// @Description require the users of a company of verification level 2 and above to log in with a TOTP code, or stop requiring it.
+// @Description Admins other than superadmins set the policy of their own company.
+// @Tags Two Factor
+// @Accept  json
+// @Produce  json
+// @Param company_id path string true "Company ID"
+// @Param        policy  body      request.TwoFactorPolicy  true  "Policy"
+// @Success 200 {object} helper.Response{data=response.Company}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /2fa/policy/company/{company_id} [put]
+func (e *handler) CompanyPolicyTwoFactor(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("company_id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	var r request.TwoFactorPolicy
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	company, err := e.usecase.SetCompanyPolicy(c.Request.Context(), c.GetInt("userID"), id, *r.Require)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewCompany(company))
+}
+
+// RolePolicyTwoFactor godoc
+// @Summary Set the two-factor policy of a role
+// @Schemes
+// @Description require every user of a role to log in with a TOTP code, or stop requiring it. Superadmins set the policy of every role,
+// @Description other admins the policy of the roles of their company, not of those shared by all companies.
+// @Tags Two Factor
+// @Accept  json
+// @Produce  json
+// @Param role_id path string true "Role ID"
+// @Param        policy  body      request.TwoFactorPolicy  true  "Policy"
+// @Success 200 {object} helper.Response{data=response.Role}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /2fa/policy/role/{role_id} [put]
+func (e *handler) RolePolicyTwoFactor(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("role_id"))
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	var r request.TwoFactorPolicy
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	role, err := e.usecase.SetRolePolicy(c.Request.Context(), c.GetInt("userID"), id, *r.Require)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewRole(role))
+}
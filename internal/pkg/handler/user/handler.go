@@ -7,23 +7,31 @@
 package user
 
 import (
-	"net/http"
 	"strconv"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/patch"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/response"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler interface {
 	AddUser(c *gin.Context)
+	AddServiceAccount(c *gin.Context)
 	ViewUserId(c *gin.Context)
 	ViewUsers(c *gin.Context)
 	EditUser(c *gin.Context)
+	PatchUser(c *gin.Context)
 	ChangePassword(c *gin.Context)
 	DeleteUser(c *gin.Context)
+	AssignRole(c *gin.Context)
+	RevokeRole(c *gin.Context)
+	ViewPermissions(c *gin.Context)
 }
 
 type handler struct {
@@ -37,66 +45,96 @@ func NewHandler(uc user.Usecase) Handler {
 // AddUser godoc
 // @Summary Add new user
 // @Schemes
-// @Description add new user
+// @Description add a user to a company. Admins other than superadmins add users to their own company, with roles granting no permission they are not granted.
 // @Tags User
 // @Accept json
 // @Produce json
-// @Param        user  body      request.User  true  "Add user"
-// @Success 201 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Param        user  body      request.CreateUser  true  "Add user"
+// @Success 201 {object} helper.Response{data=response.User}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /user [post]
 func (e *handler) AddUser(c *gin.Context) {
-	var userModel = model.User{}
-	err := c.Bind(&userModel)
+	var r request.CreateUser
+	err := c.ShouldBind(&r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
-	if userModel.ID != 0 {
-		helper.HandleError(c, http.StatusBadRequest, "input not permitted")
+
+	newUser, err := e.usecase.Create(c.Request.Context(), c.GetInt("userID"), &r)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
+	helper.HandleSuccess(c, response.NewUser(newUser))
+}
 
-	if userModel.Name == "" || userModel.Email == "" || userModel.Password == "" {
-		helper.HandleError(c, http.StatusBadRequest, "column cannot be empty")
+// AddServiceAccount godoc
+// @Summary Add new service account
+// @Schemes
+// @Description add a user scripts authenticate as with access tokens only, created at /user/{id}/access_token. It has no password and cannot log in.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param        account  body      request.ServiceAccount  true  "Add service account"
+// @Success 201 {object} helper.Response{data=response.User}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /user/service_account [post]
+func (e *handler) AddServiceAccount(c *gin.Context) {
+	var r request.ServiceAccount
+	if err := c.ShouldBind(&r); err != nil {
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
-	newUser, err := e.usecase.Create(&userModel)
+
+	account, err := e.usecase.CreateServiceAccount(c.Request.Context(), c.GetInt("userID"), &r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, newUser)
+	helper.HandleSuccess(c, response.NewUser(account))
 }
 
 // ViewUsers godoc
 // @Summary Find All user
 // @Schemes
-// @Description find all user
+// @Description find users, of the company of the caller unless they are a superadmin
+// @Description Filters are field=value or field[op]=value with op one of in, gte, lte and between, e.g. created_at[between]=2022-06-01,2022-06-30.
+// @Description Filterable fields: name, email, role_id, company_id, service_account, verification_level, created_at.
 // @Tags User
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Param   page      query    int     false        "Page, starting at 1"
+// @Param   size      query    int     false        "Size, 1 to 100, default 20"
+// @Param   cursor    query    string  false        "next_cursor of the previous page, instead of page"
+// @Param   sort      query    string  false        "Comma separated fields, prefixed with - for descending, e.g. -created_at"
+// @Param   search    query    string  false        "Prefix of name or email"
+// @Success 200 {object} helper.ResponsePaged{data=[]response.User}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /user [get]
 func (e *handler) ViewUsers(c *gin.Context) {
-	users, err := e.usecase.ReadAll()
+	q, err := query.Parse(c.Request.URL.Query(), request.UserQuery)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	if len(*users) == 0 {
-		helper.HandleError(c, http.StatusNotFound, "list user is empty")
+	users, page, err := e.usecase.ReadAllBy(c.Request.Context(), c.GetInt("userID"), q)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, users)
+	helper.HandlePagedSuccess(c, response.NewUsers(*users), page)
 }
 
 // ViewUserId godoc
@@ -107,26 +145,27 @@ func (e *handler) ViewUsers(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param id path string true "User ID"
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 200 {object} helper.Response{data=response.User}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /user/{id} [get]
 func (e *handler) ViewUserId(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	u, err := e.usecase.ReadById(id)
+	u, err := e.usecase.ReadById(c.Request.Context(), c.GetInt("userID"), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, u)
+	helper.SetETag(c, u.Version)
+	helper.HandleSuccess(c, response.NewUser(u))
 }
 
 // EditUser UpdateUser godoc
@@ -137,48 +176,92 @@ func (e *handler) ViewUserId(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param id path string true "User ID"
-// @Param        user  body      request.User  true  "Update user"
-// @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Param If-Match header string false "ETag of the user"
+// @Param        user  body      request.UpdateUser  true  "Update user"
+// @Success 200 {object} helper.Response{data=response.User}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
 // @Security BearerAuth
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 412 {object} helper.ErrorResponse
 // @Router /user/{id} [put]
 func (e *handler) EditUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	_, err = e.usecase.ReadById(id)
+	current, err := e.usecase.ReadById(c.Request.Context(), c.GetInt("userID"), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
+		return
+	}
+	if err = helper.CheckIfMatch(c, current.Version); err != nil {
+		_ = c.Error(err)
 		return
 	}
-	var tempUser = model.User{}
-	err = c.Bind(&tempUser)
+	var r request.UpdateUser
+	err = c.ShouldBind(&r)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
-	if tempUser.ID != 0 {
-		helper.HandleError(c, http.StatusBadRequest, "input not permitted")
+
+	u, err := e.usecase.Update(c.Request.Context(), c.GetInt("userID"), id, &r)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	if tempUser.Name == "" || tempUser.Email == "" || tempUser.Password == "" {
-		helper.HandleError(c, http.StatusBadRequest, "column cannot be empty")
+	helper.SetETag(c, u.Version)
+	helper.HandleSuccess(c, response.NewUser(u))
+}
+
+// PatchUser godoc
+// @Summary partially update user by id
+// @Schemes
+// @Description apply a JSON Merge Patch (RFC 7396) to a user: only the given fields change and null resets a field.
+// @Description Send the ETag of the user in If-Match to get 412 when it was modified since it was read.
+// @Tags User
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param If-Match header string false "ETag of the user"
+// @Param        patch  body      request.UpdateUser  true  "Merge patch"
+// @Success 200 {object} helper.Response{data=response.User}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 412 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /user/{id} [patch]
+func (e *handler) PatchUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	version, err := helper.IfMatchVersion(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	doc, err := patch.Parse(c.Request.Body)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
-	u, err := e.usecase.Update(id, &tempUser)
+	patched, err := e.usecase.Patch(c.Request.Context(), c.GetInt("userID"), id, version, doc)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
-	helper.HandleSuccess(c, u)
+	helper.SetETag(c, patched.Version)
+	helper.HandleSuccess(c, response.NewUser(patched))
 }
 
 // DeleteUser godoc
@@ -190,39 +273,41 @@ func (e *handler) EditUser(c *gin.Context) {
 // @Produce  json
 // @Param id path string true "User ID"
 // @Success 200 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /user/{id} [delete]
 func (e *handler) DeleteUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-	err = e.usecase.Delete(id)
+	err = e.usecase.Delete(c.Request.Context(), c.GetInt("userID"), id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusNotFound, err.Error())
+		_ = c.Error(err)
 		return
 	}
 	helper.HandleSuccess(c, "success delete data")
 }
 
 // ChangePassword godoc
-// @Summary Add new user
+// @Summary Change password
 // @Schemes
-// @Description add new user
+// @Description change the password of the logged in user, whose id must be given. The tokens issued before are revoked, use the one returned.
 // @Tags User
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
 // @Param        password  body      request.ChangePassword  true  "Change Password"
-// @Success 201 {object} helper.Response
-// @Failure 500 {object} helper.Response
-// @Failure 400 {object} helper.Response
+// @Success 201 {object} helper.Response{data=response.Token}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
 // @Security BearerAuth
 // @Router /user/change_password/{id} [put]
 func (e *handler) ChangePassword(c *gin.Context) {
@@ -230,34 +315,134 @@ func (e *handler) ChangePassword(c *gin.Context) {
 	idStr := c.Param("id")
 	userID, err := strconv.Atoi(idStr)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusBadRequest, "id has be number")
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
 		return
 	}
-
-	err = c.Bind(&changePassword)
-	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, "Oopss server someting wrong")
+	if userID != c.GetInt("userID") {
+		_ = c.Error(apperror.Forbidden("password_forbidden", "users change their own password only"))
 		return
 	}
 
-	if changePassword.Password == "" {
-		helper.HandleError(c, http.StatusBadRequest, "column cannot be empty")
+	err = c.ShouldBind(&changePassword)
+	if err != nil {
+		_ = c.Error(validation.FromBind(err))
 		return
 	}
 
 	changePassword.UserID = userID
 
-	helper.HashPassword(&changePassword.Password)
-	m, err := e.usecase.ChangePassword(changePassword)
+	m, err := e.usecase.ChangePassword(c.Request.Context(), changePassword)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		helper.HandleError(c, http.StatusInternalServerError, err.Error())
+		_ = c.Error(err)
 		return
 	}
 
 	token := helper.GenerateToken(m)
-	result := map[string]interface{}{"token": token, "must_change_password": m.MustChangePassword}
-	helper.HandleSuccess(c, result)
+	helper.HandleSuccess(c, response.Token{Token: token, MustChangePassword: m.MustChangePassword})
+}
+
+// AssignRole godoc
+// @Summary Assign role to user
+// @Schemes
+// @Description make the role the only role of the user. Admins other than superadmins give roles granting no permission they are not granted.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param        role  body      request.UserRole  true  "Role"
+// @Success 200 {object} helper.Response{data=response.User}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 412 {object} helper.ErrorResponse
+// @Failure 422 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /user/{id}/role [put]
+func (e *handler) AssignRole(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+	var r request.UserRole
+	err = c.ShouldBind(&r)
+	if err != nil {
+		_ = c.Error(validation.FromBind(err))
+		return
+	}
+
+	updatedUser, err := e.usecase.AssignRole(c.Request.Context(), c.GetInt("userID"), id, r.RoleID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewUser(updatedUser))
+}
+
+// RevokeRole godoc
+// @Summary Revoke role of user
+// @Schemes
+// @Description leave the user without any role, so they are granted nothing
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} helper.Response{data=response.User}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Failure 412 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /user/{id}/role [delete]
+func (e *handler) RevokeRole(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+
+	updatedUser, err := e.usecase.RevokeRole(c.Request.Context(), c.GetInt("userID"), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	helper.HandleSuccess(c, response.NewUser(updatedUser))
+}
+
+// ViewPermissions godoc
+// @Summary Find effective permissions of user
+// @Schemes
+// @Description find the roles of the user and the permissions they grant
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} helper.Response{data=response.UserPermissions}
+// @Failure 500 {object} helper.ErrorResponse
+// @Failure 400 {object} helper.ErrorResponse
+// @Failure 403 {object} helper.ErrorResponse
+// @Failure 404 {object} helper.ErrorResponse
+// @Security BearerAuth
+// @Router /user/{id}/permissions [get]
+func (e *handler) ViewPermissions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		_ = c.Error(apperror.BadRequest("invalid_id", "id has be number").Wrap(err))
+		return
+	}
+
+	roles, permissions, err := e.usecase.Permissions(c.Request.Context(), c.GetInt("userID"), id)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	helper.HandleSuccess(c, response.UserPermissions{UserID: id, Roles: roles, Permissions: response.NewPermissions(permissions)})
 }
@@ -13,20 +13,8 @@ import (
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/spf13/viper"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(pass *string) {
-	bytePass := []byte(*pass)
-	hPass, _ := bcrypt.GenerateFromPassword(bytePass, bcrypt.DefaultCost)
-	*pass = string(hPass)
-}
-
-func ComparePassword(dbPass, pass string) bool {
-	return bcrypt.CompareHashAndPassword([]byte(dbPass), []byte(pass)) == nil
-}
-
 //GenerateToken -> generates token
 func GenerateToken(user *model.User) string {
 	claims := jwt.MapClaims{
@@ -34,6 +22,7 @@ func GenerateToken(user *model.User) string {
 		"iat":  time.Now().Unix(),
 		"data": user,
 		"sub":  uint(user.ID),
+		"ver":  user.SessionVersion,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -54,3 +43,41 @@ func ValidateToken(token string) (*jwt.Token, error) {
 		return []byte(viper.Get("JWT_SECRET").(string)), nil
 	})
 }
+
+// ChallengeTokenType is the typ claim of challenge tokens, which AuthorizeJWT refuses.
+const ChallengeTokenType = "2fa"
+
+// GenerateChallengeToken -> generates the token proving the password of user was right,
+// which only buys a JWT along with a second factor before ttl elapses
+func GenerateChallengeToken(user *model.User, ttl time.Duration) string {
+	claims := jwt.MapClaims{
+		"exp": time.Now().Add(ttl).Unix(),
+		"iat": time.Now().Unix(),
+		"sub": uint(user.ID),
+		"ver": user.SessionVersion,
+		"typ": ChallengeTokenType,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t, _ := token.SignedString([]byte(viper.Get("JWT_SECRET").(string)))
+	return t
+}
+
+// ValidateChallengeToken --> validate the given challenge token, returning the user id
+// and session version it was issued for
+func ValidateChallengeToken(token string) (int, int, error) {
+	t, err := ValidateToken(token)
+	if err != nil {
+		return 0, 0, err
+	}
+	claims, ok := t.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != ChallengeTokenType {
+		return 0, 0, fmt.Errorf("not a challenge token")
+	}
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return 0, 0, fmt.Errorf("challenge token has no subject")
+	}
+	ver, _ := claims["ver"].(float64)
+	return int(sub), int(ver), nil
+}
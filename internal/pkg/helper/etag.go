@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"strconv"
+	"strings"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"github.com/gin-gonic/gin"
+)
+
+// ETag formats the version of a resource as an entity tag.
+func ETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// SetETag writes the ETag header for the version of the returned resource.
+func SetETag(c *gin.Context, version int) {
+	c.Header("ETag", ETag(version))
+}
+
+// IfMatchVersion returns the version sent in the If-Match header, or 0 when the
+// header is missing or "*", meaning the client does not ask for a version check.
+func IfMatchVersion(c *gin.Context) (int, error) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	value = strings.TrimPrefix(value, "W/")
+	version, err := strconv.Atoi(strings.Trim(value, `"`))
+	if err != nil || version <= 0 {
+		return 0, apperror.BadRequest("invalid_if_match", "If-Match must be an ETag returned by this API")
+	}
+	return version, nil
+}
+
+// CheckIfMatch compares the If-Match header with the stored version of a resource.
+func CheckIfMatch(c *gin.Context, version int) error {
+	expected, err := IfMatchVersion(c)
+	if err != nil {
+		return err
+	}
+	if expected != 0 && expected != version {
+		return apperror.VersionMismatch("resource")
+	}
+	return nil
+}
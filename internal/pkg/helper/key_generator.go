@@ -7,7 +7,6 @@ import (
 	"encoding/base64"
 	"encoding/pem"
 	"errors"
-	"fmt"
 	"strings"
 	"time"
 )
@@ -17,7 +16,6 @@ func ValidateApiKey(key string) error {
 	b1, err := Base64Dec(key)
 	if err != nil {
 		CommonLogger().Error(err)
-		fmt.Println(err)
 		return errors.New("not valid key")
 	}
 
@@ -29,8 +27,6 @@ func ValidateApiKey(key string) error {
 
 	tokenTime, _ := time.Parse("2006-01-02 15:04:05", (strings.Split(string(b2), "%"))[1])
 	if tokenTime.Before(curTime) {
-		fmt.Println(tokenTime)
-		fmt.Println(curTime)
 		return errors.New("expired key")
 	}
 
@@ -1,26 +1,152 @@
 package helper
 
 import (
+	"context"
 	"io"
+	"net/http"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
 	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func CommonLogger() *logrus.Logger {
+const redacted = "[REDACTED]"
+
+type requestIDKey struct{}
+
+var logger = newLogger()
+
+// sensitiveKeys are log fields and headers whose values are never written to the log.
+var sensitiveKeys = []string{"authorization", "password", "token", "secret", "api_key", "apikey", "client_key", "cookie"}
+
+// sensitivePattern masks secrets that end up inside free text messages, e.g. "Bearer eyJ..." or "password=...".
+var sensitivePattern = regexp.MustCompile(`(?i)(bearer\s+|(?:password|token|secret|api_key|secret_key|client_key)["']?\s*[:=]\s*["']?)[^\s"',&]+`)
+
+func newLogger() *logrus.Logger {
 	log := logrus.New()
-	path := "./logs/common.log"
-	writer, _ := rotatelogs.New(
+	log.SetFormatter(&redactFormatter{&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano}})
+	log.SetReportCaller(true)
+	log.SetOutput(os.Stdout)
+	return log
+}
+
+// InitLogger configures the shared logger once at startup. An empty path logs to stdout only.
+func InitLogger(level, path string) error {
+	if level != "" {
+		lvl, err := logrus.ParseLevel(level)
+		if err != nil {
+			return err
+		}
+		logger.SetLevel(lvl)
+	}
+
+	if path == "" {
+		return nil
+	}
+
+	writer, err := rotatelogs.New(
 		path+".%Y%m%d",
 		rotatelogs.WithLinkName(path),
 		rotatelogs.WithMaxAge(time.Duration(30*24*3600)*time.Second),
 		rotatelogs.WithRotationTime(time.Duration(24*3600)*time.Second),
 	)
+	if err != nil {
+		return err
+	}
 
-	log.SetReportCaller(true)
 	//print to multiple medium
-	log.SetOutput(io.MultiWriter(writer, os.Stdout))
-	return log
+	logger.SetOutput(io.MultiWriter(writer, os.Stdout))
+	return nil
+}
+
+// CommonLogger returns the shared logger for code that runs outside of a request.
+func CommonLogger() *logrus.Logger {
+	return logger
+}
+
+// Logger returns the shared logger tagged with the request id and trace id carried by ctx.
+func Logger(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(logger)
+	if ctx == nil {
+		return entry
+	}
+
+	if id := RequestIDFromContext(ctx); id != "" {
+		entry = entry.WithField("request_id", id)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		entry = entry.WithFields(logrus.Fields{"trace_id": sc.TraceID().String(), "span_id": sc.SpanID().String()})
+	}
+	return entry
+}
+
+// WithRequestID returns a copy of ctx carrying the request id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request id stored by WithRequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RedactHeaders flattens headers for logging with sensitive values masked.
+func RedactHeaders(headers http.Header) map[string]string {
+	result := make(map[string]string, len(headers))
+	for key, values := range headers {
+		if isSensitive(key) {
+			result[key] = redacted
+			continue
+		}
+		result[key] = strings.Join(values, ",")
+	}
+	return result
+}
+
+// Redact masks secrets found in a free text value.
+func Redact(s string) string {
+	return sensitivePattern.ReplaceAllString(s, "${1}"+redacted)
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(strings.ReplaceAll(key, "-", "_"))
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactFormatter masks sensitive fields and message fragments before the entry is encoded.
+type redactFormatter struct {
+	logrus.Formatter
+}
+
+func (f *redactFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := make(logrus.Fields, len(entry.Data))
+	for key, value := range entry.Data {
+		switch {
+		case isSensitive(key):
+			data[key] = redacted
+		case key == logrus.ErrorKey:
+			if err, ok := value.(error); ok {
+				data[key] = Redact(err.Error())
+			} else {
+				data[key] = value
+			}
+		default:
+			data[key] = value
+		}
+	}
+
+	clone := *entry
+	clone.Data = data
+	clone.Message = Redact(entry.Message)
+	return f.Formatter.Format(&clone)
 }
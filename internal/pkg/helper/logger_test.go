@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerRedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	defer logger.SetOutput(os.Stdout)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	Logger(ctx).WithFields(logrus.Fields{
+		"password":      "s3cret",
+		"Authorization": "Bearer abc.def.ghi",
+		"email":         "alex@gmail.com",
+	}).Error("login failed for token=abc123 with header Bearer xyz")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, "req-1", line["request_id"])
+	require.Equal(t, redacted, line["password"])
+	require.Equal(t, redacted, line["Authorization"])
+	require.Equal(t, "alex@gmail.com", line["email"])
+	require.NotContains(t, line["msg"], "abc123")
+	require.NotContains(t, line["msg"], "xyz")
+}
+
+func TestRedactHeaders(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer abc")
+	headers.Set("Secret_key", "2gzwwaoof988jd51qpyu")
+	headers.Set("Content-Type", "application/json")
+
+	result := RedactHeaders(headers)
+	require.Equal(t, redacted, result["Authorization"])
+	require.Equal(t, redacted, result["Secret_key"])
+	require.Equal(t, "application/json", result["Content-Type"])
+}
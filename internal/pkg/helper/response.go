@@ -7,10 +7,12 @@
 package helper
 
 import (
+	"net/http"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
 	"github.com/gin-gonic/gin"
-	"net/http"
-	"strconv"
 )
 
 type Response struct {
@@ -19,6 +21,13 @@ type Response struct {
 	Data    interface{} `json:"data"`
 }
 
+type ErrorResponse struct {
+	Status  string                `json:"status"`
+	Message string                `json:"message"`
+	Code    string                `json:"code"`
+	Details []apperror.FieldError `json:"details,omitempty"`
+}
+
 type ResponsePari struct {
 	Status  int               `json:"status"`
 	Message string            `json:"message"`
@@ -31,13 +40,16 @@ type ResponsePariDetail struct {
 	Data    model.PariProductDetail `json:"data"`
 }
 
+// ResponsePaged is the envelope of every list endpoint. Page is 0 when the page was
+// addressed by cursor; NextCursor is empty on the last page.
 type ResponsePaged struct {
-	Status  string      `json:"status"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
-	Page    int         `json:"page"`
-	Size    int         `json:"size"`
-	Total   int         `json:"total"`
+	Status     string      `json:"status"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data"`
+	Page       int         `json:"page"`
+	Size       int         `json:"size"`
+	Total      int         `json:"total"`
+	NextCursor string      `json:"next_cursor,omitempty"`
 }
 
 type ProductResponse struct {
@@ -59,22 +71,15 @@ func HandleSuccess(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, responseData)
 }
 
-func HandlePagedSuccess(c *gin.Context, data interface{}, page, size, total int) {
+func HandlePagedSuccess(c *gin.Context, data interface{}, page *query.Page) {
 	responseData := ResponsePaged{
-		Status:  "200",
-		Message: "Success",
-		Data:    data,
-		Page:    page,
-		Size:    size,
-		Total:   total,
+		Status:     "200",
+		Message:    "Success",
+		Data:       data,
+		Page:       page.Page,
+		Size:       page.Size,
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
 	}
 	c.JSON(http.StatusOK, responseData)
 }
-
-func HandleError(c *gin.Context, status int, message string) {
-	responseData := Response{
-		Status:  strconv.Itoa(status),
-		Message: message,
-	}
-	c.JSON(status, responseData)
-}
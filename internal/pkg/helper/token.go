@@ -0,0 +1,32 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/spf13/viper"
+)
+
+// AccessTokenPrefix starts every personal access token, telling it from a JWT.
+const AccessTokenPrefix = "pat_"
+
+// NewSecretToken is a random token to hand out once, e.g. in a link sent by email.
+// Only its HashToken is stored.
+func NewSecretToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken signs a secret token with JWT_SECRET, so what is stored can neither be
+// used as the token nor matched without the secret.
+func HashToken(token string) string {
+	mac := hmac.New(sha256.New, []byte(viper.GetString("JWT_SECRET")))
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,28 @@
+package helper
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretToken(t *testing.T) {
+	viper.Set("JWT_SECRET", "test-secret")
+	defer viper.Set("JWT_SECRET", nil)
+
+	a, err := NewSecretToken()
+	require.NoError(t, err)
+	b, err := NewSecretToken()
+	require.NoError(t, err)
+	require.NotEqual(t, a, b)
+	require.Len(t, a, 43)
+
+	require.Equal(t, HashToken(a), HashToken(a))
+	require.NotEqual(t, HashToken(a), HashToken(b))
+	require.NotEqual(t, a, HashToken(a))
+
+	signed := HashToken(a)
+	viper.Set("JWT_SECRET", "other-secret")
+	require.NotEqual(t, signed, HashToken(a))
+}
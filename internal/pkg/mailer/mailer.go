@@ -0,0 +1,140 @@
+// Package mailer sends email. Mailers are picked by name: smtp delivers through an
+// SMTP server, file writes every message to a directory and memory keeps them for tests.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// Config is what the mailers are configured with, e.g. from MAILER, MAIL_FROM and the
+// SMTP_ variables.
+type Config struct {
+	Driver   string
+	From     string
+	Host     string
+	Port     string
+	Username string
+	Password string
+	Dir      string
+}
+
+// New is the mailer named by config.Driver, smtp when it is empty.
+func New(config Config) (Mailer, error) {
+	switch config.Driver {
+	case "", "smtp":
+		if config.Host == "" {
+			return nil, fmt.Errorf("mailer: SMTP host is required")
+		}
+		return &SMTP{From: config.From, Host: config.Host, Port: config.Port, Username: config.Username, Password: config.Password}, nil
+	case "file":
+		return &File{From: config.From, Dir: config.Dir}, nil
+	case "memory":
+		return &Memory{}, nil
+	}
+	return nil, fmt.Errorf("mailer: unknown driver %q", config.Driver)
+}
+
+// SMTP sends through an SMTP server, authenticating with PLAIN when Username is set.
+type SMTP struct {
+	From     string
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+func (m *SMTP) Send(ctx context.Context, message Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	port := m.Port
+	if port == "" {
+		port = "587"
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(m.Host, port), auth, m.From, []string{message.To}, format(m.From, message))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// File writes every message to Dir as an .eml file, for local development.
+type File struct {
+	From string
+	Dir  string
+}
+
+func (m *File) Send(ctx context.Context, message Message) error {
+	if err := os.MkdirAll(m.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000000"), sanitize(message.To))
+	return os.WriteFile(filepath.Join(m.Dir, name), format(m.From, message), 0o600)
+}
+
+// Memory keeps the messages it is sent.
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (m *Memory) Send(ctx context.Context, message Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+// Messages are the messages sent so far, oldest first.
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
+
+func format(from string, message Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", strings.ReplaceAll(message.Subject, "\n", " "))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return b.Bytes()
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
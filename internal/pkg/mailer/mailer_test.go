@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	m, err := New(Config{Driver: "memory"})
+	require.NoError(t, err)
+
+	require.NoError(t, m.Send(context.Background(), Message{To: "alex@example.com", Subject: "Hi", Body: "Hello"}))
+	require.Equal(t, []Message{{To: "alex@example.com", Subject: "Hi", Body: "Hello"}}, m.(*Memory).Messages())
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	m, err := New(Config{Driver: "file", From: "noreply@example.com", Dir: dir})
+	require.NoError(t, err)
+
+	require.NoError(t, m.Send(context.Background(), Message{To: "alex@example.com", Subject: "Hi", Body: "line 1\nline 2"}))
+	files, err := filepath.Glob(filepath.Join(dir, "*alex_example.com.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	raw, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	require.Contains(t, string(raw), "From: noreply@example.com\r\n")
+	require.Contains(t, string(raw), "To: alex@example.com\r\n")
+	require.Contains(t, string(raw), "Subject: Hi\r\n")
+	require.Contains(t, string(raw), "\r\n\r\nline 1\r\nline 2")
+}
+
+func TestUnknownDriver(t *testing.T) {
+	_, err := New(Config{Driver: "carrier-pigeon"})
+	require.Error(t, err)
+
+	_, err = New(Config{Driver: "smtp"})
+	require.Error(t, err)
+}
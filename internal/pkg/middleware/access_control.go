@@ -3,43 +3,49 @@ package middleware
 import (
 	"fmt"
 
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
 	"github.com/casbin/casbin"
 
 	"github.com/gin-gonic/gin"
 )
 
-// Authorize determines if current user has been authorized to take an action on an object.
-func Authorize(obj string, act string, enforcer *casbin.Enforcer) gin.HandlerFunc {
+// Authorize determines if current user has been granted the permission in their
+// company. The policy is enforced from memory, the watcher of the enforcer reloading it
+// when it changes.
+func Authorize(p permission.Permission, enforcer *casbin.SyncedEnforcer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get current user/subject
 		sub, existed := c.Get("userID")
 		if !existed {
-			c.AbortWithStatusJSON(401, gin.H{"msg": "User hasn't logged in yet"})
+			_ = c.Error(apperror.Unauthorized("not_logged_in", "User hasn't logged in yet"))
+			c.Abort()
 			return
 		}
 
-		// Load policy from Database
-		err := enforcer.LoadPolicy()
-		if err != nil {
-			helper.CommonLogger().Error(err)
-			c.AbortWithStatusJSON(500, gin.H{"msg": "Failed to load policy from DB"})
+		// access tokens are granted no more than their scopes
+		if scopes, scoped := c.Get("tokenScopes"); scoped && !scopedTo(scopes.([]string), p) {
+			_ = c.Error(apperror.Forbidden("token_scope", "token is not scoped to "+p.String()))
+			c.Abort()
 			return
 		}
 
 		// Casbin enforces policy
-		ok := enforcer.Enforce(fmt.Sprint(sub), obj, act)
-
-		//if err != nil {
-		helper.CommonLogger().Error(err)
-		//	c.AbortWithStatusJSON(500, gin.H{"msg": "Error occurred when authorizing user"})
-		//	return
-		//}
-
-		if !ok {
-			c.AbortWithStatusJSON(403, gin.H{"msg": "You are not authorized"})
+		domain := permission.Domain(c.GetInt("companyID"))
+		if ok := enforcer.Enforce(fmt.Sprint(sub), domain, p.Object, p.Action); !ok {
+			_ = c.Error(apperror.Forbidden("forbidden", "You are not authorized"))
+			c.Abort()
 			return
 		}
 		c.Next()
 	}
 }
+
+func scopedTo(scopes []string, p permission.Permission) bool {
+	for _, s := range scopes {
+		if s == p.String() {
+			return true
+		}
+	}
+	return false
+}
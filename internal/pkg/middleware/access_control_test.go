@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"github.com/casbin/casbin"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	enforcer := casbin.NewSyncedEnforcer("../config/rbac_model.conf", false)
+	enforcer.AddPolicy("verificator", permission.AllCompanies, "product", "verify")
+	enforcer.AddPolicy("auditor", "5", "product", "create")
+	enforcer.AddGroupingPolicy("7", "verificator", "5")
+	enforcer.AddGroupingPolicy("7", "auditor", "5")
+	enforcer.AddGroupingPolicy("8", "verificator", "6")
+	login := func(userID, companyID int) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("userID", userID)
+			c.Set("companyID", companyID)
+		}
+	}
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.POST("/verification", login(7, 5), Authorize(permission.ProductVerify, enforcer), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/product", login(8, 6), Authorize(permission.ProductCreate, enforcer), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/other_company/product", login(7, 6), Authorize(permission.ProductCreate, enforcer), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/own_company/product", login(7, 5), Authorize(permission.ProductCreate, enforcer), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/anonymous", Authorize(permission.ProductRead, enforcer), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for path, status := range map[string]int{
+		"/verification":          http.StatusNoContent,
+		"/product":               http.StatusForbidden,
+		"/other_company/product": http.StatusForbidden,
+		"/own_company/product":   http.StatusNoContent,
+		"/anonymous":             http.StatusUnauthorized,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		require.Equal(t, status, w.Code, path)
+	}
+}
@@ -1,37 +1,79 @@
 package middleware
 
 import (
-	"fmt"
-	"net/http"
+	"context"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 
-//AuthorizeJWT -> to authorize JWT Token
-func AuthorizeJWT() gin.HandlerFunc {
+// Sessions tells the session version of a user, which tokens issued before it was
+// increased, e.g. by a password reset, no longer match, and the company of the user.
+type Sessions interface {
+	Session(ctx context.Context, userID int) (*model.User, error)
+}
+
+// AuthorizeJWT -> to authorize JWT Token
+func AuthorizeJWT(sessions Sessions) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "No Authorization header found"})
+			_ = ctx.Error(apperror.Unauthorized("missing_authorization", "No Authorization header found"))
+			ctx.Abort()
+			return
 		}
 
-		if token, err := helper.ValidateToken(authHeader); err != nil {
+		token, err := helper.ValidateToken(authHeader)
+		if err != nil {
+			_ = ctx.Error(apperror.Unauthorized("invalid_token", "Not Valid Token").Wrap(err))
+			ctx.Abort()
+			return
+		}
 
-			fmt.Println("token", authHeader, err.Error())
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "Not Valid Token"})
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			_ = ctx.Error(apperror.Unauthorized("invalid_token", "Not Valid Token"))
+			ctx.Abort()
+			return
+		}
 
-		} else {
+		// challenge tokens only prove the password, the second factor is still awaited
+		if _, typed := claims["typ"]; typed {
+			_ = ctx.Error(apperror.Unauthorized("invalid_token", "Not Valid Token"))
+			ctx.Abort()
+			return
+		}
 
-			if _, ok := token.Claims.(jwt.MapClaims); !ok {
-				ctx.AbortWithStatus(http.StatusUnauthorized)
+		// the subject is the user id, which casbin groups into the role of the user
+		sub, ok := claims["sub"].(float64)
+		if !ok {
+			_ = ctx.Error(apperror.Unauthorized("invalid_token", "Not Valid Token"))
+			ctx.Abort()
+			return
+		}
 
+		// tokens issued before ver was added to the claims are version 0
+		ver, _ := claims["ver"].(float64)
+		current, err := sessions.Session(ctx.Request.Context(), int(sub))
+		if err != nil {
+			if apperror.IsNotFound(err) {
+				err = apperror.Unauthorized("invalid_token", "Not Valid Token").Wrap(err)
 			}
+			_ = ctx.Error(err)
+			ctx.Abort()
+			return
 		}
-
+		if int(ver) != current.SessionVersion {
+			_ = ctx.Error(apperror.Unauthorized("session_revoked", "session was revoked, log in again"))
+			ctx.Abort()
+			return
+		}
+		ctx.Set("userID", int(sub))
+		// casbin enforces in the domain of the company of the user
+		ctx.Set("companyID", current.CompanyID)
 	}
 
 }
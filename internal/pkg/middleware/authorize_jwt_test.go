@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+type sessions map[int]int
+
+func (s sessions) Session(_ context.Context, userID int) (*model.User, error) {
+	return &model.User{ID: userID, SessionVersion: s[userID]}, nil
+}
+
+func TestAuthorizeJWTSetsUserID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("JWT_SECRET", "test-secret")
+	defer viper.Set("JWT_SECRET", nil)
+
+	var userID interface{}
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/", AuthorizeJWT(sessions{}), func(c *gin.Context) {
+		userID, _ = c.Get("userID")
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", helper.GenerateToken(&model.User{ID: 7}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, 7, userID)
+
+	userID = nil
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "not-a-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Nil(t, userID)
+}
+
+func TestAuthorizeJWTRevokedSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("JWT_SECRET", "test-secret")
+	defer viper.Set("JWT_SECRET", nil)
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/", AuthorizeJWT(sessions{7: 2}), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", helper.GenerateToken(&model.User{ID: 7, SessionVersion: 1}))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, w.Body.String(), "session_revoked")
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", helper.GenerateToken(&model.User{ID: 7, SessionVersion: 2}))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestAuthorizeJWTRefusesChallengeToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("JWT_SECRET", "test-secret")
+	defer viper.Set("JWT_SECRET", nil)
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.GET("/", AuthorizeJWT(sessions{7: 0}), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", helper.GenerateChallengeToken(&model.User{ID: 7}, time.Minute))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, w.Body.String(), "invalid_token")
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"github.com/gin-gonic/gin"
+)
+
+// Tokens finds personal access tokens by the hash of the token, and records their use.
+type Tokens interface {
+	ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.AccessToken, error)
+	Touch(ctx context.Context, id int, ip string) error
+}
+
+// AuthorizeToken authorizes a personal access token, optionally prefixed with Bearer,
+// or else a JWT as AuthorizeJWT does. The scopes of the token are set as tokenScopes,
+// which Authorize grants no more than.
+func AuthorizeToken(sessions Sessions, tokens Tokens) gin.HandlerFunc {
+	authorizeJWT := AuthorizeJWT(sessions)
+	return func(ctx *gin.Context) {
+		raw := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !strings.HasPrefix(raw, helper.AccessTokenPrefix) {
+			authorizeJWT(ctx)
+			return
+		}
+
+		token, err := tokens.ReadBy(ctx.Request.Context(), map[string]interface{}{"token_hash": helper.HashToken(raw)})
+		if apperror.IsNotFound(err) || (err == nil && !token.Active(time.Now())) {
+			err = apperror.Unauthorized("invalid_token", "Not Valid Token").Wrap(err)
+		}
+		if err != nil {
+			_ = ctx.Error(err)
+			ctx.Abort()
+			return
+		}
+
+		// the tokens of deleted users stop working along with their sessions
+		current, err := sessions.Session(ctx.Request.Context(), token.UserID)
+		if err != nil {
+			if apperror.IsNotFound(err) {
+				err = apperror.Unauthorized("invalid_token", "Not Valid Token").Wrap(err)
+			}
+			_ = ctx.Error(err)
+			ctx.Abort()
+			return
+		}
+
+		if err := tokens.Touch(ctx.Request.Context(), token.ID, ctx.ClientIP()); err != nil {
+			helper.Logger(ctx.Request.Context()).WithError(err).Error("[middleware.AuthorizeToken] failed recording token use")
+		}
+		ctx.Set("userID", token.UserID)
+		ctx.Set("companyID", current.CompanyID)
+		ctx.Set("tokenScopes", token.ScopeList())
+	}
+}
+
+// SessionOnly refuses personal access tokens, on the routes managing the credentials of
+// the user, which only they may after logging in.
+func SessionOnly() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if _, scoped := ctx.Get("tokenScopes"); scoped {
+			_ = ctx.Error(apperror.Forbidden("session_required", "log in to do this, access tokens cannot"))
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
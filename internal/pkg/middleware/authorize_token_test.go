@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"github.com/casbin/casbin"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+// tokens keeps access tokens in memory by the hash of the token.
+type tokens struct {
+	rows    map[string]*model.AccessToken
+	touched map[int]string
+}
+
+func (t tokens) ReadBy(_ context.Context, criteria map[string]interface{}) (*model.AccessToken, error) {
+	if row, ok := t.rows[criteria["token_hash"].(string)]; ok {
+		return row, nil
+	}
+	return nil, apperror.NotFound("access_token_not_found", "access token is not exists")
+}
+
+func (t tokens) Touch(_ context.Context, id int, ip string) error {
+	t.touched[id] = ip
+	return nil
+}
+
+func TestAuthorizeToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("JWT_SECRET", "test-secret")
+	defer viper.Set("JWT_SECRET", nil)
+
+	enforcer := casbin.NewSyncedEnforcer("../config/rbac_model.conf", false)
+	enforcer.AddPolicy("verificator", permission.AllCompanies, "product", "read")
+	enforcer.AddPolicy("verificator", permission.AllCompanies, "product", "verify")
+	enforcer.AddGroupingPolicy("7", "verificator", permission.Domain(0))
+
+	now := time.Now()
+	revoked := now.Add(-time.Minute)
+	store := tokens{rows: map[string]*model.AccessToken{}, touched: map[int]string{}}
+	for raw, token := range map[string]*model.AccessToken{
+		"pat_active":  {ID: 1, UserID: 7, Scopes: "product:read", ExpiresAt: now.Add(time.Hour)},
+		"pat_expired": {ID: 2, UserID: 7, Scopes: "product:read", ExpiresAt: now.Add(-time.Hour)},
+		"pat_revoked": {ID: 3, UserID: 7, Scopes: "product:read", ExpiresAt: now.Add(time.Hour), RevokedAt: &revoked},
+	} {
+		store.rows[helper.HashToken(raw)] = token
+	}
+
+	router := gin.New()
+	router.Use(ErrorHandler())
+	authorize := AuthorizeToken(sessions{}, store)
+	router.GET("/product", authorize, Authorize(permission.ProductRead, enforcer), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.POST("/verification", authorize, Authorize(permission.ProductVerify, enforcer), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	router.PUT("/change_password", authorize, SessionOnly(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	for _, tc := range []struct {
+		method, path, authorization string
+		status                      int
+	}{
+		{http.MethodGet, "/product", "pat_active", http.StatusNoContent},
+		{http.MethodGet, "/product", "Bearer pat_active", http.StatusNoContent},
+		{http.MethodPost, "/verification", "pat_active", http.StatusForbidden},
+		{http.MethodPost, "/verification", helper.GenerateToken(&model.User{ID: 7}), http.StatusNoContent},
+		{http.MethodPut, "/change_password", "pat_active", http.StatusForbidden},
+		{http.MethodPut, "/change_password", helper.GenerateToken(&model.User{ID: 7}), http.StatusNoContent},
+		{http.MethodGet, "/product", "pat_expired", http.StatusUnauthorized},
+		{http.MethodGet, "/product", "pat_revoked", http.StatusUnauthorized},
+		{http.MethodGet, "/product", "pat_unknown", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Authorization", tc.authorization)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, tc.status, w.Code, tc.path+" "+tc.authorization)
+	}
+	require.Equal(t, map[int]string{1: "192.0.2.1"}, store.touched)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"strconv"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler renders the last error attached with c.Error as a helper.ErrorResponse,
+// using the domain error kind to pick the HTTP status code.
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil || c.Writer.Written() {
+			return
+		}
+
+		appErr := apperror.From(last.Err)
+		status := appErr.StatusCode()
+
+		entry := helper.Logger(c.Request.Context()).WithError(last.Err).WithField("code", appErr.Code)
+		if status >= 500 {
+			entry.Error("request failed")
+		} else {
+			entry.Debug("request rejected")
+		}
+
+		c.AbortWithStatusJSON(status, helper.ErrorResponse{
+			Status:  strconv.Itoa(status),
+			Message: appErr.Message,
+			Code:    appErr.Code,
+			Details: appErr.Details,
+		})
+	}
+}
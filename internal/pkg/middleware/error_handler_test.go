@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"validation", apperror.Validation("validation_error", "column cannot be empty"), http.StatusUnprocessableEntity, "validation_error", "column cannot be empty"},
+		{"not found", apperror.FromDB(gorm.ErrRecordNotFound, "product", "failed view data"), http.StatusNotFound, "product_not_found", "product is not exists"},
+		{"upstream", apperror.Upstream("pari_unavailable", "failed calling PARI"), http.StatusBadGateway, "pari_unavailable", "failed calling PARI"},
+		{"unknown", errors.New("dial tcp: connection refused"), http.StatusInternalServerError, "internal_error", "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/", func(c *gin.Context) {
+				_ = c.Error(tt.err)
+			})
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			require.Equal(t, tt.status, w.Code)
+
+			var body helper.ErrorResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			require.Equal(t, tt.code, body.Code)
+			require.Equal(t, tt.message, body.Message)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Logger writes one structured access log line per request with sensitive headers redacted.
+func Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		entry := helper.Logger(c.Request.Context()).WithFields(logrus.Fields{
+			"method":    c.Request.Method,
+			"path":      c.Request.URL.Path,
+			"route":     c.FullPath(),
+			"status":    c.Writer.Status(),
+			"latency":   time.Since(start).String(),
+			"client_ip": c.ClientIP(),
+			"headers":   helper.RedactHeaders(c.Request.Header),
+		})
+		if len(c.Errors) > 0 {
+			entry = entry.WithField("errors", helper.Redact(c.Errors.String()))
+		}
+
+		switch status := c.Writer.Status(); {
+		case status >= 500:
+			entry.Error("request completed")
+		case status >= 400:
+			entry.Warn("request completed")
+		default:
+			entry.Info("request completed")
+		}
+	}
+}
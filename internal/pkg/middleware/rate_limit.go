@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter allows a client limit requests in every window, counted in memory so
+// each instance of the API counts on its own.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{limit: limit, window: window, now: time.Now, windows: make(map[string]*rateWindow)}
+}
+
+// Allow counts a request of key and tells whether it is allowed, and if not how long
+// until the next one is.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if len(l.windows) > 10000 {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		l.windows[key] = &rateWindow{start: now, count: 1}
+		return true, 0
+	}
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// RateLimit rejects the requests of a client, known by its IP, beyond what limiter
+// allows on the route.
+func RateLimit(limiter *RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, retryAfter := limiter.Allow(c.ClientIP() + " " + c.FullPath())
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			_ = c.Error(apperror.RateLimited("too_many_requests", "too many requests, try again later"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiterWindow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewRateLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.Allow("a")
+	require.True(t, ok)
+	ok, _ = l.Allow("a")
+	require.True(t, ok)
+	ok, retryAfter := l.Allow("a")
+	require.False(t, ok)
+	require.Equal(t, time.Minute, retryAfter)
+
+	ok, _ = l.Allow("b")
+	require.True(t, ok)
+
+	now = now.Add(time.Minute)
+	ok, _ = l.Allow("a")
+	require.True(t, ok)
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler())
+	router.POST("/password/reset", RateLimit(NewRateLimiter(1, time.Minute)), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/password/reset", nil))
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/password/reset", nil))
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "60", w.Header().Get("Retry-After"))
+}
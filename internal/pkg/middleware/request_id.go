@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID reuses the caller's X-Request-ID or generates a new one, returns it in the
+// response header and stores it in the request context for every log line of the request.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = newRequestID()
+		}
+
+		c.Set("requestID", id)
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(helper.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := tracing.NewProvider("ms-pari-web-test", exporter)
+	defer tp.Shutdown(context.Background())
+
+	var traceparent string
+	pari := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer pari.Close()
+
+	router := gin.New()
+	router.Use(Tracing())
+	router.GET("/product/:id", func(c *gin.Context) {
+		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodPost, pari.URL+"/product/detail", nil)
+		require.NoError(t, err)
+		resp, err := tracing.NewHTTPClient().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/product/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, tp.ForceFlush(context.Background()))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	client, server := spans[0], spans[1]
+	require.Equal(t, "GET /product/:id", server.Name)
+	require.Equal(t, trace.SpanKindServer, server.SpanKind)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+
+	require.Equal(t, trace.SpanKindClient, client.SpanKind)
+	require.Equal(t, server.SpanContext.SpanID(), client.Parent.SpanID())
+	require.Contains(t, traceparent, server.SpanContext.TraceID().String())
+	require.Contains(t, traceparent, client.SpanContext.SpanID().String())
+}
+
+func TestTracingFailedRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for name, middlewares := range map[string][]gin.HandlerFunc{
+		"tracing first":       {Tracing(), ErrorHandler()},
+		"error handler first": {ErrorHandler(), Tracing()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := tracing.NewProvider("ms-pari-web-test", exporter)
+			defer tp.Shutdown(context.Background())
+
+			router := gin.New()
+			router.Use(middlewares...)
+			router.GET("/product/:id", func(c *gin.Context) {
+				_ = c.Error(apperror.Internal("pari_unavailable", "failed reaching PARI"))
+			})
+			router.GET("/commodity/:id", func(c *gin.Context) {
+				_ = c.Error(apperror.NotFound("commodity_not_found", "commodity is not exists"))
+			})
+
+			for _, path := range []string{"/product/1", "/commodity/1"} {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			}
+			require.NoError(t, tp.ForceFlush(context.Background()))
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 2)
+			require.Contains(t, spans[0].Attributes, semconv.HTTPResponseStatusCode(http.StatusInternalServerError))
+			require.Equal(t, codes.Error, spans[0].Status.Code)
+			require.Contains(t, spans[1].Attributes, semconv.HTTPResponseStatusCode(http.StatusNotFound))
+			require.Equal(t, codes.Unset, spans[1].Status.Code)
+		})
+	}
+}
@@ -5,9 +5,12 @@
 package mock
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	model "bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	query "bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -34,91 +37,238 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// Count mocks base method.
+func (m *MockRepository) Count(ctx context.Context, criteria map[string]interface{}) int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, criteria)
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockRepositoryMockRecorder) Count(ctx, criteria interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockRepository)(nil).Count), ctx, criteria)
+}
+
 // Create mocks base method.
-func (m *MockRepository) Create(person *model.User) (*model.User, error) {
+func (m *MockRepository) Create(ctx context.Context, user *model.User) (*model.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", person)
+	ret := m.ctrl.Call(m, "Create", ctx, user)
 	ret0, _ := ret[0].(*model.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockRepositoryMockRecorder) Create(person interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Create(ctx, user interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), person)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), ctx, user)
 }
 
 // Delete mocks base method.
-func (m *MockRepository) Delete(id int) error {
+func (m *MockRepository) Delete(ctx context.Context, id int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockRepositoryMockRecorder) Delete(id interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), ctx, id)
+}
+
+// FailLogin mocks base method.
+func (m *MockRepository) FailLogin(ctx context.Context, id int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailLogin", ctx, id)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FailLogin indicates an expected call of FailLogin.
+func (mr *MockRepositoryMockRecorder) FailLogin(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailLogin", reflect.TypeOf((*MockRepository)(nil).FailLogin), ctx, id)
+}
+
+// Lock mocks base method.
+func (m *MockRepository) Lock(ctx context.Context, id int, until time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Lock", ctx, id, until)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Lock indicates an expected call of Lock.
+func (mr *MockRepositoryMockRecorder) Lock(ctx, id, until interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockRepository)(nil).Lock), ctx, id, until)
+}
+
+// Patch mocks base method.
+func (m *MockRepository) Patch(ctx context.Context, id, version int, fields map[string]interface{}) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", ctx, id, version, fields)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Patch indicates an expected call of Patch.
+func (mr *MockRepositoryMockRecorder) Patch(ctx, id, version, fields interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*MockRepository)(nil).Patch), ctx, id, version, fields)
 }
 
 // ReadAll mocks base method.
-func (m *MockRepository) ReadAll() (*[]model.User, error) {
+func (m *MockRepository) ReadAll(ctx context.Context) (*[]model.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ReadAll")
+	ret := m.ctrl.Call(m, "ReadAll", ctx)
 	ret0, _ := ret[0].(*[]model.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ReadAll indicates an expected call of ReadAll.
-func (mr *MockRepositoryMockRecorder) ReadAll() *gomock.Call {
+func (mr *MockRepositoryMockRecorder) ReadAll(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAll", reflect.TypeOf((*MockRepository)(nil).ReadAll), ctx)
+}
+
+// ReadAllBy mocks base method.
+func (m *MockRepository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.User, *query.Page, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadAllBy", ctx, q)
+	ret0, _ := ret[0].(*[]model.User)
+	ret1, _ := ret[1].(*query.Page)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadAllBy indicates an expected call of ReadAllBy.
+func (mr *MockRepositoryMockRecorder) ReadAllBy(ctx, q interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAll", reflect.TypeOf((*MockRepository)(nil).ReadAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadAllBy", reflect.TypeOf((*MockRepository)(nil).ReadAllBy), ctx, q)
 }
 
 // ReadByEmail mocks base method.
-func (m *MockRepository) ReadByEmail(email string) (*model.User, error) {
+func (m *MockRepository) ReadByEmail(ctx context.Context, email string) (*model.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ReadByEmail", email)
+	ret := m.ctrl.Call(m, "ReadByEmail", ctx, email)
 	ret0, _ := ret[0].(*model.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ReadByEmail indicates an expected call of ReadByEmail.
-func (mr *MockRepositoryMockRecorder) ReadByEmail(email interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) ReadByEmail(ctx, email interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByEmail", reflect.TypeOf((*MockRepository)(nil).ReadByEmail), email)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadByEmail", reflect.TypeOf((*MockRepository)(nil).ReadByEmail), ctx, email)
 }
 
 // ReadById mocks base method.
-func (m *MockRepository) ReadById(id int) (*model.User, error) {
+func (m *MockRepository) ReadById(ctx context.Context, id int) (*model.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ReadById", id)
+	ret := m.ctrl.Call(m, "ReadById", ctx, id)
 	ret0, _ := ret[0].(*model.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ReadById indicates an expected call of ReadById.
-func (mr *MockRepositoryMockRecorder) ReadById(id interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) ReadById(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadById", reflect.TypeOf((*MockRepository)(nil).ReadById), ctx, id)
+}
+
+// ResetPassword mocks base method.
+func (m *MockRepository) ResetPassword(ctx context.Context, user *model.User, resetID int) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, user, resetID)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockRepositoryMockRecorder) ResetPassword(ctx, user, resetID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadById", reflect.TypeOf((*MockRepository)(nil).ReadById), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockRepository)(nil).ResetPassword), ctx, user, resetID)
+}
+
+// Session mocks base method.
+func (m *MockRepository) Session(ctx context.Context, id int) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Session", ctx, id)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Session indicates an expected call of Session.
+func (mr *MockRepositoryMockRecorder) Session(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Session", reflect.TypeOf((*MockRepository)(nil).Session), ctx, id)
+}
+
+// Unlock mocks base method.
+func (m *MockRepository) Unlock(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockRepositoryMockRecorder) Unlock(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockRepository)(nil).Unlock), ctx, id)
 }
 
 // Update mocks base method.
-func (m *MockRepository) Update(id int, person *model.User) (*model.User, error) {
+func (m *MockRepository) Update(ctx context.Context, id int, user *model.User) (*model.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", id, person)
+	ret := m.ctrl.Call(m, "Update", ctx, id, user)
 	ret0, _ := ret[0].(*model.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockRepositoryMockRecorder) Update(id, person interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Update(ctx, id, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), ctx, id, user)
+}
+
+// UpdatePasswordHash mocks base method.
+func (m *MockRepository) UpdatePasswordHash(ctx context.Context, id int, old, hash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordHash", ctx, id, old, hash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordHash indicates an expected call of UpdatePasswordHash.
+func (mr *MockRepositoryMockRecorder) UpdatePasswordHash(ctx, id, old, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordHash", reflect.TypeOf((*MockRepository)(nil).UpdatePasswordHash), ctx, id, old, hash)
+}
+
+// UpdatePasswordLogin mocks base method.
+func (m *MockRepository) UpdatePasswordLogin(ctx context.Context, user *model.User) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordLogin", ctx, user)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePasswordLogin indicates an expected call of UpdatePasswordLogin.
+func (mr *MockRepositoryMockRecorder) UpdatePasswordLogin(ctx, user interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), id, person)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordLogin", reflect.TypeOf((*MockRepository)(nil).UpdatePasswordLogin), ctx, user)
 }
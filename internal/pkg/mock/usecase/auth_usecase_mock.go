@@ -5,9 +5,13 @@
 package mock
 
 import (
+	context "context"
 	reflect "reflect"
 
 	model "bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	query "bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	request "bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	two_factor "bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/two_factor"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -34,32 +38,147 @@ func (m *MockUsecase) EXPECT() *MockUsecaseMockRecorder {
 	return m.recorder
 }
 
-// Login mocks base method.
-func (m *MockUsecase) Login(user *model.User) (*model.User, error) {
+// BulkRegister mocks base method.
+func (m *MockUsecase) BulkRegister(ctx context.Context, users request.Users) ([]*model.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Login", user)
-	ret0, _ := ret[0].(*model.User)
+	ret := m.ctrl.Call(m, "BulkRegister", ctx, users)
+	ret0, _ := ret[0].([]*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkRegister indicates an expected call of BulkRegister.
+func (mr *MockUsecaseMockRecorder) BulkRegister(ctx, users interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkRegister", reflect.TypeOf((*MockUsecase)(nil).BulkRegister), ctx, users)
+}
+
+// GetToken mocks base method.
+func (m *MockUsecase) GetToken(ctx context.Context, clientKey, secretKey string) (*request.OpenKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetToken", ctx, clientKey, secretKey)
+	ret0, _ := ret[0].(*request.OpenKey)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// GetToken indicates an expected call of GetToken.
+func (mr *MockUsecaseMockRecorder) GetToken(ctx, clientKey, secretKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetToken", reflect.TypeOf((*MockUsecase)(nil).GetToken), ctx, clientKey, secretKey)
+}
+
+// Login mocks base method.
+func (m *MockUsecase) Login(ctx context.Context, login request.Login) (*model.User, *two_factor.Challenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", ctx, login)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(*two_factor.Challenge)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // Login indicates an expected call of Login.
-func (mr *MockUsecaseMockRecorder) Login(user interface{}) *gomock.Call {
+func (mr *MockUsecaseMockRecorder) Login(ctx, login interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUsecase)(nil).Login), ctx, login)
+}
+
+// ReadLoginAttempts mocks base method.
+func (m *MockUsecase) ReadLoginAttempts(ctx context.Context, actorID int, q *query.Query) (*[]model.LoginAttempt, *query.Page, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadLoginAttempts", ctx, actorID, q)
+	ret0, _ := ret[0].(*[]model.LoginAttempt)
+	ret1, _ := ret[1].(*query.Page)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadLoginAttempts indicates an expected call of ReadLoginAttempts.
+func (mr *MockUsecaseMockRecorder) ReadLoginAttempts(ctx, actorID, q interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockUsecase)(nil).Login), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadLoginAttempts", reflect.TypeOf((*MockUsecase)(nil).ReadLoginAttempts), ctx, actorID, q)
 }
 
 // Register mocks base method.
-func (m *MockUsecase) Register(user *model.User) (*model.User, error) {
+func (m *MockUsecase) Register(ctx context.Context, user request.User) (*model.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Register", user)
+	ret := m.ctrl.Call(m, "Register", ctx, user)
 	ret0, _ := ret[0].(*model.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Register indicates an expected call of Register.
-func (mr *MockUsecaseMockRecorder) Register(user interface{}) *gomock.Call {
+func (mr *MockUsecaseMockRecorder) Register(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockUsecase)(nil).Register), ctx, user)
+}
+
+// Unlock mocks base method.
+func (m *MockUsecase) Unlock(ctx context.Context, actorID, id int) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unlock", ctx, actorID, id)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Unlock indicates an expected call of Unlock.
+func (mr *MockUsecaseMockRecorder) Unlock(ctx, actorID, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockUsecase)(nil).Unlock), ctx, actorID, id)
+}
+
+// ValidateGiro mocks base method.
+func (m *MockUsecase) ValidateGiro(ctx context.Context, code string) (*model.Giro, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateGiro", ctx, code)
+	ret0, _ := ret[0].(*model.Giro)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateGiro indicates an expected call of ValidateGiro.
+func (mr *MockUsecaseMockRecorder) ValidateGiro(ctx, code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateGiro", reflect.TypeOf((*MockUsecase)(nil).ValidateGiro), ctx, code)
+}
+
+// MockChallenger is a mock of Challenger interface.
+type MockChallenger struct {
+	ctrl     *gomock.Controller
+	recorder *MockChallengerMockRecorder
+}
+
+// MockChallengerMockRecorder is the mock recorder for MockChallenger.
+type MockChallengerMockRecorder struct {
+	mock *MockChallenger
+}
+
+// NewMockChallenger creates a new mock instance.
+func NewMockChallenger(ctrl *gomock.Controller) *MockChallenger {
+	mock := &MockChallenger{ctrl: ctrl}
+	mock.recorder = &MockChallengerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockChallenger) EXPECT() *MockChallengerMockRecorder {
+	return m.recorder
+}
+
+// Challenge mocks base method.
+func (m *MockChallenger) Challenge(ctx context.Context, u *model.User) (*two_factor.Challenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Challenge", ctx, u)
+	ret0, _ := ret[0].(*two_factor.Challenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Challenge indicates an expected call of Challenge.
+func (mr *MockChallengerMockRecorder) Challenge(ctx, u interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Register", reflect.TypeOf((*MockUsecase)(nil).Register), user)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Challenge", reflect.TypeOf((*MockChallenger)(nil).Challenge), ctx, u)
 }
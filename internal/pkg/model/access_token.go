@@ -0,0 +1,34 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// AccessToken is a personal access token, authenticating the scripts of a user or a
+// service account without a password. Only TokenHash is kept of the token, and Prefix,
+// its first characters, to recognize it by. It grants no more than Scopes, space
+// separated permissions such as product:read, until ExpiresAt or until it is revoked.
+type AccessToken struct {
+	ID         int        `json:"id" gorm:"primary_key"`
+	UserID     int        `json:"user_id" gorm:"index"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"-" gorm:"unique"`
+	Prefix     string     `json:"prefix"`
+	Scopes     string     `json:"scopes" gorm:"type:text"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip" gorm:"column:last_used_ip"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Active tells whether the token still authenticates.
+func (t *AccessToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// ScopeList splits Scopes.
+func (t *AccessToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+)
+
+// Commodity is an entry of the commodity catalog products are classified by. PariCode
+// is the commodity as PARI knows it; UnitQuantity and UnitPrice are the units a new
+// product of the commodity gets when none are given.
+type Commodity struct {
+	ID           int                    `json:"id" gorm:"primary_key"`
+	Code         string                 `json:"code" gorm:"unique"`
+	Name         string                 `json:"name" gorm:"unique"`
+	Category     enum.CommodityCategory `json:"category"`
+	UnitQuantity string                 `json:"unit_quantity"`
+	UnitPrice    string                 `json:"unit_price"`
+	PariCode     string                 `json:"pari_code"`
+	IsActive     bool                   `json:"is_active" gorm:"default:true"`
+	Version      int                    `json:"version" gorm:"not null;default:1"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+	DeletedAt    *time.Time             `sql:"index" json:"deleted_at"`
+}
@@ -1,15 +1,30 @@
 package model
 
-import "time"
+import (
+	"time"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+)
+
+// Company is a corporate customer. GiroID links it to the giro account it was
+// onboarded with; a company onboarded by itself is pending until a superadmin
+// approves it, and its users cannot log in before. RequireTwoFactor makes its users of
+// verification level 2 and above log in with a TOTP code.
 type Company struct {
-	ID        int        `json:"id" gorm:"primary_key"`
-	Name      string     `json:"name"  gorm:"unique"`
-	Code      string     `json:"code"  gorm:"unique"`
-	Alias     string     `json:"alias"`
-	Address   string     `json:"address"`
-	Giro      string     `json:"giro"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `sql:"index" json:"deleted_at"`
+	ID               int                `json:"id" gorm:"primary_key"`
+	Name             string             `json:"name"  gorm:"unique"`
+	Code             string             `json:"code"  gorm:"unique"`
+	Alias            string             `json:"alias"`
+	Address          string             `json:"address"`
+	Giro             string             `json:"giro"`
+	GiroID           *int               `json:"giro_id" gorm:"unique"`
+	Status           enum.StatusCompany `json:"status" gorm:"type:varchar(20);not null;default:'approved';index"`
+	RejectReason     string             `json:"reject_reason"`
+	ReviewedBy       int                `json:"reviewed_by"`
+	ReviewedAt       *time.Time         `json:"reviewed_at"`
+	RequireTwoFactor bool               `json:"require_two_factor" gorm:"not null;default:false"`
+	Version          int                `json:"version" gorm:"not null;default:1"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+	DeletedAt        *time.Time         `sql:"index" json:"deleted_at"`
 }
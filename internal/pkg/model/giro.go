@@ -2,11 +2,17 @@ package model
 
 import "time"
 
+// Giro is a giro account a company can onboard with. CompanyName is the name of the
+// account holder, which the company onboarded with it starts with; an inactive giro
+// cannot onboard a company.
 type Giro struct {
 	ID          int        `json:"id" gorm:"primary_key"`
 	Code        string     `json:"code"  gorm:"unique"`
 	CompanyName string     `json:"company_name"`
+	IsActive    bool       `json:"is_active" gorm:"not null;default:true"`
+	Version     int        `json:"version" gorm:"not null;default:1"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	DeletedAt   *time.Time `sql:"index" json:"deleted_at"`
+	Company     *Company   `json:"company,omitempty" gorm:"-"`
 }
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+)
+
+// Invitation lets the owner of Email register with the role and company an admin chose
+// and a password of their own. The link sent to them holds a token only TokenHash is
+// kept of; it can be used once, until ExpiresAt, unless the invitation is revoked.
+type Invitation struct {
+	ID                int                    `json:"id" gorm:"primary_key"`
+	Email             string                 `json:"email" gorm:"index"`
+	RoleID            int                    `json:"role_id"`
+	RoleName          string                 `json:"role_name" gorm:"-"`
+	CompanyID         int                    `json:"company_id" gorm:"index"`
+	CompanyName       string                 `json:"company_name" gorm:"-"`
+	VerificationLevel enum.VerificationLevel `json:"verification_level"`
+	TokenHash         string                 `json:"-" gorm:"unique"`
+	Status            enum.StatusInvitation  `json:"status" gorm:"index"`
+	InvitedBy         int                    `json:"invited_by"`
+	UserID            int                    `json:"user_id"`
+	ExpiresAt         time.Time              `json:"expires_at"`
+	AcceptedAt        *time.Time             `json:"accepted_at"`
+	RevokedAt         *time.Time             `json:"revoked_at"`
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+}
+
+// Expired tells whether the invitation can no longer be accepted for its age.
+func (i *Invitation) Expired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
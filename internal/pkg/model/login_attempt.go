@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+)
+
+// LoginAttempt audits a login, successful or not. UserID and CompanyID are those of the
+// user of Email, zero when the email is unknown.
+type LoginAttempt struct {
+	ID        int              `json:"id" gorm:"primary_key"`
+	Email     string           `json:"email" gorm:"index"`
+	UserID    int              `json:"user_id" gorm:"index"`
+	CompanyID int              `json:"company_id" gorm:"index"`
+	IP        string           `json:"ip" gorm:"column:ip;index"`
+	UserAgent string           `json:"user_agent"`
+	Success   bool             `json:"success"`
+	Result    enum.LoginResult `json:"result"`
+	CreatedAt time.Time        `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+)
+
+// OnboardingStep records a step a company took to onboard, and the user who took it
+// when there is one.
+type OnboardingStep struct {
+	ID        int                 `json:"id" gorm:"primary_key"`
+	CompanyID int                 `json:"company_id" gorm:"index"`
+	GiroID    int                 `json:"giro_id"`
+	Step      enum.OnboardingStep `json:"step"`
+	UserID    int                 `json:"user_id"`
+	Note      string              `json:"note"`
+	CreatedAt time.Time           `json:"created_at"`
+}
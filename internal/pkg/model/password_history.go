@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// PasswordHistory is a password hash a user had, kept so it is not chosen again.
+type PasswordHistory struct {
+	ID        int       `json:"id" gorm:"primary_key"`
+	UserID    int       `json:"user_id" gorm:"index"`
+	Password  string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
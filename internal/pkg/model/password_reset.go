@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// PasswordReset lets a user who forgot their password set a new one. The link sent to
+// them holds a token only TokenHash is kept of; it can be used once, until ExpiresAt.
+type PasswordReset struct {
+	ID        int        `json:"id" gorm:"primary_key"`
+	UserID    int        `json:"user_id" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"unique"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Expired tells whether the token can no longer be used for its age.
+func (r *PasswordReset) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
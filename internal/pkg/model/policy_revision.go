@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// PolicyRevision is increased by every instance changing the casbin policy, so the
+// other instances know to reload it. There is a single row.
+type PolicyRevision struct {
+	ID        int       `json:"id" gorm:"primary_key"`
+	Revision  int       `json:"revision" gorm:"not null;default:0"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -5,28 +5,37 @@ import (
 	"time"
 )
 
+// Product is a product offered by a company. Quantity is counted in UnitQuantity and
+// the price amounts, in minor units of Currency, are per UnitPrice. PricePerBaseUnit
+// is the price per base unit of their dimension, e.g. per kg, to compare products by.
 type Product struct {
 	ID               int                `json:"id" gorm:"primary_key"`
 	Name             string             `json:"name"`
 	Description      string             `json:"description"`
 	Quantity         int                `json:"quantity" form:"quantity"`
 	UnitQuantity     string             `json:"unit_quantity"`
-	Price            float64            `json:"price"`
+	PriceAmount      int64              `json:"price_amount"`
 	UnitPrice        string             `json:"unit_price"`
+	Currency         string             `json:"currency" gorm:"type:char(3);not null;default:'IDR'"`
+	BaseUnit         string             `json:"base_unit"`
+	PricePerBaseUnit int64              `json:"price_per_base_unit" gorm:"index"`
 	Image            string             `json:"image"`
 	TmpImagePath     string             `json:"-"`
 	Status           enum.StatusProduct `json:"status"`
 	ProductCreatedAt string             `json:"product_created_at"`
 	ExpiredAt        string             `json:"expired_at"`
+	CommodityID      int                `json:"commodity_id" gorm:"index"`
 	Commodity        string             `json:"commodity"`
 	CompanyID        int                `json:"company_id"`
 	IsPreOrder       bool               `json:"is_pre_order"  gorm:"default:false"`
-	MinPrice         float64            `json:"min_price"`
-	MaxPrice         float64            `json:"max_price"`
+	MinPriceAmount   int64              `json:"min_price_amount"`
+	MaxPriceAmount   int64              `json:"max_price_amount"`
 	PariProductId    string             `json:"pari_product_id" form:"pari_product_id"`
 	IsActive         bool               `json:"is_active" gorm:"default:true"`
+	Version          int                `json:"version" gorm:"not null;default:1"`
 	CreatedAt        time.Time          `json:"created_at"`
 	UpdatedAt        time.Time          `json:"updated_at"`
 	DeletedAt        *time.Time         `sql:"index" json:"deleted_at"`
 	Transaction      []PariTransaction  `json:"transaction" gorm:"-"`
+	PendingRevision  *ProductRevision   `json:"pending_revision" gorm:"-"`
 }
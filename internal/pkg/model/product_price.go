@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// ProductPrice is a price a product took, effective from EffectiveAt, so the rows of a
+// product ordered by it are its price history. RevisionID is the approved revision
+// that changed the price, 0 for prices set before the product was on PARI. SyncedAt is
+// set once PARI has the price.
+type ProductPrice struct {
+	ID               int        `json:"id" gorm:"primary_key"`
+	ProductID        int        `json:"product_id" gorm:"index"`
+	RevisionID       int        `json:"revision_id" gorm:"index"`
+	PriceAmount      int64      `json:"price_amount"`
+	MinPriceAmount   int64      `json:"min_price_amount"`
+	MaxPriceAmount   int64      `json:"max_price_amount"`
+	Currency         string     `json:"currency" gorm:"type:char(3);not null;default:'IDR'"`
+	UnitPrice        string     `json:"unit_price"`
+	PricePerBaseUnit int64      `json:"price_per_base_unit"`
+	EffectiveAt      *time.Time `json:"effective_at"`
+	SyncedAt         *time.Time `json:"synced_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+)
+
+// ProductRevision is an edit of an approved product waiting for its verifiers. Changes
+// holds the edited product columns and their new values as a JSON object; the product
+// keeps its approved values until every verifier approves the revision, and a rejected
+// revision is kept with the reason. SyncedAt is set once PARI has the edit.
+type ProductRevision struct {
+	ID           int                `json:"id" gorm:"primary_key"`
+	ProductID    int                `json:"product_id" gorm:"index"`
+	Changes      string             `json:"changes" gorm:"type:text"`
+	Status       enum.StatusProduct `json:"status" gorm:"index"`
+	RejectReason string             `json:"reject_reason"`
+	RejectedBy   int                `json:"rejected_by"`
+	ReviewedAt   *time.Time         `json:"reviewed_at"`
+	SyncedAt     *time.Time         `json:"synced_at"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// Fields decodes Changes, keeping numbers as json.Number so amounts stay exact.
+func (r *ProductRevision) Fields() (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader([]byte(r.Changes)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// ProductRevisionUser records that a user approved a product revision.
+type ProductRevisionUser struct {
+	ID                int        `json:"id" gorm:"primary_key"`
+	ProductRevisionID int        `json:"product_revision_id" gorm:"column:product_revision_id"`
+	UserID            int        `json:"user_id" gorm:"column:user_id"`
+	CompanyID         int        `json:"company_id" gorm:"column:company_id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	DeletedAt         *time.Time `sql:"index" json:"deleted_at"`
+}
@@ -0,0 +1,19 @@
+package model
+
+// ProductHit is a product found by a full-text search.
+type ProductHit struct {
+	Product
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights" gorm:"-"`
+}
+
+func (ProductHit) TableName() string {
+	return "products"
+}
+
+// ProductSearch is a page of search hits with the number of matching products per
+// value of each facet, e.g. Facets["commodity"]["padi"].
+type ProductSearch struct {
+	Hits   []ProductHit
+	Facets map[string]map[string]int
+}
@@ -6,12 +6,32 @@
 
 package model
 
-import "time"
+import (
+	"time"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+)
+
+// Role groups users for casbin. A role of a company is granted permissions and held by
+// users in that company only, while roles of no company are shared by all of them.
+// RequireTwoFactor makes its users log in with a TOTP code.
 type Role struct {
-	ID        int        `json:"id" gorm:"primary_key"`
-	Name      string     `json:"name"  gorm:"unique"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `sql:"index" json:"deleted_at"`
+	ID               int        `json:"id" gorm:"primary_key"`
+	Name             string     `json:"name"  gorm:"unique_index:idx_roles_company_id_name"`
+	CompanyID        int        `json:"company_id" gorm:"not null;default:0;unique_index:idx_roles_company_id_name"`
+	RequireTwoFactor bool       `json:"require_two_factor" gorm:"not null;default:false"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	DeletedAt        *time.Time `sql:"index" json:"deleted_at"`
+}
+
+// AvailableTo tells whether users of the company can hold the role.
+func (r *Role) AvailableTo(companyID int) bool {
+	return r.CompanyID == 0 || r.CompanyID == companyID
+}
+
+// IsSuperadmin tells whether the role is superadmin, the one shared by all companies
+// that administers the whole platform. A company role of the same name is not.
+func (r *Role) IsSuperadmin() bool {
+	return r.Name == enum.RoleSuperadmin && r.CompanyID == 0
 }
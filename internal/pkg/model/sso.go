@@ -0,0 +1,99 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SSOProvider is the OpenID Connect provider the users of a company log in with. The
+// client secret is kept as is, the provider asking for it at every login.
+type SSOProvider struct {
+	ID           int    `json:"id" gorm:"primary_key"`
+	CompanyID    int    `json:"company_id" gorm:"unique"`
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"-"`
+	// Scopes are space separated, requested along with openid, email and profile.
+	Scopes string `json:"scopes"`
+	// RoleClaim names the claim of the ID token RoleMapping applies to, such as groups.
+	RoleClaim string `json:"role_claim"`
+	// RoleMapping is the JSON of the SSORoleRule list, the first one matching winning.
+	RoleMapping string `json:"-" gorm:"type:text"`
+	// DefaultRoleID is the role of the users provisioned without a rule matching, none
+	// being provisioned then when it is zero.
+	DefaultRoleID int  `json:"default_role_id"`
+	Enabled       bool `json:"enabled"`
+	// ConfiguredBy is the admin who last saved the provider. Existing users granted
+	// more than them are not linked to the identities of the provider.
+	ConfiguredBy int       `json:"configured_by"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SSORoleRule gives RoleID to the users whose role claim holds Value.
+type SSORoleRule struct {
+	Value  string `json:"value"`
+	RoleID int    `json:"role_id"`
+}
+
+// Rules decodes RoleMapping.
+func (p *SSOProvider) Rules() []SSORoleRule {
+	var rules []SSORoleRule
+	if p.RoleMapping == "" || json.Unmarshal([]byte(p.RoleMapping), &rules) != nil {
+		return nil
+	}
+	return rules
+}
+
+// SetRules encodes rules into RoleMapping.
+func (p *SSOProvider) SetRules(rules []SSORoleRule) {
+	if len(rules) == 0 {
+		p.RoleMapping = ""
+		return
+	}
+	b, _ := json.Marshal(rules)
+	p.RoleMapping = string(b)
+}
+
+// Role is the role of the first rule matching one of values, the values of the role
+// claim of a user, or zero when none does.
+func (p *SSOProvider) Role(values []string) int {
+	for _, rule := range p.Rules() {
+		for _, v := range values {
+			if v == rule.Value {
+				return rule.RoleID
+			}
+		}
+	}
+	return 0
+}
+
+// SSOLogin is a login started with a provider, until the user comes back with a code.
+// Only StateHash is kept of the state; Nonce and CodeVerifier are checked against the
+// ID token and sent to the provider with the code. It can be used once, until ExpiresAt.
+type SSOLogin struct {
+	ID           int        `json:"id" gorm:"primary_key"`
+	ProviderID   int        `json:"provider_id" gorm:"index"`
+	StateHash    string     `json:"-" gorm:"unique"`
+	Nonce        string     `json:"-"`
+	CodeVerifier string     `json:"-"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	UsedAt       *time.Time `json:"used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Expired tells whether the login can no longer be completed for its age.
+func (l *SSOLogin) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// UserIdentity links a user to the subject identifying them at a provider. Email is the
+// one the provider vouched for when they were linked.
+type UserIdentity struct {
+	ID         int       `json:"id" gorm:"primary_key"`
+	UserID     int       `json:"user_id" gorm:"index"`
+	ProviderID int       `json:"provider_id" gorm:"unique_index:idx_user_identities_provider_id_subject"`
+	Subject    string    `json:"subject" gorm:"unique_index:idx_user_identities_provider_id_subject"`
+	Email      string    `json:"email"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// TwoFactor is the TOTP secret of a user. It is pending until the user confirms it with
+// a code, and LastCounter is the period of the last code accepted, so no code is
+// accepted twice.
+type TwoFactor struct {
+	ID          int        `json:"id" gorm:"primary_key"`
+	UserID      int        `json:"user_id" gorm:"unique"`
+	Secret      string     `json:"-"`
+	Enabled     bool       `json:"enabled"`
+	LastCounter int64      `json:"-"`
+	EnabledAt   *time.Time `json:"enabled_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// RecoveryCode logs a user in once instead of a TOTP code, e.g. when their phone is
+// lost. Only CodeHash is kept of it.
+type RecoveryCode struct {
+	ID        int        `json:"id" gorm:"primary_key"`
+	UserID    int        `json:"user_id" gorm:"index"`
+	CodeHash  string     `json:"-" gorm:"unique"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -16,12 +16,17 @@ type User struct {
 	Name               string                 `json:"name"`
 	Email              string                 `json:"email"  gorm:"unique"`
 	VerificationLevel  enum.VerificationLevel `sql:"verification_level"`
-	Password           string                 `json:"password"`
+	Password           string                 `json:"-"`
 	RoleID             int                    `json:"role_id" gorm:"column:role_id"`
 	RoleName           string                 `json:"role_name" gorm:"-"`
 	CompanyID          int                    `json:"company_id" gorm:"column:company_id"`
 	CompanyName        string                 `json:"company_name" gorm:"-"`
 	MustChangePassword bool                   `json:"must_change_password" gorm:"default:true"`
+	ServiceAccount     bool                   `json:"service_account" gorm:"not null;default:false"`
+	Version            int                    `json:"version" gorm:"not null;default:1"`
+	SessionVersion     int                    `json:"-" gorm:"not null;default:0"`
+	FailedLogins       int                    `json:"failed_logins" gorm:"not null;default:0"`
+	LockedUntil        *time.Time             `json:"locked_until"`
 	CreatedAt          time.Time              `json:"created_at"`
 	UpdatedAt          time.Time              `json:"updated_at"`
 	DeletedAt          *time.Time             `sql:"index" json:"deleted_at"`
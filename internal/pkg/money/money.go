@@ -0,0 +1,92 @@
+// Package money handles amounts of money stored as integers in the minor unit of their
+// currency, e.g. 550000 IDR is Rp5.500,00. Amounts are never held in floating point
+// so sums and comparisons are exact.
+package money
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency of amounts given without one.
+const DefaultCurrency = "IDR"
+
+// exponents are the number of minor unit digits of the supported ISO 4217 currencies.
+var exponents = map[string]int{
+	"IDR": 2,
+	"USD": 2,
+	"SGD": 2,
+	"MYR": 2,
+	"JPY": 0,
+}
+
+// IsCurrency reports whether code is a supported currency.
+func IsCurrency(code string) bool {
+	_, ok := exponents[code]
+	return ok
+}
+
+// Exponent returns the number of minor unit digits of currency, 2 if it is unknown.
+func Exponent(currency string) int {
+	if exp, ok := exponents[currency]; ok {
+		return exp
+	}
+	return 2
+}
+
+// FromMajor converts an amount in major units, e.g. 5500.5, to minor units, rounding
+// half away from zero. It is meant for prices that were floating point, in the
+// database before amounts were stored in minor units or as PARI sends them.
+func FromMajor(major float64, currency string) int64 {
+	return int64(math.Round(major * math.Pow10(Exponent(currency))))
+}
+
+// Whole returns amount in whole major units, rounding half away from zero.
+func Whole(amount int64, currency string) int64 {
+	return Div(amount, int64(math.Pow10(Exponent(currency))))
+}
+
+// Format writes amount in major units with the digits of its currency, e.g. "5500.00".
+func Format(amount int64, currency string) string {
+	exp := Exponent(currency)
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := strconv.FormatInt(amount, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return fmt.Sprintf("%s%s.%s", sign, digits[:len(digits)-exp], digits[len(digits)-exp:])
+}
+
+// MulDiv returns amount * num / den rounded half away from zero, without overflowing
+// in between.
+func MulDiv(amount, num, den int64) int64 {
+	r := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(amount), big.NewInt(num)), big.NewInt(den))
+	return round(r)
+}
+
+// Div returns a / b rounded half away from zero.
+func Div(a, b int64) int64 {
+	return round(new(big.Rat).SetFrac64(a, b))
+}
+
+func round(r *big.Rat) int64 {
+	num, den := new(big.Int).Abs(r.Num()), r.Denom()
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+	if m.Mul(m, big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
@@ -0,0 +1,40 @@
+package money
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromMajor(t *testing.T) {
+	require.Equal(t, int64(550050), FromMajor(5500.5, "IDR"))
+	require.Equal(t, int64(1999), FromMajor(19.99, "USD"))
+	require.Equal(t, int64(120), FromMajor(119.5, "JPY"))
+}
+
+func TestFormat(t *testing.T) {
+	require.Equal(t, "5500.00", Format(550000, "IDR"))
+	require.Equal(t, "0.05", Format(5, "USD"))
+	require.Equal(t, "-12.30", Format(-1230, "IDR"))
+	require.Equal(t, "1200", Format(1200, "JPY"))
+}
+
+func TestWhole(t *testing.T) {
+	require.Equal(t, int64(5500), Whole(550000, "IDR"))
+	require.Equal(t, int64(5501), Whole(550050, "IDR"))
+	require.Equal(t, int64(1200), Whole(1200, "JPY"))
+}
+
+func TestMulDivRoundsHalfAwayFromZero(t *testing.T) {
+	require.Equal(t, int64(2), MulDiv(3, 1, 2))
+	require.Equal(t, int64(-2), MulDiv(-3, 1, 2))
+	require.Equal(t, int64(1), MulDiv(4, 1, 3))
+	require.Equal(t, int64(math.MaxInt64/1000*1000), MulDiv(math.MaxInt64/1000, 1000000, 1000), "must not overflow in between")
+}
+
+func TestIsCurrency(t *testing.T) {
+	require.True(t, IsCurrency("IDR"))
+	require.False(t, IsCurrency("idr"))
+	require.False(t, IsCurrency("XXX"))
+}
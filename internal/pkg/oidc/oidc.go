@@ -0,0 +1,374 @@
+// Package oidc logs users in with an OpenID Connect provider, through the authorization
+// code flow with PKCE. The provider is discovered from its issuer, and the ID token it
+// returns is verified against its published RS256 keys.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// ErrRejected is wrapped by the errors of a provider refusing the login or returning
+// an ID token that fails verification, as opposed to a provider that cannot be reached.
+var ErrRejected = errors.New("oidc: login rejected")
+
+// Config is how a client is registered with a provider.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes are requested along with openid, email and profile.
+	Scopes []string
+}
+
+// Metadata is the part of the discovery document of a provider the login needs.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims are the verified claims of an ID token. Raw holds all of them, for the
+// claims providers name as they please, such as groups.
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Raw           map[string]interface{}
+}
+
+// Strings reads a claim holding a string or a list of strings.
+func (c *Claims) Strings(name string) []string {
+	switch v := c.Raw[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+type Client interface {
+	// Discover fetches the discovery document of issuer.
+	Discover(ctx context.Context, issuer string) (*Metadata, error)
+	// AuthorizationURL is where the user logs in with the provider, which then redirects
+	// them to the redirect URL with a code and state.
+	AuthorizationURL(ctx context.Context, config Config, state, nonce, challenge string) (string, error)
+	// Login exchanges code for an ID token, proving the login started with verifier, and
+	// verifies it was issued for the client, with nonce.
+	Login(ctx context.Context, config Config, code, verifier, nonce string) (*Claims, error)
+}
+
+type client struct {
+	http *http.Client
+	now  func() time.Time
+
+	mu       sync.Mutex
+	metadata map[string]*Metadata
+	keys     map[string]map[string]*rsa.PublicKey
+}
+
+// NewClient is a client sending its requests with httpClient. Discovery documents and
+// keys are kept in memory, the keys being fetched again when a token is signed with an
+// unknown one.
+func NewClient(httpClient *http.Client) Client {
+	return &client{
+		http:     httpClient,
+		now:      time.Now,
+		metadata: map[string]*Metadata{},
+		keys:     map[string]map[string]*rsa.PublicKey{},
+	}
+}
+
+// NewVerifier is a PKCE code verifier: 43 characters of a random 32 bytes.
+func NewVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge is the S256 PKCE code challenge of verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (e *client) Discover(ctx context.Context, issuer string) (*Metadata, error) {
+	e.mu.Lock()
+	m, ok := e.metadata[issuer]
+	e.mu.Unlock()
+	if ok {
+		return m, nil
+	}
+
+	m = &Metadata{}
+	if err := e.get(ctx, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", m); err != nil {
+		return nil, err
+	}
+	if m.Issuer != issuer {
+		return nil, fmt.Errorf("oidc: discovery document is for issuer %q, not %q", m.Issuer, issuer)
+	}
+	if m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: discovery document of %q misses an endpoint", issuer)
+	}
+
+	e.mu.Lock()
+	e.metadata[issuer] = m
+	e.mu.Unlock()
+	return m, nil
+}
+
+func (e *client) AuthorizationURL(ctx context.Context, config Config, state, nonce, challenge string) (string, error) {
+	m, err := e.Discover(ctx, config.Issuer)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(m.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc: authorization endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", config.ClientID)
+	q.Set("redirect_uri", config.RedirectURL)
+	q.Set("scope", strings.Join(append([]string{"openid", "email", "profile"}, config.Scopes...), " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", challenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func (e *client) Login(ctx context.Context, config Config, code, verifier, nonce string) (*Claims, error) {
+	m, err := e.Discover(ctx, config.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := e.exchange(ctx, m, config, code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	return e.verify(ctx, m, config, raw, nonce)
+}
+
+// exchange redeems code at the token endpoint, authenticating the client with its
+// secret in the body.
+func (e *client) exchange(ctx context.Context, m *Metadata, config Config, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {config.RedirectURL},
+		"client_id":     {config.ClientID},
+		"client_secret": {config.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	res, err := e.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc: token request: %w", err)
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("oidc: token response: %w", err)
+	}
+	// the provider refuses a bad code or verifier with 400, anything else is its failure
+	if res.StatusCode == http.StatusBadRequest || res.StatusCode == http.StatusUnauthorized {
+		return "", fmt.Errorf("%w: %s %s", ErrRejected, body.Error, body.ErrorDescription)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oidc: token endpoint answered %d", res.StatusCode)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("%w: no id_token", ErrRejected)
+	}
+	return body.IDToken, nil
+}
+
+// verify checks the signature of the ID token and that it was issued by the provider,
+// to the client, for this login and is not expired.
+func (e *client) verify(ctx context.Context, m *Metadata, config Config, raw, nonce string) (*Claims, error) {
+	var keyErr error
+	token, err := new(jwt.Parser).ParseWithClaims(raw, jwt.MapClaims{}, func(t *jwt.Token) (interface{}, error) {
+		if t.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+		}
+		kid, _ := t.Header["kid"].(string)
+		key, err := e.key(ctx, m, kid)
+		keyErr = err
+		return key, err
+	})
+	if keyErr != nil && !errors.Is(keyErr, ErrRejected) {
+		return nil, keyErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRejected, err)
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	now := e.now().Unix()
+	if !claims.VerifyExpiresAt(now, true) {
+		return nil, fmt.Errorf("%w: id_token expired", ErrRejected)
+	}
+	if iss, _ := claims["iss"].(string); iss != m.Issuer {
+		return nil, fmt.Errorf("%w: id_token issued by %q", ErrRejected, iss)
+	}
+	if !audience(claims, config.ClientID) {
+		return nil, fmt.Errorf("%w: id_token not issued to the client", ErrRejected)
+	}
+	if n, _ := claims["nonce"].(string); nonce == "" || n != nonce {
+		return nil, fmt.Errorf("%w: id_token nonce does not match", ErrRejected)
+	}
+
+	result := &Claims{Raw: claims}
+	result.Subject, _ = claims["sub"].(string)
+	result.Email, _ = claims["email"].(string)
+	result.Name, _ = claims["name"].(string)
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = v
+	case string:
+		result.EmailVerified = v == "true"
+	}
+	if result.Subject == "" {
+		return nil, fmt.Errorf("%w: id_token has no subject", ErrRejected)
+	}
+	return result, nil
+}
+
+// audience tells whether the token was issued to clientID, the authorized party having
+// to be the client when it was issued to others as well.
+func audience(claims jwt.MapClaims, clientID string) bool {
+	var aud []string
+	switch v := claims["aud"].(type) {
+	case string:
+		aud = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+	}
+	found := false
+	for _, a := range aud {
+		found = found || a == clientID
+	}
+	if !found {
+		return false
+	}
+	if azp, ok := claims["azp"].(string); ok || len(aud) > 1 {
+		return azp == clientID
+	}
+	return true
+}
+
+// key is the public key kid of the provider, fetching the keys again when it is unknown.
+func (e *client) key(ctx context.Context, m *Metadata, kid string) (*rsa.PublicKey, error) {
+	e.mu.Lock()
+	key, ok := e.keys[m.Issuer][kid]
+	e.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := e.get(ctx, m.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		exp, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(exp).Int64())}
+	}
+
+	e.mu.Lock()
+	e.keys[m.Issuer] = keys
+	e.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	// a single key may be used without naming it
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrRejected, kid)
+}
+
+func (e *client) get(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := e.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("oidc: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %s answered %d", u, res.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("oidc: %s: %w", u, err)
+	}
+	return nil
+}
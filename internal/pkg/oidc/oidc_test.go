@@ -0,0 +1,143 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/oidc"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/oidc/oidctest"
+	"github.com/stretchr/testify/require"
+)
+
+const redirectURL = "https://pari.example.com/sso/callback"
+
+func start(t *testing.T, client oidc.Client, config oidc.Config, nonce string) (authURL, verifier string) {
+	verifier, err := oidc.NewVerifier()
+	require.NoError(t, err)
+	require.Len(t, verifier, 43)
+	authURL, err = client.AuthorizationURL(context.Background(), config, "state", nonce, oidc.Challenge(verifier))
+	require.NoError(t, err)
+	return authURL, verifier
+}
+
+func TestLogin(t *testing.T) {
+	p := oidctest.NewProvider()
+	defer p.Close()
+	client := oidc.NewClient(http.DefaultClient)
+	config := p.Config(redirectURL)
+	config.Scopes = []string{"groups"}
+
+	authURL, verifier := start(t, client, config, "nonce")
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	require.Equal(t, "openid email profile groups", u.Query().Get("scope"))
+	require.Equal(t, redirectURL, u.Query().Get("redirect_uri"))
+
+	code, state, err := p.Authorize(authURL, map[string]interface{}{
+		"sub":            "u-1",
+		"email":          "alex@example.com",
+		"email_verified": true,
+		"name":           "Alex",
+		"groups":         []string{"finance", "ops"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "state", state)
+
+	claims, err := client.Login(context.Background(), config, code, verifier, "nonce")
+	require.NoError(t, err)
+	require.Equal(t, "u-1", claims.Subject)
+	require.Equal(t, "alex@example.com", claims.Email)
+	require.True(t, claims.EmailVerified)
+	require.Equal(t, "Alex", claims.Name)
+	require.Equal(t, []string{"finance", "ops"}, claims.Strings("groups"))
+	require.Nil(t, claims.Strings("missing"))
+
+	// a code is redeemed once
+	_, err = client.Login(context.Background(), config, code, verifier, "nonce")
+	require.True(t, errors.Is(err, oidc.ErrRejected))
+}
+
+func TestLoginRejected(t *testing.T) {
+	p := oidctest.NewProvider()
+	defer p.Close()
+	client := oidc.NewClient(http.DefaultClient)
+	config := p.Config(redirectURL)
+	claims := map[string]interface{}{"sub": "u-1"}
+
+	tests := map[string]struct {
+		claims   map[string]interface{}
+		verifier string
+		nonce    string
+		config   func(oidc.Config) oidc.Config
+	}{
+		"wrong verifier": {verifier: "another-verifier"},
+		"wrong nonce":    {nonce: "another"},
+		"wrong secret": {config: func(c oidc.Config) oidc.Config {
+			c.ClientSecret = "guess"
+			return c
+		}},
+		"other audience": {claims: map[string]interface{}{"sub": "u-1", "aud": "other"}},
+		"other party":    {claims: map[string]interface{}{"sub": "u-1", "aud": []string{"pari", "other"}, "azp": "other"}},
+		"other issuer":   {claims: map[string]interface{}{"sub": "u-1", "iss": "https://evil.example.com"}},
+		"expired":        {claims: map[string]interface{}{"sub": "u-1", "exp": time.Now().Add(-time.Minute).Unix()}},
+		"no subject":     {claims: map[string]interface{}{"email": "alex@example.com"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			authURL, verifier := start(t, client, config, "nonce")
+			c := claims
+			if tt.claims != nil {
+				c = tt.claims
+			}
+			code, _, err := p.Authorize(authURL, c)
+			require.NoError(t, err)
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+			nonce := "nonce"
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			cfg := config
+			if tt.config != nil {
+				cfg = tt.config(config)
+			}
+
+			_, err = client.Login(context.Background(), cfg, code, verifier, nonce)
+			require.Error(t, err)
+			require.True(t, errors.Is(err, oidc.ErrRejected), err.Error())
+		})
+	}
+}
+
+func TestAudience(t *testing.T) {
+	p := oidctest.NewProvider()
+	defer p.Close()
+	client := oidc.NewClient(http.DefaultClient)
+	config := p.Config(redirectURL)
+
+	authURL, verifier := start(t, client, config, "nonce")
+	code, _, err := p.Authorize(authURL, map[string]interface{}{"sub": "u-1", "aud": []string{"other", "pari"}, "azp": "pari"})
+	require.NoError(t, err)
+	claims, err := client.Login(context.Background(), config, code, verifier, "nonce")
+	require.NoError(t, err)
+	require.Equal(t, "u-1", claims.Subject)
+}
+
+func TestUnreachable(t *testing.T) {
+	p := oidctest.NewProvider()
+	issuer := p.Issuer()
+	p.Close()
+
+	client := oidc.NewClient(http.DefaultClient)
+	_, err := client.Discover(context.Background(), issuer)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, oidc.ErrRejected))
+
+	_, err = client.Discover(context.Background(), "http://127.0.0.1:1")
+	require.Error(t, err)
+}
@@ -0,0 +1,170 @@
+// Package oidctest runs an OpenID Connect provider in memory, for testing logins without
+// a real identity provider.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/oidc"
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Provider is an OpenID Connect provider knowing a single client. Users log in by
+// calling Authorize with the claims the provider vouches for.
+type Provider struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]grant
+	seq   int
+}
+
+type grant struct {
+	redirectURL string
+	challenge   string
+	nonce       string
+	claims      map[string]interface{}
+}
+
+const kid = "oidctest"
+
+// NewProvider starts a provider, closed with Close.
+func NewProvider() *Provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	p := &Provider{ClientID: "pari", ClientSecret: "secret", key: key, codes: map[string]grant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/keys", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *Provider) Close() { p.Server.Close() }
+
+// Issuer is the URL the provider is discovered from.
+func (p *Provider) Issuer() string { return p.Server.URL }
+
+// Config is a client configuration for the provider, redirecting to redirectURL.
+func (p *Provider) Config(redirectURL string) oidc.Config {
+	return oidc.Config{Issuer: p.Issuer(), ClientID: p.ClientID, ClientSecret: p.ClientSecret, RedirectURL: redirectURL}
+}
+
+// Authorize logs a user in at authURL with claims, returning the code and state the
+// provider redirects them back with. Claims default to those of an ID token issued to
+// the client, with the nonce of the login.
+func (p *Provider) Authorize(authURL string, claims map[string]interface{}) (code, state string, err error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	q := u.Query()
+	if q.Get("client_id") != p.ClientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		return "", "", fmt.Errorf("oidctest: invalid authorization request %s", authURL)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seq++
+	code = fmt.Sprintf("code-%d", p.seq)
+	p.codes[code] = grant{
+		redirectURL: q.Get("redirect_uri"),
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+		claims:      claims,
+	}
+	return code, q.Get("state"), nil
+}
+
+// Sign signs claims with the key of the provider.
+func (p *Provider) Sign(claims map[string]interface{}) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims(claims))
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, oidc.Metadata{
+		Issuer:                p.Issuer(),
+		AuthorizationEndpoint: p.Issuer() + "/authorize",
+		TokenEndpoint:         p.Issuer() + "/token",
+		JWKSURI:               p.Issuer() + "/keys",
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, _ *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if r.PostForm.Get("client_id") != p.ClientID || r.PostForm.Get("client_secret") != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	g, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("redirect_uri") != g.redirectURL ||
+		oidc.Challenge(r.PostForm.Get("code_verifier")) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := map[string]interface{}{
+		"iss":   p.Issuer(),
+		"aud":   p.ClientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(5 * time.Minute).Unix(),
+		"nonce": g.nonce,
+	}
+	for k, v := range g.claims {
+		claims[k] = v
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-" + r.PostForm.Get("code"),
+		"token_type":   "Bearer",
+		"id_token":     p.Sign(claims),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
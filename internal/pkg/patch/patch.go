@@ -0,0 +1,168 @@
+// Package patch applies JSON Merge Patch (RFC 7396) documents to request DTOs.
+//
+// A patch is merged into the DTO built from the stored resource, so members that are
+// absent keep their current value, members set to null are reset to their zero value
+// and members set to false, 0 or "" are written as such. Only the members present in
+// the patch are returned as columns to update, keyed by their json name, which must
+// therefore match the database column.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// ContentType is the media type of a merge patch document.
+const ContentType = "application/merge-patch+json"
+
+// Document is a parsed merge patch.
+type Document map[string]interface{}
+
+// Parse reads a merge patch document. The document must be a JSON object.
+func Parse(r io.Reader) (Document, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var doc Document
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, apperror.BadRequest("invalid_patch", "patch must be a JSON object").Wrap(err)
+	}
+	if doc == nil {
+		return nil, apperror.BadRequest("invalid_patch", "patch must be a JSON object")
+	}
+	return doc, nil
+}
+
+// Apply merges doc into dst, a pointer to a DTO holding the current state of the
+// resource, validates the result and returns the changed columns. Members that are
+// not fields of dst are rejected.
+func (doc Document) Apply(dst interface{}) (map[string]interface{}, error) {
+	fields := jsonFields(dst)
+
+	var unknown []apperror.FieldError
+	for _, key := range doc.keys() {
+		if _, ok := fields[key]; !ok {
+			unknown = append(unknown, apperror.FieldError{Field: key, Message: "cannot be changed"})
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, apperror.Validation("validation_error", "request validation failed").WithDetails(unknown...)
+	}
+
+	current, err := toMap(dst)
+	if err != nil {
+		return nil, apperror.Internal("patch_failed", "failed applying patch").Wrap(err)
+	}
+	merged, err := json.Marshal(merge(current, doc))
+	if err != nil {
+		return nil, apperror.Internal("patch_failed", "failed applying patch").Wrap(err)
+	}
+
+	target := reflect.ValueOf(dst).Elem()
+	target.Set(reflect.Zero(target.Type()))
+	if err := json.Unmarshal(merged, dst); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, apperror.Validation("validation_error", "request validation failed").WithDetails(apperror.FieldError{
+				Field:   typeErr.Field,
+				Message: fmt.Sprintf("must be a %s", typeErr.Type.Kind()),
+			}).Wrap(err)
+		}
+		return nil, apperror.BadRequest("invalid_patch", "invalid patch").Wrap(err)
+	}
+
+	if err := binding.Validator.ValidateStruct(dst); err != nil {
+		return nil, validation.FromBind(err)
+	}
+
+	fields = jsonFields(dst)
+	columns := make(map[string]interface{}, len(doc))
+	for _, key := range doc.keys() {
+		columns[key] = fields[key].Interface()
+	}
+	return columns, nil
+}
+
+func (doc Document) keys() []string {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// merge implements the MergePatch algorithm of RFC 7396.
+func merge(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := asObject(patch)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := asObject(target)
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = merge(targetObj[key], value)
+	}
+	return targetObj
+}
+
+func asObject(v interface{}) (map[string]interface{}, bool) {
+	switch obj := v.(type) {
+	case Document:
+		return obj, true
+	case map[string]interface{}:
+		return obj, true
+	default:
+		return nil, false
+	}
+}
+
+func toMap(v interface{}) (map[string]interface{}, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var result map[string]interface{}
+	return result, decoder.Decode(&result)
+}
+
+// jsonFields maps the json name of every exported field of the struct dst points to.
+func jsonFields(dst interface{}) map[string]reflect.Value {
+	value := reflect.ValueOf(dst).Elem()
+	fields := make(map[string]reflect.Value, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = value.Field(i)
+	}
+	return fields
+}
@@ -0,0 +1,104 @@
+package patch
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
+	"github.com/stretchr/testify/require"
+)
+
+func currentProduct() request.UpdateProduct {
+	return request.UpdateProduct{
+		Name:             "Gabah Kering Panen",
+		Description:      "Gabah dari Karawang",
+		Quantity:         10,
+		UnitQuantity:     "kg",
+		PriceAmount:      550000,
+		UnitPrice:        "kg",
+		Currency:         "IDR",
+		MinPriceAmount:   500000,
+		MaxPriceAmount:   600000,
+		ProductCreatedAt: "2022-06-01",
+		ExpiredAt:        "2022-06-30",
+		CommodityID:      1,
+		IsActive:         true,
+	}
+}
+
+func TestApply(t *testing.T) {
+	validation.Register()
+
+	tests := []struct {
+		name    string
+		patch   string
+		columns map[string]interface{}
+		status  int
+		field   string
+	}{
+		{
+			name:    "merges given members only",
+			patch:   `{"quantity": 0, "is_active": false}`,
+			columns: map[string]interface{}{"quantity": 0, "is_active": false},
+		},
+		{
+			name:    "null resets a member",
+			patch:   `{"description": null}`,
+			columns: map[string]interface{}{"description": ""},
+		},
+		{
+			name:   "null on a required member fails validation",
+			patch:  `{"name": null}`,
+			status: http.StatusUnprocessableEntity,
+			field:  "name",
+		},
+		{
+			name:   "unknown member is rejected",
+			patch:  `{"company_id": 2}`,
+			status: http.StatusUnprocessableEntity,
+			field:  "company_id",
+		},
+		{
+			name:   "wrong type is rejected",
+			patch:  `{"quantity": "ten"}`,
+			status: http.StatusUnprocessableEntity,
+			field:  "quantity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(strings.NewReader(tt.patch))
+			require.NoError(t, err)
+
+			dto := currentProduct()
+			columns, err := doc.Apply(&dto)
+			if tt.status == 0 {
+				require.NoError(t, err)
+				require.Equal(t, tt.columns, columns)
+				require.Equal(t, "Gabah Kering Panen", dto.Name)
+				return
+			}
+
+			var appErr *apperror.Error
+			require.True(t, errors.As(err, &appErr))
+			require.Equal(t, tt.status, appErr.StatusCode())
+			require.NotEmpty(t, appErr.Details)
+			require.Equal(t, tt.field, appErr.Details[0].Field)
+		})
+	}
+}
+
+func TestParseRejectsNonObject(t *testing.T) {
+	for _, body := range []string{`[]`, `null`, `{`} {
+		_, err := Parse(strings.NewReader(body))
+
+		var appErr *apperror.Error
+		require.True(t, errors.As(err, &appErr), body)
+		require.Equal(t, "invalid_patch", appErr.Code)
+	}
+}
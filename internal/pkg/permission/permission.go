@@ -0,0 +1,130 @@
+// Package permission is the catalog of what casbin grants roles, and what each role is
+// granted by default.
+package permission
+
+import (
+	"strings"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+)
+
+// Permission is an action on a kind of resource, stored in casbin as the object and
+// action of a policy and written as object:action, e.g. product:verify.
+type Permission struct {
+	Object string
+	Action string
+}
+
+// Parse reads a permission written as object:action.
+func Parse(s string) (Permission, bool) {
+	object, action, ok := strings.Cut(s, ":")
+	if !ok || object == "" || action == "" {
+		return Permission{}, false
+	}
+	return Permission{object, action}, true
+}
+
+// Lookup reads a permission of the catalog written as object:action.
+func Lookup(s string) (Permission, bool) {
+	p, ok := Parse(s)
+	if !ok {
+		return Permission{}, false
+	}
+	for _, c := range Catalog {
+		if c == p {
+			return p, true
+		}
+	}
+	return Permission{}, false
+}
+
+func (p Permission) String() string {
+	return p.Object + ":" + p.Action
+}
+
+// Missing are the permissions of wanted that are not granted, in the order of wanted.
+func Missing(granted, wanted []Permission) []Permission {
+	held := make(map[Permission]bool, len(granted))
+	for _, p := range granted {
+		held[p] = true
+	}
+	var result []Permission
+	for _, p := range wanted {
+		if !held[p] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+var (
+	UserRead         = Permission{"user", "read"}
+	UserManage       = Permission{"user", "manage"}
+	RoleRead         = Permission{"role", "read"}
+	RoleManage       = Permission{"role", "manage"}
+	CompanyRead      = Permission{"company", "read"}
+	CompanyManage    = Permission{"company", "manage"}
+	CommodityRead    = Permission{"commodity", "read"}
+	CommodityManage  = Permission{"commodity", "manage"}
+	ProductRead      = Permission{"product", "read"}
+	ProductCreate    = Permission{"product", "create"}
+	ProductUpdate    = Permission{"product", "update"}
+	ProductDelete    = Permission{"product", "delete"}
+	ProductVerify    = Permission{"product", "verify"}
+	PreorderRead     = Permission{"preorder", "read"}
+	PreorderCreate   = Permission{"preorder", "create"}
+	PreorderUpdate   = Permission{"preorder", "update"}
+	PreorderDelete   = Permission{"preorder", "delete"}
+	PreorderVerify   = Permission{"preorder", "verify"}
+	GiroManage       = Permission{"giro", "manage"}
+	InvitationManage = Permission{"invitation", "manage"}
+	OnboardingManage = Permission{"onboarding", "manage"}
+	LoginManage      = Permission{"login", "manage"}
+	TrashManage      = Permission{"trash", "manage"}
+)
+
+// Catalog is every permission a route requires. Routes about the current user only,
+// such as changing their password or their two-factor authentication, require none.
+var Catalog = []Permission{
+	UserRead, UserManage,
+	RoleRead, RoleManage,
+	CompanyRead, CompanyManage,
+	CommodityRead, CommodityManage,
+	ProductRead, ProductCreate, ProductUpdate, ProductDelete, ProductVerify,
+	PreorderRead, PreorderCreate, PreorderUpdate, PreorderDelete, PreorderVerify,
+	GiroManage, InvitationManage, OnboardingManage, LoginManage, TrashManage,
+}
+
+// Baseline is what roles created later are granted: reading what every user of a
+// company sees.
+var Baseline = []Permission{UserRead, RoleRead, CompanyRead, CommodityRead, ProductRead, PreorderRead}
+
+// Defaults is what the built-in roles are granted. Company users submit products and
+// pre-orders, which verificators verify. Admins define the roles of their company.
+var Defaults = map[string][]Permission{
+	enum.RoleSuperadmin: Catalog,
+	enum.RoleCompanyAdmin: with(Baseline,
+		ProductCreate, ProductUpdate, ProductDelete,
+		PreorderCreate, PreorderUpdate, PreorderDelete,
+		RoleManage, InvitationManage, LoginManage),
+	"verificator": with(Baseline, ProductVerify, PreorderVerify),
+	"user": with(Baseline,
+		ProductCreate, ProductUpdate, ProductDelete,
+		PreorderCreate, PreorderUpdate, PreorderDelete),
+}
+
+func with(base []Permission, more ...Permission) []Permission {
+	return append(append(make([]Permission, 0, len(base)+len(more)), base...), more...)
+}
+
+// legacy maps the permissions granted before the catalog to their replacement.
+var legacy = map[Permission]Permission{
+	{"report", "read"}:      UserRead,
+	{"report", "write"}:     UserManage,
+	{"commodity", "write"}:  CommodityManage,
+	{"trash", "write"}:      TrashManage,
+	{"giro", "write"}:       GiroManage,
+	{"invitation", "write"}: InvitationManage,
+	{"onboarding", "write"}: OnboardingManage,
+	{"login", "write"}:      LoginManage,
+}
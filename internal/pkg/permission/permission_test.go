@@ -0,0 +1,168 @@
+package permission
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin"
+	"github.com/stretchr/testify/require"
+)
+
+func newEnforcer() *casbin.SyncedEnforcer {
+	return casbin.NewSyncedEnforcer("../config/rbac_model.conf", false)
+}
+
+func TestParse(t *testing.T) {
+	p, ok := Parse("product:verify")
+	require.True(t, ok)
+	require.Equal(t, ProductVerify, p)
+	require.Equal(t, "product:verify", p.String())
+
+	for _, s := range []string{"product", ":verify", "product:", ""} {
+		_, ok := Parse(s)
+		require.False(t, ok, s)
+	}
+}
+
+// TestMatrix asserts what every built-in role is granted, once seeded, whatever the
+// company of its users.
+func TestMatrix(t *testing.T) {
+	enforcer := newEnforcer()
+	Seed(enforcer)
+	enforcer.AddGroupingPolicy("1", "superadmin", AllCompanies)
+	enforcer.AddGroupingPolicy("2", "admin", "5")
+	enforcer.AddGroupingPolicy("3", "verificator", "5")
+	enforcer.AddGroupingPolicy("4", "user", "6")
+	domains := []string{AllCompanies, "5", "5", "6"}
+
+	const (
+		superadmin = 1 << iota
+		admin
+		verificator
+		user
+		everyone = superadmin | admin | verificator | user
+	)
+	matrix := map[Permission]int{
+		UserRead:         everyone,
+		UserManage:       superadmin,
+		RoleRead:         everyone,
+		RoleManage:       superadmin | admin,
+		CompanyRead:      everyone,
+		CompanyManage:    superadmin,
+		CommodityRead:    everyone,
+		CommodityManage:  superadmin,
+		ProductRead:      everyone,
+		ProductCreate:    superadmin | admin | user,
+		ProductUpdate:    superadmin | admin | user,
+		ProductDelete:    superadmin | admin | user,
+		ProductVerify:    superadmin | verificator,
+		PreorderRead:     everyone,
+		PreorderCreate:   superadmin | admin | user,
+		PreorderUpdate:   superadmin | admin | user,
+		PreorderDelete:   superadmin | admin | user,
+		PreorderVerify:   superadmin | verificator,
+		GiroManage:       superadmin,
+		InvitationManage: superadmin | admin,
+		OnboardingManage: superadmin,
+		LoginManage:      superadmin | admin,
+		TrashManage:      superadmin,
+	}
+	require.Len(t, matrix, len(Catalog))
+
+	for p, granted := range matrix {
+		for i, sub := range []string{"1", "2", "3", "4"} {
+			want := granted&(1<<i) != 0
+			require.Equal(t, want, enforcer.Enforce(sub, domains[i], p.Object, p.Action), "user %s, %s", sub, p)
+		}
+	}
+
+	// users without a role are granted nothing
+	require.False(t, enforcer.Enforce("5", "5", ProductRead.Object, ProductRead.Action))
+}
+
+// TestDomains asserts that a role of a company grants nothing in another company, where
+// its users hold no role anyway.
+func TestDomains(t *testing.T) {
+	enforcer := newEnforcer()
+	Set(enforcer, "auditor", "5", []Permission{UserRead})
+	Set(enforcer, "auditor", "6", []Permission{RoleRead})
+	Assign(enforcer, 7, "auditor", "5")
+	enforcer.AddGroupingPolicy("8", "auditor", "6")
+
+	require.True(t, enforcer.Enforce("7", "5", UserRead.Object, UserRead.Action))
+	require.False(t, enforcer.Enforce("7", "5", RoleRead.Object, RoleRead.Action))
+	require.False(t, enforcer.Enforce("7", "6", UserRead.Object, UserRead.Action))
+	require.False(t, enforcer.Enforce("7", "6", RoleRead.Object, RoleRead.Action))
+	require.True(t, enforcer.Enforce("8", "6", RoleRead.Object, RoleRead.Action))
+
+	Remove(enforcer, "auditor", "5")
+	require.False(t, enforcer.Enforce("7", "5", UserRead.Object, UserRead.Action))
+	require.True(t, enforcer.Enforce("8", "6", RoleRead.Object, RoleRead.Action))
+}
+
+func TestSeedMigratesLegacyPolicies(t *testing.T) {
+	enforcer := newEnforcer()
+	enforcer.AddPolicy("supeardmin", AllCompanies, "report", "read")
+	enforcer.AddPolicy("auditor", AllCompanies, "report", "read")
+	enforcer.AddPolicy("auditor", AllCompanies, "login", "write")
+
+	Seed(enforcer)
+	Seed(enforcer)
+
+	require.Empty(t, enforcer.GetFilteredPolicy(0, "supeardmin"))
+	require.ElementsMatch(t, [][]string{
+		{"auditor", AllCompanies, "user", "read"},
+		{"auditor", AllCompanies, "login", "manage"},
+	}, enforcer.GetFilteredPolicy(0, "auditor"))
+	require.Len(t, enforcer.GetFilteredPolicy(0, "superadmin"), len(Catalog))
+}
+
+func TestSeedKeepsRevocations(t *testing.T) {
+	enforcer := newEnforcer()
+	Seed(enforcer)
+	enforcer.RemovePolicy("admin", AllCompanies, InvitationManage.Object, InvitationManage.Action)
+
+	Seed(enforcer)
+
+	require.False(t, enforcer.HasPolicy("admin", AllCompanies, InvitationManage.Object, InvitationManage.Action))
+	require.True(t, enforcer.HasPolicy(seedSubject, AllCompanies, "catalog", CatalogVersion))
+}
+
+func TestAssign(t *testing.T) {
+	enforcer := newEnforcer()
+	Set(enforcer, "auditor", "5", []Permission{UserRead, RoleRead})
+	Set(enforcer, "clerk", AllCompanies, []Permission{ProductCreate})
+
+	Assign(enforcer, 7, "auditor", "5")
+	Assign(enforcer, 7, "clerk", "5")
+
+	roles, permissions := Effective(enforcer, 7, "5")
+	require.Equal(t, []string{"clerk"}, roles)
+	require.Equal(t, []Permission{ProductCreate}, permissions)
+
+	Assign(enforcer, 7, "", "5")
+	roles, permissions = Effective(enforcer, 7, "5")
+	require.Empty(t, roles)
+	require.Empty(t, permissions)
+}
+
+func TestRename(t *testing.T) {
+	enforcer := newEnforcer()
+	Set(enforcer, "auditor", "5", []Permission{UserRead, RoleRead})
+	Assign(enforcer, 7, "auditor", "5")
+
+	Rename(enforcer, "auditor", "reviewer", "5")
+
+	require.Empty(t, Of(enforcer, "auditor", "5"))
+	roles, permissions := Effective(enforcer, 7, "5")
+	require.Equal(t, []string{"reviewer"}, roles)
+	require.Equal(t, []Permission{RoleRead, UserRead}, permissions)
+
+	Remove(enforcer, "reviewer", "5")
+	roles, _ = Effective(enforcer, 7, "5")
+	require.Empty(t, roles)
+}
+
+func TestMissing(t *testing.T) {
+	require.Equal(t, []Permission{ProductVerify, PreorderVerify}, Missing(Defaults["admin"], Defaults["verificator"]))
+	require.Empty(t, Missing(Catalog, Defaults["user"]))
+}
@@ -0,0 +1,195 @@
+package permission
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/casbin/casbin"
+)
+
+// CatalogVersion is increased whenever the defaults change, so they are seeded again.
+// Between two versions, what admins grant and revoke is left alone.
+const CatalogVersion = "2"
+
+// AllCompanies is the domain of the policies granted in every company, those of the
+// roles shared by all companies.
+const AllCompanies = "*"
+
+// seedSubject names the policy recording the catalog version seeded. No user is grouped
+// into it, so it grants nothing.
+const seedSubject = "_seed"
+
+// Domain is the casbin domain of a company, AllCompanies for the roles of none.
+func Domain(companyID int) string {
+	if companyID == 0 {
+		return AllCompanies
+	}
+	return strconv.Itoa(companyID)
+}
+
+// Seed grants the built-in roles their default permissions they miss, and replaces the
+// permissions granted before the catalog with their replacement, whatever the role,
+// once per CatalogVersion.
+func Seed(enforcer *casbin.SyncedEnforcer) {
+	if enforcer.HasPolicy(seedSubject, AllCompanies, "catalog", CatalogVersion) {
+		return
+	}
+
+	// superadmin was once seeded misspelled
+	enforcer.RemoveFilteredPolicy(0, "supeardmin")
+
+	for _, rule := range enforcer.GetPolicy() {
+		if len(rule) < 4 {
+			continue
+		}
+		replacement, ok := legacy[Permission{rule[2], rule[3]}]
+		if !ok {
+			continue
+		}
+		if !enforcer.HasPolicy(rule[0], rule[1], replacement.Object, replacement.Action) {
+			enforcer.AddPolicy(rule[0], rule[1], replacement.Object, replacement.Action)
+		}
+		enforcer.RemovePolicy(rule[0], rule[1], rule[2], rule[3])
+	}
+
+	for role, permissions := range Defaults {
+		Grant(enforcer, role, AllCompanies, permissions...)
+	}
+
+	enforcer.RemoveFilteredPolicy(0, seedSubject)
+	enforcer.AddPolicy(seedSubject, AllCompanies, "catalog", CatalogVersion)
+}
+
+// Grant adds the permissions role misses in domain.
+func Grant(enforcer *casbin.SyncedEnforcer, role, domain string, permissions ...Permission) {
+	for _, p := range permissions {
+		if hasPolicy := enforcer.HasPolicy(role, domain, p.Object, p.Action); !hasPolicy {
+			enforcer.AddPolicy(role, domain, p.Object, p.Action)
+		}
+	}
+}
+
+// Of is what role is granted in domain, sorted.
+func Of(enforcer *casbin.SyncedEnforcer, role, domain string) []Permission {
+	var result []Permission
+	for _, rule := range enforcer.GetFilteredPolicy(0, role, domain) {
+		if len(rule) >= 4 {
+			result = append(result, Permission{rule[2], rule[3]})
+		}
+	}
+	sortPermissions(result)
+	return result
+}
+
+// Set grants role exactly permissions in domain, revoking the others.
+func Set(enforcer *casbin.SyncedEnforcer, role, domain string, permissions []Permission) {
+	wanted := make(map[Permission]bool, len(permissions))
+	for _, p := range permissions {
+		wanted[p] = true
+	}
+	for _, p := range Of(enforcer, role, domain) {
+		if !wanted[p] {
+			enforcer.RemovePolicy(role, domain, p.Object, p.Action)
+		}
+	}
+	Grant(enforcer, role, domain, permissions...)
+}
+
+// Subject is how casbin names a user.
+func Subject(userID int) string {
+	return strconv.Itoa(userID)
+}
+
+// Assign makes role the only role of the user, in the domain of their company, or
+// leaves them without any when role is empty.
+func Assign(enforcer *casbin.SyncedEnforcer, userID int, role, domain string) {
+	sub := Subject(userID)
+	for _, current := range enforcer.GetFilteredGroupingPolicy(0, sub) {
+		if len(current) != 3 || current[1] != role || current[2] != domain {
+			enforcer.RemoveGroupingPolicy(toInterfaces(current)...)
+		}
+	}
+	if role != "" && !enforcer.HasGroupingPolicy(sub, role, domain) {
+		enforcer.AddGroupingPolicy(sub, role, domain)
+	}
+}
+
+// Rename moves the permissions and users of a role of domain to its new name.
+func Rename(enforcer *casbin.SyncedEnforcer, from, to, domain string) {
+	Grant(enforcer, to, domain, Of(enforcer, from, domain)...)
+	enforcer.RemoveFilteredPolicy(0, from, domain)
+	for _, rule := range groupings(enforcer, from, domain) {
+		enforcer.RemoveGroupingPolicy(rule[0], rule[1], rule[2])
+		enforcer.AddGroupingPolicy(rule[0], to, rule[2])
+	}
+}
+
+// Remove forgets the permissions and users of a role of domain.
+func Remove(enforcer *casbin.SyncedEnforcer, role, domain string) {
+	enforcer.RemoveFilteredPolicy(0, role, domain)
+	for _, rule := range groupings(enforcer, role, domain) {
+		enforcer.RemoveGroupingPolicy(rule[0], rule[1], rule[2])
+	}
+}
+
+// Effective is what the user is granted in the domain of their company, through their
+// roles, sorted, along with their roles.
+func Effective(enforcer *casbin.SyncedEnforcer, userID int, domain string) ([]string, []Permission) {
+	var roles []string
+	for _, rule := range enforcer.GetFilteredGroupingPolicy(0, Subject(userID)) {
+		if len(rule) == 3 && rule[2] == domain {
+			roles = append(roles, rule[1])
+		}
+	}
+
+	seen := map[Permission]bool{}
+	var result []Permission
+	for _, role := range roles {
+		for _, p := range Grants(enforcer, role, domain) {
+			if !seen[p] {
+				seen[p] = true
+				result = append(result, p)
+			}
+		}
+	}
+	sortPermissions(result)
+	sort.Strings(roles)
+	return roles, result
+}
+
+// Grants is what role grants its users in domain: its permissions in domain along with
+// those it is granted in all companies, sorted.
+func Grants(enforcer *casbin.SyncedEnforcer, role, domain string) []Permission {
+	result := Of(enforcer, role, domain)
+	if domain != AllCompanies {
+		result = append(result, Of(enforcer, role, AllCompanies)...)
+	}
+	sortPermissions(result)
+	return result
+}
+
+// groupings are the users grouped into a role of domain. The roles shared by all
+// companies are held in the domain of each company.
+func groupings(enforcer *casbin.SyncedEnforcer, role, domain string) [][]string {
+	var result [][]string
+	for _, rule := range enforcer.GetFilteredGroupingPolicy(1, role) {
+		if len(rule) == 3 && (domain == AllCompanies || rule[2] == domain) {
+			result = append(result, rule)
+		}
+	}
+	return result
+}
+
+func toInterfaces(rule []string) []interface{} {
+	result := make([]interface{}, 0, len(rule))
+	for _, s := range rule {
+		result = append(result, s)
+	}
+	return result
+}
+
+func sortPermissions(permissions []Permission) {
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].String() < permissions[j].String()
+	})
+}
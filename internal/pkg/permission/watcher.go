@@ -0,0 +1,110 @@
+package permission
+
+import (
+	"sync"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"github.com/jinzhu/gorm"
+)
+
+// revisionID is the id of the single row of policy_revisions.
+const revisionID = 1
+
+// Watcher is a casbin watcher telling the instances sharing a database that the policy
+// changed. Changing it increases the revision stored in policy_revisions, which every
+// instance polls, reloading the policy when another one increased it.
+type Watcher struct {
+	db       *gorm.DB
+	mu       sync.Mutex
+	revision int
+	callback func(string)
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// NewWatcher polls the revision every interval until it is closed.
+func NewWatcher(db *gorm.DB, interval time.Duration) (*Watcher, error) {
+	var revision = model.PolicyRevision{}
+	if err := db.Where(model.PolicyRevision{ID: revisionID}).FirstOrCreate(&revision).Error; err != nil {
+		return nil, err
+	}
+	w := &Watcher{db: db, revision: revision.Revision, stop: make(chan struct{})}
+	go w.poll(interval)
+	return w, nil
+}
+
+// SetUpdateCallback sets what is called when another instance changed the policy,
+// usually the LoadPolicy of the enforcer.
+func (w *Watcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callback = callback
+	return nil
+}
+
+// Update tells the other instances this one changed the policy. It is called by the
+// enforcer holding its lock, so it never calls back itself.
+func (w *Watcher) Update() error {
+	tx := w.db.Begin()
+	defer tx.Rollback()
+
+	var revision = model.PolicyRevision{}
+	err := tx.Model(&model.PolicyRevision{}).Where("id = ?", revisionID).UpdateColumn("revision", gorm.Expr("revision + ?", 1)).Error
+	if err == nil {
+		err = tx.Where("id = ?", revisionID).First(&revision).Error
+	}
+	if err == nil {
+		err = tx.Commit().Error
+	}
+	if err != nil {
+		helper.CommonLogger().WithError(err).Error("[permission.Watcher] failed increasing policy revision")
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// a revision skipped is a change of another instance, left for the next poll
+	if revision.Revision == w.revision+1 {
+		w.revision = revision.Revision
+	}
+	return nil
+}
+
+// Close stops polling.
+func (w *Watcher) Close() {
+	w.once.Do(func() { close(w.stop) })
+}
+
+func (w *Watcher) poll(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check calls back when the revision is not the one last seen.
+func (w *Watcher) check() {
+	var revision = model.PolicyRevision{}
+	if err := w.db.Where("id = ?", revisionID).First(&revision).Error; err != nil {
+		helper.CommonLogger().WithError(err).Error("[permission.Watcher] failed reading policy revision")
+		return
+	}
+
+	w.mu.Lock()
+	callback := w.callback
+	changed := revision.Revision != w.revision
+	w.revision = revision.Revision
+	w.mu.Unlock()
+
+	if changed && callback != nil {
+		callback("")
+	}
+}
@@ -0,0 +1,60 @@
+package permission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/require"
+)
+
+func expectRevision(mock sqlmock.Sqlmock, revision int) {
+	mock.ExpectQuery("SELECT \\* FROM `policy_revisions`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "revision"}).AddRow(1, revision))
+}
+
+func expectUpdate(mock sqlmock.Sqlmock, revision int) {
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `policy_revisions` SET `revision` = revision \\+ \\?").
+		WithArgs(1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectRevision(mock, revision)
+	mock.ExpectCommit()
+}
+
+func TestWatcher(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	gormDb, _ := gorm.Open("mysql", db)
+	defer gormDb.Close()
+
+	expectRevision(mock, 0)
+	w, err := NewWatcher(gormDb, time.Hour)
+	require.NoError(t, err)
+	defer w.Close()
+	reloads := 0
+	require.NoError(t, w.SetUpdateCallback(func(string) { reloads++ }))
+
+	// its own changes are not reloaded
+	expectUpdate(mock, 1)
+	require.NoError(t, w.Update())
+	expectRevision(mock, 1)
+	w.check()
+	require.Equal(t, 0, reloads)
+
+	// those of other instances are
+	expectRevision(mock, 2)
+	w.check()
+	require.Equal(t, 1, reloads)
+
+	// even when it changed the policy meanwhile
+	expectUpdate(mock, 4)
+	require.NoError(t, w.Update())
+	expectRevision(mock, 4)
+	w.check()
+	require.Equal(t, 2, reloads)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
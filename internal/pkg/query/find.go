@@ -0,0 +1,189 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/search"
+	"github.com/jinzhu/gorm"
+)
+
+// cursor is the decoded form of next_cursor: the sort of the query it belongs to and
+// the sort values of the last row of the previous page.
+type cursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+// Find loads the page described by q into dest, a pointer to a slice of models, and
+// returns the page with the total number of matching rows. db must select from the
+// model of dest.
+func (q *Query) Find(db *gorm.DB, dest interface{}) (*Page, error) {
+	db = q.Filter(db)
+
+	var total int
+	if err := db.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	page := &Page{Page: q.page, Size: q.size, Total: total}
+	if q.cursor != nil {
+		expr, args := q.after()
+		db = db.Where(expr, args...)
+		page.Page = 0
+	} else {
+		db = db.Offset((q.page - 1) * q.size)
+	}
+	if q.rank != "" {
+		db = db.Order(gorm.Expr(q.rank+" DESC", q.rankArgs...))
+	}
+	for _, s := range q.sorts {
+		direction := "ASC"
+		if s.desc {
+			direction = "DESC"
+		}
+		db = db.Order(s.column + " " + direction)
+	}
+
+	// one extra row tells whether there is a next page
+	if err := db.Limit(q.size + 1).Find(dest).Error; err != nil {
+		return nil, err
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	if rows.Len() > q.size {
+		rows.Set(rows.Slice(0, q.size))
+		if q.rank != "" {
+			return page, nil
+		}
+		next, err := q.encodeCursor(db, rows.Index(q.size-1).Addr().Interface())
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = next
+	}
+	return page, nil
+}
+
+// Filter applies the filters and search of q to db, without sorting or paging.
+func (q *Query) Filter(db *gorm.DB) *gorm.DB {
+	for _, f := range q.filters {
+		db = db.Where(f.expr, f.args...)
+	}
+	if q.term == "" {
+		return db
+	}
+
+	var exprs []string
+	var args []interface{}
+	if match := search.Prefix(q.term); q.match != "" && match != "" {
+		exprs = append(exprs, "MATCH("+q.match+") AGAINST (? IN BOOLEAN MODE)")
+		args = append(args, match)
+	}
+	term := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(q.term) + "%"
+	for _, column := range q.search {
+		exprs = append(exprs, column+" LIKE ?")
+		args = append(args, term)
+	}
+	if len(exprs) > 0 {
+		db = db.Where("("+strings.Join(exprs, " OR ")+")", args...)
+	}
+	return db
+}
+
+// after builds the keyset condition selecting the rows that sort after the cursor:
+// (a > ?) OR (a = ? AND b > ?) OR ...
+func (q *Query) after() (string, []interface{}) {
+	var exprs []string
+	var args []interface{}
+	for i, s := range q.sorts {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, q.sorts[j].column+" = ?")
+			args = append(args, q.cursor[j])
+		}
+		op := " > ?"
+		if s.desc {
+			op = " < ?"
+		}
+		parts = append(parts, s.column+op)
+		args = append(args, q.cursor[i])
+		exprs = append(exprs, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(exprs, " OR ") + ")", args
+}
+
+func (q *Query) sortString() string {
+	names := make([]string, len(q.sorts))
+	for i, s := range q.sorts {
+		names[i] = s.name
+		if s.desc {
+			names[i] = "-" + s.name
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func (q *Query) encodeCursor(db *gorm.DB, row interface{}) (string, error) {
+	scope := db.NewScope(row)
+	c := cursor{Sort: q.sortString()}
+	for _, s := range q.sorts {
+		field, ok := scope.FieldByName(columnName(s.column))
+		if !ok {
+			return "", fmt.Errorf("query: %T has no column %s", row, s.column)
+		}
+		value := field.Field.Interface()
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339Nano)
+		}
+		c.Values = append(c.Values, value)
+	}
+	body, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(body), nil
+}
+
+func (q *Query) decodeCursor(raw string) error {
+	invalid := fmt.Errorf("is not a valid cursor for this sort")
+	body, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return invalid
+	}
+	var c cursor
+	if err := json.Unmarshal(body, &c); err != nil || c.Sort != q.sortString() || len(c.Values) != len(q.sorts) {
+		return invalid
+	}
+
+	for i, s := range q.sorts {
+		switch value := c.Values[i].(type) {
+		case float64:
+			if s.kind != Number {
+				return invalid
+			}
+		case bool:
+			if s.kind != Bool {
+				return invalid
+			}
+		case string:
+			if s.kind == Time {
+				t, err := time.Parse(time.RFC3339Nano, value)
+				if err != nil {
+					return invalid
+				}
+				c.Values[i] = t
+			} else if s.kind != String && s.kind != Date {
+				return invalid
+			}
+		default:
+			return invalid
+		}
+	}
+	q.cursor = c.Values
+	return nil
+}
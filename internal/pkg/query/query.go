@@ -0,0 +1,366 @@
+// Package query turns the query string of a list endpoint into filters, sorting and
+// pagination applied to a gorm query.
+//
+// Every list endpoint declares a Spec: the fields a client may filter and sort by,
+// the operators allowed on each of them and the columns matched by ?search=. Filters
+// are written as field=value (eq) or field[op]=value, for example
+//
+//	?status=approved&commodity[in]=Padi,Jagung&price_amount[gte]=500000
+//	&created_at[between]=2022-06-01,2022-06-30&sort=-created_at,name&size=20
+//
+// Pages are addressed either by ?page= or, for large tables, by the opaque
+// next_cursor returned with the previous page (keyset pagination).
+package query
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+)
+
+const (
+	// DefaultSize is the page size used when the client does not send ?size=.
+	DefaultSize = 20
+	// MaxSize is the largest page size a client may ask for.
+	MaxSize = 100
+)
+
+// Kind is the type of the values of a field.
+type Kind int
+
+const (
+	String Kind = iota
+	Number
+	Bool
+	// Date is a column holding a YYYY-MM-DD date.
+	Date
+	// Time is a timestamp column filtered by YYYY-MM-DD dates; lte and between
+	// include the whole last day.
+	Time
+)
+
+// Operator is a filter operator.
+type Operator string
+
+const (
+	Eq      Operator = "eq"
+	In      Operator = "in"
+	Gte     Operator = "gte"
+	Lte     Operator = "lte"
+	Between Operator = "between"
+)
+
+// Operator sets shared by most fields.
+var (
+	Exact = []Operator{Eq, In}
+	Range = []Operator{Eq, Gte, Lte, Between}
+)
+
+// Field is a field of a resource that can be filtered or sorted by.
+type Field struct {
+	Column    string
+	Kind      Kind
+	Operators []Operator
+	// Values, when set, are the only values accepted by the filters of the field.
+	Values   []string
+	Sortable bool
+}
+
+// Spec declares what a list endpoint accepts, keyed by the name used in the query string.
+type Spec struct {
+	Fields map[string]Field
+	// Search are the columns matched by prefix with ?search=.
+	Search []string
+	// Match, when set, are the columns of a FULLTEXT index that ?search= also matches
+	// word by word.
+	Match string
+	// Sort is the sort used when the client does not send ?sort=.
+	Sort string
+	// Key is a unique column appended to every sort so rows have a stable order.
+	Key string
+}
+
+// Page describes the page of rows returned by Find.
+type Page struct {
+	Page       int    `json:"page"`
+	Size       int    `json:"size"`
+	Total      int    `json:"total"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type filter struct {
+	expr string
+	args []interface{}
+}
+
+type sortKey struct {
+	name   string
+	column string
+	kind   Kind
+	desc   bool
+}
+
+// Query is a parsed list request.
+type Query struct {
+	filters []filter
+	sorts   []sortKey
+	search  []string
+	match   string
+	term    string
+	cursor  []interface{}
+	page    int
+	size    int
+	// sorted is set when the client chose the sort with ?sort=
+	sorted   bool
+	rank     string
+	rankArgs []interface{}
+}
+
+var filterKey = regexp.MustCompile(`^([a-z0-9_]+)\[([a-z]+)\]$`)
+
+// Parse validates values against spec. Unknown filter fields and operators, malformed
+// values and unsortable fields are reported as validation errors; plain parameters
+// that are not part of spec are ignored.
+func Parse(values url.Values, spec Spec) (*Query, error) {
+	q := &Query{search: spec.Search, match: spec.Match, page: 1, size: DefaultSize}
+	var details []apperror.FieldError
+	fail := func(field, message string) {
+		details = append(details, apperror.FieldError{Field: field, Message: message})
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := values.Get(key)
+		switch key {
+		case "page":
+			page, err := strconv.Atoi(value)
+			if err != nil || page < 1 {
+				fail(key, "must be a number greater than 0")
+				continue
+			}
+			q.page = page
+		case "size":
+			size, err := strconv.Atoi(value)
+			if err != nil || size < 1 || size > MaxSize {
+				fail(key, fmt.Sprintf("must be a number between 1 and %d", MaxSize))
+				continue
+			}
+			q.size = size
+		case "search":
+			q.term = strings.TrimSpace(value)
+		case "sort", "cursor":
+		default:
+			name, op := key, Eq
+			if m := filterKey.FindStringSubmatch(key); m != nil {
+				name, op = m[1], Operator(m[2])
+			}
+			field, ok := spec.Fields[name]
+			if !ok {
+				if name != key {
+					fail(key, "is not a filterable field")
+				}
+				continue
+			}
+			if !field.allows(op) {
+				fail(key, fmt.Sprintf("does not support the %s operator", op))
+				continue
+			}
+			f, err := field.filter(op, value)
+			if err != nil {
+				fail(key, err.Error())
+				continue
+			}
+			q.filters = append(q.filters, f)
+		}
+	}
+
+	order := values.Get("sort")
+	q.sorted = order != ""
+	if order == "" {
+		order = spec.Sort
+	}
+	if err := q.parseSort(spec, order); err != nil {
+		fail("sort", err.Error())
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		if values.Get("page") != "" {
+			fail("cursor", "cannot be combined with page")
+		} else if err := q.decodeCursor(cursor); err != nil {
+			fail("cursor", err.Error())
+		}
+	}
+
+	if len(details) > 0 {
+		return nil, apperror.Validation("validation_error", "request validation failed").WithDetails(details...)
+	}
+	return q, nil
+}
+
+// Where adds a filter set by the server, such as the company of a nested route.
+func (q *Query) Where(column string, value interface{}) *Query {
+	q.filters = append(q.filters, filter{expr: column + " = ?", args: []interface{}{value}})
+	return q
+}
+
+// WhereIn adds a filter set by the server matching any of values.
+func (q *Query) WhereIn(column string, values ...interface{}) *Query {
+	q.filters = append(q.filters, filter{expr: column + " IN (?)", args: []interface{}{values}})
+	return q
+}
+
+// RankBy orders rows by expr, highest first, unless the client chose a sort. Ranked
+// pages are addressed by page only.
+func (q *Query) RankBy(expr string, args ...interface{}) error {
+	if q.sorted {
+		return nil
+	}
+	if q.cursor != nil {
+		return apperror.Validation("validation_error", "request validation failed").WithDetails(apperror.FieldError{
+			Field:   "cursor",
+			Message: "requires sort, results ranked by relevance are paged with page",
+		})
+	}
+	q.rank, q.rankArgs = expr, args
+	return nil
+}
+
+func (q *Query) parseSort(spec Spec, order string) error {
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(order, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		desc := strings.HasPrefix(item, "-")
+		name := strings.TrimPrefix(item, "-")
+		field, ok := spec.Fields[name]
+		if !ok || !field.Sortable {
+			return fmt.Errorf("cannot sort by %s", name)
+		}
+		if seen[name] {
+			return fmt.Errorf("sorts by %s more than once", name)
+		}
+		seen[name] = true
+		q.sorts = append(q.sorts, sortKey{name: name, column: field.Column, kind: field.Kind, desc: desc})
+	}
+	for _, s := range q.sorts {
+		if s.column == spec.Key {
+			return nil
+		}
+	}
+	q.sorts = append(q.sorts, sortKey{name: columnName(spec.Key), column: spec.Key, kind: Number})
+	return nil
+}
+
+func (f Field) allows(op Operator) bool {
+	for _, allowed := range f.Operators {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Field) filter(op Operator, raw string) (filter, error) {
+	switch op {
+	case In:
+		var args []interface{}
+		for _, item := range strings.Split(raw, ",") {
+			value, err := f.parse(strings.TrimSpace(item))
+			if err != nil {
+				return filter{}, err
+			}
+			args = append(args, value)
+		}
+		return filter{expr: f.Column + " IN (?)", args: []interface{}{args}}, nil
+	case Between:
+		bounds := strings.Split(raw, ",")
+		if len(bounds) != 2 {
+			return filter{}, fmt.Errorf("must be two values separated by a comma")
+		}
+		from, err := f.parse(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return filter{}, err
+		}
+		to, err := f.parse(strings.TrimSpace(bounds[1]))
+		if err != nil {
+			return filter{}, err
+		}
+		if f.Kind == Time {
+			return filter{expr: f.Column + " >= ? AND " + f.Column + " < ?", args: []interface{}{from, to.(time.Time).AddDate(0, 0, 1)}}, nil
+		}
+		return filter{expr: f.Column + " BETWEEN ? AND ?", args: []interface{}{from, to}}, nil
+	}
+
+	value, err := f.parse(raw)
+	if err != nil {
+		return filter{}, err
+	}
+	switch op {
+	case Gte:
+		return filter{expr: f.Column + " >= ?", args: []interface{}{value}}, nil
+	case Lte:
+		if f.Kind == Time {
+			return filter{expr: f.Column + " < ?", args: []interface{}{value.(time.Time).AddDate(0, 0, 1)}}, nil
+		}
+		return filter{expr: f.Column + " <= ?", args: []interface{}{value}}, nil
+	default:
+		if f.Kind == Time {
+			return filter{expr: f.Column + " >= ? AND " + f.Column + " < ?", args: []interface{}{value, value.(time.Time).AddDate(0, 0, 1)}}, nil
+		}
+		return filter{expr: f.Column + " = ?", args: []interface{}{value}}, nil
+	}
+}
+
+func (f Field) parse(raw string) (interface{}, error) {
+	if len(f.Values) > 0 {
+		for _, allowed := range f.Values {
+			if raw == allowed {
+				return raw, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %s", strings.Join(f.Values, ", "))
+	}
+
+	switch f.Kind {
+	case Number:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return value, nil
+	case Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		return value, nil
+	case Date, Time:
+		value, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			return nil, fmt.Errorf("must be a date formatted as YYYY-MM-DD")
+		}
+		if f.Kind == Date {
+			return raw, nil
+		}
+		return value, nil
+	default:
+		return raw, nil
+	}
+}
+
+func columnName(column string) string {
+	return column[strings.LastIndex(column, ".")+1:]
+}
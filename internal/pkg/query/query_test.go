@@ -0,0 +1,145 @@
+package query
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	ID        int
+	Name      string
+	Price     float64
+	CreatedAt time.Time
+}
+
+var itemQuery = Spec{
+	Fields: map[string]Field{
+		"name":       {Column: "name", Operators: Exact, Sortable: true},
+		"status":     {Column: "status", Operators: Exact, Values: []string{"approved", "rejected"}},
+		"price":      {Column: "price", Kind: Number, Operators: Range, Sortable: true},
+		"created_at": {Column: "created_at", Kind: Time, Operators: Range, Sortable: true},
+	},
+	Search: []string{"name"},
+	Sort:   "created_at",
+	Key:    "id",
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		filters []filter
+		sort    string
+		field   string
+	}{
+		{
+			name:  "defaults",
+			query: "",
+			sort:  "created_at,id",
+		},
+		{
+			name:  "filters and sort",
+			query: "status[in]=approved,rejected&price[gte]=5000&sort=-price,name&unknown=1",
+			filters: []filter{
+				{expr: "price >= ?", args: []interface{}{5000.0}},
+				{expr: "status IN (?)", args: []interface{}{[]interface{}{"approved", "rejected"}}},
+			},
+			sort: "-price,name,id",
+		},
+		{
+			name:  "time between includes the last day",
+			query: "created_at[between]=2022-06-01,2022-06-30",
+			filters: []filter{{
+				expr: "created_at >= ? AND created_at < ?",
+				args: []interface{}{time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC)},
+			}},
+			sort: "created_at,id",
+		},
+		{name: "unknown filter field", query: "secret[eq]=1", field: "secret[eq]"},
+		{name: "unsupported operator", query: "name[gte]=a", field: "name[gte]"},
+		{name: "value not allowed", query: "status=deleted", field: "status"},
+		{name: "malformed number", query: "price[lte]=cheap", field: "price[lte]"},
+		{name: "unsortable field", query: "sort=status", field: "sort"},
+		{name: "size too large", query: "size=1000", field: "size"},
+		{name: "cursor with page", query: "page=2&cursor=abc", field: "cursor"},
+		{name: "cursor of another sort", query: "cursor=eyJzIjoibmFtZSxpZCIsInYiOlsiYSIsMV19", field: "cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+
+			q, err := Parse(values, itemQuery)
+			if tt.field != "" {
+				var appErr *apperror.Error
+				require.True(t, errors.As(err, &appErr))
+				require.Equal(t, apperror.KindValidation, appErr.Kind)
+				require.Equal(t, tt.field, appErr.Details[0].Field)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.filters, q.filters)
+			require.Equal(t, tt.sort, q.sortString())
+		})
+	}
+}
+
+func TestFindPagesByCursor(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	gormDb, err := gorm.Open("mysql", db)
+	require.NoError(t, err)
+	defer gormDb.Close()
+
+	created := time.Date(2022, 6, 1, 8, 0, 0, 0, time.UTC)
+
+	q, err := Parse(url.Values{"size": {"2"}, "sort": {"-price"}, "search": {"gabah_"}}, itemQuery)
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT count(*) FROM `items`  WHERE ((name LIKE ?))").
+		WithArgs(`gabah\_%`).
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(3))
+	mock.ExpectQuery("SELECT * FROM `items`  WHERE ((name LIKE ?)) ORDER BY price DESC,id ASC LIMIT 3 OFFSET 0").
+		WithArgs(`gabah\_%`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "created_at"}).
+			AddRow(1, "gabah_a", 7000, created).
+			AddRow(2, "gabah_b", 6000, created).
+			AddRow(3, "gabah_c", 6000, created))
+
+	var items []item
+	page, err := q.Find(gormDb.Model(&item{}), &items)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, 3, page.Total)
+	require.NotEmpty(t, page.NextCursor)
+
+	next, err := Parse(url.Values{"size": {"2"}, "sort": {"-price"}, "cursor": {page.NextCursor}}, itemQuery)
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT count(*) FROM `items`").
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(3))
+	mock.ExpectQuery("SELECT * FROM `items`  WHERE (((price < ?) OR (price = ? AND id > ?))) ORDER BY price DESC,id ASC LIMIT 3").
+		WithArgs(6000.0, 6000.0, 2.0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "created_at"}).
+			AddRow(3, "gabah_c", 6000, created))
+
+	items = nil
+	page, err = next.Find(gormDb.Model(&item{}), &items)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, 0, page.Page)
+	require.Empty(t, page.NextCursor)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,103 @@
+package access_token
+
+import (
+	"context"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/jinzhu/gorm"
+)
+
+// touchInterval is how stale the last use of a token is let go before it is written
+// again, so a busy script does not write at every request.
+const touchInterval = time.Minute
+
+type Repository interface {
+	Create(ctx context.Context, token *model.AccessToken) (*model.AccessToken, error)
+	ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.AccessToken, error)
+	ReadAllByUserId(ctx context.Context, userID int) (*[]model.AccessToken, error)
+	CountActive(ctx context.Context, userID int, now time.Time) (int, error)
+	Revoke(ctx context.Context, id int) error
+	Touch(ctx context.Context, id int, ip string) error
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+func (e *repository) Create(ctx context.Context, token *model.AccessToken) (*model.AccessToken, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(token).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[accessTokenRepository.Create] error execute query")
+		return nil, apperror.FromDB(err, "access_token", "failed insert data")
+	}
+	return token, nil
+}
+
+func (e *repository) ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.AccessToken, error) {
+	var token = model.AccessToken{}
+	err := tracing.WithContext(ctx, e.DB).Where(criteria).First(&token).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[accessTokenRepository.ReadBy] error execute query")
+		return nil, apperror.FromDB(err, "access_token", "failed view data")
+	}
+	return &token, nil
+}
+
+// ReadAllByUserId lists the tokens of the user, revoked and expired ones included,
+// newest first.
+func (e *repository) ReadAllByUserId(ctx context.Context, userID int) (*[]model.AccessToken, error) {
+	var tokens []model.AccessToken
+	err := tracing.WithContext(ctx, e.DB).Where("user_id = ?", userID).Order("id desc").Find(&tokens).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[accessTokenRepository.ReadAllByUserId] error execute query")
+		return nil, apperror.FromDB(err, "access_token", "failed view data")
+	}
+	return &tokens, nil
+}
+
+// CountActive counts the tokens of the user neither revoked nor expired at now.
+func (e *repository) CountActive(ctx context.Context, userID int, now time.Time) (int, error) {
+	var result int
+	err := tracing.WithContext(ctx, e.DB).Model(&model.AccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Count(&result).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[accessTokenRepository.CountActive] error execute query")
+		return 0, apperror.FromDB(err, "access_token", "failed view data")
+	}
+	return result, nil
+}
+
+// Revoke stops the token authenticating, keeping it listed.
+func (e *repository) Revoke(ctx context.Context, id int) error {
+	err := tracing.WithContext(ctx, e.DB).Model(&model.AccessToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		UpdateColumn("revoked_at", time.Now()).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[accessTokenRepository.Revoke] error execute query")
+		return apperror.FromDB(err, "access_token", "failed update data")
+	}
+	return nil
+}
+
+// Touch records that the token was used now from ip, unless it was within
+// touchInterval.
+func (e *repository) Touch(ctx context.Context, id int, ip string) error {
+	now := time.Now()
+	err := tracing.WithContext(ctx, e.DB).Model(&model.AccessToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, now.Add(-touchInterval)).
+		UpdateColumns(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[accessTokenRepository.Touch] error execute query")
+		return apperror.FromDB(err, "access_token", "failed update data")
+	}
+	return nil
+}
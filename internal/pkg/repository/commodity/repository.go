@@ -0,0 +1,100 @@
+package commodity
+
+import (
+	"context"
+	"errors"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/jinzhu/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, commodity *model.Commodity) (*model.Commodity, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Commodity, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.Commodity, error)
+	Update(ctx context.Context, id, version int, commodity *model.Commodity) (*model.Commodity, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+func (e *repository) Create(ctx context.Context, commodity *model.Commodity) (*model.Commodity, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(commodity).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.Create] error execute query")
+		return nil, apperror.FromDB(err, "commodity", "failed insert data")
+	}
+	return commodity, nil
+}
+
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Commodity, *query.Page, error) {
+	var commodities []model.Commodity
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.Commodity{}), &commodities)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "commodity", "failed view all data")
+	}
+	return &commodities, page, nil
+}
+
+func (e *repository) ReadById(ctx context.Context, id int) (*model.Commodity, error) {
+	var commodity = model.Commodity{}
+	err := tracing.WithContext(ctx, e.DB).Where("id = ?", id).First(&commodity).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "commodity", "failed view data")
+	}
+	return &commodity, nil
+}
+
+// Update replaces the commodity if its stored version still equals version and renames
+// the commodity of its products along with it, as products keep the name for search.
+func (e *repository) Update(ctx context.Context, id, version int, commodity *model.Commodity) (*model.Commodity, error) {
+	err := tracing.WithContext(ctx, e.DB).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Commodity{}).Where("id = ? AND version = ?", id, version).Updates(map[string]interface{}{
+			"code":          commodity.Code,
+			"name":          commodity.Name,
+			"category":      commodity.Category,
+			"unit_quantity": commodity.UnitQuantity,
+			"unit_price":    commodity.UnitPrice,
+			"pari_code":     commodity.PariCode,
+			"is_active":     commodity.IsActive,
+			"version":       gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return apperror.VersionMismatch("commodity")
+		}
+		return tx.Model(&model.Product{}).Where("commodity_id = ?", id).UpdateColumn("commodity", commodity.Name).Error
+	})
+	if appErr := (*apperror.Error)(nil); errors.As(err, &appErr) {
+		return nil, appErr
+	}
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.Update] error execute query")
+		return nil, apperror.FromDB(err, "commodity", "failed update data")
+	}
+	return e.ReadById(ctx, id)
+}
+
+func (e *repository) Delete(ctx context.Context, id int) error {
+	var commodity = model.Commodity{}
+	err := tracing.WithContext(ctx, e.DB).Where("id = ?", id).First(&commodity).Delete(&commodity).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.Delete] error execute query")
+		return apperror.FromDB(err, "commodity", "failed delete data")
+	}
+	return nil
+}
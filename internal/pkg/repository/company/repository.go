@@ -1,19 +1,26 @@
 package company
 
 import (
-	"fmt"
+	"context"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/trash"
 	"github.com/jinzhu/gorm"
 )
 
 type Repository interface {
-	Create(person *model.Company) (*model.Company, error)
-	ReadAll() (*[]model.Company, error)
-	ReadById(id int) (*model.Company, error)
-	Update(id int, person *model.Company) (*model.Company, error)
-	Delete(id int) error
+	Create(ctx context.Context, person *model.Company) (*model.Company, error)
+	ReadAll(ctx context.Context) (*[]model.Company, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Company, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.Company, error)
+	ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.Company, error)
+	Update(ctx context.Context, id int, person *model.Company) (*model.Company, error)
+	Patch(ctx context.Context, id, version int, fields map[string]interface{}) (*model.Company, error)
+	Delete(ctx context.Context, id int) error
 }
 
 type repository struct {
@@ -24,56 +31,96 @@ func NewRepository(DB *gorm.DB) Repository {
 	return &repository{DB}
 }
 
-func (e *repository) Create(company *model.Company) (*model.Company, error) {
-	err := e.DB.Save(&company).Error
+func (e *repository) Create(ctx context.Context, company *model.Company) (*model.Company, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(&company).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Create] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed insert data")
+		helper.Logger(ctx).WithError(err).Error("[repository.Create] error execute query")
+		return nil, apperror.FromDB(err, "company", "failed insert data")
 	}
 	return company, nil
 }
 
-func (e *repository) ReadAll() (*[]model.Company, error) {
+func (e *repository) ReadAll(ctx context.Context) (*[]model.Company, error) {
 	var companies []model.Company
-	err := e.DB.Find(&companies).Error
+	err := tracing.WithContext(ctx, e.DB).Find(&companies).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadAll] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed view all data")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadAll] error execute query")
+		return nil, apperror.FromDB(err, "company", "failed view all data")
 	}
 	return &companies, nil
 }
 
-func (e *repository) ReadById(id int) (*model.Company, error) {
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Company, *query.Page, error) {
+	var companies []model.Company
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.Company{}), &companies)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "company", "failed view all data")
+	}
+	return &companies, page, nil
+}
+
+func (e *repository) ReadById(ctx context.Context, id int) (*model.Company, error) {
 	var company = model.Company{}
-	err := e.DB.Table("companies").Where("id = ?", id).First(&company).Error
+	err := tracing.WithContext(ctx, e.DB).Table("companies").Where("id = ?", id).First(&company).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadById] error execute query %v \n", err)
-		return nil, fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "company", "failed view data")
 	}
 	return &company, nil
 }
 
-func (e *repository) Update(id int, company *model.Company) (*model.Company, error) {
+func (e *repository) ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.Company, error) {
+	var company = model.Company{}
+	err := tracing.WithContext(ctx, e.DB).Where(criteria).First(&company).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadBy] error execute query")
+		return nil, apperror.FromDB(err, "company", "failed view data")
+	}
+	return &company, nil
+}
+
+func (e *repository) Update(ctx context.Context, id int, company *model.Company) (*model.Company, error) {
 	var upCompany = model.Company{}
-	err := e.DB.Table("companies").Where("id = ?", id).First(&upCompany).Update(&company).Error
+	db := tracing.WithContext(ctx, e.DB)
+	err := db.Table("companies").Where("id = ?", id).First(&upCompany).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Update] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed update data")
+		helper.Logger(ctx).WithError(err).Error("[repository.Update] error execute query")
+		return nil, apperror.FromDB(err, "company", "failed update data")
+	}
+
+	company.Version = upCompany.Version + 1
+	result := db.Model(&upCompany).Where("version = ?", upCompany.Version).Update(company)
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[repository.Update] error execute query")
+		return nil, apperror.FromDB(result.Error, "company", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperror.VersionMismatch("company")
 	}
 	return &upCompany, nil
 }
 
-func (e *repository) Delete(id int) error {
-	var company = model.Company{}
-	err := e.DB.Table("companies").Where("id = ?", id).First(&company).Delete(&company).Error
+// Patch updates the given columns only if the stored version still equals version,
+// so a concurrent change makes it fail with a precondition error instead of being lost.
+func (e *repository) Patch(ctx context.Context, id, version int, fields map[string]interface{}) (*model.Company, error) {
+	fields["version"] = gorm.Expr("version + 1")
+	result := tracing.WithContext(ctx, e.DB).Model(&model.Company{}).Where("id = ? AND version = ?", id, version).Updates(fields)
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[repository.Patch] error execute query")
+		return nil, apperror.FromDB(result.Error, "company", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperror.VersionMismatch("company")
+	}
+	return e.ReadById(ctx, id)
+}
+
+func (e *repository) Delete(ctx context.Context, id int) error {
+	err := trash.Delete(tracing.WithContext(ctx, e.DB), trash.Companies, id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Delete] error execute query %v \n", err)
-		return fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[repository.Delete] error execute query")
+		return apperror.FromDB(err, "company", "failed delete data")
 	}
 	return nil
 }
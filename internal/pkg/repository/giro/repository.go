@@ -1,20 +1,25 @@
 package giro
 
 import (
-	"fmt"
+	"context"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
 	"github.com/jinzhu/gorm"
 )
 
 type Repository interface {
-	Create(person *model.Giro) (*model.Giro, error)
-	ReadAll() (*[]model.Giro, error)
-	ReadById(id int) (*model.Giro, error)
-	ReadByCode(code string) (*model.Giro, error)
-	Update(id int, person *model.Giro) (*model.Giro, error)
-	Delete(id int) error
+	Create(ctx context.Context, person *model.Giro) (*model.Giro, error)
+	ReadAll(ctx context.Context) (*[]model.Giro, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Giro, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.Giro, error)
+	ReadByCode(ctx context.Context, code string) (*model.Giro, error)
+	Update(ctx context.Context, id, version int, giro *model.Giro) (*model.Giro, error)
+	Import(ctx context.Context, giros []model.Giro) (created, updated int, err error)
+	Delete(ctx context.Context, id int) error
 }
 
 type repository struct {
@@ -25,66 +30,129 @@ func NewRepository(DB *gorm.DB) Repository {
 	return &repository{DB}
 }
 
-func (e *repository) Create(giro *model.Giro) (*model.Giro, error) {
-	err := e.DB.Save(&giro).Error
+func (e *repository) Create(ctx context.Context, giro *model.Giro) (*model.Giro, error) {
+	err := tracing.WithContext(ctx, e.DB).Transaction(func(tx *gorm.DB) error {
+		return create(tx, giro)
+	})
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Create] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed insert data")
+		helper.Logger(ctx).WithError(err).Error("[repository.Create] error execute query")
+		return nil, apperror.FromDB(err, "giro", "failed insert data")
 	}
 	return giro, nil
 }
 
-func (e *repository) ReadAll() (*[]model.Giro, error) {
+// create inserts giro, setting is_active apart as gorm leaves false out for the
+// column default.
+func create(db *gorm.DB, giro *model.Giro) error {
+	if err := db.Create(giro).Error; err != nil {
+		return err
+	}
+	if giro.IsActive {
+		return nil
+	}
+	return db.Model(giro).UpdateColumn("is_active", false).Error
+}
+
+func (e *repository) ReadAll(ctx context.Context) (*[]model.Giro, error) {
 	var giros []model.Giro
-	err := e.DB.Find(&giros).Error
+	err := tracing.WithContext(ctx, e.DB).Find(&giros).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadAll] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed view all data")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadAll] error execute query")
+		return nil, apperror.FromDB(err, "giro", "failed view all data")
 	}
 	return &giros, nil
 }
 
-func (e *repository) ReadById(id int) (*model.Giro, error) {
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Giro, *query.Page, error) {
+	var giros []model.Giro
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.Giro{}), &giros)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "giro", "failed view all data")
+	}
+	return &giros, page, nil
+}
+
+func (e *repository) ReadById(ctx context.Context, id int) (*model.Giro, error) {
 	var giro = model.Giro{}
-	err := e.DB.Table("giros").Where("id = ?", id).First(&giro).Error
+	err := tracing.WithContext(ctx, e.DB).Table("giros").Where("id = ?", id).First(&giro).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadById] error execute query %v \n", err)
-		return nil, fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "giro", "failed view data")
 	}
 	return &giro, nil
 }
-func (e *repository) ReadByCode(code string) (*model.Giro, error) {
+func (e *repository) ReadByCode(ctx context.Context, code string) (*model.Giro, error) {
 	var giro = model.Giro{}
-	err := e.DB.Table("giros").Where("code = ?", code).First(&giro).Error
+	err := tracing.WithContext(ctx, e.DB).Table("giros").Where("code = ?", code).First(&giro).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadById] error execute query %v \n", err)
-		return nil, fmt.Errorf("code is not exists")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "giro", "failed view data")
 	}
 	return &giro, nil
 }
 
-func (e *repository) Update(id int, giro *model.Giro) (*model.Giro, error) {
-	var upGiro = model.Giro{}
-	err := e.DB.Table("giros").Where("id = ?", id).First(&upGiro).Update(&giro).Error
+// Update replaces the giro if its stored version still equals version.
+func (e *repository) Update(ctx context.Context, id, version int, giro *model.Giro) (*model.Giro, error) {
+	result := tracing.WithContext(ctx, e.DB).Model(&model.Giro{}).Where("id = ? AND version = ?", id, version).Updates(map[string]interface{}{
+		"code":         giro.Code,
+		"company_name": giro.CompanyName,
+		"is_active":    giro.IsActive,
+		"version":      gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[repository.Update] error execute query")
+		return nil, apperror.FromDB(result.Error, "giro", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperror.VersionMismatch("giro")
+	}
+	return e.ReadById(ctx, id)
+}
+
+// Import creates the giros whose code is new and updates the others, bringing back the
+// deleted ones, all or none of them.
+func (e *repository) Import(ctx context.Context, giros []model.Giro) (created, updated int, err error) {
+	err = tracing.WithContext(ctx, e.DB).Transaction(func(tx *gorm.DB) error {
+		for i := range giros {
+			var current model.Giro
+			err := tx.Unscoped().Where("code = ?", giros[i].Code).First(&current).Error
+			if gorm.IsRecordNotFoundError(err) {
+				if err := create(tx, &giros[i]); err != nil {
+					return err
+				}
+				created++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			err = tx.Unscoped().Model(&current).Updates(map[string]interface{}{
+				"company_name": giros[i].CompanyName,
+				"is_active":    giros[i].IsActive,
+				"deleted_at":   gorm.Expr("NULL"),
+				"version":      gorm.Expr("version + 1"),
+			}).Error
+			if err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Update] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed update data")
+		helper.Logger(ctx).WithError(err).Error("[repository.Import] error execute query")
+		return 0, 0, apperror.FromDB(err, "giro", "failed import data")
 	}
-	return &upGiro, nil
+	return created, updated, nil
 }
 
-func (e *repository) Delete(id int) error {
+func (e *repository) Delete(ctx context.Context, id int) error {
 	var giro = model.Giro{}
-	err := e.DB.Table("giros").Where("id = ?", id).First(&giro).Delete(&giro).Error
+	err := tracing.WithContext(ctx, e.DB).Table("giros").Where("id = ?", id).First(&giro).Delete(&giro).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Delete] error execute query %v \n", err)
-		return fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[repository.Delete] error execute query")
+		return apperror.FromDB(err, "giro", "failed delete data")
 	}
 	return nil
 }
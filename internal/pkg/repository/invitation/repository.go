@@ -0,0 +1,82 @@
+package invitation
+
+import (
+	"context"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/jinzhu/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, invitation *model.Invitation) (*model.Invitation, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Invitation, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.Invitation, error)
+	ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.Invitation, error)
+	Patch(ctx context.Context, id int, status enum.StatusInvitation, fields map[string]interface{}) (*model.Invitation, error)
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+func (e *repository) Create(ctx context.Context, invitation *model.Invitation) (*model.Invitation, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(invitation).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[invitationRepository.Create] error execute query")
+		return nil, apperror.FromDB(err, "invitation", "failed insert data")
+	}
+	return invitation, nil
+}
+
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Invitation, *query.Page, error) {
+	var invitations []model.Invitation
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.Invitation{}), &invitations)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[invitationRepository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "invitation", "failed view all data")
+	}
+	return &invitations, page, nil
+}
+
+func (e *repository) ReadById(ctx context.Context, id int) (*model.Invitation, error) {
+	var invitation = model.Invitation{}
+	err := tracing.WithContext(ctx, e.DB).Where("id = ?", id).First(&invitation).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[invitationRepository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "invitation", "failed view data")
+	}
+	return &invitation, nil
+}
+
+func (e *repository) ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.Invitation, error) {
+	var invitation = model.Invitation{}
+	err := tracing.WithContext(ctx, e.DB).Where(criteria).Order("id DESC").First(&invitation).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[invitationRepository.ReadBy] error execute query")
+		return nil, apperror.FromDB(err, "invitation", "failed view data")
+	}
+	return &invitation, nil
+}
+
+// Patch updates the given columns only if the invitation still has status, so an
+// invitation is accepted or revoked once.
+func (e *repository) Patch(ctx context.Context, id int, status enum.StatusInvitation, fields map[string]interface{}) (*model.Invitation, error) {
+	result := tracing.WithContext(ctx, e.DB).Model(&model.Invitation{}).Where("id = ? AND status = ?", id, status).Updates(fields)
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[invitationRepository.Patch] error execute query")
+		return nil, apperror.FromDB(result.Error, "invitation", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperror.Conflict("invitation_changed", "invitation was accepted or revoked meanwhile")
+	}
+	return e.ReadById(ctx, id)
+}
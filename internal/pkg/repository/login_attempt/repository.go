@@ -0,0 +1,61 @@
+package login_attempt
+
+import (
+	"context"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/jinzhu/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, attempt *model.LoginAttempt) (*model.LoginAttempt, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.LoginAttempt, *query.Page, error)
+	CountFailures(ctx context.Context, criteria map[string]interface{}, since time.Time) (int, error)
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+func (e *repository) Create(ctx context.Context, attempt *model.LoginAttempt) (*model.LoginAttempt, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(attempt).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[loginAttemptRepository.Create] error execute query")
+		return nil, apperror.FromDB(err, "login_attempt", "failed insert data")
+	}
+	return attempt, nil
+}
+
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.LoginAttempt, *query.Page, error) {
+	var attempts []model.LoginAttempt
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.LoginAttempt{}), &attempts)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[loginAttemptRepository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "login_attempt", "failed view all data")
+	}
+	return &attempts, page, nil
+}
+
+// CountFailures counts the failed attempts matching criteria since the given time. An
+// attempt waiting for its second factor has not failed yet.
+func (e *repository) CountFailures(ctx context.Context, criteria map[string]interface{}, since time.Time) (int, error) {
+	var result int
+	err := tracing.WithContext(ctx, e.DB).Model(&model.LoginAttempt{}).
+		Where(criteria).Where("success = ? AND result <> ? AND created_at >= ?", false, enum.LoginTwoFactorPending, since).
+		Count(&result).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[loginAttemptRepository.CountFailures] error execute query")
+		return 0, apperror.FromDB(err, "login_attempt", "failed view data")
+	}
+	return result, nil
+}
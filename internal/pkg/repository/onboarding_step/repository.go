@@ -0,0 +1,44 @@
+package onboarding_step
+
+import (
+	"context"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/jinzhu/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, step *model.OnboardingStep) (*model.OnboardingStep, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.OnboardingStep, *query.Page, error)
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+func (e *repository) Create(ctx context.Context, step *model.OnboardingStep) (*model.OnboardingStep, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(step).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[onboardingStepRepository.Create] error execute query")
+		return nil, apperror.FromDB(err, "onboarding step", "failed insert data")
+	}
+	return step, nil
+}
+
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.OnboardingStep, *query.Page, error) {
+	var steps []model.OnboardingStep
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.OnboardingStep{}), &steps)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[onboardingStepRepository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "onboarding step", "failed view all data")
+	}
+	return &steps, page, nil
+}
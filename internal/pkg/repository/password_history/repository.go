@@ -0,0 +1,35 @@
+package password_history
+
+import (
+	"context"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/jinzhu/gorm"
+)
+
+// Repository reads the password history the user repository writes along with every
+// password it stores.
+type Repository interface {
+	ReadLatest(ctx context.Context, userID, limit int) ([]model.PasswordHistory, error)
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+func (e *repository) ReadLatest(ctx context.Context, userID, limit int) ([]model.PasswordHistory, error) {
+	var histories []model.PasswordHistory
+	err := tracing.WithContext(ctx, e.DB).Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&histories).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[passwordHistoryRepository.ReadLatest] error execute query")
+		return nil, apperror.FromDB(err, "password_history", "failed view data")
+	}
+	return histories, nil
+}
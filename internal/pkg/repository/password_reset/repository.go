@@ -0,0 +1,70 @@
+package password_reset
+
+import (
+	"context"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/jinzhu/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, reset *model.PasswordReset) (*model.PasswordReset, error)
+	ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.PasswordReset, error)
+	CountSince(ctx context.Context, userID int, since time.Time) (int, error)
+	ExpireAll(ctx context.Context, userID int) error
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+func (e *repository) Create(ctx context.Context, reset *model.PasswordReset) (*model.PasswordReset, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(reset).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[passwordResetRepository.Create] error execute query")
+		return nil, apperror.FromDB(err, "password_reset", "failed insert data")
+	}
+	return reset, nil
+}
+
+func (e *repository) ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.PasswordReset, error) {
+	var reset = model.PasswordReset{}
+	err := tracing.WithContext(ctx, e.DB).Where(criteria).First(&reset).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[passwordResetRepository.ReadBy] error execute query")
+		return nil, apperror.FromDB(err, "password_reset", "failed view data")
+	}
+	return &reset, nil
+}
+
+// CountSince counts the tokens issued to the user since the given time.
+func (e *repository) CountSince(ctx context.Context, userID int, since time.Time) (int, error) {
+	var result int
+	err := tracing.WithContext(ctx, e.DB).Model(&model.PasswordReset{}).Where("user_id = ? AND created_at >= ?", userID, since).Count(&result).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[passwordResetRepository.CountSince] error execute query")
+		return 0, apperror.FromDB(err, "password_reset", "failed view data")
+	}
+	return result, nil
+}
+
+// ExpireAll makes the unused tokens of the user expire now.
+func (e *repository) ExpireAll(ctx context.Context, userID int) error {
+	now := time.Now()
+	err := tracing.WithContext(ctx, e.DB).Model(&model.PasswordReset{}).
+		Where("user_id = ? AND used_at IS NULL AND expires_at > ?", userID, now).
+		Update("expires_at", now).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[passwordResetRepository.ExpireAll] error execute query")
+		return apperror.FromDB(err, "password_reset", "failed update data")
+	}
+	return nil
+}
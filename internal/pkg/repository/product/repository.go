@@ -1,25 +1,36 @@
 package product
 
 import (
-	"fmt"
+	"context"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/search"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/trash"
 	"github.com/jinzhu/gorm"
 )
 
 type Repository interface {
-	Create(person *model.Product) (*model.Product, error)
-	ReadAll() (*[]model.Product, error)
-	ReadAllBy(criteria map[string]interface{}, search string, page, size int) (*[]model.Product, error)
-	ReadById(id int) (*model.Product, error)
-	ReadByPariProductId(pariProductId string) (*model.Product, error)
-	Update(id int, person *model.Product) (*model.Product, error)
-	Delete(id int) error
-	Count(criteria map[string]interface{}) int
-	CreatePariProduct(product *model.Product) (*model.Product, error)
+	Create(ctx context.Context, person *model.Product) (*model.Product, error)
+	ReadAll(ctx context.Context) (*[]model.Product, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Product, *query.Page, error)
+	ReadByCompany(ctx context.Context, companyId int) (*[]model.Product, error)
+	ReadById(ctx context.Context, id int) (*model.Product, error)
+	ReadByPariProductId(ctx context.Context, pariProductId string) (*model.Product, error)
+	Update(ctx context.Context, id int, person *model.Product) (*model.Product, error)
+	Patch(ctx context.Context, id, version int, fields map[string]interface{}) (*model.Product, error)
+	Delete(ctx context.Context, id int) error
+	Count(ctx context.Context, criteria map[string]interface{}) int
+	CreatePariProduct(ctx context.Context, product *model.Product) (*model.Product, error)
+	Search(ctx context.Context, match string, q *query.Query) (*[]model.ProductHit, *query.Page, error)
+	Facets(ctx context.Context, match string, q *query.Query, columns ...string) (map[string]map[string]int, error)
 }
 
+const matchExpr = "MATCH(" + search.Columns + ") AGAINST (? IN BOOLEAN MODE)"
+
 type repository struct {
 	DB *gorm.DB
 }
@@ -28,108 +39,166 @@ func NewRepository(DB *gorm.DB) Repository {
 	return &repository{DB}
 }
 
-func (e *repository) Create(product *model.Product) (*model.Product, error) {
-	err := e.DB.Save(&product).Error
+func (e *repository) Create(ctx context.Context, product *model.Product) (*model.Product, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(&product).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[productRepository.Create] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed insert data")
+		helper.Logger(ctx).WithError(err).Error("[productRepository.Create] error execute query")
+		return nil, apperror.FromDB(err, "product", "failed insert data")
 	}
 	return product, nil
 }
 
-func (e *repository) ReadAll() (*[]model.Product, error) {
+func (e *repository) ReadAll(ctx context.Context) (*[]model.Product, error) {
 	var products []model.Product
-	err := e.DB.Find(&products).Error
+	err := tracing.WithContext(ctx, e.DB).Find(&products).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[productRepository.ReadAll] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed view all data")
+		helper.Logger(ctx).WithError(err).Error("[productRepository.ReadAll] error execute query")
+		return nil, apperror.FromDB(err, "product", "failed view all data")
 	}
 	return &products, nil
 }
 
-func (e *repository) ReadAllBy(criteria map[string]interface{}, search string, page, size int) (*[]model.Product, error) {
+func (e *repository) ReadByCompany(ctx context.Context, companyId int) (*[]model.Product, error) {
 	var products []model.Product
-
-	query := e.DB.Where(criteria)
-
-	if search != "" {
-		query.Where("name LIKE ?", search+"%")
-	}
-
-	if page == 0 || size == 0 {
-		page, size = -1, -1
+	err := tracing.WithContext(ctx, e.DB).Where("company_id = ?", companyId).Find(&products).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productRepository.ReadByCompany] error execute query")
+		return nil, apperror.FromDB(err, "product", "failed view all data")
 	}
+	return &products, nil
+}
 
-	limit, offset := helper.GetLimitOffset(page, size)
-	err := query.Offset(offset).Order("created_at ASC").Limit(limit).Find(&products).Error
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Product, *query.Page, error) {
+	var products []model.Product
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.Product{}), &products)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[productRepository.ReadAllBy] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed view all data")
+		helper.Logger(ctx).WithError(err).Error("[productRepository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "product", "failed view all data")
 	}
-	return &products, nil
+	return &products, page, nil
 }
 
-func (e *repository) ReadById(id int) (*model.Product, error) {
+func (e *repository) ReadById(ctx context.Context, id int) (*model.Product, error) {
 	var product = model.Product{}
-	err := e.DB.Table("products").Where("id = ?", id).First(&product).Error
+	err := tracing.WithContext(ctx, e.DB).Table("products").Where("id = ?", id).First(&product).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[productRepository.ReadById] error execute query %v \n", err)
-		return nil, fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[productRepository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "product", "failed view data")
 	}
 	return &product, nil
 }
 
-func (e *repository) ReadByPariProductId(pariProductId string) (*model.Product, error) {
+func (e *repository) ReadByPariProductId(ctx context.Context, pariProductId string) (*model.Product, error) {
 	var product = model.Product{}
-	if err := e.DB.Table("products").Where("pari_product_id = ?", pariProductId).First(&product).Error; err != nil {
-		fmt.Printf("[productRepository.ReadByPariProductId] error execute query %v \n", err)
-		return nil, fmt.Errorf("pari product id is not exists")
+	if err := tracing.WithContext(ctx, e.DB).Table("products").Where("pari_product_id = ?", pariProductId).First(&product).Error; err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productRepository.ReadByPariProductId] error execute query")
+		return nil, apperror.FromDB(err, "product", "failed view data")
 	}
 	return &product, nil
 }
 
-func (e *repository) Update(id int, product *model.Product) (*model.Product, error) {
+func (e *repository) Update(ctx context.Context, id int, product *model.Product) (*model.Product, error) {
 	var upProduct = model.Product{}
-	err := e.DB.Table("products").Where("id = ?", id).First(&upProduct).Update(&product).Error
+	db := tracing.WithContext(ctx, e.DB)
+	err := db.Table("products").Where("id = ?", id).First(&upProduct).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[productRepository.Update] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed update data")
+		helper.Logger(ctx).WithError(err).Error("[productRepository.Update] error execute query")
+		return nil, apperror.FromDB(err, "product", "failed update data")
+	}
+
+	product.Version = upProduct.Version + 1
+	result := db.Model(&upProduct).Where("version = ?", upProduct.Version).Update(product)
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[productRepository.Update] error execute query")
+		return nil, apperror.FromDB(result.Error, "product", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperror.VersionMismatch("product")
 	}
 	return &upProduct, nil
 }
 
-func (e *repository) Delete(id int) error {
-	var product = model.Product{}
-	err := e.DB.Table("products").Where("id = ?", id).First(&product).Delete(&product).Error
+// Patch updates the given columns only if the stored version still equals version,
+// so a concurrent change makes it fail with a precondition error instead of being lost.
+func (e *repository) Patch(ctx context.Context, id, version int, fields map[string]interface{}) (*model.Product, error) {
+	fields["version"] = gorm.Expr("version + 1")
+	result := tracing.WithContext(ctx, e.DB).Model(&model.Product{}).Where("id = ? AND version = ?", id, version).Updates(fields)
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[productRepository.Patch] error execute query")
+		return nil, apperror.FromDB(result.Error, "product", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperror.VersionMismatch("product")
+	}
+	return e.ReadById(ctx, id)
+}
+
+func (e *repository) Delete(ctx context.Context, id int) error {
+	err := trash.Delete(tracing.WithContext(ctx, e.DB), trash.Products, id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[productRepository.Delete] error execute query %v \n", err)
-		return fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[productRepository.Delete] error execute query")
+		return apperror.FromDB(err, "product", "failed delete data")
 	}
 	return nil
 }
 
-func (e *repository) Count(criteria map[string]interface{}) int {
+func (e *repository) Count(ctx context.Context, criteria map[string]interface{}) int {
 	var result int
-	err := e.DB.Table("products").Where(criteria).Count(&result).Error
+	err := tracing.WithContext(ctx, e.DB).Table("products").Where(criteria).Count(&result).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		helper.Logger(ctx).Error(err)
 		return 0
 	}
 	return result
 }
 
-func (e *repository) CreatePariProduct(product *model.Product) (*model.Product, error) {
-	err := e.DB.Save(&product).Error
+func (e *repository) CreatePariProduct(ctx context.Context, product *model.Product) (*model.Product, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(&product).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[productRepository.CreatePariProduct] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed insert data")
+		helper.Logger(ctx).WithError(err).Error("[productRepository.CreatePariProduct] error execute query")
+		return nil, apperror.FromDB(err, "product", "failed insert data")
 	}
 	return product, nil
 }
+
+// Search finds the products matching match, a boolean mode full-text query, ranked by
+// relevance unless q is sorted.
+func (e *repository) Search(ctx context.Context, match string, q *query.Query) (*[]model.ProductHit, *query.Page, error) {
+	if err := q.RankBy(matchExpr, match); err != nil {
+		return nil, nil, err
+	}
+
+	var hits []model.ProductHit
+	db := tracing.WithContext(ctx, e.DB).Model(&model.ProductHit{}).
+		Select("products.*, "+matchExpr+" AS score", match).
+		Where(matchExpr, match)
+	page, err := q.Find(db, &hits)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productRepository.Search] error execute query")
+		return nil, nil, apperror.FromDB(err, "product", "failed search data")
+	}
+	return &hits, page, nil
+}
+
+// Facets counts the products matching match and the filters of q by each value of
+// the given columns.
+func (e *repository) Facets(ctx context.Context, match string, q *query.Query, columns ...string) (map[string]map[string]int, error) {
+	facets := make(map[string]map[string]int, len(columns))
+	for _, column := range columns {
+		var rows []struct {
+			Value string
+			Total int
+		}
+		db := tracing.WithContext(ctx, e.DB).Model(&model.Product{}).Where(matchExpr, match)
+		err := q.Filter(db).Select(column + " AS value, COUNT(*) AS total").Group(column).Scan(&rows).Error
+		if err != nil {
+			helper.Logger(ctx).WithError(err).Error("[productRepository.Facets] error execute query")
+			return nil, apperror.FromDB(err, "product", "failed search data")
+		}
+		facets[column] = make(map[string]int, len(rows))
+		for _, row := range rows {
+			facets[column][row.Value] = row.Total
+		}
+	}
+	return facets, nil
+}
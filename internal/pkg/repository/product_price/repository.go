@@ -0,0 +1,77 @@
+package product_price
+
+import (
+	"context"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/jinzhu/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, price *model.ProductPrice) (*model.ProductPrice, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.ProductPrice, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.ProductPrice, error)
+	ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.ProductPrice, error)
+	Patch(ctx context.Context, id int, fields map[string]interface{}) (*model.ProductPrice, error)
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+func (e *repository) Create(ctx context.Context, price *model.ProductPrice) (*model.ProductPrice, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(price).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productPriceRepository.Create] error execute query")
+		return nil, apperror.FromDB(err, "product price", "failed insert data")
+	}
+	return price, nil
+}
+
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.ProductPrice, *query.Page, error) {
+	var prices []model.ProductPrice
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.ProductPrice{}), &prices)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productPriceRepository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "product price", "failed view all data")
+	}
+	return &prices, page, nil
+}
+
+func (e *repository) ReadById(ctx context.Context, id int) (*model.ProductPrice, error) {
+	var price = model.ProductPrice{}
+	err := tracing.WithContext(ctx, e.DB).Where("id = ?", id).First(&price).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productPriceRepository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "product price", "failed view data")
+	}
+	return &price, nil
+}
+
+func (e *repository) ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.ProductPrice, error) {
+	var price = model.ProductPrice{}
+	err := tracing.WithContext(ctx, e.DB).Where(criteria).Order("id DESC").First(&price).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productPriceRepository.ReadBy] error execute query")
+		return nil, apperror.FromDB(err, "product price", "failed view data")
+	}
+	return &price, nil
+}
+
+// Patch updates the given columns of a price.
+func (e *repository) Patch(ctx context.Context, id int, fields map[string]interface{}) (*model.ProductPrice, error) {
+	err := tracing.WithContext(ctx, e.DB).Model(&model.ProductPrice{}).Where("id = ?", id).Updates(fields).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productPriceRepository.Patch] error execute query")
+		return nil, apperror.FromDB(err, "product price", "failed update data")
+	}
+	return e.ReadById(ctx, id)
+}
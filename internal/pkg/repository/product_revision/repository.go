@@ -0,0 +1,90 @@
+package product_revision
+
+import (
+	"context"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/jinzhu/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, revision *model.ProductRevision) (*model.ProductRevision, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.ProductRevision, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.ProductRevision, error)
+	ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.ProductRevision, error)
+	Patch(ctx context.Context, id int, status enum.StatusProduct, fields map[string]interface{}) (*model.ProductRevision, error)
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+func (e *repository) Create(ctx context.Context, revision *model.ProductRevision) (*model.ProductRevision, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(revision).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productRevisionRepository.Create] error execute query")
+		return nil, apperror.FromDB(err, "product revision", "failed insert data")
+	}
+	return revision, nil
+}
+
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.ProductRevision, *query.Page, error) {
+	var revisions []model.ProductRevision
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.ProductRevision{}), &revisions)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productRevisionRepository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "product revision", "failed view all data")
+	}
+	return &revisions, page, nil
+}
+
+func (e *repository) ReadById(ctx context.Context, id int) (*model.ProductRevision, error) {
+	var revision = model.ProductRevision{}
+	err := tracing.WithContext(ctx, e.DB).Where("id = ?", id).First(&revision).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productRevisionRepository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "product revision", "failed view data")
+	}
+	return &revision, nil
+}
+
+func (e *repository) ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.ProductRevision, error) {
+	var revision = model.ProductRevision{}
+	err := tracing.WithContext(ctx, e.DB).Where(criteria).Order("id DESC").First(&revision).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productRevisionRepository.ReadBy] error execute query")
+		return nil, apperror.FromDB(err, "product revision", "failed view data")
+	}
+	return &revision, nil
+}
+
+// Patch updates the given columns only if the revision still has status, so of two
+// verifiers resolving it at once only one does.
+func (e *repository) Patch(ctx context.Context, id int, status enum.StatusProduct, fields map[string]interface{}) (*model.ProductRevision, error) {
+	result := tracing.WithContext(ctx, e.DB).Model(&model.ProductRevision{}).Where("id = ? AND status = ?", id, status).Updates(fields)
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[productRevisionRepository.Patch] error execute query")
+		return nil, apperror.FromDB(result.Error, "product revision", "failed update data")
+	}
+	if result.RowsAffected == 0 && !e.hasStatus(ctx, id, status) {
+		return nil, apperror.Conflict("product_revision_changed", "product revision was changed meanwhile")
+	}
+	return e.ReadById(ctx, id)
+}
+
+// hasStatus tells a revision that lost its status from one an update left as it was,
+// which MySQL does not count as affected either.
+func (e *repository) hasStatus(ctx context.Context, id int, status enum.StatusProduct) bool {
+	var count int
+	tracing.WithContext(ctx, e.DB).Model(&model.ProductRevision{}).Where("id = ? AND status = ?", id, status).Count(&count)
+	return count > 0
+}
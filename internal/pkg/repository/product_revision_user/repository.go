@@ -0,0 +1,54 @@
+package product_revision_user
+
+import (
+	"context"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/jinzhu/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, productRevisionUser *model.ProductRevisionUser) (*model.ProductRevisionUser, error)
+	Count(ctx context.Context, criteria map[string]interface{}) int
+	DeleteBy(ctx context.Context, criteria map[string]interface{}) error
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+func (e *repository) Create(ctx context.Context, productRevisionUser *model.ProductRevisionUser) (*model.ProductRevisionUser, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(productRevisionUser).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productRevisionUserRepository.Create] error execute query")
+		return nil, apperror.FromDB(err, "product revision user", "failed insert data")
+	}
+	return productRevisionUser, nil
+}
+
+func (e *repository) Count(ctx context.Context, criteria map[string]interface{}) int {
+	var result int
+	err := tracing.WithContext(ctx, e.DB).Model(&model.ProductRevisionUser{}).Where(criteria).Count(&result).Error
+	if err != nil {
+		helper.Logger(ctx).Error(err)
+		return 0
+	}
+	return result
+}
+
+// DeleteBy withdraws the approvals matching criteria.
+func (e *repository) DeleteBy(ctx context.Context, criteria map[string]interface{}) error {
+	err := tracing.WithContext(ctx, e.DB).Where(criteria).Delete(&model.ProductRevisionUser{}).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[productRevisionUserRepository.DeleteBy] error execute query")
+		return apperror.FromDB(err, "product revision user", "failed delete data")
+	}
+	return nil
+}
@@ -7,22 +7,25 @@
 package product_user
 
 import (
-	"fmt"
+	"context"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
 	"github.com/jinzhu/gorm"
 )
 
 type Repository interface {
-	Create(productUser *model.ProductUser) (*model.ProductUser, error)
-	ReadAll() (*[]model.ProductUser, error)
-	ReadAllBy(criteria map[string]interface{}, search string, page, size int) (*[]model.ProductUser, error)
-	ReadById(id int) (*model.ProductUser, error)
-	ReadBy(criteria map[string]interface{}) (*model.ProductUser, error)
-	Update(id int, person *model.ProductUser) (*model.ProductUser, error)
-	Delete(id int) error
-	Count(criteria map[string]interface{}) int
+	Create(ctx context.Context, productUser *model.ProductUser) (*model.ProductUser, error)
+	ReadAll(ctx context.Context) (*[]model.ProductUser, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.ProductUser, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.ProductUser, error)
+	ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.ProductUser, error)
+	Update(ctx context.Context, id int, person *model.ProductUser) (*model.ProductUser, error)
+	Delete(ctx context.Context, id int) error
+	Count(ctx context.Context, criteria map[string]interface{}) int
 }
 
 type repository struct {
@@ -33,100 +36,81 @@ func NewRepository(DB *gorm.DB) Repository {
 	return &repository{DB}
 }
 
-func (e *repository) Create(product_user *model.ProductUser) (*model.ProductUser, error) {
-	err := e.DB.Save(&product_user).Error
+func (e *repository) Create(ctx context.Context, product_user *model.ProductUser) (*model.ProductUser, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(&product_user).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Create] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed insert data")
+		helper.Logger(ctx).WithError(err).Error("[repository.Create] error execute query")
+		return nil, apperror.FromDB(err, "product user", "failed insert data")
 	}
 	return product_user, nil
 }
 
-func (e *repository) ReadAll() (*[]model.ProductUser, error) {
+func (e *repository) ReadAll(ctx context.Context) (*[]model.ProductUser, error) {
 	var product_users []model.ProductUser
-	err := e.DB.Find(&product_users).Error
+	err := tracing.WithContext(ctx, e.DB).Find(&product_users).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadAll] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed view all data")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadAll] error execute query")
+		return nil, apperror.FromDB(err, "product user", "failed view all data")
 	}
 	return &product_users, nil
 }
 
-func (e *repository) ReadAllBy(criteria map[string]interface{}, search string, page, size int) (*[]model.ProductUser, error) {
-	var product_users []model.ProductUser
-
-	query := e.DB.Where(criteria)
-
-	if search != "" {
-		query.Where("name LIKE ?", search+"%")
-	}
-
-	if page == 0 || size == 0 {
-		page, size = -1, -1
-	}
-
-	limit, offset := helper.GetLimitOffset(page, size)
-	err := query.Offset(offset).Order("created_at DESC").Limit(limit).Find(&product_users).Error
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.ProductUser, *query.Page, error) {
+	var productUsers []model.ProductUser
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.ProductUser{}), &productUsers)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadAllBy] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed view all data")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "product user", "failed view all data")
 	}
-	return &product_users, nil
+	return &productUsers, page, nil
 }
 
-func (e *repository) ReadById(id int) (*model.ProductUser, error) {
+func (e *repository) ReadById(ctx context.Context, id int) (*model.ProductUser, error) {
 	var product_user = model.ProductUser{}
-	err := e.DB.Table("product_users").Where("id = ?", id).First(&product_user).Error
+	err := tracing.WithContext(ctx, e.DB).Table("product_users").Where("id = ?", id).First(&product_user).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadById] error execute query %v \n", err)
-		return nil, fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "product user", "failed view data")
 	}
 	return &product_user, nil
 }
 
-func (e *repository) Update(id int, product_user *model.ProductUser) (*model.ProductUser, error) {
+func (e *repository) Update(ctx context.Context, id int, product_user *model.ProductUser) (*model.ProductUser, error) {
 	var upProductUser = model.ProductUser{}
-	err := e.DB.Table("product_users").Where("id = ?", id).First(&upProductUser).Update(&product_user).Error
+	err := tracing.WithContext(ctx, e.DB).Table("product_users").Where("id = ?", id).First(&upProductUser).Update(&product_user).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Update] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed update data")
+		helper.Logger(ctx).WithError(err).Error("[repository.Update] error execute query")
+		return nil, apperror.FromDB(err, "product user", "failed update data")
 	}
 	return &upProductUser, nil
 }
 
-func (e *repository) Delete(id int) error {
+func (e *repository) Delete(ctx context.Context, id int) error {
 	var product_user = model.ProductUser{}
-	err := e.DB.Table("product_users").Where("id = ?", id).First(&product_user).Delete(&product_user).Error
+	err := tracing.WithContext(ctx, e.DB).Table("product_users").Where("id = ?", id).First(&product_user).Delete(&product_user).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Delete] error execute query %v \n", err)
-		return fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[repository.Delete] error execute query")
+		return apperror.FromDB(err, "product user", "failed delete data")
 	}
 	return nil
 }
 
-func (e *repository) Count(criteria map[string]interface{}) int {
+func (e *repository) Count(ctx context.Context, criteria map[string]interface{}) int {
 	var result int
-	err := e.DB.Table("product_users").Where(criteria).Count(&result).Error
+	err := tracing.WithContext(ctx, e.DB).Table("product_users").Where(criteria).Count(&result).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		helper.Logger(ctx).Error(err)
 		return 0
 	}
 	return result
 }
 
-func (e *repository) ReadBy(criteria map[string]interface{}) (*model.ProductUser, error) {
+func (e *repository) ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.ProductUser, error) {
 	var product_user = model.ProductUser{}
-	err := e.DB.Table("product_users").Where(criteria).First(&product_user).Error
+	err := tracing.WithContext(ctx, e.DB).Table("product_users").Where(criteria).First(&product_user).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[productUserRepository.ReadBy] error execute query %v \n", err)
-		return nil, err
+		helper.Logger(ctx).WithError(err).Error("[productUserRepository.ReadBy] error execute query")
+		return nil, apperror.FromDB(err, "product user", "failed view data")
 	}
 	return &product_user, nil
 }
@@ -1,20 +1,26 @@
 package role
 
 import (
-	"fmt"
+	"context"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
 	"github.com/jinzhu/gorm"
 )
 
 type Repository interface {
-	Create(person *model.Role) (*model.Role, error)
-	ReadAll() (*[]model.Role, error)
-	ReadById(id int) (*model.Role, error)
-	ReadByName(name string) (*model.Role, error)
-	Update(id int, person *model.Role) (*model.Role, error)
-	Delete(id int) error
+	Create(ctx context.Context, person *model.Role) (*model.Role, error)
+	ReadAll(ctx context.Context) (*[]model.Role, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Role, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.Role, error)
+	ReadByName(ctx context.Context, name string) (*model.Role, error)
+	ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.Role, error)
+	Update(ctx context.Context, id int, person *model.Role) (*model.Role, error)
+	Patch(ctx context.Context, id int, fields map[string]interface{}) (*model.Role, error)
+	Delete(ctx context.Context, id int) error
 }
 
 type repository struct {
@@ -25,67 +31,92 @@ func NewRepository(DB *gorm.DB) Repository {
 	return &repository{DB}
 }
 
-func (e *repository) Create(role *model.Role) (*model.Role, error) {
-	err := e.DB.Save(&role).Error
+func (e *repository) Create(ctx context.Context, role *model.Role) (*model.Role, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(&role).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Create] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed insert data")
+		helper.Logger(ctx).WithError(err).Error("[repository.Create] error execute query")
+		return nil, apperror.FromDB(err, "role", "failed insert data")
 	}
 	return role, nil
 }
 
-func (e *repository) ReadAll() (*[]model.Role, error) {
+func (e *repository) ReadAll(ctx context.Context) (*[]model.Role, error) {
 	var roles []model.Role
-	err := e.DB.Find(&roles).Error
+	err := tracing.WithContext(ctx, e.DB).Find(&roles).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadAll] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed view all data")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadAll] error execute query")
+		return nil, apperror.FromDB(err, "role", "failed view all data")
 	}
 	return &roles, nil
 }
 
-func (e *repository) ReadById(id int) (*model.Role, error) {
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Role, *query.Page, error) {
+	var roles []model.Role
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.Role{}), &roles)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "role", "failed view all data")
+	}
+	return &roles, page, nil
+}
+
+func (e *repository) ReadById(ctx context.Context, id int) (*model.Role, error) {
+	var role = model.Role{}
+	err := tracing.WithContext(ctx, e.DB).Table("roles").Where("id = ?", id).First(&role).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[roleRepository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "role", "failed view data")
+	}
+	return &role, nil
+}
+
+// ReadByName finds the role shared by all companies with the given name.
+func (e *repository) ReadByName(ctx context.Context, name string) (*model.Role, error) {
 	var role = model.Role{}
-	err := e.DB.Table("roles").Where("id = ?", id).First(&role).Error
+	err := tracing.WithContext(ctx, e.DB).Table("roles").Where("name = ? AND company_id = 0", name).First(&role).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[roleRepository.ReadById] error execute query %v \n", err)
-		return nil, fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "role", "failed view data")
 	}
 	return &role, nil
 }
 
-func (e *repository) ReadByName(name string) (*model.Role, error) {
+func (e *repository) ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.Role, error) {
 	var role = model.Role{}
-	err := e.DB.Table("roles").Where("name = ?", name).First(&role).Error
+	err := tracing.WithContext(ctx, e.DB).Where(criteria).First(&role).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadById] error execute query %v \n", err)
-		return nil, fmt.Errorf("name is not exists")
+		helper.Logger(ctx).WithError(err).Error("[roleRepository.ReadBy] error execute query")
+		return nil, apperror.FromDB(err, "role", "failed view data")
 	}
 	return &role, nil
 }
 
-func (e *repository) Update(id int, role *model.Role) (*model.Role, error) {
+func (e *repository) Update(ctx context.Context, id int, role *model.Role) (*model.Role, error) {
 	var upRole = model.Role{}
-	err := e.DB.Table("roles").Where("id = ?", id).First(&upRole).Update(&role).Error
+	err := tracing.WithContext(ctx, e.DB).Table("roles").Where("id = ?", id).First(&upRole).Update(&role).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Update] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed update data")
+		helper.Logger(ctx).WithError(err).Error("[repository.Update] error execute query")
+		return nil, apperror.FromDB(err, "role", "failed update data")
 	}
 	return &upRole, nil
 }
 
-func (e *repository) Delete(id int) error {
+// Patch updates the given columns only, so zero values such as false are written too.
+func (e *repository) Patch(ctx context.Context, id int, fields map[string]interface{}) (*model.Role, error) {
+	result := tracing.WithContext(ctx, e.DB).Model(&model.Role{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[repository.Patch] error execute query")
+		return nil, apperror.FromDB(result.Error, "role", "failed update data")
+	}
+	return e.ReadById(ctx, id)
+}
+
+func (e *repository) Delete(ctx context.Context, id int) error {
 	var role = model.Role{}
-	err := e.DB.Table("roles").Where("id = ?", id).First(&role).Delete(&role).Error
+	err := tracing.WithContext(ctx, e.DB).Table("roles").Where("id = ?", id).First(&role).Delete(&role).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Delete] error execute query %v \n", err)
-		return fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[repository.Delete] error execute query")
+		return apperror.FromDB(err, "role", "failed delete data")
 	}
 	return nil
 }
@@ -0,0 +1,126 @@
+package sso
+
+import (
+	"context"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/jinzhu/gorm"
+)
+
+// Repository keeps the OpenID Connect providers of companies, the logins started with
+// them and the identities of the users linked to them.
+type Repository interface {
+	ReadProviderBy(ctx context.Context, criteria map[string]interface{}) (*model.SSOProvider, error)
+	SaveProvider(ctx context.Context, provider *model.SSOProvider) (*model.SSOProvider, error)
+	DeleteProvider(ctx context.Context, id int) error
+	CreateLogin(ctx context.Context, login *model.SSOLogin) (*model.SSOLogin, error)
+	ReadLoginBy(ctx context.Context, criteria map[string]interface{}) (*model.SSOLogin, error)
+	UseLogin(ctx context.Context, id int) error
+	ReadIdentityBy(ctx context.Context, criteria map[string]interface{}) (*model.UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error)
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+func (e *repository) ReadProviderBy(ctx context.Context, criteria map[string]interface{}) (*model.SSOProvider, error) {
+	var provider = model.SSOProvider{}
+	err := tracing.WithContext(ctx, e.DB).Where(criteria).First(&provider).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[ssoRepository.ReadProviderBy] error execute query")
+		return nil, apperror.FromDB(err, "sso_provider", "failed view data")
+	}
+	return &provider, nil
+}
+
+func (e *repository) SaveProvider(ctx context.Context, provider *model.SSOProvider) (*model.SSOProvider, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(provider).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[ssoRepository.SaveProvider] error execute query")
+		return nil, apperror.FromDB(err, "sso_provider", "failed insert data")
+	}
+	return provider, nil
+}
+
+// DeleteProvider deletes a provider along with the identities linked to it and the
+// logins started with it, so a provider configured again links users anew.
+func (e *repository) DeleteProvider(ctx context.Context, id int) error {
+	tx := tracing.WithContext(ctx, e.DB).Begin()
+	defer tx.Rollback()
+
+	err := tx.Where("provider_id = ?", id).Delete(&model.UserIdentity{}).Error
+	if err == nil {
+		err = tx.Where("provider_id = ?", id).Delete(&model.SSOLogin{}).Error
+	}
+	if err == nil {
+		err = tx.Where("id = ?", id).Delete(&model.SSOProvider{}).Error
+	}
+	if err == nil {
+		err = tx.Commit().Error
+	}
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[ssoRepository.DeleteProvider] error execute query")
+		return apperror.FromDB(err, "sso_provider", "failed delete data")
+	}
+	return nil
+}
+
+func (e *repository) CreateLogin(ctx context.Context, login *model.SSOLogin) (*model.SSOLogin, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(login).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[ssoRepository.CreateLogin] error execute query")
+		return nil, apperror.FromDB(err, "sso_login", "failed insert data")
+	}
+	return login, nil
+}
+
+func (e *repository) ReadLoginBy(ctx context.Context, criteria map[string]interface{}) (*model.SSOLogin, error) {
+	var login = model.SSOLogin{}
+	err := tracing.WithContext(ctx, e.DB).Where(criteria).First(&login).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[ssoRepository.ReadLoginBy] error execute query")
+		return nil, apperror.FromDB(err, "sso_login", "failed view data")
+	}
+	return &login, nil
+}
+
+// UseLogin marks the login used only if it was not yet, so it is completed once.
+func (e *repository) UseLogin(ctx context.Context, id int) error {
+	result := tracing.WithContext(ctx, e.DB).Model(&model.SSOLogin{}).Where("id = ? AND used_at IS NULL", id).Update("used_at", time.Now())
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[ssoRepository.UseLogin] error execute query")
+		return apperror.FromDB(result.Error, "sso_login", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return apperror.Conflict("sso_login_used", "sso login was completed meanwhile")
+	}
+	return nil
+}
+
+func (e *repository) ReadIdentityBy(ctx context.Context, criteria map[string]interface{}) (*model.UserIdentity, error) {
+	var identity = model.UserIdentity{}
+	err := tracing.WithContext(ctx, e.DB).Where(criteria).First(&identity).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[ssoRepository.ReadIdentityBy] error execute query")
+		return nil, apperror.FromDB(err, "user_identity", "failed view data")
+	}
+	return &identity, nil
+}
+
+func (e *repository) CreateIdentity(ctx context.Context, identity *model.UserIdentity) (*model.UserIdentity, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(identity).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[ssoRepository.CreateIdentity] error execute query")
+		return nil, apperror.FromDB(err, "user_identity", "failed insert data")
+	}
+	return identity, nil
+}
@@ -1,21 +1,27 @@
 package transaction_pre_order
 
 import (
-	"fmt"
+	"context"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/trash"
 	"github.com/jinzhu/gorm"
 )
 
 type Repository interface {
-	Create(transactionPreOrder *model.TransactionPreOrder) (*model.TransactionPreOrder, error)
-	ReadAll() (*[]model.TransactionPreOrder, error)
-	ReadAllBy(criteria map[string]interface{}, search string, page, size int) (*[]model.TransactionPreOrder, error)
-	ReadById(id int) (*model.TransactionPreOrder, error)
-	Update(id int, person *model.TransactionPreOrder) (*model.TransactionPreOrder, error)
-	Delete(id int) error
-	Count(criteria map[string]interface{}) int
+	Create(ctx context.Context, transactionPreOrder *model.TransactionPreOrder) (*model.TransactionPreOrder, error)
+	ReadAll(ctx context.Context) (*[]model.TransactionPreOrder, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.TransactionPreOrder, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.TransactionPreOrder, error)
+	Update(ctx context.Context, id int, person *model.TransactionPreOrder) (*model.TransactionPreOrder, error)
+	Patch(ctx context.Context, id, version int, fields map[string]interface{}) (*model.TransactionPreOrder, error)
+	Delete(ctx context.Context, id int) error
+	Count(ctx context.Context, criteria map[string]interface{}) int
+	Totals(ctx context.Context, companyId int) (*[]model.TransactionPreOrderTotal, error)
 }
 
 type repository struct {
@@ -26,23 +32,24 @@ func NewRepository(DB *gorm.DB) Repository {
 	return &repository{DB}
 }
 
-func (e *repository) Create(product *model.TransactionPreOrder) (*model.TransactionPreOrder, error) {
-	err := e.DB.Save(&product).Error
+func (e *repository) Create(ctx context.Context, product *model.TransactionPreOrder) (*model.TransactionPreOrder, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(&product).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderRepository.Create] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed insert data")
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderRepository.Create] error execute query")
+		return nil, apperror.FromDB(err, "transaction pre order", "failed insert data")
 	}
 	return product, nil
 }
 
-func (e *repository) ReadAll() (*[]model.TransactionPreOrder, error) {
+func (e *repository) ReadAll(ctx context.Context) (*[]model.TransactionPreOrder, error) {
 	var transactionPreOrders []model.TransactionPreOrder
-	err := e.DB.Select("*, p.name AS product_name, " +
+	err := tracing.WithContext(ctx, e.DB).Select("transaction_pre_orders.*, p.name AS product_name, " +
 		"p.image AS product_image, " +
 		"p.commodity AS product_commodity, " +
-		"p.min_price AS product_min_price, " +
-		"p.max_price AS product_max_price, " +
+		"p.min_price_amount AS product_min_price_amount, " +
+		"p.max_price_amount AS product_max_price_amount, " +
+		"p.unit_quantity AS product_unit_quantity, " +
+		"p.unit_price AS product_unit_price, " +
 		"p.product_created_at AS product_created_at," +
 		"p.expired_at AS product_expired_at," +
 		"p.is_pre_order AS product_is_pre_order," +
@@ -52,54 +59,43 @@ func (e *repository) ReadAll() (*[]model.TransactionPreOrder, error) {
 		Find(&transactionPreOrders).Error
 
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderRepository.ReadAll] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed view all data")
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderRepository.ReadAll] error execute query")
+		return nil, apperror.FromDB(err, "transaction pre order", "failed view all data")
 	}
 	return &transactionPreOrders, nil
 }
 
-func (e *repository) ReadAllBy(criteria map[string]interface{}, search string, page, size int) (*[]model.TransactionPreOrder, error) {
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.TransactionPreOrder, *query.Page, error) {
 	var transactionPreOrders []model.TransactionPreOrder
-
-	query := e.DB.Select("*, p.name AS product_name, " +
+	db := tracing.WithContext(ctx, e.DB).Model(&model.TransactionPreOrder{}).Select("transaction_pre_orders.*, p.name AS product_name, " +
 		"p.image AS product_image, " +
 		"p.commodity AS product_commodity," +
-		"p.min_price AS product_min_price," +
-		"p.max_price AS product_max_price," +
+		"p.min_price_amount AS product_min_price_amount," +
+		"p.max_price_amount AS product_max_price_amount," +
+		"p.unit_quantity AS product_unit_quantity, " +
+		"p.unit_price AS product_unit_price, " +
 		"p.product_created_at AS product_created_at," +
 		"p.expired_at AS product_expired_at," +
 		"p.is_pre_order AS product_is_pre_order," +
 		"p.is_active AS product_is_active").
-		Table("transaction_pre_orders").
-		Joins("JOIN products p ON p.id = transaction_pre_orders.product_id").
-		Where(criteria)
-
-	if search != "" {
-		query.Where("p.name LIKE ?", search+"%")
-	}
-
-	if page == 0 || size == 0 {
-		page, size = -1, -1
-	}
-
-	limit, offset := helper.GetLimitOffset(page, size)
-	err := query.Offset(offset).Order("transaction_pre_orders.created_at ASC").Limit(limit).Find(&transactionPreOrders).Error
+		Joins("JOIN products p ON p.id = transaction_pre_orders.product_id")
+	page, err := q.Find(db, &transactionPreOrders)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderRepository.ReadAllBy] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed view all data")
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderRepository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "transaction pre order", "failed view all data")
 	}
-	return &transactionPreOrders, nil
+	return &transactionPreOrders, page, nil
 }
 
-func (e *repository) ReadById(id int) (*model.TransactionPreOrder, error) {
+func (e *repository) ReadById(ctx context.Context, id int) (*model.TransactionPreOrder, error) {
 	var transactionPreOrder = model.TransactionPreOrder{}
-	err := e.DB.Select("*, p.name AS product_name, "+
+	err := tracing.WithContext(ctx, e.DB).Select("transaction_pre_orders.*, p.name AS product_name, "+
 		"p.image AS product_image, "+
 		"p.commodity AS product_commodity, "+
-		"p.min_price AS product_min_price, "+
-		"p.max_price AS product_max_price, "+
+		"p.min_price_amount AS product_min_price_amount, "+
+		"p.max_price_amount AS product_max_price_amount, "+
+		"p.unit_quantity AS product_unit_quantity, "+
+		"p.unit_price AS product_unit_price, "+
 		"p.product_created_at AS product_created_at,"+
 		"p.expired_at AS product_expired_at,"+
 		"p.is_pre_order AS product_is_pre_order,"+
@@ -108,41 +104,80 @@ func (e *repository) ReadById(id int) (*model.TransactionPreOrder, error) {
 		Joins("JOIN products p ON p.id = transaction_pre_orders.product_id").
 		Where("transaction_pre_orders.id = ?", id).First(&transactionPreOrder).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderRepository.ReadById] error execute query %v \n", err)
-		return nil, fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderRepository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "transaction pre order", "failed view data")
 	}
 	return &transactionPreOrder, nil
 }
 
-func (e *repository) Update(id int, product *model.TransactionPreOrder) (*model.TransactionPreOrder, error) {
+func (e *repository) Update(ctx context.Context, id int, product *model.TransactionPreOrder) (*model.TransactionPreOrder, error) {
 	var upTransactionPreOrder = model.TransactionPreOrder{}
-	err := e.DB.Table("transaction_pre_orders").Where("id = ?", id).First(&upTransactionPreOrder).Update(&product).Error
+	db := tracing.WithContext(ctx, e.DB)
+	err := db.Table("transaction_pre_orders").Where("id = ?", id).First(&upTransactionPreOrder).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderRepository.Update] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed update data")
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderRepository.Update] error execute query")
+		return nil, apperror.FromDB(err, "transaction pre order", "failed update data")
+	}
+
+	product.Version = upTransactionPreOrder.Version + 1
+	result := db.Model(&upTransactionPreOrder).Where("version = ?", upTransactionPreOrder.Version).Update(product)
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[transactionPreOrderRepository.Update] error execute query")
+		return nil, apperror.FromDB(result.Error, "transaction pre order", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperror.VersionMismatch("transaction pre order")
 	}
 	return &upTransactionPreOrder, nil
 }
 
-func (e *repository) Delete(id int) error {
-	var product = model.TransactionPreOrder{}
-	err := e.DB.Table("transaction_pre_orders").Where("id = ?", id).First(&product).Delete(&product).Error
+// Patch updates the given columns only if the stored version still equals version,
+// so a concurrent change makes it fail with a precondition error instead of being lost.
+func (e *repository) Patch(ctx context.Context, id, version int, fields map[string]interface{}) (*model.TransactionPreOrder, error) {
+	fields["version"] = gorm.Expr("version + 1")
+	result := tracing.WithContext(ctx, e.DB).Model(&model.TransactionPreOrder{}).Where("id = ? AND version = ?", id, version).Updates(fields)
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[transactionPreOrderRepository.Patch] error execute query")
+		return nil, apperror.FromDB(result.Error, "transaction pre order", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperror.VersionMismatch("transaction pre order")
+	}
+	return e.ReadById(ctx, id)
+}
+
+func (e *repository) Delete(ctx context.Context, id int) error {
+	err := trash.Delete(tracing.WithContext(ctx, e.DB), trash.PreOrders, id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderRepository.Delete] error execute query %v \n", err)
-		return fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderRepository.Delete] error execute query")
+		return apperror.FromDB(err, "transaction pre order", "failed delete data")
 	}
 	return nil
 }
 
-func (e *repository) Count(criteria map[string]interface{}) int {
+func (e *repository) Count(ctx context.Context, criteria map[string]interface{}) int {
 	var result int
-	err := e.DB.Table("transaction_pre_orders").Where(criteria).Count(&result).Error
+	err := tracing.WithContext(ctx, e.DB).Table("transaction_pre_orders").Where(criteria).Count(&result).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		helper.Logger(ctx).Error(err)
 		return 0
 	}
 	return result
 }
+
+func (e *repository) Totals(ctx context.Context, companyId int) (*[]model.TransactionPreOrderTotal, error) {
+	var totals []model.TransactionPreOrderTotal
+	err := tracing.WithContext(ctx, e.DB).Model(&model.TransactionPreOrder{}).
+		Select("p.unit_quantity, transaction_pre_orders.currency, "+
+			"SUM(transaction_pre_orders.quantity) AS quantity, "+
+			"SUM(transaction_pre_orders.total_amount) AS total_amount").
+		Joins("JOIN products p ON p.id = transaction_pre_orders.product_id").
+		Where("transaction_pre_orders.company_id = ?", companyId).
+		Group("p.unit_quantity, transaction_pre_orders.currency").
+		Scan(&totals).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderRepository.Totals] error execute query")
+		return nil, apperror.FromDB(err, "transaction pre order", "failed view data")
+	}
+	return &totals, nil
+}
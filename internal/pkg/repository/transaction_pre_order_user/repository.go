@@ -1,22 +1,25 @@
 package transaction_pre_order_user
 
 import (
-	"fmt"
+	"context"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
 	"github.com/jinzhu/gorm"
 )
 
 type Repository interface {
-	Create(transactionPreOrderUser *model.TransactionPreOrderUser) (*model.TransactionPreOrderUser, error)
-	ReadAll() (*[]model.TransactionPreOrderUser, error)
-	ReadAllBy(criteria map[string]interface{}, search string, page, size int) (*[]model.TransactionPreOrderUser, error)
-	ReadById(id int) (*model.TransactionPreOrderUser, error)
-	ReadBy(criteria map[string]interface{}) (*model.TransactionPreOrderUser, error)
-	Update(id int, person *model.TransactionPreOrderUser) (*model.TransactionPreOrderUser, error)
-	Delete(id int) error
-	Count(criteria map[string]interface{}) int
+	Create(ctx context.Context, transactionPreOrderUser *model.TransactionPreOrderUser) (*model.TransactionPreOrderUser, error)
+	ReadAll(ctx context.Context) (*[]model.TransactionPreOrderUser, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.TransactionPreOrderUser, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.TransactionPreOrderUser, error)
+	ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.TransactionPreOrderUser, error)
+	Update(ctx context.Context, id int, person *model.TransactionPreOrderUser) (*model.TransactionPreOrderUser, error)
+	Delete(ctx context.Context, id int) error
+	Count(ctx context.Context, criteria map[string]interface{}) int
 }
 
 type repository struct {
@@ -27,100 +30,81 @@ func NewRepository(DB *gorm.DB) Repository {
 	return &repository{DB}
 }
 
-func (e *repository) Create(transactionPreOrderUser *model.TransactionPreOrderUser) (*model.TransactionPreOrderUser, error) {
-	err := e.DB.Save(&transactionPreOrderUser).Error
+func (e *repository) Create(ctx context.Context, transactionPreOrderUser *model.TransactionPreOrderUser) (*model.TransactionPreOrderUser, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(&transactionPreOrderUser).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderUserRepository.Create] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed insert data")
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderUserRepository.Create] error execute query")
+		return nil, apperror.FromDB(err, "transaction pre order user", "failed insert data")
 	}
 	return transactionPreOrderUser, nil
 }
 
-func (e *repository) ReadAll() (*[]model.TransactionPreOrderUser, error) {
+func (e *repository) ReadAll(ctx context.Context) (*[]model.TransactionPreOrderUser, error) {
 	var transactionPreOrderUsers []model.TransactionPreOrderUser
-	err := e.DB.Find(&transactionPreOrderUsers).Error
+	err := tracing.WithContext(ctx, e.DB).Find(&transactionPreOrderUsers).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderUserRepository.ReadAll] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed view all data")
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderUserRepository.ReadAll] error execute query")
+		return nil, apperror.FromDB(err, "transaction pre order user", "failed view all data")
 	}
 	return &transactionPreOrderUsers, nil
 }
 
-func (e *repository) ReadAllBy(criteria map[string]interface{}, search string, page, size int) (*[]model.TransactionPreOrderUser, error) {
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.TransactionPreOrderUser, *query.Page, error) {
 	var transactionPreOrderUsers []model.TransactionPreOrderUser
-
-	query := e.DB.Where(criteria)
-
-	if search != "" {
-		query.Where("name LIKE ?", search+"%")
-	}
-
-	if page == 0 || size == 0 {
-		page, size = -1, -1
-	}
-
-	limit, offset := helper.GetLimitOffset(page, size)
-	err := query.Offset(offset).Order("created_at DESC").Limit(limit).Find(&transactionPreOrderUsers).Error
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.TransactionPreOrderUser{}), &transactionPreOrderUsers)
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderUserRepository.ReadAllBy] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed view all data")
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderUserRepository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "transaction pre order user", "failed view all data")
 	}
-	return &transactionPreOrderUsers, nil
+	return &transactionPreOrderUsers, page, nil
 }
 
-func (e *repository) ReadById(id int) (*model.TransactionPreOrderUser, error) {
+func (e *repository) ReadById(ctx context.Context, id int) (*model.TransactionPreOrderUser, error) {
 	var transactionPreOrderUser = model.TransactionPreOrderUser{}
-	err := e.DB.Table("transaction_pre_order_users").Where("id = ?", id).First(&transactionPreOrderUser).Error
+	err := tracing.WithContext(ctx, e.DB).Table("transaction_pre_order_users").Where("id = ?", id).First(&transactionPreOrderUser).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderUserRepository.ReadById] error execute query %v \n", err)
-		return nil, fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderUserRepository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "transaction pre order user", "failed view data")
 	}
 	return &transactionPreOrderUser, nil
 }
 
-func (e *repository) Update(id int, transactionPreOrderUser *model.TransactionPreOrderUser) (*model.TransactionPreOrderUser, error) {
+func (e *repository) Update(ctx context.Context, id int, transactionPreOrderUser *model.TransactionPreOrderUser) (*model.TransactionPreOrderUser, error) {
 	var upTransactionPreOrderUser = model.TransactionPreOrderUser{}
-	err := e.DB.Table("transaction_pre_order_users").Where("id = ?", id).First(&upTransactionPreOrderUser).Update(&transactionPreOrderUser).Error
+	err := tracing.WithContext(ctx, e.DB).Table("transaction_pre_order_users").Where("id = ?", id).First(&upTransactionPreOrderUser).Update(&transactionPreOrderUser).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderUserRepository.Update] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed update data")
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderUserRepository.Update] error execute query")
+		return nil, apperror.FromDB(err, "transaction pre order user", "failed update data")
 	}
 	return &upTransactionPreOrderUser, nil
 }
 
-func (e *repository) Delete(id int) error {
+func (e *repository) Delete(ctx context.Context, id int) error {
 	var transactionPreOrderUser = model.TransactionPreOrderUser{}
-	err := e.DB.Table("transaction_pre_order_users").Where("id = ?", id).First(&transactionPreOrderUser).Delete(&transactionPreOrderUser).Error
+	err := tracing.WithContext(ctx, e.DB).Table("transaction_pre_order_users").Where("id = ?", id).First(&transactionPreOrderUser).Delete(&transactionPreOrderUser).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderUserRepository.Delete] error execute query %v \n", err)
-		return fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderUserRepository.Delete] error execute query")
+		return apperror.FromDB(err, "transaction pre order user", "failed delete data")
 	}
 	return nil
 }
 
-func (e *repository) Count(criteria map[string]interface{}) int {
+func (e *repository) Count(ctx context.Context, criteria map[string]interface{}) int {
 	var result int
-	err := e.DB.Table("transaction_pre_order_users").Where(criteria).Count(&result).Error
+	err := tracing.WithContext(ctx, e.DB).Table("transaction_pre_order_users").Where(criteria).Count(&result).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		helper.Logger(ctx).Error(err)
 		return 0
 	}
 	return result
 }
 
-func (e *repository) ReadBy(criteria map[string]interface{}) (*model.TransactionPreOrderUser, error) {
+func (e *repository) ReadBy(ctx context.Context, criteria map[string]interface{}) (*model.TransactionPreOrderUser, error) {
 	var transactionPreOrderUser = model.TransactionPreOrderUser{}
-	err := e.DB.Table("transaction_pre_order_users").Where(criteria).First(&transactionPreOrderUser).Error
+	err := tracing.WithContext(ctx, e.DB).Table("transaction_pre_order_users").Where(criteria).First(&transactionPreOrderUser).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[transactionPreOrderUserRepository.ReadBy] error execute query %v \n", err)
-		return nil, err
+		helper.Logger(ctx).WithError(err).Error("[transactionPreOrderUserRepository.ReadBy] error execute query")
+		return nil, apperror.FromDB(err, "transaction pre order user", "failed view data")
 	}
 	return &transactionPreOrderUser, nil
 }
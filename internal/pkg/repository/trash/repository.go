@@ -0,0 +1,66 @@
+package trash
+
+import (
+	"context"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/trash"
+	"github.com/jinzhu/gorm"
+)
+
+type Repository interface {
+	ReadAllBy(ctx context.Context, r *trash.Resource, q *query.Query, out interface{}) (*query.Page, error)
+	Restore(ctx context.Context, r *trash.Resource, id int) error
+	Purge(ctx context.Context, r *trash.Resource, id int) error
+	PurgeDeletedBefore(ctx context.Context, r *trash.Resource, t time.Time) (int, error)
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+// ReadAllBy loads the deleted records of r into out, a pointer to a slice of its model.
+func (e *repository) ReadAllBy(ctx context.Context, r *trash.Resource, q *query.Query, out interface{}) (*query.Page, error) {
+	db := tracing.WithContext(ctx, e.DB).Unscoped().Table(r.Table).Where(r.Table + ".deleted_at IS NOT NULL")
+	page, err := q.Find(db, out)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[trashRepository.ReadAllBy] error execute query")
+		return nil, apperror.FromDB(err, r.Entity, "failed view all data")
+	}
+	return page, nil
+}
+
+func (e *repository) Restore(ctx context.Context, r *trash.Resource, id int) error {
+	err := trash.Restore(tracing.WithContext(ctx, e.DB), r, id)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[trashRepository.Restore] error execute query")
+		return apperror.FromDB(err, r.Entity, "failed restore data")
+	}
+	return nil
+}
+
+func (e *repository) Purge(ctx context.Context, r *trash.Resource, id int) error {
+	err := trash.Purge(tracing.WithContext(ctx, e.DB), r, id)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[trashRepository.Purge] error execute query")
+		return apperror.FromDB(err, r.Entity, "failed purge data")
+	}
+	return nil
+}
+
+func (e *repository) PurgeDeletedBefore(ctx context.Context, r *trash.Resource, t time.Time) (int, error) {
+	count, err := trash.PurgeDeletedBefore(tracing.WithContext(ctx, e.DB), r, t)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[trashRepository.PurgeDeletedBefore] error execute query")
+		return 0, apperror.FromDB(err, r.Entity, "failed purge data")
+	}
+	return count, nil
+}
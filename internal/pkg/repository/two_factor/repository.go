@@ -0,0 +1,162 @@
+package two_factor
+
+import (
+	"context"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"github.com/jinzhu/gorm"
+)
+
+// Repository keeps the TOTP secrets of users and their recovery codes.
+type Repository interface {
+	ReadByUserId(ctx context.Context, userID int) (*model.TwoFactor, error)
+	Save(ctx context.Context, twoFactor *model.TwoFactor) (*model.TwoFactor, error)
+	Enable(ctx context.Context, id int, counter int64, codeHashes []string) error
+	Advance(ctx context.Context, id int, counter int64) error
+	Delete(ctx context.Context, userID int) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) error
+	CountRecoveryCodes(ctx context.Context, userID int) (int, error)
+}
+
+type repository struct {
+	DB *gorm.DB
+}
+
+func NewRepository(DB *gorm.DB) Repository {
+	return &repository{DB}
+}
+
+func (e *repository) ReadByUserId(ctx context.Context, userID int) (*model.TwoFactor, error) {
+	var twoFactor = model.TwoFactor{}
+	err := tracing.WithContext(ctx, e.DB).Where("user_id = ?", userID).First(&twoFactor).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[twoFactorRepository.ReadByUserId] error execute query")
+		return nil, apperror.FromDB(err, "two_factor", "failed view data")
+	}
+	return &twoFactor, nil
+}
+
+func (e *repository) Save(ctx context.Context, twoFactor *model.TwoFactor) (*model.TwoFactor, error) {
+	err := tracing.WithContext(ctx, e.DB).Save(twoFactor).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[twoFactorRepository.Save] error execute query")
+		return nil, apperror.FromDB(err, "two_factor", "failed insert data")
+	}
+	return twoFactor, nil
+}
+
+// Enable turns a pending secret on, counter being the period of the code that
+// confirmed it, along with the first recovery codes.
+func (e *repository) Enable(ctx context.Context, id int, counter int64, codeHashes []string) error {
+	tx := tracing.WithContext(ctx, e.DB).Begin()
+	defer tx.Rollback()
+
+	var twoFactor = model.TwoFactor{}
+	err := tx.Where("id = ?", id).First(&twoFactor).Error
+	if err == nil {
+		err = tx.Model(&twoFactor).Updates(map[string]interface{}{"enabled": true, "enabled_at": time.Now(), "last_counter": counter}).Error
+	}
+	if err == nil {
+		err = replaceRecoveryCodes(tx, twoFactor.UserID, codeHashes)
+	}
+	if err == nil {
+		err = tx.Commit().Error
+	}
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[twoFactorRepository.Enable] error execute query")
+		return apperror.FromDB(err, "two_factor", "failed update data")
+	}
+	return nil
+}
+
+// Advance records counter as the period of the last code accepted, unless a code of
+// that period or a later one was accepted meanwhile.
+func (e *repository) Advance(ctx context.Context, id int, counter int64) error {
+	result := tracing.WithContext(ctx, e.DB).Model(&model.TwoFactor{}).Where("id = ? AND last_counter < ?", id, counter).UpdateColumn("last_counter", counter)
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[twoFactorRepository.Advance] error execute query")
+		return apperror.FromDB(result.Error, "two_factor", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return apperror.Conflict("code_used", "code was already used")
+	}
+	return nil
+}
+
+// Delete forgets the secret and recovery codes of the user.
+func (e *repository) Delete(ctx context.Context, userID int) error {
+	tx := tracing.WithContext(ctx, e.DB).Begin()
+	defer tx.Rollback()
+
+	err := tx.Where("user_id = ?", userID).Delete(&model.TwoFactor{}).Error
+	if err == nil {
+		err = tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+	}
+	if err == nil {
+		err = tx.Commit().Error
+	}
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[twoFactorRepository.Delete] error execute query")
+		return apperror.FromDB(err, "two_factor", "failed delete data")
+	}
+	return nil
+}
+
+func (e *repository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	tx := tracing.WithContext(ctx, e.DB).Begin()
+	defer tx.Rollback()
+
+	err := replaceRecoveryCodes(tx, userID, codeHashes)
+	if err == nil {
+		err = tx.Commit().Error
+	}
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[twoFactorRepository.ReplaceRecoveryCodes] error execute query")
+		return apperror.FromDB(err, "recovery_code", "failed insert data")
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID int, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if err := tx.Create(&model.RecoveryCode{UserID: userID, CodeHash: hash}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code of the user used, failing as not found
+// when there is none.
+func (e *repository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	result := tracing.WithContext(ctx, e.DB).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		UpdateColumn("used_at", time.Now())
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[twoFactorRepository.UseRecoveryCode] error execute query")
+		return apperror.FromDB(result.Error, "recovery_code", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return apperror.NotFound("recovery_code_not_found", "recovery code is not exists")
+	}
+	return nil
+}
+
+// CountRecoveryCodes counts the unused recovery codes of the user.
+func (e *repository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	var result int
+	err := tracing.WithContext(ctx, e.DB).Model(&model.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&result).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[twoFactorRepository.CountRecoveryCodes] error execute query")
+		return 0, apperror.FromDB(err, "recovery_code", "failed view data")
+	}
+	return result, nil
+}
@@ -7,22 +7,35 @@
 package user
 
 import (
-	"fmt"
+	"context"
+	"time"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/trash"
 	"github.com/jinzhu/gorm"
 )
 
 type Repository interface {
-	Create(user *model.User) (*model.User, error)
-	ReadAll() (*[]model.User, error)
-	ReadById(id int) (*model.User, error)
-	ReadByEmail(email string) (*model.User, error)
-	Update(id int, user *model.User) (*model.User, error)
-	UpdatePasswordLogin(user *model.User) (*model.User, error)
-	Delete(id int) error
-	Count(criteria map[string]interface{}) int
+	Create(ctx context.Context, user *model.User) (*model.User, error)
+	ReadAll(ctx context.Context) (*[]model.User, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.User, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.User, error)
+	ReadByEmail(ctx context.Context, email string) (*model.User, error)
+	Update(ctx context.Context, id int, user *model.User) (*model.User, error)
+	Patch(ctx context.Context, id, version int, fields map[string]interface{}) (*model.User, error)
+	UpdatePasswordLogin(ctx context.Context, user *model.User) (*model.User, error)
+	ResetPassword(ctx context.Context, user *model.User, resetID int) (*model.User, error)
+	UpdatePasswordHash(ctx context.Context, id int, old, hash string) error
+	Delete(ctx context.Context, id int) error
+	Count(ctx context.Context, criteria map[string]interface{}) int
+	Session(ctx context.Context, id int) (*model.User, error)
+	FailLogin(ctx context.Context, id int) (int, error)
+	Lock(ctx context.Context, id int, until time.Time) error
+	Unlock(ctx context.Context, id int) error
 }
 
 type repository struct {
@@ -33,122 +46,242 @@ func NewRepository(DB *gorm.DB) Repository {
 	return &repository{DB}
 }
 
-func (e *repository) Create(user *model.User) (*model.User, error) {
-	//var role = model.Role{}
-	//var company = model.Company{}
-
-	tx := e.DB.Begin()
+func (e *repository) Create(ctx context.Context, user *model.User) (*model.User, error) {
+	tx := tracing.WithContext(ctx, e.DB).Begin()
 	defer tx.Rollback()
 
 	err := tx.Save(&user).Error
+	if err == nil && user.Password != "" {
+		err = tx.Create(&model.PasswordHistory{UserID: user.ID, Password: user.Password}).Error
+	}
+	if err == nil {
+		err = tx.Commit().Error
+	}
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Create] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed insert data")
-	}
-
-	//err = tx.Table("roles").Where("id = ?", user.RoleID).First(&role).Error
-	//if err != nil {
-	helper.CommonLogger().Error(err)
-	//	fmt.Printf("[repository.Create] error execute query %v \n", err)
-	//	return nil, fmt.Errorf("id role is not exists")
-	//}
-	//
-	//err = tx.Table("companies").Where("id = ?", user.CompanyID).First(&company).Error
-	//if err != nil {
-	helper.CommonLogger().Error(err)
-	//	fmt.Printf("[repository.Create] error execute query %v \n", err)
-	//	return nil, fmt.Errorf("id company is not exists")
-	//}
-	//
-	//user.Role = role
-	//user.Company = company
-
-	tx.Commit()
+		helper.Logger(ctx).WithError(err).Error("[repository.Create] error execute query")
+		return nil, apperror.FromDB(err, "user", "failed insert data")
+	}
 
 	return user, nil
 }
 
-func (e *repository) ReadAll() (*[]model.User, error) {
+func (e *repository) ReadAll(ctx context.Context) (*[]model.User, error) {
 	var users []model.User
-	err := e.DB.Find(&users).Error
+	err := tracing.WithContext(ctx, e.DB).Find(&users).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadAll] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed view all data")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadAll] error execute query")
+		return nil, apperror.FromDB(err, "user", "failed view all data")
 	}
 	return &users, nil
 }
 
-func (e *repository) ReadById(id int) (*model.User, error) {
+func (e *repository) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.User, *query.Page, error) {
+	var users []model.User
+	page, err := q.Find(tracing.WithContext(ctx, e.DB).Model(&model.User{}), &users)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadAllBy] error execute query")
+		return nil, nil, apperror.FromDB(err, "user", "failed view all data")
+	}
+	return &users, page, nil
+}
+
+func (e *repository) ReadById(ctx context.Context, id int) (*model.User, error) {
 	var user = model.User{}
-	err := e.DB.Select("*, r.name AS role_name, c.name AS company_name").
+	err := tracing.WithContext(ctx, e.DB).Select("users.*, r.name AS role_name, c.name AS company_name").
 		Table("users").
-		Joins("JOIN roles r ON r.id = users.role_id").
+		Joins("LEFT JOIN roles r ON r.id = users.role_id").
 		Joins("JOIN companies c ON c.id = users.company_id").
 		Where("users.id = ?", id).First(&user).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadById] error execute query %v \n", err)
-		return nil, fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadById] error execute query")
+		return nil, apperror.FromDB(err, "user", "failed view data")
 	}
 	return &user, nil
 }
 
-func (e *repository) ReadByEmail(email string) (*model.User, error) {
+func (e *repository) ReadByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user = model.User{}
-	err := e.DB.Select("*, r.name AS role_name, c.name AS company_name").
+	err := tracing.WithContext(ctx, e.DB).Select("users.*, r.name AS role_name, c.name AS company_name").
 		Table("users").
-		Joins("JOIN roles r ON r.id = users.role_id").
+		Joins("LEFT JOIN roles r ON r.id = users.role_id").
 		Joins("JOIN companies c ON c.id = users.company_id").
 		Where("users.email = ?", email).First(&user).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.ReadByEmail] error execute query %v \n", err)
-		return nil, fmt.Errorf("id is not exists")
+		helper.Logger(ctx).WithError(err).Error("[repository.ReadByEmail] error execute query")
+		return nil, apperror.FromDB(err, "user", "failed view data")
 	}
 	return &user, nil
 }
 
-func (e *repository) Update(id int, user *model.User) (*model.User, error) {
+func (e *repository) Update(ctx context.Context, id int, user *model.User) (*model.User, error) {
 	var upUser = model.User{}
-	err := e.DB.Table("users").Where("id = ?", id).First(&upUser).Update(&user).Error
+	db := tracing.WithContext(ctx, e.DB)
+	err := db.Table("users").Where("id = ?", id).First(&upUser).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Update] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed update data")
+		helper.Logger(ctx).WithError(err).Error("[repository.Update] error execute query")
+		return nil, apperror.FromDB(err, "user", "failed update data")
+	}
+
+	user.Version = upUser.Version + 1
+	result := db.Model(&upUser).Where("version = ?", upUser.Version).Update(user)
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[repository.Update] error execute query")
+		return nil, apperror.FromDB(result.Error, "user", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperror.VersionMismatch("user")
 	}
 	return &upUser, nil
 }
 
-func (e *repository) UpdatePasswordLogin(user *model.User) (*model.User, error) {
-	var upUser = model.User{}
-	err := e.DB.Model(&user).Update(map[string]interface{}{"password": &user.Password, "must_change_password": &user.MustChangePassword}).Error
+// Patch updates the given columns only if the stored version still equals version,
+// so a concurrent change makes it fail with a precondition error instead of being lost.
+func (e *repository) Patch(ctx context.Context, id, version int, fields map[string]interface{}) (*model.User, error) {
+	fields["version"] = gorm.Expr("version + 1")
+	result := tracing.WithContext(ctx, e.DB).Model(&model.User{}).Where("id = ? AND version = ?", id, version).Updates(fields)
+	if result.Error != nil {
+		helper.Logger(ctx).WithError(result.Error).Error("[repository.Patch] error execute query")
+		return nil, apperror.FromDB(result.Error, "user", "failed update data")
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperror.VersionMismatch("user")
+	}
+	return e.ReadById(ctx, id)
+}
+
+// UpdatePasswordLogin sets the password of the user, keeping it in their password
+// history, and increases their session version, so the tokens issued before can no
+// longer be used.
+func (e *repository) UpdatePasswordLogin(ctx context.Context, user *model.User) (*model.User, error) {
+	tx := tracing.WithContext(ctx, e.DB).Begin()
+	defer tx.Rollback()
+
+	err := setPassword(tx, user)
+	if err == nil {
+		err = tx.Commit().Error
+	}
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Update] error execute query %v \n", err)
-		return nil, fmt.Errorf("failed update data")
+		helper.Logger(ctx).WithError(err).Error("[repository.UpdatePasswordLogin] error execute query")
+		return nil, apperror.FromDB(err, "user", "failed update data")
 	}
-	return &upUser, nil
+	return e.ReadById(ctx, user.ID)
 }
 
-func (e *repository) Delete(id int) error {
-	var user = model.User{}
-	err := e.DB.Table("users").Where("id = ?", id).First(&user).Delete(&user).Error
+// ResetPassword sets the password of the user like UpdatePasswordLogin, marking the
+// password reset resetID used in the same transaction. The reset is used once, and
+// stays unused if the password cannot be set.
+func (e *repository) ResetPassword(ctx context.Context, user *model.User, resetID int) (*model.User, error) {
+	tx := tracing.WithContext(ctx, e.DB).Begin()
+	defer tx.Rollback()
+
+	result := tx.Model(&model.PasswordReset{}).Where("id = ? AND used_at IS NULL", resetID).Update("used_at", time.Now())
+	err := result.Error
+	if err == nil && result.RowsAffected == 0 {
+		return nil, apperror.Conflict("password_reset_used", "password reset was used meanwhile")
+	}
+	if err == nil {
+		err = setPassword(tx, user)
+	}
+	if err == nil {
+		err = tx.Commit().Error
+	}
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.ResetPassword] error execute query")
+		return nil, apperror.FromDB(err, "user", "failed update data")
+	}
+	return e.ReadById(ctx, user.ID)
+}
+
+// setPassword sets the password of the user in tx, keeping it in their password history,
+// and increases their session version.
+func setPassword(tx *gorm.DB, user *model.User) error {
+	err := tx.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"password":             user.Password,
+		"must_change_password": user.MustChangePassword,
+		"session_version":      gorm.Expr("session_version + 1"),
+		"version":              gorm.Expr("version + 1"),
+	}).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		fmt.Printf("[repository.Delete] error execute query %v \n", err)
-		return fmt.Errorf("id is not exists")
+		return err
+	}
+	return tx.Create(&model.PasswordHistory{UserID: user.ID, Password: user.Password}).Error
+}
+
+// UpdatePasswordHash replaces the hash of the same password, e.g. made with a higher
+// cost, unless the password was changed meanwhile.
+func (e *repository) UpdatePasswordHash(ctx context.Context, id int, old, hash string) error {
+	err := tracing.WithContext(ctx, e.DB).Model(&model.User{}).Where("id = ? AND password = ?", id, old).UpdateColumn("password", hash).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.UpdatePasswordHash] error execute query")
+		return apperror.FromDB(err, "user", "failed update data")
+	}
+	return nil
+}
+
+func (e *repository) Delete(ctx context.Context, id int) error {
+	err := trash.Delete(tracing.WithContext(ctx, e.DB), trash.Users, id)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.Delete] error execute query")
+		return apperror.FromDB(err, "user", "failed delete data")
 	}
 	return nil
 }
 
-func (e *repository) Count(criteria map[string]interface{}) int {
+func (e *repository) Count(ctx context.Context, criteria map[string]interface{}) int {
 	var result int
-	err := e.DB.Table("users").Where(criteria).Count(&result).Error
+	err := tracing.WithContext(ctx, e.DB).Table("users").Where(criteria).Count(&result).Error
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		helper.Logger(ctx).Error(err)
 		return 0
 	}
 	return result
 }
+
+// Session finds the session version and the company of the user.
+func (e *repository) Session(ctx context.Context, id int) (*model.User, error) {
+	var user = model.User{}
+	err := tracing.WithContext(ctx, e.DB).Select("id, session_version, company_id").Where("id = ?", id).First(&user).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.Session] error execute query")
+		return nil, apperror.FromDB(err, "user", "failed view data")
+	}
+	return &user, nil
+}
+
+// FailLogin counts a failed login of the user and returns their failures in a row.
+func (e *repository) FailLogin(ctx context.Context, id int) (int, error) {
+	db := tracing.WithContext(ctx, e.DB)
+	err := db.Model(&model.User{}).Where("id = ?", id).UpdateColumn("failed_logins", gorm.Expr("failed_logins + 1")).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.FailLogin] error execute query")
+		return 0, apperror.FromDB(err, "user", "failed update data")
+	}
+	var user = model.User{}
+	err = db.Select("failed_logins").Where("id = ?", id).First(&user).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.FailLogin] error execute query")
+		return 0, apperror.FromDB(err, "user", "failed view data")
+	}
+	return user.FailedLogins, nil
+}
+
+// Lock refuses the logins of the user until the given time.
+func (e *repository) Lock(ctx context.Context, id int, until time.Time) error {
+	err := tracing.WithContext(ctx, e.DB).Model(&model.User{}).Where("id = ?", id).UpdateColumn("locked_until", until).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.Lock] error execute query")
+		return apperror.FromDB(err, "user", "failed update data")
+	}
+	return nil
+}
+
+// Unlock forgets the failed logins of the user.
+func (e *repository) Unlock(ctx context.Context, id int) error {
+	err := tracing.WithContext(ctx, e.DB).Model(&model.User{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{"failed_logins": 0, "locked_until": nil}).Error
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Error("[repository.Unlock] error execute query")
+		return apperror.FromDB(err, "user", "failed update data")
+	}
+	return nil
+}
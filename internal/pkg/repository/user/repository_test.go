@@ -7,6 +7,8 @@
 package user
 
 import (
+	"context"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jinzhu/gorm"
@@ -28,9 +30,6 @@ func TestCreate(t *testing.T) {
 
 	t.Run("Register", func(t *testing.T) {
 
-		rows := sqlmock.NewRows([]string{"name", "password", "email", "role"}).AddRow(
-			"Alex", "123456", "alex@gmail.com", 1)
-
 		user := &model.User{
 			Name:     "Alex",
 			Email:    "alex@gmail.com",
@@ -38,15 +37,93 @@ func TestCreate(t *testing.T) {
 			RoleID:   2,
 		}
 
-		mock.ExpectQuery(`INSERT INTO users (name, email, verification_level,status,password, role_id , company_id , created_at,updated_at,deleted_at)
-						VALUES ($1, $2, $3, $4, $5, $6, now(), now(), NULL) 
-						RETURNING *`).WithArgs(&user.Name, &user.Email,
-			&user.Password, &user.RoleID).WillReturnRows(rows)
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `users` (`name`,`email`,`verification_level`,`password`,`role_id`,`company_id`,`locked_until`,`created_at`,`updated_at`,`deleted_at`) VALUES (?,?,?,?,?,?,?,?,?,?)").
+			WithArgs(user.Name, user.Email, user.VerificationLevel, user.Password, user.RoleID, user.CompanyID, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("SELECT `must_change_password`, `service_account`, `version`, `session_version`, `failed_logins` FROM `users`  WHERE (id = ?)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"must_change_password", "service_account", "version", "session_version", "failed_logins"}).AddRow(true, false, 1, 0, 0))
+		mock.ExpectExec("INSERT INTO `password_histories` (`user_id`,`password`,`created_at`) VALUES (?,?,?)").
+			WithArgs(1, user.Password, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 
-		createdUser, err := userRepo.Create(user)
+		createdUser, err := userRepo.Create(context.Background(), user)
 
 		require.NoError(t, err)
 		require.NotNil(t, createdUser)
 		require.Equal(t, createdUser, user)
+		require.Equal(t, 1, createdUser.ID)
+		require.Equal(t, 1, createdUser.Version)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("CommitFailed", func(t *testing.T) {
+		user := &model.User{
+			Name:   "Alex",
+			Email:  "alex@gmail.com",
+			RoleID: 2,
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO `users` (`name`,`email`,`verification_level`,`password`,`role_id`,`company_id`,`locked_until`,`created_at`,`updated_at`,`deleted_at`) VALUES (?,?,?,?,?,?,?,?,?,?)").
+			WithArgs(user.Name, user.Email, user.VerificationLevel, user.Password, user.RoleID, user.CompanyID, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectQuery("SELECT `must_change_password`, `service_account`, `version`, `session_version`, `failed_logins` FROM `users`  WHERE (id = ?)").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"must_change_password", "service_account", "version", "session_version", "failed_logins"}).AddRow(true, false, 1, 0, 0))
+		mock.ExpectCommit().WillReturnError(sqlmock.ErrCancelled)
+
+		createdUser, err := userRepo.Create(context.Background(), user)
+
+		require.Error(t, err)
+		require.Nil(t, createdUser)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	t.Parallel()
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	gormDb, _ := gorm.Open("mysql", db)
+	defer gormDb.Close()
+
+	userRepo := NewRepository(gormDb)
+	user := &model.User{ID: 7, Password: "hash"}
+
+	t.Run("UsedMeanwhile", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `password_resets` SET `used_at` = ?  WHERE (id = ? AND used_at IS NULL)").
+			WithArgs(sqlmock.AnyArg(), 3).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		updated, err := userRepo.ResetPassword(context.Background(), user, 3)
+
+		require.True(t, apperror.Is(err, apperror.KindConflict))
+		require.Nil(t, updated)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UpdateFailedKeepsReset", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `password_resets` SET `used_at` = ?  WHERE (id = ? AND used_at IS NULL)").
+			WithArgs(sqlmock.AnyArg(), 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE `users` SET `must_change_password` = ?, `password` = ?, `session_version` = session_version + 1, `updated_at` = ?, `version` = version + 1  WHERE `users`.`deleted_at` IS NULL AND ((id = ?))").
+			WithArgs(false, user.Password, sqlmock.AnyArg(), user.ID).
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		updated, err := userRepo.ResetPassword(context.Background(), user, 3)
+
+		require.Error(t, err)
+		require.Nil(t, updated)
+		require.NoError(t, mock.ExpectationsWereMet())
 	})
 }
@@ -0,0 +1,9 @@
+package request
+
+// AccessToken is the body of POST /access_token and POST /user/:id/access_token. Scopes
+// are permissions such as product:read.
+type AccessToken struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,max=50,dive,required,max=64"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"required,gt=0"`
+}
@@ -0,0 +1,30 @@
+package request
+
+import (
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+)
+
+type Commodity struct {
+	Code         string                 `json:"code" binding:"required,max=50"`
+	Name         string                 `json:"name" binding:"required,max=100"`
+	Category     enum.CommodityCategory `json:"category" binding:"required,oneof=pangan hortikultura perkebunan peternakan perikanan"`
+	UnitQuantity string                 `json:"unit_quantity" binding:"required,unit"`
+	UnitPrice    string                 `json:"unit_price" binding:"required,unit"`
+	PariCode     string                 `json:"pari_code" binding:"max=50"`
+	IsActive     bool                   `json:"is_active"`
+}
+
+// CommodityQuery is what GET /commodity accepts.
+var CommodityQuery = query.Spec{
+	Fields: map[string]query.Field{
+		"code":       {Column: "code", Operators: query.Exact, Sortable: true},
+		"name":       {Column: "name", Operators: query.Exact, Sortable: true},
+		"category":   {Column: "category", Operators: query.Exact, Values: []string{string(enum.Pangan), string(enum.Hortikultura), string(enum.Perkebunan), string(enum.Peternakan), string(enum.Perikanan)}},
+		"is_active":  {Column: "is_active", Kind: query.Bool, Operators: []query.Operator{query.Eq}},
+		"created_at": {Column: "created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	},
+	Search: []string{"name", "code"},
+	Sort:   "name",
+	Key:    "id",
+}
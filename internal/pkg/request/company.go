@@ -1,9 +1,29 @@
 package request
 
+import (
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+)
+
 type Company struct {
-	Name    string `json:"name"`
-	Code    string `json:"code"`
-	Address string `json:"address"`
-	Alias   string `json:"alias"`
-	Giro    string `json:"giro"`
+	Name    string `json:"name" binding:"required,max=255"`
+	Code    string `json:"code" binding:"required,max=50"`
+	Address string `json:"address" binding:"max=255"`
+	Alias   string `json:"alias" binding:"max=100"`
+	Giro    string `json:"giro" binding:"required,giro"`
+}
+
+// CompanyQuery is what GET /company accepts.
+var CompanyQuery = query.Spec{
+	Fields: map[string]query.Field{
+		"name":       {Column: "name", Operators: query.Exact, Sortable: true},
+		"code":       {Column: "code", Operators: query.Exact, Sortable: true},
+		"giro":       {Column: "giro", Operators: query.Exact},
+		"giro_id":    {Column: "giro_id", Kind: query.Number, Operators: query.Exact},
+		"status":     {Column: "status", Operators: query.Exact, Values: []string{string(enum.CompanyPending), string(enum.CompanyApproved), string(enum.CompanyRejected)}},
+		"created_at": {Column: "created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	},
+	Search: []string{"name", "code", "alias"},
+	Sort:   "name",
+	Key:    "id",
 }
@@ -0,0 +1,26 @@
+package request
+
+import "bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+
+// Giro is the body of POST and PUT /giro. IsActive defaults to true; a giro that is
+// not active cannot onboard a company.
+type Giro struct {
+	Code        string `json:"code" binding:"required,giro"`
+	CompanyName string `json:"company_name" binding:"required,max=255"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+type Giros []Giro
+
+// GiroQuery is what GET /giro accepts.
+var GiroQuery = query.Spec{
+	Fields: map[string]query.Field{
+		"code":         {Column: "code", Operators: query.Exact, Sortable: true},
+		"company_name": {Column: "company_name", Operators: query.Exact, Sortable: true},
+		"is_active":    {Column: "is_active", Kind: query.Bool, Operators: []query.Operator{query.Eq}},
+		"created_at":   {Column: "created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	},
+	Search: []string{"code", "company_name"},
+	Sort:   "code",
+	Key:    "id",
+}
@@ -0,0 +1,38 @@
+package request
+
+import (
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+)
+
+// Invitation is the body of POST /invitation. Admins other than superadmins invite to
+// their own company.
+type Invitation struct {
+	Email             string                 `json:"email" binding:"required,email,max=100"`
+	RoleID            int                    `json:"role_id" binding:"required,gt=0"`
+	CompanyID         int                    `json:"company_id" binding:"required,gt=0"`
+	VerificationLevel enum.VerificationLevel `json:"verification_level" binding:"gte=0,lte=3"`
+}
+
+// InvitationAcceptance is the body of POST /invitation/acceptance, Token being the
+// token of the link the invitee received.
+type InvitationAcceptance struct {
+	Token    string `json:"token" binding:"required"`
+	Name     string `json:"name" binding:"required,max=100"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+// InvitationQuery is what GET /invitation accepts.
+var InvitationQuery = query.Spec{
+	Fields: map[string]query.Field{
+		"email":      {Column: "email", Operators: query.Exact, Sortable: true},
+		"company_id": {Column: "company_id", Kind: query.Number, Operators: query.Exact},
+		"role_id":    {Column: "role_id", Kind: query.Number, Operators: query.Exact},
+		"status":     {Column: "status", Operators: query.Exact, Values: []string{string(enum.InvitationPending), string(enum.InvitationAccepted), string(enum.InvitationRevoked)}},
+		"expires_at": {Column: "expires_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+		"created_at": {Column: "created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	},
+	Search: []string{"email"},
+	Sort:   "-created_at",
+	Key:    "id",
+}
@@ -0,0 +1,27 @@
+package request
+
+import (
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+)
+
+// LoginAttemptQuery is what GET /login/attempts accepts.
+var LoginAttemptQuery = query.Spec{
+	Fields: map[string]query.Field{
+		"email":      {Column: "email", Operators: query.Exact, Sortable: true},
+		"user_id":    {Column: "user_id", Kind: query.Number, Operators: query.Exact},
+		"company_id": {Column: "company_id", Kind: query.Number, Operators: query.Exact},
+		"ip":         {Column: "ip", Operators: query.Exact},
+		"success":    {Column: "success", Kind: query.Bool, Operators: query.Exact},
+		"result": {Column: "result", Operators: query.Exact, Values: []string{
+			string(enum.LoginSucceeded), string(enum.LoginUnknownEmail), string(enum.LoginWrongPassword), string(enum.LoginLocked),
+			string(enum.LoginIPThrottled), string(enum.LoginCompanyPending), string(enum.LoginCompanyRejected),
+			string(enum.LoginTwoFactorPending), string(enum.LoginTwoFactorFailed),
+			string(enum.LoginSSOSucceeded), string(enum.LoginSSORejected),
+		}},
+		"created_at": {Column: "created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	},
+	Search: []string{"email"},
+	Sort:   "-created_at",
+	Key:    "id",
+}
@@ -0,0 +1,32 @@
+package request
+
+import (
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+)
+
+// Onboarding is the body of POST /onboarding, the first registrant of a company
+// signing it up with its giro account.
+type Onboarding struct {
+	GiroCode string `json:"giro_code" binding:"required,max=50"`
+	Name     string `json:"name" binding:"required,max=100"`
+	Email    string `json:"email" binding:"required,email,max=100"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type OnboardingRejection struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// OnboardingStepQuery is what the onboarding steps of a company accept.
+var OnboardingStepQuery = query.Spec{
+	Fields: map[string]query.Field{
+		"step": {Column: "step", Operators: query.Exact, Values: []string{
+			string(enum.StepGiroValidated), string(enum.StepCompanyCreated), string(enum.StepAdminRegistered),
+			string(enum.StepCompanyApproved), string(enum.StepCompanyRejected),
+		}},
+		"created_at": {Column: "created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	},
+	Sort: "created_at",
+	Key:  "id",
+}
@@ -0,0 +1,13 @@
+package request
+
+// PasswordForgot is the body of POST /password/forgot.
+type PasswordForgot struct {
+	Email string `json:"email" binding:"required,email,max=100"`
+}
+
+// PasswordReset is the body of POST /password/reset, Token being the token of the link
+// the user received.
+type PasswordReset struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
@@ -0,0 +1,12 @@
+package request
+
+// RolePermissions is the body of PUT /role/:id/permissions, the permissions granted to
+// the role afterwards, each as object:action.
+type RolePermissions struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// UserRole is the body of PUT /user/:id/role.
+type UserRole struct {
+	RoleID int `json:"role_id" binding:"required"`
+}
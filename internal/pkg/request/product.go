@@ -1,42 +1,99 @@
 package request
 
 import (
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
 	"mime/multipart"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/search"
 )
 
+// Product is the form of POST /product. Amounts are in minor units of the currency,
+// IDR unless given, and units left empty default to those of the commodity. New
+// products always start processing and belong to the company of the caller.
 type Product struct {
-	Name             string                `json:"name" form:"name"`
-	Description      string                `json:"description" form:"description"`
-	Quantity         int                   `json:"quantity" form:"quantity"`
-	UnitQuantity     string                `json:"unit_quantity" form:"unit_quantity"`
-	Price            float64               `json:"price" form:"price"`
-	UnitPrice        string                `json:"unit_price" form:"unit_price"`
+	Name             string                `json:"name" form:"name" binding:"required,max=255"`
+	Description      string                `json:"description" form:"description" binding:"max=2000"`
+	Quantity         int                   `json:"quantity" form:"quantity" binding:"gt=0"`
+	UnitQuantity     string                `json:"unit_quantity" form:"unit_quantity" binding:"omitempty,unit"`
+	PriceAmount      int64                 `json:"price_amount" form:"price_amount" binding:"gt=0"`
+	UnitPrice        string                `json:"unit_price" form:"unit_price" binding:"omitempty,unit"`
+	Currency         string                `json:"currency" form:"currency" binding:"omitempty,currency"`
 	Image            string                `json:"-"`
 	ImagePath        string                `json:"-"`
-	Status           enum.StatusProduct    `json:"status" form:"status"`
 	IsPreOrder       bool                  `json:"is_pre_order"  form:"is_pre_order"`
-	MinPrice         float64               `json:"min_price" form:"min_price"`
-	MaxPrice         float64               `json:"max_price" form:"max_price"`
-	ProductCreatedAt string                `json:"product_created_at" form:"product_created_at"`
-	ExpiredAt        string                `json:"expired_at" form:"expired_at"`
-	CompanyID        int                   `json:"company_id" form:"company_id"`
-	Commodity        string                `json:"commodity" form:"commodity"`
-	File             *multipart.FileHeader `json:"-" form:"file"`
+	MinPriceAmount   int64                 `json:"min_price_amount" form:"min_price_amount" binding:"gte=0"`
+	MaxPriceAmount   int64                 `json:"max_price_amount" form:"max_price_amount" binding:"gte=0"`
+	ProductCreatedAt string                `json:"product_created_at" form:"product_created_at" binding:"required,date"`
+	ExpiredAt        string                `json:"expired_at" form:"expired_at" binding:"required,date_gtefield=ProductCreatedAt"`
+	CompanyID        int                   `json:"-" form:"-"`
+	CommodityID      int                   `json:"commodity_id" form:"commodity_id" binding:"required,gt=0"`
+	File             *multipart.FileHeader `json:"-" form:"file" binding:"required"`
 	IsActive         bool                  `json:"is_active" form:"is_active"`
 	TmpImagePath     string                `json:"-"`
 }
 
-type ProductPaged struct {
-	CompanyID int    `uri:"company_id"`
-	Search    string `form:"search"`
-	Page      int    `form:"page"`
-	Size      int    `form:"size"`
-	Commodity string `form:"commodity"`
-	Status    string `form:"status"`
+// UpdateProduct is the body of PUT /product/:id. Status, company, image and the PARI id
+// are managed by the service and cannot be set by the client. Amounts are in minor
+// units of the currency and units left empty default to those of the commodity.
+type UpdateProduct struct {
+	Name             string `json:"name" binding:"required,max=255"`
+	Description      string `json:"description" binding:"max=2000"`
+	Quantity         int    `json:"quantity" binding:"gte=0"`
+	UnitQuantity     string `json:"unit_quantity" binding:"omitempty,unit"`
+	PriceAmount      int64  `json:"price_amount" binding:"gt=0"`
+	UnitPrice        string `json:"unit_price" binding:"omitempty,unit"`
+	Currency         string `json:"currency" binding:"required,currency"`
+	IsPreOrder       bool   `json:"is_pre_order"`
+	MinPriceAmount   int64  `json:"min_price_amount" binding:"gte=0"`
+	MaxPriceAmount   int64  `json:"max_price_amount" binding:"gte=0"`
+	ProductCreatedAt string `json:"product_created_at" binding:"required,date"`
+	ExpiredAt        string `json:"expired_at" binding:"required,date_gtefield=ProductCreatedAt"`
+	CommodityID      int    `json:"commodity_id" binding:"required,gt=0"`
+	IsActive         bool   `json:"is_active"`
+}
+
+// ProductTransaction is sent by PARI when a buyer purchases a product.
+type ProductTransaction struct {
+	PariProductId string `json:"pari_product_id" form:"pari_product_id" binding:"required"`
+	Quantity      int    `json:"quantity" form:"quantity" binding:"gt=0"`
+}
+
+type ProductSearch struct {
+	Q string `form:"q" binding:"required,max=200"`
 }
 
 type ProductDetail struct {
 	ID     int `uri:"id"`
 	UserID int `form:"user_id"`
 }
+
+// ProductQuery is what the product list and search endpoints accept.
+var ProductQuery = query.Spec{
+	Fields: map[string]query.Field{
+		"name":                {Column: "name", Operators: query.Exact, Sortable: true},
+		"status":              {Column: "status", Operators: query.Exact, Values: []string{string(enum.Processing), enum.Approved, enum.Rejected}},
+		"commodity":           {Column: "commodity", Operators: query.Exact, Sortable: true},
+		"commodity_id":        {Column: "commodity_id", Kind: query.Number, Operators: query.Exact},
+		"company_id":          {Column: "company_id", Kind: query.Number, Operators: query.Exact},
+		"is_pre_order":        {Column: "is_pre_order", Kind: query.Bool, Operators: []query.Operator{query.Eq}},
+		"is_active":           {Column: "is_active", Kind: query.Bool, Operators: []query.Operator{query.Eq}},
+		"quantity":            {Column: "quantity", Kind: query.Number, Operators: query.Range, Sortable: true},
+		"price_amount":        {Column: "price_amount", Kind: query.Number, Operators: query.Range, Sortable: true},
+		"min_price_amount":    {Column: "min_price_amount", Kind: query.Number, Operators: query.Range},
+		"max_price_amount":    {Column: "max_price_amount", Kind: query.Number, Operators: query.Range},
+		"currency":            {Column: "currency", Operators: query.Exact},
+		"unit_quantity":       {Column: "unit_quantity", Operators: query.Exact},
+		"unit_price":          {Column: "unit_price", Operators: query.Exact},
+		"base_unit":           {Column: "base_unit", Operators: query.Exact},
+		"price_per_base_unit": {Column: "price_per_base_unit", Kind: query.Number, Operators: query.Range, Sortable: true},
+		"product_created_at":  {Column: "product_created_at", Kind: query.Date, Operators: query.Range, Sortable: true},
+		"expired_at":          {Column: "expired_at", Kind: query.Date, Operators: query.Range, Sortable: true},
+		"created_at":          {Column: "created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+		"updated_at":          {Column: "updated_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	},
+	Search: []string{"name"},
+	Match:  search.Columns,
+	Sort:   "created_at",
+	Key:    "id",
+}
@@ -0,0 +1,17 @@
+package request
+
+import "bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+
+// ProductPriceQuery is what the price history of a product accepts.
+var ProductPriceQuery = query.Spec{
+	Fields: map[string]query.Field{
+		"revision_id":         {Column: "revision_id", Kind: query.Number, Operators: query.Exact},
+		"price_amount":        {Column: "price_amount", Kind: query.Number, Operators: query.Range, Sortable: true},
+		"currency":            {Column: "currency", Operators: query.Exact},
+		"price_per_base_unit": {Column: "price_per_base_unit", Kind: query.Number, Operators: query.Range, Sortable: true},
+		"effective_at":        {Column: "effective_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+		"created_at":          {Column: "created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	},
+	Sort: "created_at",
+	Key:  "id",
+}
@@ -0,0 +1,30 @@
+package request
+
+import (
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+)
+
+// ProductRevisionUser is the body of POST /product/revision/verification. The
+// verifier is the signed in user.
+type ProductRevisionUser struct {
+	ProductRevisionID int `json:"product_revision_id" binding:"required,gt=0"`
+}
+
+// ProductRevisionRejection is the body of POST /product/revision/rejection. The
+// rejection is recorded as made by the signed in user.
+type ProductRevisionRejection struct {
+	ProductRevisionID int    `json:"product_revision_id" binding:"required,gt=0"`
+	Reason            string `json:"reason" binding:"required,max=500"`
+}
+
+// ProductRevisionQuery is what the revisions of a product accept.
+var ProductRevisionQuery = query.Spec{
+	Fields: map[string]query.Field{
+		"status":      {Column: "status", Operators: query.Exact, Values: []string{string(enum.Processing), enum.Approved, enum.Rejected}},
+		"reviewed_at": {Column: "reviewed_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+		"created_at":  {Column: "created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	},
+	Sort: "created_at",
+	Key:  "id",
+}
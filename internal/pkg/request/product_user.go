@@ -7,8 +7,5 @@
 package request
 
 type ProductUser struct {
-	ProductID int `json:"product_id"`
-	UserID    int `json:"user_id"`
-	CompanyID int `json:"company_id"`
-	RoleID    int `json:"role_id"`
+	ProductID int `json:"product_id" binding:"required,gt=0"`
 }
@@ -1,5 +1,22 @@
 package request
 
+import "bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+
+// Role is the body of POST /role and PUT /role/:id. Superadmins choose the company of a
+// new role, none sharing it with all companies; it is the company of the user otherwise.
 type Role struct {
-	Name string `json:"name"`
+	Name      string `json:"name" binding:"required,max=50"`
+	CompanyID int    `json:"company_id" binding:"gte=0"`
+}
+
+// RoleQuery is what GET /role accepts.
+var RoleQuery = query.Spec{
+	Fields: map[string]query.Field{
+		"name":       {Column: "name", Operators: query.Exact, Sortable: true},
+		"company_id": {Column: "company_id", Kind: query.Number, Operators: query.Exact},
+		"created_at": {Column: "created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	},
+	Search: []string{"name"},
+	Sort:   "name",
+	Key:    "id",
 }
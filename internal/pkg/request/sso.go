@@ -0,0 +1,34 @@
+package request
+
+// SSOProvider is the body of PUT /sso/company/:company_id. ClientSecret may be left out
+// to keep the one configured.
+type SSOProvider struct {
+	Issuer        string        `json:"issuer" binding:"required,url,max=255"`
+	ClientID      string        `json:"client_id" binding:"required,max=255"`
+	ClientSecret  string        `json:"client_secret" binding:"max=255"`
+	Scopes        []string      `json:"scopes" binding:"max=10,dive,required,max=64"`
+	RoleClaim     string        `json:"role_claim" binding:"max=64"`
+	RoleMapping   []SSORoleRule `json:"role_mapping" binding:"max=50,dive"`
+	DefaultRoleID int           `json:"default_role_id" binding:"gte=0"`
+	Enabled       *bool         `json:"enabled" binding:"required"`
+}
+
+// SSORoleRule gives RoleID to the users whose role claim holds Value.
+type SSORoleRule struct {
+	Value  string `json:"value" binding:"required,max=255"`
+	RoleID int    `json:"role_id" binding:"required,gt=0"`
+}
+
+// SSOAuthorization is the body of POST /login/sso/authorization.
+type SSOAuthorization struct {
+	CompanyID int `json:"company_id" binding:"required,gt=0"`
+}
+
+// SSOLogin is the body of POST /login/sso, State and Code being those the provider
+// redirected the user back with.
+type SSOLogin struct {
+	State     string `json:"state" binding:"required,max=128"`
+	Code      string `json:"code" binding:"required,max=2048"`
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
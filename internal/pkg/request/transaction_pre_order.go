@@ -2,31 +2,57 @@ package request
 
 import (
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
 )
 
+// TransactionPreOrder is a pre-order sent by PARI. ActualPrice is per price unit of the
+// product in major units of its currency, as PARI sends prices.
 type TransactionPreOrder struct {
-	PariProductId     string             `json:"pari_product_id" form:"pari_product_id"`
-	PariTransactionId string             `json:"pari_transaction_id" form:"pari_transaction_id"`
-	ProductID         int                `json:"product_id" form:"product_id"`
-	CompanyID         int                `json:"company_id" form:"company_id"`
-	Quantity          int                `json:"quantity" form:"quantity"`
-	BuyerName         string             `json:"buyer_name" form:"buyer_name"`
-	BuyerAddress      string             `json:"buyer_address" form:"buyer_address"`
-	BuyerContact      string             `json:"buyer_contact" form:"buyer_contact"`
-	ActualPrice       float64            `json:"actual_price" form:"actual_price"`
-	Status            enum.StatusProduct `json:"status" form:"status"`
+	PariProductId     string             `json:"pari_product_id" form:"pari_product_id" binding:"required"`
+	PariTransactionId string             `json:"pari_transaction_id" form:"pari_transaction_id" binding:"required"`
+	ProductID         int                `json:"product_id" form:"product_id" binding:"required,gt=0"`
+	CompanyID         int                `json:"company_id" form:"company_id" binding:"required,gt=0"`
+	Quantity          int                `json:"quantity" form:"quantity" binding:"gt=0"`
+	BuyerName         string             `json:"buyer_name" form:"buyer_name" binding:"required,max=255"`
+	BuyerAddress      string             `json:"buyer_address" form:"buyer_address" binding:"max=500"`
+	BuyerContact      string             `json:"buyer_contact" form:"buyer_contact" binding:"required,max=50"`
+	ActualPrice       float64            `json:"actual_price" form:"actual_price" binding:"gt=0"`
+	Status            enum.StatusProduct `json:"status" form:"status" binding:"omitempty,oneof=processing approved rejected"`
 }
 
-type TransactionPreOrderPaged struct {
-	CompanyID int    `uri:"company_id"`
-	Search    string `form:"search"`
-	Page      int    `form:"page"`
-	Size      int    `form:"size"`
-	Commodity string `form:"commodity"`
-	Status    string `form:"status"`
+// UpdateTransactionPreOrder is the body of PUT /transaction/preorder/:id. The actual
+// price is in minor units of the currency of the product.
+type UpdateTransactionPreOrder struct {
+	Quantity          int    `json:"quantity" binding:"gt=0"`
+	BuyerName         string `json:"buyer_name" binding:"required,max=255"`
+	BuyerAddress      string `json:"buyer_address" binding:"max=500"`
+	BuyerContact      string `json:"buyer_contact" binding:"required,max=50"`
+	ActualPriceAmount int64  `json:"actual_price_amount" binding:"gt=0"`
 }
 
 type TransactionPreOrderDetail struct {
 	ID     int `uri:"id"`
 	UserID int `form:"user_id"`
 }
+
+// TransactionPreOrderQuery is what the transaction pre-order list endpoints accept.
+// Product fields are read from the joined products table.
+var TransactionPreOrderQuery = query.Spec{
+	Fields: map[string]query.Field{
+		"status":              {Column: "transaction_pre_orders.status", Operators: query.Exact, Values: []string{string(enum.Processing), enum.Approved, enum.Rejected}},
+		"company_id":          {Column: "transaction_pre_orders.company_id", Kind: query.Number, Operators: query.Exact},
+		"product_id":          {Column: "transaction_pre_orders.product_id", Kind: query.Number, Operators: query.Exact},
+		"commodity":           {Column: "p.commodity", Operators: query.Exact},
+		"buyer_name":          {Column: "transaction_pre_orders.buyer_name", Operators: query.Exact, Sortable: true},
+		"quantity":            {Column: "transaction_pre_orders.quantity", Kind: query.Number, Operators: query.Range, Sortable: true},
+		"actual_price_amount": {Column: "transaction_pre_orders.actual_price_amount", Kind: query.Number, Operators: query.Range, Sortable: true},
+		"total_amount":        {Column: "transaction_pre_orders.total_amount", Kind: query.Number, Operators: query.Range, Sortable: true},
+		"currency":            {Column: "transaction_pre_orders.currency", Operators: query.Exact},
+		"created_at":          {Column: "transaction_pre_orders.created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+		"updated_at":          {Column: "transaction_pre_orders.updated_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	},
+	Search: []string{"p.name", "transaction_pre_orders.buyer_name"},
+	Match:  "p.name, p.description, p.commodity",
+	Sort:   "created_at",
+	Key:    "transaction_pre_orders.id",
+}
@@ -0,0 +1,28 @@
+package request
+
+import "bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+
+// trashQuery is what the trash listing of table accepts, newest deletes first.
+func trashQuery(table string, companyScoped bool, search ...string) query.Spec {
+	fields := map[string]query.Field{
+		"deleted_at": {Column: table + ".deleted_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+		"created_at": {Column: table + ".created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	}
+	if companyScoped {
+		fields["company_id"] = query.Field{Column: table + ".company_id", Kind: query.Number, Operators: query.Exact}
+	}
+	return query.Spec{
+		Fields: fields,
+		Search: search,
+		Sort:   "-deleted_at",
+		Key:    table + ".id",
+	}
+}
+
+// Trash queries of GET /trash/:resource.
+var (
+	ProductTrashQuery  = trashQuery("products", true, "products.name")
+	UserTrashQuery     = trashQuery("users", true, "users.name", "users.email")
+	CompanyTrashQuery  = trashQuery("companies", false, "companies.name", "companies.code")
+	PreOrderTrashQuery = trashQuery("transaction_pre_orders", true, "transaction_pre_orders.buyer_name")
+)
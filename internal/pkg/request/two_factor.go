@@ -0,0 +1,27 @@
+package request
+
+// TwoFactorCode is the body of the two-factor endpoints asking for a code, which is
+// either a TOTP code or a recovery code.
+type TwoFactorCode struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+// TwoFactorChallenge is the body of POST /login/2fa/enrollment, ChallengeToken being
+// the token POST /login returned.
+type TwoFactorChallenge struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// TwoFactorLogin is the body of POST /login/2fa.
+type TwoFactorLogin struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required,max=32"`
+	IP             string `json:"-"`
+	UserAgent      string `json:"-"`
+}
+
+// TwoFactorPolicy is the body of PUT /2fa/policy/company/:company_id and
+// PUT /2fa/policy/role/:role_id.
+type TwoFactorPolicy struct {
+	Require *bool `json:"require" binding:"required"`
+}
@@ -6,27 +6,57 @@
 
 package request
 
-import "bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+import (
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+)
 
 type User struct {
-	Name               string                 `json:"name"`
-	Email              string                 `json:"email"`
-	Password           string                 `json:"password"`
-	Role               string                 `json:"role"`
-	CompanyID          int                    `json:"company_id"`
-	VerificationLevel  enum.VerificationLevel `json:"verification_level"`
+	Name               string                 `json:"name" binding:"required,max=100"`
+	Email              string                 `json:"email" binding:"required,email,max=100"`
+	Password           string                 `json:"password" binding:"required,min=8,max=72"`
+	Role               string                 `json:"role" binding:"required"`
+	CompanyID          int                    `json:"company_id" binding:"required,gt=0"`
+	VerificationLevel  enum.VerificationLevel `json:"verification_level" binding:"gte=0,lte=3"`
 	MustChangePassword bool                   `json:"must_change_password"`
 }
 
 type Users []User
 
+// CreateUser is the body of POST /user; role and company are chosen by an administrator.
+type CreateUser struct {
+	Name              string                 `json:"name" binding:"required,max=100"`
+	Email             string                 `json:"email" binding:"required,email,max=100"`
+	Password          string                 `json:"password" binding:"required,min=8,max=72"`
+	RoleID            int                    `json:"role_id" binding:"required,gt=0"`
+	CompanyID         int                    `json:"company_id" binding:"required,gt=0"`
+	VerificationLevel enum.VerificationLevel `json:"verification_level" binding:"gte=0,lte=3"`
+}
+
+// ServiceAccount is the body of POST /user/service_account. Service accounts have no
+// password nor email of their own and authenticate with access tokens only.
+type ServiceAccount struct {
+	Name      string `json:"name" binding:"required,max=100"`
+	RoleID    int    `json:"role_id" binding:"required,gt=0"`
+	CompanyID int    `json:"company_id" binding:"required,gt=0"`
+}
+
+// UpdateUser is the body of PUT /user/:id. Password, role and company are not editable here.
+type UpdateUser struct {
+	Name  string `json:"name" binding:"required,max=100"`
+	Email string `json:"email" binding:"required,email,max=100"`
+}
+
+// Login is the body of POST /login, IP and UserAgent being those of the client.
 type Login struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password" binding:"required"`
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 type ChangePassword struct {
-	Password string `json:"password"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
 	UserID   int    `json:"-"`
 }
 
@@ -34,3 +64,19 @@ type OpenKey struct {
 	Token     string `json:"token"`
 	ExpiredAt string `json:"expired_at"`
 }
+
+// UserQuery is what GET /user accepts.
+var UserQuery = query.Spec{
+	Fields: map[string]query.Field{
+		"name":               {Column: "name", Operators: query.Exact, Sortable: true},
+		"email":              {Column: "email", Operators: query.Exact, Sortable: true},
+		"role_id":            {Column: "role_id", Kind: query.Number, Operators: query.Exact},
+		"company_id":         {Column: "company_id", Kind: query.Number, Operators: query.Exact},
+		"service_account":    {Column: "service_account", Kind: query.Bool, Operators: query.Exact},
+		"verification_level": {Column: "verification_level", Kind: query.Number, Operators: query.Range},
+		"created_at":         {Column: "created_at", Kind: query.Time, Operators: query.Range, Sortable: true},
+	},
+	Search: []string{"name", "email"},
+	Sort:   "created_at",
+	Key:    "id",
+}
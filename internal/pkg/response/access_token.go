@@ -0,0 +1,46 @@
+package response
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+)
+
+// AccessToken is a personal access token. Token is only returned once, when it is
+// created; Prefix recognizes it afterwards.
+type AccessToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func NewAccessToken(m *model.AccessToken) AccessToken {
+	return AccessToken{
+		ID:         m.ID,
+		UserID:     m.UserID,
+		Name:       m.Name,
+		Prefix:     m.Prefix,
+		Scopes:     m.ScopeList(),
+		ExpiresAt:  m.ExpiresAt,
+		LastUsedAt: m.LastUsedAt,
+		LastUsedIP: m.LastUsedIP,
+		RevokedAt:  m.RevokedAt,
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+func NewAccessTokens(ms []model.AccessToken) []AccessToken {
+	result := make([]AccessToken, 0, len(ms))
+	for i := range ms {
+		result = append(result, NewAccessToken(&ms[i]))
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+package response
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+)
+
+type Commodity struct {
+	ID           int                    `json:"id"`
+	Code         string                 `json:"code"`
+	Name         string                 `json:"name"`
+	Category     enum.CommodityCategory `json:"category"`
+	UnitQuantity string                 `json:"unit_quantity"`
+	UnitPrice    string                 `json:"unit_price"`
+	PariCode     string                 `json:"pari_code"`
+	IsActive     bool                   `json:"is_active"`
+	Version      int                    `json:"version"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+}
+
+func NewCommodity(m *model.Commodity) *Commodity {
+	if m == nil {
+		return nil
+	}
+	return &Commodity{
+		ID:           m.ID,
+		Code:         m.Code,
+		Name:         m.Name,
+		Category:     m.Category,
+		UnitQuantity: m.UnitQuantity,
+		UnitPrice:    m.UnitPrice,
+		PariCode:     m.PariCode,
+		IsActive:     m.IsActive,
+		Version:      m.Version,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+	}
+}
+
+func NewCommodities(ms []model.Commodity) []Commodity {
+	result := make([]Commodity, 0, len(ms))
+	for i := range ms {
+		result = append(result, *NewCommodity(&ms[i]))
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+package response
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+)
+
+type Company struct {
+	ID               int                `json:"id"`
+	Name             string             `json:"name"`
+	Code             string             `json:"code"`
+	Alias            string             `json:"alias"`
+	Address          string             `json:"address"`
+	Giro             string             `json:"giro"`
+	GiroID           *int               `json:"giro_id"`
+	Status           enum.StatusCompany `json:"status"`
+	RejectReason     string             `json:"reject_reason,omitempty"`
+	ReviewedBy       int                `json:"reviewed_by,omitempty"`
+	ReviewedAt       *time.Time         `json:"reviewed_at,omitempty"`
+	RequireTwoFactor bool               `json:"require_two_factor"`
+	Version          int                `json:"version"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+	DeletedAt        *time.Time         `json:"deleted_at,omitempty"`
+}
+
+func NewCompany(m *model.Company) *Company {
+	if m == nil {
+		return nil
+	}
+	return &Company{
+		ID:               m.ID,
+		Name:             m.Name,
+		Code:             m.Code,
+		Alias:            m.Alias,
+		Address:          m.Address,
+		Giro:             m.Giro,
+		GiroID:           m.GiroID,
+		Status:           m.Status,
+		RejectReason:     m.RejectReason,
+		ReviewedBy:       m.ReviewedBy,
+		ReviewedAt:       m.ReviewedAt,
+		RequireTwoFactor: m.RequireTwoFactor,
+		Version:          m.Version,
+		CreatedAt:        m.CreatedAt,
+		UpdatedAt:        m.UpdatedAt,
+		DeletedAt:        m.DeletedAt,
+	}
+}
+
+func NewCompanies(ms []model.Company) []Company {
+	result := make([]Company, 0, len(ms))
+	for i := range ms {
+		result = append(result, *NewCompany(&ms[i]))
+	}
+	return result
+}
+
+// Giro is a giro account, with the company onboarded with it if there is one.
+type Giro struct {
+	ID            int                `json:"id"`
+	Code          string             `json:"code"`
+	CompanyName   string             `json:"company_name"`
+	IsActive      bool               `json:"is_active"`
+	Version       int                `json:"version"`
+	CompanyID     int                `json:"company_id,omitempty"`
+	CompanyStatus enum.StatusCompany `json:"company_status,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+func NewGiro(m *model.Giro) *Giro {
+	if m == nil {
+		return nil
+	}
+	g := &Giro{
+		ID:          m.ID,
+		Code:        m.Code,
+		CompanyName: m.CompanyName,
+		IsActive:    m.IsActive,
+		Version:     m.Version,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+	if m.Company != nil {
+		g.CompanyID = m.Company.ID
+		g.CompanyStatus = m.Company.Status
+	}
+	return g
+}
+
+func NewGiros(ms []model.Giro) []Giro {
+	result := make([]Giro, 0, len(ms))
+	for i := range ms {
+		result = append(result, *NewGiro(&ms[i]))
+	}
+	return result
+}
+
+// GiroImport counts the giros an import created and updated.
+type GiroImport struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+}
+
+// Onboarding is a company signed up with its giro account and its admin.
+type Onboarding struct {
+	Company *Company `json:"company"`
+	User    *User    `json:"user"`
+}
+
+type OnboardingStep struct {
+	ID        int                 `json:"id"`
+	CompanyID int                 `json:"company_id"`
+	GiroID    int                 `json:"giro_id"`
+	Step      enum.OnboardingStep `json:"step"`
+	UserID    int                 `json:"user_id,omitempty"`
+	Note      string              `json:"note,omitempty"`
+	CreatedAt time.Time           `json:"created_at"`
+}
+
+func NewOnboardingSteps(ms []model.OnboardingStep) []OnboardingStep {
+	result := make([]OnboardingStep, 0, len(ms))
+	for _, m := range ms {
+		result = append(result, OnboardingStep{
+			ID:        m.ID,
+			CompanyID: m.CompanyID,
+			GiroID:    m.GiroID,
+			Step:      m.Step,
+			UserID:    m.UserID,
+			Note:      m.Note,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return result
+}
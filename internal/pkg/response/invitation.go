@@ -0,0 +1,62 @@
+package response
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+)
+
+// InvitationExpired is the status of a pending invitation past its expiry.
+const InvitationExpired = "expired"
+
+type Invitation struct {
+	ID                int                    `json:"id"`
+	Email             string                 `json:"email"`
+	RoleID            int                    `json:"role_id"`
+	RoleName          string                 `json:"role_name,omitempty"`
+	CompanyID         int                    `json:"company_id"`
+	CompanyName       string                 `json:"company_name,omitempty"`
+	VerificationLevel enum.VerificationLevel `json:"verification_level"`
+	Status            string                 `json:"status"`
+	InvitedBy         int                    `json:"invited_by"`
+	UserID            int                    `json:"user_id,omitempty"`
+	ExpiresAt         time.Time              `json:"expires_at"`
+	AcceptedAt        *time.Time             `json:"accepted_at,omitempty"`
+	RevokedAt         *time.Time             `json:"revoked_at,omitempty"`
+	CreatedAt         time.Time              `json:"created_at"`
+}
+
+func NewInvitation(m *model.Invitation) *Invitation {
+	if m == nil {
+		return nil
+	}
+	status := string(m.Status)
+	if m.Status == enum.InvitationPending && m.Expired(time.Now()) {
+		status = InvitationExpired
+	}
+	return &Invitation{
+		ID:                m.ID,
+		Email:             m.Email,
+		RoleID:            m.RoleID,
+		RoleName:          m.RoleName,
+		CompanyID:         m.CompanyID,
+		CompanyName:       m.CompanyName,
+		VerificationLevel: m.VerificationLevel,
+		Status:            status,
+		InvitedBy:         m.InvitedBy,
+		UserID:            m.UserID,
+		ExpiresAt:         m.ExpiresAt,
+		AcceptedAt:        m.AcceptedAt,
+		RevokedAt:         m.RevokedAt,
+		CreatedAt:         m.CreatedAt,
+	}
+}
+
+func NewInvitations(ms []model.Invitation) []Invitation {
+	result := make([]Invitation, 0, len(ms))
+	for i := range ms {
+		result = append(result, *NewInvitation(&ms[i]))
+	}
+	return result
+}
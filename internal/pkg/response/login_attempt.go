@@ -0,0 +1,38 @@
+package response
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+)
+
+type LoginAttempt struct {
+	ID        int              `json:"id"`
+	Email     string           `json:"email"`
+	UserID    int              `json:"user_id,omitempty"`
+	CompanyID int              `json:"company_id,omitempty"`
+	IP        string           `json:"ip"`
+	UserAgent string           `json:"user_agent"`
+	Success   bool             `json:"success"`
+	Result    enum.LoginResult `json:"result"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+func NewLoginAttempts(ms []model.LoginAttempt) []LoginAttempt {
+	result := make([]LoginAttempt, 0, len(ms))
+	for _, m := range ms {
+		result = append(result, LoginAttempt{
+			ID:        m.ID,
+			Email:     m.Email,
+			UserID:    m.UserID,
+			CompanyID: m.CompanyID,
+			IP:        m.IP,
+			UserAgent: m.UserAgent,
+			Success:   m.Success,
+			Result:    m.Result,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return result
+}
@@ -0,0 +1,26 @@
+package response
+
+import "bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+
+// RolePermissions is what a role is granted.
+type RolePermissions struct {
+	RoleID      int      `json:"role_id"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+// UserPermissions is what a user is granted through their roles.
+type UserPermissions struct {
+	UserID      int      `json:"user_id"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// NewPermissions names permissions as object:action.
+func NewPermissions(permissions []permission.Permission) []string {
+	result := make([]string, 0, len(permissions))
+	for _, p := range permissions {
+		result = append(result, p.String())
+	}
+	return result
+}
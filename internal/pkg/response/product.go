@@ -0,0 +1,122 @@
+package response
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+)
+
+type Product struct {
+	ID               int                     `json:"id"`
+	Name             string                  `json:"name"`
+	Description      string                  `json:"description"`
+	Quantity         int                     `json:"quantity"`
+	UnitQuantity     string                  `json:"unit_quantity"`
+	PriceAmount      int64                   `json:"price_amount"`
+	UnitPrice        string                  `json:"unit_price"`
+	Currency         string                  `json:"currency"`
+	BaseUnit         string                  `json:"base_unit"`
+	PricePerBaseUnit int64                   `json:"price_per_base_unit"`
+	Image            string                  `json:"image"`
+	Status           enum.StatusProduct      `json:"status"`
+	ProductCreatedAt string                  `json:"product_created_at"`
+	ExpiredAt        string                  `json:"expired_at"`
+	CommodityID      int                     `json:"commodity_id"`
+	Commodity        string                  `json:"commodity"`
+	CompanyID        int                     `json:"company_id"`
+	IsPreOrder       bool                    `json:"is_pre_order"`
+	MinPriceAmount   int64                   `json:"min_price_amount"`
+	MaxPriceAmount   int64                   `json:"max_price_amount"`
+	PariProductId    string                  `json:"pari_product_id"`
+	IsActive         bool                    `json:"is_active"`
+	Version          int                     `json:"version"`
+	CreatedAt        time.Time               `json:"created_at"`
+	UpdatedAt        time.Time               `json:"updated_at"`
+	DeletedAt        *time.Time              `json:"deleted_at,omitempty"`
+	Transaction      []model.PariTransaction `json:"transaction,omitempty"`
+	PendingRevision  *ProductRevision        `json:"pending_revision,omitempty"`
+}
+
+func NewProduct(m *model.Product) *Product {
+	if m == nil {
+		return nil
+	}
+	return &Product{
+		ID:               m.ID,
+		Name:             m.Name,
+		Description:      m.Description,
+		Quantity:         m.Quantity,
+		UnitQuantity:     m.UnitQuantity,
+		PriceAmount:      m.PriceAmount,
+		UnitPrice:        m.UnitPrice,
+		Currency:         m.Currency,
+		BaseUnit:         m.BaseUnit,
+		PricePerBaseUnit: m.PricePerBaseUnit,
+		Image:            m.Image,
+		Status:           m.Status,
+		ProductCreatedAt: m.ProductCreatedAt,
+		ExpiredAt:        m.ExpiredAt,
+		CommodityID:      m.CommodityID,
+		Commodity:        m.Commodity,
+		CompanyID:        m.CompanyID,
+		IsPreOrder:       m.IsPreOrder,
+		MinPriceAmount:   m.MinPriceAmount,
+		MaxPriceAmount:   m.MaxPriceAmount,
+		PariProductId:    m.PariProductId,
+		IsActive:         m.IsActive,
+		Version:          m.Version,
+		CreatedAt:        m.CreatedAt,
+		UpdatedAt:        m.UpdatedAt,
+		DeletedAt:        m.DeletedAt,
+		Transaction:      m.Transaction,
+		PendingRevision:  NewProductRevision(m.PendingRevision),
+	}
+}
+
+func NewProducts(ms []model.Product) []Product {
+	result := make([]Product, 0, len(ms))
+	for i := range ms {
+		result = append(result, *NewProduct(&ms[i]))
+	}
+	return result
+}
+
+// ProductDetail is a product as seen by a verifying user.
+type ProductDetail struct {
+	*Product
+	IsVerifiedByUser bool `json:"is_verified_by_user"`
+}
+
+func NewProductDetail(r *helper.ProductResponse) *ProductDetail {
+	if r == nil {
+		return nil
+	}
+	return &ProductDetail{Product: NewProduct(r.Product), IsVerifiedByUser: r.IsVerifiedByUser}
+}
+
+// ProductHit is a product found by search. Highlights holds the name and a
+// description excerpt with the matched words wrapped in <em> tags.
+type ProductHit struct {
+	*Product
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+type ProductSearch struct {
+	Hits   []ProductHit              `json:"hits"`
+	Facets map[string]map[string]int `json:"facets"`
+}
+
+func NewProductSearch(m *model.ProductSearch) *ProductSearch {
+	hits := make([]ProductHit, 0, len(m.Hits))
+	for i := range m.Hits {
+		hits = append(hits, ProductHit{
+			Product:    NewProduct(&m.Hits[i].Product),
+			Score:      m.Hits[i].Score,
+			Highlights: m.Hits[i].Highlights,
+		})
+	}
+	return &ProductSearch{Hits: hits, Facets: m.Facets}
+}
@@ -0,0 +1,52 @@
+package response
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+)
+
+type ProductPrice struct {
+	ID               int        `json:"id"`
+	ProductID        int        `json:"product_id"`
+	RevisionID       int        `json:"revision_id"`
+	PriceAmount      int64      `json:"price_amount"`
+	MinPriceAmount   int64      `json:"min_price_amount"`
+	MaxPriceAmount   int64      `json:"max_price_amount"`
+	Currency         string     `json:"currency"`
+	UnitPrice        string     `json:"unit_price"`
+	PricePerBaseUnit int64      `json:"price_per_base_unit"`
+	EffectiveAt      *time.Time `json:"effective_at"`
+	SyncedAt         *time.Time `json:"synced_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func NewProductPrice(m *model.ProductPrice) *ProductPrice {
+	if m == nil {
+		return nil
+	}
+	return &ProductPrice{
+		ID:               m.ID,
+		ProductID:        m.ProductID,
+		RevisionID:       m.RevisionID,
+		PriceAmount:      m.PriceAmount,
+		MinPriceAmount:   m.MinPriceAmount,
+		MaxPriceAmount:   m.MaxPriceAmount,
+		Currency:         m.Currency,
+		UnitPrice:        m.UnitPrice,
+		PricePerBaseUnit: m.PricePerBaseUnit,
+		EffectiveAt:      m.EffectiveAt,
+		SyncedAt:         m.SyncedAt,
+		CreatedAt:        m.CreatedAt,
+		UpdatedAt:        m.UpdatedAt,
+	}
+}
+
+func NewProductPrices(ms []model.ProductPrice) []ProductPrice {
+	result := make([]ProductPrice, 0, len(ms))
+	for i := range ms {
+		result = append(result, *NewProductPrice(&ms[i]))
+	}
+	return result
+}
@@ -0,0 +1,48 @@
+package response
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+)
+
+type ProductRevision struct {
+	ID           int                    `json:"id"`
+	ProductID    int                    `json:"product_id"`
+	Changes      map[string]interface{} `json:"changes"`
+	Status       enum.StatusProduct     `json:"status"`
+	RejectReason string                 `json:"reject_reason,omitempty"`
+	RejectedBy   int                    `json:"rejected_by,omitempty"`
+	ReviewedAt   *time.Time             `json:"reviewed_at"`
+	SyncedAt     *time.Time             `json:"synced_at"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+}
+
+func NewProductRevision(m *model.ProductRevision) *ProductRevision {
+	if m == nil {
+		return nil
+	}
+	changes, _ := m.Fields()
+	return &ProductRevision{
+		ID:           m.ID,
+		ProductID:    m.ProductID,
+		Changes:      changes,
+		Status:       m.Status,
+		RejectReason: m.RejectReason,
+		RejectedBy:   m.RejectedBy,
+		ReviewedAt:   m.ReviewedAt,
+		SyncedAt:     m.SyncedAt,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+	}
+}
+
+func NewProductRevisions(ms []model.ProductRevision) []ProductRevision {
+	result := make([]ProductRevision, 0, len(ms))
+	for i := range ms {
+		result = append(result, *NewProductRevision(&ms[i]))
+	}
+	return result
+}
@@ -0,0 +1,31 @@
+package response
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+)
+
+type Role struct {
+	ID               int       `json:"id"`
+	Name             string    `json:"name"`
+	CompanyID        int       `json:"company_id"`
+	RequireTwoFactor bool      `json:"require_two_factor"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+func NewRole(m *model.Role) *Role {
+	if m == nil {
+		return nil
+	}
+	return &Role{ID: m.ID, Name: m.Name, CompanyID: m.CompanyID, RequireTwoFactor: m.RequireTwoFactor, CreatedAt: m.CreatedAt, UpdatedAt: m.UpdatedAt}
+}
+
+func NewRoles(ms []model.Role) []Role {
+	result := make([]Role, 0, len(ms))
+	for i := range ms {
+		result = append(result, *NewRole(&ms[i]))
+	}
+	return result
+}
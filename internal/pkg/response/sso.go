@@ -0,0 +1,60 @@
+package response
+
+import (
+	"strings"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+)
+
+// SSOProvider is the OpenID Connect provider of a company. The client secret is never
+// shown, ClientSecretSet telling whether one is configured.
+type SSOProvider struct {
+	ID              int           `json:"id"`
+	CompanyID       int           `json:"company_id"`
+	Issuer          string        `json:"issuer"`
+	ClientID        string        `json:"client_id"`
+	ClientSecretSet bool          `json:"client_secret_set"`
+	Scopes          []string      `json:"scopes"`
+	RoleClaim       string        `json:"role_claim"`
+	RoleMapping     []SSORoleRule `json:"role_mapping"`
+	DefaultRoleID   int           `json:"default_role_id"`
+	Enabled         bool          `json:"enabled"`
+	ConfiguredBy    int           `json:"configured_by"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+type SSORoleRule struct {
+	Value  string `json:"value"`
+	RoleID int    `json:"role_id"`
+}
+
+func NewSSOProvider(p *model.SSOProvider) SSOProvider {
+	rules := []SSORoleRule{}
+	for _, r := range p.Rules() {
+		rules = append(rules, SSORoleRule{Value: r.Value, RoleID: r.RoleID})
+	}
+	return SSOProvider{
+		ID:              p.ID,
+		CompanyID:       p.CompanyID,
+		Issuer:          p.Issuer,
+		ClientID:        p.ClientID,
+		ClientSecretSet: p.ClientSecret != "",
+		Scopes:          strings.Fields(p.Scopes),
+		RoleClaim:       p.RoleClaim,
+		RoleMapping:     rules,
+		DefaultRoleID:   p.DefaultRoleID,
+		Enabled:         p.Enabled,
+		ConfiguredBy:    p.ConfiguredBy,
+		CreatedAt:       p.CreatedAt,
+		UpdatedAt:       p.UpdatedAt,
+	}
+}
+
+// SSOAuthorization is where to send the user to log in with the provider of their
+// company, before ExpiresAt.
+type SSOAuthorization struct {
+	AuthorizationURL string    `json:"authorization_url"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
@@ -0,0 +1,97 @@
+package response
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+)
+
+type TransactionPreOrder struct {
+	ID                    int                `json:"id"`
+	PariProductID         string             `json:"pari_product_id"`
+	PariTransactionID     string             `json:"pari_transaction_id"`
+	ProductID             int                `json:"product_id"`
+	ProductName           string             `json:"product_name,omitempty"`
+	ProductCommodity      string             `json:"product_commodity,omitempty"`
+	ProductImage          string             `json:"product_image,omitempty"`
+	ProductMinPriceAmount int64              `json:"product_min_price_amount,omitempty"`
+	ProductMaxPriceAmount int64              `json:"product_max_price_amount,omitempty"`
+	ProductUnitQuantity   string             `json:"product_unit_quantity,omitempty"`
+	ProductUnitPrice      string             `json:"product_unit_price,omitempty"`
+	ProductExpiredAt      string             `json:"product_expired_at,omitempty"`
+	ProductCreatedAt      string             `json:"product_created_at,omitempty"`
+	ProductIsPreOrder     bool               `json:"product_is_pre_order,omitempty"`
+	ProductIsActive       bool               `json:"product_is_active,omitempty"`
+	CompanyID             int                `json:"company_id"`
+	Quantity              int                `json:"quantity"`
+	Status                enum.StatusProduct `json:"status"`
+	ActualPriceAmount     int64              `json:"actual_price_amount"`
+	Currency              string             `json:"currency"`
+	TotalAmount           int64              `json:"total_amount"`
+	BuyerName             string             `json:"buyer_name"`
+	BuyerAddress          string             `json:"buyer_address"`
+	BuyerContact          string             `json:"buyer_contact"`
+	Version               int                `json:"version"`
+	CreatedAt             time.Time          `json:"created_at"`
+	UpdatedAt             time.Time          `json:"updated_at"`
+	DeletedAt             *time.Time         `json:"deleted_at,omitempty"`
+}
+
+func NewTransactionPreOrder(m *model.TransactionPreOrder) *TransactionPreOrder {
+	if m == nil {
+		return nil
+	}
+	return &TransactionPreOrder{
+		ID:                    m.ID,
+		PariProductID:         m.PariProductID,
+		PariTransactionID:     m.PariTransactionID,
+		ProductID:             m.ProductID,
+		ProductName:           m.ProductName,
+		ProductCommodity:      m.ProductCommodity,
+		ProductImage:          m.ProductImage,
+		ProductMinPriceAmount: m.ProductMinPriceAmount,
+		ProductMaxPriceAmount: m.ProductMaxPriceAmount,
+		ProductUnitQuantity:   m.ProductUnitQuantity,
+		ProductUnitPrice:      m.ProductUnitPrice,
+		ProductExpiredAt:      m.ProductExpiredAt,
+		ProductCreatedAt:      m.ProductCreatedAt,
+		ProductIsPreOrder:     m.ProductIsPreOrder,
+		ProductIsActive:       m.ProductIsActive,
+		CompanyID:             m.CompanyID,
+		Quantity:              m.Quantity,
+		Status:                m.Status,
+		ActualPriceAmount:     m.ActualPriceAmount,
+		Currency:              m.Currency,
+		TotalAmount:           m.TotalAmount,
+		BuyerName:             m.BuyerName,
+		BuyerAddress:          m.BuyerAddress,
+		BuyerContact:          m.BuyerContact,
+		Version:               m.Version,
+		CreatedAt:             m.CreatedAt,
+		UpdatedAt:             m.UpdatedAt,
+		DeletedAt:             m.DeletedAt,
+	}
+}
+
+func NewTransactionPreOrders(ms []model.TransactionPreOrder) []TransactionPreOrder {
+	result := make([]TransactionPreOrder, 0, len(ms))
+	for i := range ms {
+		result = append(result, *NewTransactionPreOrder(&ms[i]))
+	}
+	return result
+}
+
+// TransactionPreOrderDetail is a pre-order as seen by a verifying user.
+type TransactionPreOrderDetail struct {
+	*TransactionPreOrder
+	IsVerifiedByUser bool `json:"is_verified_by_user"`
+}
+
+func NewTransactionPreOrderDetail(r *helper.TransactionPreOrderResponse) *TransactionPreOrderDetail {
+	if r == nil {
+		return nil
+	}
+	return &TransactionPreOrderDetail{TransactionPreOrder: NewTransactionPreOrder(r.TransactionPreOrder), IsVerifiedByUser: r.IsVerifiedByUser}
+}
@@ -0,0 +1,20 @@
+package response
+
+// TwoFactor tells whether a user logs in with a TOTP code, whether they have to, and
+// how many unused recovery codes they have left.
+type TwoFactor struct {
+	Enabled       bool `json:"enabled"`
+	Required      bool `json:"required"`
+	RecoveryCodes int  `json:"recovery_codes"`
+}
+
+// TwoFactorEnrollment is a pending secret, URI being the otpauth URI to show as a QR code.
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// RecoveryCodes are shown once, when they are generated.
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
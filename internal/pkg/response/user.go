@@ -0,0 +1,73 @@
+package response
+
+import (
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+)
+
+// User is the public representation of a user; credentials are never included.
+type User struct {
+	ID                 int                    `json:"id"`
+	Name               string                 `json:"name"`
+	Email              string                 `json:"email"`
+	VerificationLevel  enum.VerificationLevel `json:"verification_level"`
+	RoleID             int                    `json:"role_id"`
+	RoleName           string                 `json:"role_name,omitempty"`
+	CompanyID          int                    `json:"company_id"`
+	CompanyName        string                 `json:"company_name,omitempty"`
+	MustChangePassword bool                   `json:"must_change_password"`
+	ServiceAccount     bool                   `json:"service_account"`
+	FailedLogins       int                    `json:"failed_logins"`
+	LockedUntil        *time.Time             `json:"locked_until,omitempty"`
+	Version            int                    `json:"version"`
+	CreatedAt          time.Time              `json:"created_at"`
+	UpdatedAt          time.Time              `json:"updated_at"`
+	DeletedAt          *time.Time             `json:"deleted_at,omitempty"`
+}
+
+func NewUser(m *model.User) *User {
+	if m == nil {
+		return nil
+	}
+	return &User{
+		ID:                 m.ID,
+		Name:               m.Name,
+		Email:              m.Email,
+		VerificationLevel:  m.VerificationLevel,
+		RoleID:             m.RoleID,
+		RoleName:           m.RoleName,
+		CompanyID:          m.CompanyID,
+		CompanyName:        m.CompanyName,
+		MustChangePassword: m.MustChangePassword,
+		ServiceAccount:     m.ServiceAccount,
+		FailedLogins:       m.FailedLogins,
+		LockedUntil:        m.LockedUntil,
+		Version:            m.Version,
+		CreatedAt:          m.CreatedAt,
+		UpdatedAt:          m.UpdatedAt,
+		DeletedAt:          m.DeletedAt,
+	}
+}
+
+func NewUsers(ms []model.User) []User {
+	result := make([]User, 0, len(ms))
+	for i := range ms {
+		result = append(result, *NewUser(&ms[i]))
+	}
+	return result
+}
+
+// Token is returned after a successful login or password change. When a second factor
+// is awaited, Token is empty and ChallengeToken buys it at POST /login/2fa instead,
+// after enrolling first if TwoFactorEnrollment. RecoveryCodes are only returned once,
+// when enrolling at login.
+type Token struct {
+	Token               string   `json:"token,omitempty"`
+	MustChangePassword  bool     `json:"must_change_password"`
+	TwoFactorRequired   bool     `json:"two_factor_required,omitempty"`
+	TwoFactorEnrollment bool     `json:"two_factor_enrollment,omitempty"`
+	ChallengeToken      string   `json:"challenge_token,omitempty"`
+	RecoveryCodes       []string `json:"recovery_codes,omitempty"`
+}
@@ -0,0 +1,20 @@
+package response
+
+import (
+	"encoding/json"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserNeverSerializesPassword(t *testing.T) {
+	m := model.User{ID: 7, Name: "Budi", Email: "budi@example.com", Password: "$2a$10$hash", RoleID: 2, CompanyID: 3}
+
+	for _, v := range []interface{}{m, NewUser(&m), NewUsers([]model.User{m})} {
+		body, err := json.Marshal(v)
+		require.NoError(t, err)
+		require.NotContains(t, string(body), `"password"`)
+		require.NotContains(t, string(body), "$2a$10$hash")
+	}
+}
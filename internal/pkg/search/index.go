@@ -0,0 +1,176 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxFuzzy is the number of similar words a misspelled word is expanded to.
+const maxFuzzy = 5
+
+// Index is the vocabulary of the indexed products, used to correct misspelled words.
+// It lives in memory and is kept in sync by the product usecase; an instance that
+// missed a change only loses typo tolerance for it until it is rebuilt.
+type Index struct {
+	mu       sync.RWMutex
+	terms    map[string]int
+	docs     map[int][]string
+	synonyms map[string][]string
+}
+
+// NewIndex returns an empty index using the given synonym groups.
+func NewIndex(synonyms [][]string) *Index {
+	index := &Index{
+		terms:    make(map[string]int),
+		docs:     make(map[int][]string),
+		synonyms: make(map[string][]string),
+	}
+	for _, group := range synonyms {
+		for _, word := range group {
+			for _, other := range group {
+				if other != word {
+					index.synonyms[word] = append(index.synonyms[word], other)
+				}
+			}
+		}
+	}
+	return index
+}
+
+// Put indexes the words of the texts of document id, replacing what was indexed before.
+func (i *Index) Put(id int, texts ...string) {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, text := range texts {
+		for _, word := range Tokenize(text) {
+			if utf8.RuneCountInString(word) >= MinTokenSize && !seen[word] {
+				seen[word] = true
+				terms = append(terms, word)
+			}
+		}
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(id)
+	for _, term := range terms {
+		i.terms[term]++
+	}
+	i.docs[id] = terms
+}
+
+// Remove drops document id from the index.
+func (i *Index) Remove(id int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.remove(id)
+}
+
+func (i *Index) remove(id int) {
+	for _, term := range i.docs[id] {
+		if i.terms[term]--; i.terms[term] <= 0 {
+			delete(i.terms, term)
+		}
+	}
+	delete(i.docs, id)
+}
+
+// Analyze expands the words of text with their synonyms and, for words no indexed
+// word starts with, with the indexed words closest to them.
+func (i *Index) Analyze(text string) Query {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var q Query
+	for _, word := range Tokenize(text) {
+		if utf8.RuneCountInString(word) < MinTokenSize {
+			continue
+		}
+		g := group{word: word}
+		g.alternatives = append(g.alternatives, i.synonyms[word]...)
+		if !i.hasPrefix(word) {
+			g.alternatives = append(g.alternatives, i.similar(word)...)
+		}
+		q.groups = append(q.groups, g)
+	}
+	return q
+}
+
+func (i *Index) hasPrefix(word string) bool {
+	for term := range i.terms {
+		if strings.HasPrefix(term, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// similar returns the indexed words within the edit distance allowed for word,
+// closest and most frequent first.
+func (i *Index) similar(word string) []string {
+	max := 1
+	if utf8.RuneCountInString(word) >= 8 {
+		max = 2
+	}
+
+	type candidate struct {
+		term     string
+		distance int
+		count    int
+	}
+	var candidates []candidate
+	for term, count := range i.terms {
+		if d := distance(word, term, max); d <= max {
+			candidates = append(candidates, candidate{term, d, count})
+		}
+	}
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a].distance != candidates[b].distance {
+			return candidates[a].distance < candidates[b].distance
+		}
+		if candidates[a].count != candidates[b].count {
+			return candidates[a].count > candidates[b].count
+		}
+		return candidates[a].term < candidates[b].term
+	})
+
+	var terms []string
+	for n := 0; n < len(candidates) && n < maxFuzzy; n++ {
+		terms = append(terms, candidates[n].term)
+	}
+	return terms
+}
+
+// distance is the Levenshtein distance between a and b, or max+1 once it is known
+// to exceed max.
+func distance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > max || -diff > max {
+		return max + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		best := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			best = min(best, curr[j])
+		}
+		if best > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,127 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+type group struct {
+	word         string
+	alternatives []string
+}
+
+// Query is an analysed search. Each word of the search is a group that matches the
+// word as a prefix or any of its alternatives.
+type Query struct {
+	groups []group
+}
+
+// Empty reports whether the search has no indexable word.
+func (q Query) Empty() bool {
+	return len(q.groups) == 0
+}
+
+// Boolean returns the query for MATCH ... AGAINST (? IN BOOLEAN MODE).
+func (q Query) Boolean() string {
+	parts := make([]string, len(q.groups))
+	for i, g := range q.groups {
+		terms := []string{g.word + "*"}
+		for _, alternative := range g.alternatives {
+			if strings.Contains(alternative, " ") {
+				alternative = `"` + alternative + `"`
+			}
+			terms = append(terms, alternative)
+		}
+		parts[i] = "+(" + strings.Join(terms, " ") + ")"
+	}
+	return strings.Join(parts, " ")
+}
+
+func (q Query) matches(word string) bool {
+	word = strings.ToLower(word)
+	for _, g := range q.groups {
+		if strings.HasPrefix(word, g.word) {
+			return true
+		}
+		for _, alternative := range g.alternatives {
+			for _, term := range strings.Fields(alternative) {
+				if word == term {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Highlight HTML escapes text and wraps the words matched by q in <em> tags.
+func (q Query) Highlight(text string) string {
+	var b strings.Builder
+	runes := []rune(text)
+	for start := 0; start < len(runes); {
+		end := start + 1
+		inWord := isWordRune(runes[start])
+		for end < len(runes) && isWordRune(runes[end]) == inWord {
+			end++
+		}
+		segment := html.EscapeString(string(runes[start:end]))
+		if inWord && q.matches(string(runes[start:end])) {
+			segment = "<em>" + segment + "</em>"
+		}
+		b.WriteString(segment)
+		start = end
+	}
+	return b.String()
+}
+
+// Snippet returns about width characters of text around its first match, highlighted.
+func (q Query) Snippet(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return q.Highlight(text)
+	}
+
+	first := 0
+	for start := 0; start < len(runes); {
+		end := start
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		if end > start && q.matches(string(runes[start:end])) {
+			first = start
+			break
+		}
+		start = end + 1
+	}
+
+	from := first - width/3
+	if from < 0 {
+		from = 0
+	}
+	to := from + width
+	if to > len(runes) {
+		to = len(runes)
+		from = to - width
+	}
+	// do not cut words in half
+	for from > 0 && isWordRune(runes[from-1]) && isWordRune(runes[from]) {
+		from++
+	}
+	for to < len(runes) && to > from && isWordRune(runes[to-1]) && isWordRune(runes[to]) {
+		to--
+	}
+
+	snippet := q.Highlight(strings.TrimSpace(string(runes[from:to])))
+	if from > 0 {
+		snippet = "…" + snippet
+	}
+	if to < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,89 @@
+// Package search analyses full-text product searches.
+//
+// Matching and relevance ranking are done by MySQL with a FULLTEXT index on the name,
+// description and commodity of products (see EnsureIndex). This package turns what the
+// user typed into a boolean mode query: every word must match, either as a prefix, as
+// one of its commodity synonyms or, when no indexed word starts with it, as an indexed
+// word within a small edit distance. It also highlights the matched words in results.
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MinTokenSize is the shortest word indexed by InnoDB (innodb_ft_min_token_size).
+const MinTokenSize = 3
+
+const (
+	indexName = "ft_products_search"
+	// Columns are the product columns covered by the FULLTEXT index; MATCH must list
+	// exactly these columns.
+	Columns = "name, description, commodity"
+)
+
+// DefaultSynonyms are groups of commodity words that find each other.
+var DefaultSynonyms = [][]string{
+	{"padi", "gabah", "beras"},
+	{"cabai", "cabe", "lombok"},
+	{"kedelai", "kedele"},
+	{"kakao", "cokelat", "coklat"},
+	{"sawit", "tbs"},
+	{"jagung", "pipilan"},
+	{"sapi", "daging sapi"},
+}
+
+// ParseSynonyms reads synonym groups written as "padi|gabah|beras;cabai|cabe".
+func ParseSynonyms(s string) [][]string {
+	var groups [][]string
+	for _, g := range strings.Split(s, ";") {
+		var group []string
+		for _, word := range strings.Split(g, "|") {
+			if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+				group = append(group, word)
+			}
+		}
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// Tokenize splits text into lower case words of letters and digits.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Prefix builds a boolean mode query requiring every word of text as a prefix, or
+// returns "" when text has no indexable word.
+func Prefix(text string) string {
+	var terms []string
+	for _, word := range Tokenize(text) {
+		if utf8.RuneCountInString(word) >= MinTokenSize {
+			terms = append(terms, "+"+word+"*")
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// EnsureIndex creates the FULLTEXT index on products when it does not exist yet.
+// MySQL keeps it up to date on every insert, update and delete.
+func EnsureIndex(db *gorm.DB) error {
+	var count int
+	err := db.Raw("SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?",
+		"products", indexName).Row().Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return db.Exec(fmt.Sprintf("ALTER TABLE products ADD FULLTEXT INDEX %s (%s)", indexName, Columns)).Error
+}
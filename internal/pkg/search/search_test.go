@@ -0,0 +1,77 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIndex() *Index {
+	index := NewIndex(DefaultSynonyms)
+	index.Put(1, "Gabah Kering Panen", "Gabah dari Karawang, kadar air 14%", "padi")
+	index.Put(2, "Jagung Pipilan Kering", "Jagung pipilan untuk pakan ternak", "jagung")
+	index.Put(3, "Kedelai Lokal", "Kedelai grobogan", "kedelai")
+	return index
+}
+
+func TestAnalyze(t *testing.T) {
+	index := newTestIndex()
+
+	tests := []struct {
+		name    string
+		text    string
+		boolean string
+	}{
+		{name: "prefix", text: "Pane", boolean: "+(pane*)"},
+		{name: "every word is required", text: "jagung kering", boolean: "+(jagung* pipilan) +(kering*)"},
+		{name: "synonyms", text: "beras", boolean: "+(beras* padi gabah)"},
+		{name: "misspelled word", text: "kedelei", boolean: "+(kedelei* kedelai)"},
+		{name: "short words are not indexed", text: "di Karawang", boolean: "+(karawang*)"},
+		{name: "operators are dropped", text: `+gabah -"jagung" (x*)`, boolean: "+(gabah* padi beras) +(jagung* pipilan)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.boolean, index.Analyze(tt.text).Boolean())
+		})
+	}
+
+	require.True(t, index.Analyze("di ke").Empty())
+}
+
+func TestIndexStaysInSync(t *testing.T) {
+	index := newTestIndex()
+	require.Equal(t, "+(grobogn* grobogan)", index.Analyze("grobogn").Boolean())
+
+	index.Put(3, "Kedelai Lokal", "Kedelai Demak", "kedelai")
+	require.Equal(t, "+(grobogn*)", index.Analyze("grobogn").Boolean())
+
+	index.Remove(2)
+	require.Equal(t, "+(pipilan* jagung)", index.Analyze("pipilan").Boolean())
+	require.Equal(t, "+(pakan*)", index.Analyze("pakan").Boolean())
+	require.NotContains(t, index.terms, "pakan")
+}
+
+func TestHighlight(t *testing.T) {
+	q := newTestIndex().Analyze("beras karaw")
+
+	require.Equal(t, "<em>Gabah</em> dari <em>Karawang</em> &lt;grade A&gt;", q.Highlight("Gabah dari Karawang <grade A>"))
+
+	description := strings.Repeat("lorem ipsum ", 20) + "gabah pilihan " + strings.Repeat("dolor sit ", 20)
+	snippet := q.Snippet(description, 60)
+	require.True(t, strings.HasPrefix(snippet, "…"))
+	require.True(t, strings.HasSuffix(snippet, "…"))
+	require.Contains(t, snippet, "<em>gabah</em> pilihan")
+}
+
+func TestParseSynonyms(t *testing.T) {
+	require.Equal(t, [][]string{{"padi", "gabah"}, {"cabai", "cabe rawit"}}, ParseSynonyms(" Padi|gabah ; cabai| cabe rawit;single"))
+}
+
+func TestDistance(t *testing.T) {
+	require.Equal(t, 0, distance("gabah", "gabah", 2))
+	require.Equal(t, 1, distance("kedelei", "kedelai", 2))
+	require.Equal(t, 2, distance("jgung", "jagun", 2))
+	require.Equal(t, 3, distance("padi", "kedelai", 2))
+}
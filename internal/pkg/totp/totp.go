@@ -0,0 +1,90 @@
+// Package totp implements the time-based one-time passwords of RFC 6238 authenticator
+// apps generate: 6 digits, HMAC-SHA1, changing every 30 seconds.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Period is how long a code is valid.
+	Period = 30 * time.Second
+	// Skew is how many periods before and after the current one a code is accepted
+	// from, for clocks that drift.
+	Skew   = 1
+	digits = 6
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret is a random secret, base32 encoded as authenticator apps expect it.
+func NewSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// Counter is the number of the period t is in.
+func Counter(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code is the code of secret for the period counter.
+func Code(secret string, counter int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digits, value%1000000), nil
+}
+
+// Verify tells the period code of secret is for, around now, and whether there is one.
+// Callers keep the counter to refuse the same code twice.
+func Verify(secret, code string, now time.Time) (int64, bool) {
+	if len(code) != digits {
+		return 0, false
+	}
+	current := Counter(now)
+	for counter := current - Skew; counter <= current+Skew; counter++ {
+		expected, err := Code(secret, counter)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// URI is the otpauth URI authenticator apps scan as a QR code to add secret, labelled
+// with issuer and account.
+func URI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(digits))
+	v.Set("period", fmt.Sprint(int(Period/time.Second)))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
@@ -0,0 +1,58 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// the SHA1 test vectors of RFC 6238, truncated to 6 digits
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		code, err := Code(secret, Counter(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, want, code, unix)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+
+	previous, err := Code(secret, Counter(now)-1)
+	require.NoError(t, err)
+	counter, ok := Verify(secret, previous, now)
+	require.True(t, ok)
+	require.Equal(t, Counter(now)-1, counter)
+
+	old, err := Code(secret, Counter(now)-2)
+	require.NoError(t, err)
+	_, ok = Verify(secret, old, now)
+	require.False(t, ok)
+
+	_, ok = Verify(secret, "12345", now)
+	require.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(URI("PARI Korporat", "budi@example.com", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", u.Scheme)
+	require.Equal(t, "totp", u.Host)
+	require.Equal(t, "/PARI Korporat:budi@example.com", u.Path)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	require.Equal(t, "PARI Korporat", u.Query().Get("issuer"))
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	gormContextKey = "tracing:context"
+	gormSpanKey    = "tracing:span"
+)
+
+// WithContext attaches ctx to db so the query callbacks can parent their spans on it.
+func WithContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	return db.Set(gormContextKey, ctx)
+}
+
+// RegisterCallbacks wraps every gorm create, query, update, delete and row query in a client span.
+func RegisterCallbacks(db *gorm.DB) {
+	cb := db.Callback()
+	cb.Create().Before("gorm:create").Register("tracing:before_create", before("create"))
+	cb.Create().After("gorm:create").Register("tracing:after_create", after)
+	cb.Query().Before("gorm:query").Register("tracing:before_query", before("query"))
+	cb.Query().After("gorm:query").Register("tracing:after_query", after)
+	cb.Update().Before("gorm:update").Register("tracing:before_update", before("update"))
+	cb.Update().After("gorm:update").Register("tracing:after_update", after)
+	cb.Delete().Before("gorm:delete").Register("tracing:before_delete", before("delete"))
+	cb.Delete().After("gorm:delete").Register("tracing:after_delete", after)
+	cb.RowQuery().Before("gorm:row_query").Register("tracing:before_row_query", before("row_query"))
+	cb.RowQuery().After("gorm:row_query").Register("tracing:after_row_query", after)
+}
+
+func before(operation string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		ctx := context.Background()
+		if v, ok := scope.Get(gormContextKey); ok {
+			if c, ok := v.(context.Context); ok {
+				ctx = c
+			}
+		}
+
+		_, span := Tracer().Start(ctx, "gorm."+operation+" "+scope.TableName(),
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemMySQL,
+				semconv.DBOperationName(operation),
+				semconv.DBCollectionName(scope.TableName()),
+			))
+		scope.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func after(scope *gorm.Scope) {
+	v, ok := scope.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(semconv.DBQueryText(scope.SQL), attribute.Int64("db.rows_affected", scope.DB().RowsAffected))
+	if err := scope.DB().Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
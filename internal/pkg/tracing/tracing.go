@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "bitbucket.org/bridce/ms-pari-web"
+
+// Tracer returns the tracer used by every instrumented layer of the service.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// NewProvider registers a global tracer provider that sends spans to exporter.
+// A nil exporter keeps tracing enabled in-process without exporting anything.
+func NewProvider(serviceName string, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp
+}
+
+// NewOTLPExporter creates an OTLP/HTTP exporter for the given collector url,
+// e.g. http://localhost:4318. An empty url disables exporting.
+func NewOTLPExporter(ctx context.Context, url string) (sdktrace.SpanExporter, error) {
+	if url == "" {
+		return nil, nil
+	}
+	return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(url))
+}
@@ -0,0 +1,261 @@
+// Package trash soft deletes, restores and purges records together with the records
+// that depend on them.
+//
+// Deleting a record stamps it and its dependents that are not deleted yet with the
+// same deleted_at, so restoring it brings back exactly what the delete took with it.
+// A record is restored only while the records it belongs to are not deleted, and only
+// records in the trash can be purged, removing their dependents for good as well.
+package trash
+
+import (
+	"fmt"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"github.com/jinzhu/gorm"
+)
+
+// Link is a table whose rows depend on a record through Column. Rows of Soft links
+// are soft deleted and restored with the record; the others are only purged.
+type Link struct {
+	Table    string
+	Column   string
+	Soft     bool
+	Children []Link
+}
+
+// Parent is a record a resource belongs to through Column.
+type Parent struct {
+	Column string
+	Table  string
+	Entity string
+}
+
+// Resource is a soft deleted table managed through the trash.
+type Resource struct {
+	Name     string
+	Entity   string
+	Table    string
+	Children []Link
+	Parents  []Parent
+}
+
+var preOrderUsers = Link{Table: "transaction_pre_order_users", Column: "transaction_pre_order_id", Soft: true}
+
+var productLinks = []Link{
+	{Table: "transaction_pre_orders", Column: "product_id", Soft: true, Children: []Link{preOrderUsers}},
+	{Table: "product_users", Column: "product_id", Soft: true},
+	{Table: "product_prices", Column: "product_id"},
+	{Table: "product_revisions", Column: "product_id", Children: []Link{
+		{Table: "product_revision_users", Column: "product_revision_id", Soft: true},
+	}},
+}
+
+var (
+	PreOrders = &Resource{
+		Name:     "preorder",
+		Entity:   "transaction_pre_order",
+		Table:    "transaction_pre_orders",
+		Children: []Link{preOrderUsers},
+		Parents:  []Parent{{Column: "product_id", Table: "products", Entity: "product"}},
+	}
+	Products = &Resource{
+		Name:     "product",
+		Entity:   "product",
+		Table:    "products",
+		Children: productLinks,
+		Parents: []Parent{
+			{Column: "company_id", Table: "companies", Entity: "company"},
+			{Column: "commodity_id", Table: "commodities", Entity: "commodity"},
+		},
+	}
+	Users = &Resource{
+		Name:    "user",
+		Entity:  "user",
+		Table:   "users",
+		Parents: []Parent{{Column: "company_id", Table: "companies", Entity: "company"}},
+	}
+	Companies = &Resource{
+		Name:   "company",
+		Entity: "company",
+		Table:  "companies",
+		Children: []Link{
+			{Table: "users", Column: "company_id", Soft: true},
+			{Table: "products", Column: "company_id", Soft: true, Children: productLinks},
+		},
+	}
+)
+
+// Resources are the resources of the trash, dependents before what they depend on so
+// purging them in order never leaves rows behind.
+var Resources = []*Resource{PreOrders, Products, Users, Companies}
+
+// Lookup finds a resource by name.
+func Lookup(name string) (*Resource, bool) {
+	for _, r := range Resources {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Resource) notFound() error {
+	return apperror.NotFound(r.Entity+"_not_found", fmt.Sprintf("%s is not exists", r.Entity))
+}
+
+func (r *Resource) notInTrash() error {
+	return apperror.NotFound(r.Entity+"_not_in_trash", fmt.Sprintf("%s is not in the trash", r.Entity))
+}
+
+// Delete soft deletes the record id of r and its dependents.
+func Delete(db *gorm.DB, r *Resource, id int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Table(r.Table).Where("id = ? AND deleted_at IS NULL", id).UpdateColumn("deleted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return r.notFound()
+		}
+		return deleteLinks(tx, r.Children, []int{id}, now)
+	})
+}
+
+func deleteLinks(tx *gorm.DB, links []Link, ids []int, now time.Time) error {
+	for _, link := range links {
+		if !link.Soft {
+			continue
+		}
+		var linked []int
+		if err := tx.Table(link.Table).Where(link.Column+" IN (?) AND deleted_at IS NULL", ids).Pluck("id", &linked).Error; err != nil {
+			return err
+		}
+		if len(linked) == 0 {
+			continue
+		}
+		if err := tx.Table(link.Table).Where("id IN (?)", linked).UpdateColumn("deleted_at", now).Error; err != nil {
+			return err
+		}
+		if err := deleteLinks(tx, link.Children, linked, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore brings back the record id of r and the dependents deleted with it. It fails
+// with a conflict while a record it belongs to is deleted.
+func Restore(db *gorm.DB, r *Resource, id int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var deleted struct{ DeletedAt *time.Time }
+		if err := tx.Table(r.Table).Select("deleted_at").Where("id = ? AND deleted_at IS NOT NULL", id).Scan(&deleted).Error; err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				return r.notInTrash()
+			}
+			return err
+		}
+
+		for _, parent := range r.Parents {
+			var parentIDs []int
+			if err := tx.Table(r.Table).Where("id = ?", id).Pluck(parent.Column, &parentIDs).Error; err != nil {
+				return err
+			}
+			if len(parentIDs) == 0 || parentIDs[0] == 0 {
+				continue
+			}
+			var count int
+			if err := tx.Table(parent.Table).Where("id = ? AND deleted_at IS NULL", parentIDs[0]).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return apperror.Conflict(parent.Entity+"_deleted", fmt.Sprintf("%s of the %s is deleted, restore it first", parent.Entity, r.Entity))
+			}
+		}
+
+		if err := tx.Table(r.Table).Where("id = ?", id).UpdateColumn("deleted_at", gorm.Expr("NULL")).Error; err != nil {
+			return err
+		}
+		return restoreLinks(tx, r.Children, []int{id}, *deleted.DeletedAt)
+	})
+}
+
+func restoreLinks(tx *gorm.DB, links []Link, ids []int, deletedAt time.Time) error {
+	for _, link := range links {
+		if !link.Soft {
+			continue
+		}
+		var linked []int
+		if err := tx.Table(link.Table).Where(link.Column+" IN (?) AND deleted_at = ?", ids, deletedAt).Pluck("id", &linked).Error; err != nil {
+			return err
+		}
+		if len(linked) == 0 {
+			continue
+		}
+		if err := tx.Table(link.Table).Where("id IN (?)", linked).UpdateColumn("deleted_at", gorm.Expr("NULL")).Error; err != nil {
+			return err
+		}
+		if err := restoreLinks(tx, link.Children, linked, deletedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Purge removes for good the record id of r, which must be in the trash, and every
+// row depending on it.
+func Purge(db *gorm.DB, r *Resource, id int) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var count int
+		if err := tx.Table(r.Table).Where("id = ? AND deleted_at IS NOT NULL", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return r.notInTrash()
+		}
+		return purge(tx, r, []int{id})
+	})
+}
+
+// PurgeDeletedBefore removes for good the records of r deleted before t and returns
+// how many there were.
+func PurgeDeletedBefore(db *gorm.DB, r *Resource, t time.Time) (int, error) {
+	var ids []int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table(r.Table).Where("deleted_at < ?", t).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return purge(tx, r, ids)
+	})
+	return len(ids), err
+}
+
+func purge(tx *gorm.DB, r *Resource, ids []int) error {
+	if err := purgeLinks(tx, r.Children, ids); err != nil {
+		return err
+	}
+	return tx.Exec("DELETE FROM "+r.Table+" WHERE id IN (?)", ids).Error
+}
+
+func purgeLinks(tx *gorm.DB, links []Link, ids []int) error {
+	for _, link := range links {
+		var linked []int
+		if err := tx.Table(link.Table).Where(link.Column+" IN (?)", ids).Pluck("id", &linked).Error; err != nil {
+			return err
+		}
+		if len(linked) == 0 {
+			continue
+		}
+		if err := purgeLinks(tx, link.Children, linked); err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM "+link.Table+" WHERE id IN (?)", linked).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
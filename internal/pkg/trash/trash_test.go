@@ -0,0 +1,74 @@
+package trash
+
+import (
+	"testing"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/require"
+)
+
+func open(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gormDb, err := gorm.Open("mysql", db)
+	require.NoError(t, err)
+	t.Cleanup(func() { gormDb.Close() })
+	return gormDb, mock
+}
+
+func TestDeleteCascades(t *testing.T) {
+	db, mock := open(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `transaction_pre_orders` SET `deleted_at`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id FROM `transaction_pre_order_users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4).AddRow(5))
+	mock.ExpectExec("UPDATE `transaction_pre_order_users` SET `deleted_at`").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, Delete(db, PreOrders, 1))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteNotFound(t *testing.T) {
+	db, mock := open(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `deleted_at`").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := Delete(db, Users, 1)
+	require.True(t, apperror.IsNotFound(err))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreDeletedParent(t *testing.T) {
+	db, mock := open(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT deleted_at FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}).AddRow(time.Now()))
+	mock.ExpectQuery("SELECT company_id FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"company_id"}).AddRow(3))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `companies`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectRollback()
+
+	err := Restore(db, Users, 1)
+	var appErr *apperror.Error
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, "company_deleted", appErr.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLookup(t *testing.T) {
+	r, ok := Lookup("company")
+	require.True(t, ok)
+	require.Equal(t, Companies, r)
+
+	_, ok = Lookup("role")
+	require.False(t, ok)
+}
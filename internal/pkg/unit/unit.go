@@ -0,0 +1,136 @@
+// Package unit is the registry of the units products are sold in. Every unit measures
+// a dimension and is defined by a whole number of the smallest unit of that dimension,
+// so conversions between units of a dimension are exact ratios. Each dimension has a
+// base unit, the kilogram, liter or piece, that prices are compared by.
+package unit
+
+import (
+	"fmt"
+	"strings"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/money"
+)
+
+// Dimension is what a unit measures.
+type Dimension string
+
+const (
+	Mass   Dimension = "mass"
+	Volume Dimension = "volume"
+	Count  Dimension = "count"
+)
+
+// Unit is a unit of measure. Factor is the number of smallest units of the dimension
+// (gram, milliliter, piece) in one of the unit.
+type Unit struct {
+	Code      string
+	Name      string
+	Dimension Dimension
+	Factor    int64
+}
+
+var units = []Unit{
+	{Code: "g", Name: "gram", Dimension: Mass, Factor: 1},
+	{Code: "kg", Name: "kilogram", Dimension: Mass, Factor: 1000},
+	{Code: "kuintal", Name: "kuintal", Dimension: Mass, Factor: 100000},
+	{Code: "ton", Name: "ton", Dimension: Mass, Factor: 1000000},
+	{Code: "ml", Name: "mililiter", Dimension: Volume, Factor: 1},
+	{Code: "liter", Name: "liter", Dimension: Volume, Factor: 1000},
+	{Code: "pcs", Name: "buah", Dimension: Count, Factor: 1},
+	{Code: "ekor", Name: "ekor", Dimension: Count, Factor: 1},
+	{Code: "lusin", Name: "lusin", Dimension: Count, Factor: 12},
+	{Code: "kodi", Name: "kodi", Dimension: Count, Factor: 20},
+}
+
+var bases = map[Dimension]string{Mass: "kg", Volume: "liter", Count: "pcs"}
+
+// aliases are the spellings of units found in free text, used by Parse.
+var aliases = map[string]string{
+	"gr": "g", "gram": "g",
+	"kilo": "kg", "kilogram": "kg", "kgs": "kg",
+	"kw": "kuintal", "quintal": "kuintal",
+	"t": "ton", "tonne": "ton",
+	"mililiter": "ml", "milliliter": "ml",
+	"l": "liter", "lt": "liter", "ltr": "liter", "litre": "liter",
+	"pc": "pcs", "buah": "pcs", "biji": "pcs", "unit": "pcs",
+	"dozen": "lusin",
+}
+
+var byCode = func() map[string]Unit {
+	m := make(map[string]Unit, len(units))
+	for _, u := range units {
+		m[u.Code] = u
+	}
+	return m
+}()
+
+// Codes returns the codes of every unit, grouped by dimension from the smallest up.
+func Codes() []string {
+	codes := make([]string, len(units))
+	for i, u := range units {
+		codes[i] = u.Code
+	}
+	return codes
+}
+
+// Lookup returns the unit with the given code.
+func Lookup(code string) (Unit, bool) {
+	u, ok := byCode[code]
+	return u, ok
+}
+
+// Parse reads a unit written as free text, e.g. "Kilogram" or " ltr".
+func Parse(text string) (Unit, bool) {
+	code := strings.ToLower(strings.TrimSpace(text))
+	if alias, ok := aliases[code]; ok {
+		code = alias
+	}
+	return Lookup(code)
+}
+
+// Base returns the unit prices of the dimension of u are compared by.
+func (u Unit) Base() Unit {
+	return byCode[bases[u.Dimension]]
+}
+
+func compatible(a, b Unit) error {
+	if a.Dimension != b.Dimension {
+		return fmt.Errorf("unit: cannot convert %s (%s) to %s (%s)", a.Code, a.Dimension, b.Code, b.Dimension)
+	}
+	return nil
+}
+
+// Convert returns quantity of from in units of to.
+func Convert(quantity float64, from, to Unit) (float64, error) {
+	if err := compatible(from, to); err != nil {
+		return 0, err
+	}
+	return quantity * float64(from.Factor) / float64(to.Factor), nil
+}
+
+// PricePer converts a price per unit from into the price per unit to, in the same
+// minor units, rounding half away from zero.
+func PricePer(amount int64, from, to Unit) (int64, error) {
+	if err := compatible(from, to); err != nil {
+		return 0, err
+	}
+	return money.MulDiv(amount, to.Factor, from.Factor), nil
+}
+
+// Total returns the price of quantity units of quantityUnit at amount per priceUnit.
+func Total(amount int64, priceUnit Unit, quantity int, quantityUnit Unit) (int64, error) {
+	if err := compatible(quantityUnit, priceUnit); err != nil {
+		return 0, err
+	}
+	return money.MulDiv(amount, int64(quantity)*quantityUnit.Factor, priceUnit.Factor), nil
+}
+
+// Totals sums quantities of any unit by the base unit of their dimension, so 1 ton
+// and 500 kg add up to 1500 kg.
+type Totals map[string]float64
+
+// Add adds quantity of u to the total of its base unit.
+func (t Totals) Add(quantity int, u Unit) {
+	base := u.Base()
+	t[base.Code] += float64(quantity) * float64(u.Factor) / float64(base.Factor)
+}
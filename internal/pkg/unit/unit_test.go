@@ -0,0 +1,81 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustLookup(t *testing.T, code string) Unit {
+	t.Helper()
+	u, ok := Lookup(code)
+	require.True(t, ok, code)
+	return u
+}
+
+func TestParse(t *testing.T) {
+	for text, code := range map[string]string{"Kg": "kg", " kilogram ": "kg", "LTR": "liter", "buah": "pcs", "ton": "ton"} {
+		u, ok := Parse(text)
+		require.True(t, ok, text)
+		require.Equal(t, code, u.Code)
+	}
+	_, ok := Parse("karung")
+	require.False(t, ok)
+}
+
+func TestBase(t *testing.T) {
+	require.Equal(t, "kg", mustLookup(t, "ton").Base().Code)
+	require.Equal(t, "liter", mustLookup(t, "ml").Base().Code)
+	require.Equal(t, "pcs", mustLookup(t, "lusin").Base().Code)
+}
+
+func TestConvert(t *testing.T) {
+	got, err := Convert(2.5, mustLookup(t, "ton"), mustLookup(t, "kg"))
+	require.NoError(t, err)
+	require.Equal(t, 2500.0, got)
+
+	_, err = Convert(1, mustLookup(t, "kg"), mustLookup(t, "liter"))
+	require.Error(t, err)
+}
+
+func TestPricePer(t *testing.T) {
+	// Rp5.500.000,00 per ton is Rp5.500,00 per kg
+	got, err := PricePer(550000000, mustLookup(t, "ton"), mustLookup(t, "kg"))
+	require.NoError(t, err)
+	require.Equal(t, int64(550000), got)
+
+	// Rp60.000,00 per lusin is Rp5.000,00 per piece
+	got, err = PricePer(6000000, mustLookup(t, "lusin"), mustLookup(t, "pcs"))
+	require.NoError(t, err)
+	require.Equal(t, int64(500000), got)
+
+	_, err = PricePer(100, mustLookup(t, "ekor"), mustLookup(t, "kg"))
+	require.Error(t, err)
+}
+
+func TestTotal(t *testing.T) {
+	// 3 ton at Rp5.500,00 per kg
+	got, err := Total(550000, mustLookup(t, "kg"), 3, mustLookup(t, "ton"))
+	require.NoError(t, err)
+	require.Equal(t, int64(1650000000), got)
+
+	// 500 g at Rp12.345,67 per kg rounds half away from zero
+	got, err = Total(1234567, mustLookup(t, "kg"), 500, mustLookup(t, "g"))
+	require.NoError(t, err)
+	require.Equal(t, int64(617284), got)
+}
+
+func TestCodes(t *testing.T) {
+	require.Equal(t, []string{"g", "kg", "kuintal", "ton", "ml", "liter", "pcs", "ekor", "lusin", "kodi"}, Codes())
+}
+
+func TestTotals(t *testing.T) {
+	totals := Totals{}
+	totals.Add(1, mustLookup(t, "ton"))
+	totals.Add(500, mustLookup(t, "kg"))
+	totals.Add(250, mustLookup(t, "g"))
+	totals.Add(2, mustLookup(t, "lusin"))
+	totals.Add(3, mustLookup(t, "ekor"))
+
+	require.Equal(t, Totals{"kg": 1500.25, "pcs": 27}, totals)
+}
@@ -0,0 +1,179 @@
+package access_token
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/actor"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/access_token"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"github.com/casbin/casbin"
+)
+
+// maxActiveTokens caps the tokens a user has neither revoked nor expired.
+const maxActiveTokens = 20
+
+// prefixLength is how much of a token is kept to recognize it by, its prefix included.
+const prefixLength = len(helper.AccessTokenPrefix) + 6
+
+type Usecase interface {
+	Create(ctx context.Context, actorID, userID int, r request.AccessToken) (*model.AccessToken, string, error)
+	ReadAll(ctx context.Context, actorID, userID int) (*[]model.AccessToken, error)
+	Revoke(ctx context.Context, actorID, userID, id int) error
+}
+
+type usecase struct {
+	repository     access_token.Repository
+	userRepository user.Repository
+	roleRepository role.Repository
+	enforcer       *casbin.SyncedEnforcer
+	maxTTL         time.Duration
+}
+
+// NewUsecase lets tokens be valid for maxTTL at most.
+func NewUsecase(repository access_token.Repository, userRepository user.Repository, roleRepository role.Repository, enforcer *casbin.SyncedEnforcer, maxTTL time.Duration) Usecase {
+	return &usecase{repository, userRepository, roleRepository, enforcer, maxTTL}
+}
+
+// Create issues a token of the user, returning it along with the token itself, which is
+// only shown now. Users create their own tokens, admins those of the service accounts of
+// their company. A token is scoped to permissions the user holds, which it keeps being
+// granted only as long as the user does. Admins other than superadmins scope it to
+// permissions they hold too, and cannot create the tokens of superadmin service accounts.
+func (e *usecase) Create(ctx context.Context, actorID, userID int, r request.AccessToken) (*model.AccessToken, string, error) {
+	a, u, err := e.owner(ctx, actorID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.ID != actorID && !u.ServiceAccount {
+		return nil, "", apperror.Forbidden("service_account_only", "admins create the tokens of service accounts, users create their own")
+	}
+
+	var details []apperror.FieldError
+	_, held := permission.Effective(e.enforcer, u.ID, permission.Domain(u.CompanyID))
+	granted := held
+	if u.ID != actorID && !a.Superadmin {
+		ro, err := e.roleRepository.ReadById(ctx, u.RoleID)
+		if err != nil && !apperror.IsNotFound(err) {
+			return nil, "", err
+		}
+		if err == nil && ro.IsSuperadmin() {
+			return nil, "", apperror.Forbidden("access_token_forbidden", "tokens of superadmin service accounts are created by superadmins only")
+		}
+		_, granted = permission.Effective(e.enforcer, a.ID, permission.Domain(a.CompanyID))
+	}
+	scopes := make([]string, 0, len(r.Scopes))
+	for i, s := range r.Scopes {
+		p, ok := permission.Lookup(s)
+		switch {
+		case !ok:
+			details = append(details, apperror.FieldError{Field: fmt.Sprintf("scopes[%d]", i), Message: "unknown permission"})
+		case !holds(held, p):
+			details = append(details, apperror.FieldError{Field: fmt.Sprintf("scopes[%d]", i), Message: "permission is not granted to the user"})
+		case !holds(granted, p):
+			details = append(details, apperror.FieldError{Field: fmt.Sprintf("scopes[%d]", i), Message: "permission is not granted to you"})
+		case !contains(scopes, p.String()):
+			scopes = append(scopes, p.String())
+		}
+	}
+	ttl := time.Duration(r.ExpiresInDays) * 24 * time.Hour
+	if ttl > e.maxTTL {
+		details = append(details, apperror.FieldError{Field: "expires_in_days", Message: fmt.Sprintf("expires_in_days must be %d or less", int(e.maxTTL.Hours()/24))})
+	}
+	if len(details) > 0 {
+		return nil, "", apperror.Validation("validation_error", "request validation failed").WithDetails(details...)
+	}
+
+	now := time.Now()
+	active, err := e.repository.CountActive(ctx, u.ID, now)
+	if err != nil {
+		return nil, "", err
+	}
+	if active >= maxActiveTokens {
+		return nil, "", apperror.Conflict("too_many_tokens", fmt.Sprintf("at most %d tokens can be active, revoke one first", maxActiveTokens))
+	}
+
+	secret, err := helper.NewSecretToken()
+	if err != nil {
+		return nil, "", err
+	}
+	raw := helper.AccessTokenPrefix + secret
+	token, err := e.repository.Create(ctx, &model.AccessToken{
+		UserID:    u.ID,
+		Name:      r.Name,
+		TokenHash: helper.HashToken(raw),
+		Prefix:    raw[:prefixLength],
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return token, raw, nil
+}
+
+// ReadAll lists the tokens of the user, revoked and expired ones included.
+func (e *usecase) ReadAll(ctx context.Context, actorID, userID int) (*[]model.AccessToken, error) {
+	if _, _, err := e.owner(ctx, actorID, userID); err != nil {
+		return nil, err
+	}
+	return e.repository.ReadAllByUserId(ctx, userID)
+}
+
+// Revoke stops a token of the user authenticating at once.
+func (e *usecase) Revoke(ctx context.Context, actorID, userID, id int) error {
+	if _, _, err := e.owner(ctx, actorID, userID); err != nil {
+		return err
+	}
+	token, err := e.repository.ReadBy(ctx, map[string]interface{}{"id": id, "user_id": userID})
+	if err != nil {
+		return err
+	}
+	return e.repository.Revoke(ctx, token.ID)
+}
+
+// owner loads the user whose tokens the actor manages: their own, or those of a user of
+// their company, of any company for superadmins. Other users are not found.
+func (e *usecase) owner(ctx context.Context, actorID, userID int) (*actor.Actor, *model.User, error) {
+	a, err := actor.Load(ctx, e.userRepository, e.roleRepository, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if userID == actorID {
+		return a, a.User, nil
+	}
+	u, err := e.userRepository.ReadById(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !a.Sees(u.CompanyID) {
+		return nil, nil, apperror.NotFound("user_not_found", "user is not exists")
+	}
+	return a, u, nil
+}
+
+func holds(held []permission.Permission, p permission.Permission) bool {
+	for _, h := range held {
+		if h == p {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
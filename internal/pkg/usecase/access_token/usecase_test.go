@@ -0,0 +1,225 @@
+package access_token
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	mock "bitbucket.org/bridce/ms-pari-web/internal/pkg/mock/repository"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/access_token"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"github.com/casbin/casbin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// tokens keeps access tokens in memory.
+type tokens struct {
+	access_token.Repository
+	rows map[int]*model.AccessToken
+}
+
+func (t tokens) Create(_ context.Context, m *model.AccessToken) (*model.AccessToken, error) {
+	m.ID = len(t.rows) + 1
+	saved := *m
+	t.rows[m.ID] = &saved
+	return m, nil
+}
+
+func (t tokens) ReadBy(_ context.Context, criteria map[string]interface{}) (*model.AccessToken, error) {
+	for _, row := range t.rows {
+		if row.ID == criteria["id"] && row.UserID == criteria["user_id"] {
+			found := *row
+			return &found, nil
+		}
+	}
+	return nil, apperror.NotFound("access_token_not_found", "access token is not exists")
+}
+
+func (t tokens) ReadAllByUserId(_ context.Context, userID int) (*[]model.AccessToken, error) {
+	var found []model.AccessToken
+	for _, row := range t.rows {
+		if row.UserID == userID {
+			found = append(found, *row)
+		}
+	}
+	return &found, nil
+}
+
+func (t tokens) CountActive(_ context.Context, userID int, now time.Time) (int, error) {
+	count := 0
+	for _, row := range t.rows {
+		if row.UserID == userID && row.Active(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (t tokens) Revoke(_ context.Context, id int) error {
+	now := time.Now()
+	t.rows[id].RevokedAt = &now
+	return nil
+}
+
+type roles struct {
+	role.Repository
+}
+
+func (roles) ReadById(_ context.Context, id int) (*model.Role, error) {
+	names := map[int]string{1: "superadmin", 2: "admin", 3: "user", 4: "verificator"}
+	return &model.Role{ID: id, Name: names[id]}, nil
+}
+
+// The superadmin is user 1, the admin and a user of company 5 are users 2 and 3, and
+// users 4 and 5 are service accounts of companies 5 and 6. Service accounts 6 and 7 of
+// company 5 verify products and are superadmins.
+const (
+	superadmin    = 1
+	admin5        = 2
+	user5         = 3
+	service5      = 4
+	service6      = 5
+	verificator5  = 6
+	superadminOf5 = 7
+)
+
+func newUsecase(t *testing.T) (Usecase, tokens) {
+	users := mock.NewMockRepository(gomock.NewController(t))
+	for _, u := range []model.User{
+		{ID: superadmin, RoleID: 1},
+		{ID: admin5, RoleID: 2, CompanyID: 5},
+		{ID: user5, RoleID: 3, CompanyID: 5},
+		{ID: service5, RoleID: 3, CompanyID: 5, ServiceAccount: true},
+		{ID: service6, RoleID: 3, CompanyID: 6, ServiceAccount: true},
+		{ID: verificator5, RoleID: 4, CompanyID: 5, ServiceAccount: true},
+		{ID: superadminOf5, RoleID: 1, CompanyID: 5, ServiceAccount: true},
+	} {
+		u := u
+		users.EXPECT().ReadById(gomock.Any(), u.ID).Return(&u, nil).AnyTimes()
+	}
+
+	enforcer := casbin.NewSyncedEnforcer("../../config/rbac_model.conf", false)
+	permission.Seed(enforcer)
+	permission.Assign(enforcer, superadmin, "superadmin", permission.Domain(0))
+	permission.Assign(enforcer, admin5, "admin", "5")
+	permission.Assign(enforcer, user5, "user", "5")
+	permission.Assign(enforcer, service5, "user", "5")
+	permission.Assign(enforcer, service6, "user", "6")
+	permission.Assign(enforcer, verificator5, "verificator", "5")
+	permission.Assign(enforcer, superadminOf5, "superadmin", "5")
+
+	store := tokens{rows: map[int]*model.AccessToken{}}
+	return NewUsecase(store, users, roles{}, enforcer, 30*24*time.Hour), store
+}
+
+func TestCreateKeepsHashOnly(t *testing.T) {
+	uc, store := newUsecase(t)
+
+	token, raw, err := uc.Create(context.Background(), user5, user5, request.AccessToken{
+		Name:          "nightly export",
+		Scopes:        []string{"product:read", "preorder:read", "product:read"},
+		ExpiresInDays: 7,
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(raw, helper.AccessTokenPrefix))
+	require.Equal(t, helper.HashToken(raw), store.rows[token.ID].TokenHash)
+	require.Equal(t, raw[:prefixLength], token.Prefix)
+	require.Equal(t, []string{"product:read", "preorder:read"}, token.ScopeList())
+	require.WithinDuration(t, time.Now().Add(7*24*time.Hour), token.ExpiresAt, time.Minute)
+}
+
+func TestCreateValidatesScopes(t *testing.T) {
+	uc, _ := newUsecase(t)
+
+	_, _, err := uc.Create(context.Background(), user5, user5, request.AccessToken{
+		Name:          "script",
+		Scopes:        []string{"product:read", "nonsense", "giro:manage"},
+		ExpiresInDays: 60,
+	})
+	var appErr *apperror.Error
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, apperror.KindValidation, appErr.Kind)
+	require.Len(t, appErr.Details, 3)
+	require.Equal(t, "scopes[1]", appErr.Details[0].Field)
+	require.Equal(t, "scopes[2]", appErr.Details[1].Field, "tokens hold no more than their user")
+	require.Equal(t, "expires_in_days", appErr.Details[2].Field)
+}
+
+func TestCreateCapsActiveTokens(t *testing.T) {
+	uc, _ := newUsecase(t)
+	r := request.AccessToken{Name: "script", Scopes: []string{"product:read"}, ExpiresInDays: 1}
+
+	var last *model.AccessToken
+	for i := 0; i < maxActiveTokens; i++ {
+		token, _, err := uc.Create(context.Background(), user5, user5, r)
+		require.NoError(t, err)
+		last = token
+	}
+	_, _, err := uc.Create(context.Background(), user5, user5, r)
+	require.True(t, apperror.Is(err, apperror.KindConflict))
+
+	require.NoError(t, uc.Revoke(context.Background(), user5, user5, last.ID))
+	_, _, err = uc.Create(context.Background(), user5, user5, r)
+	require.NoError(t, err, "revoked tokens are not counted")
+}
+
+func TestTokensOfOtherUsers(t *testing.T) {
+	uc, _ := newUsecase(t)
+	r := request.AccessToken{Name: "integration", Scopes: []string{"product:read"}, ExpiresInDays: 1}
+
+	token, _, err := uc.Create(context.Background(), admin5, service5, r)
+	require.NoError(t, err)
+	_, _, err = uc.Create(context.Background(), admin5, user5, r)
+	require.True(t, apperror.Is(err, apperror.KindForbidden), "only service accounts are given tokens by admins")
+	_, _, err = uc.Create(context.Background(), admin5, service6, r)
+	require.True(t, apperror.IsNotFound(err))
+	_, _, err = uc.Create(context.Background(), superadmin, service6, r)
+	require.NoError(t, err)
+
+	err = uc.Revoke(context.Background(), admin5, user5, token.ID)
+	require.True(t, apperror.IsNotFound(err), "tokens are revoked through their user")
+
+	require.NoError(t, uc.Revoke(context.Background(), admin5, service5, token.ID))
+	tokens, err := uc.ReadAll(context.Background(), admin5, service5)
+	require.NoError(t, err)
+	require.Len(t, *tokens, 1)
+	require.NotNil(t, (*tokens)[0].RevokedAt)
+}
+
+func TestCreateScopedToAdmin(t *testing.T) {
+	uc, _ := newUsecase(t)
+	r := request.AccessToken{Name: "verify", Scopes: []string{"product:read", "product:verify"}, ExpiresInDays: 7}
+
+	// the account verifies products, which the admin does not
+	_, _, err := uc.Create(context.Background(), admin5, verificator5, r)
+	var appErr *apperror.Error
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, []apperror.FieldError{{Field: "scopes[1]", Message: "permission is not granted to you"}}, appErr.Details)
+
+	token, _, err := uc.Create(context.Background(), superadmin, verificator5, r)
+	require.NoError(t, err)
+	require.Equal(t, []string{"product:read", "product:verify"}, token.ScopeList())
+
+	r.Scopes = r.Scopes[:1]
+	_, _, err = uc.Create(context.Background(), admin5, verificator5, r)
+	require.NoError(t, err)
+}
+
+func TestCreateOfSuperadminServiceAccount(t *testing.T) {
+	uc, store := newUsecase(t)
+	r := request.AccessToken{Name: "export", Scopes: []string{"product:read"}, ExpiresInDays: 7}
+
+	_, _, err := uc.Create(context.Background(), admin5, superadminOf5, r)
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+	require.Empty(t, store.rows)
+
+	_, _, err = uc.Create(context.Background(), superadmin, superadminOf5, r)
+	require.NoError(t, err)
+}
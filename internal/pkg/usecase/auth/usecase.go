@@ -7,50 +7,74 @@
 package auth
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"regexp"
 	"time"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/actor"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/credential"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/company"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/giro"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/login_attempt"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/two_factor"
 )
 
 type Usecase interface {
-	Register(user request.User) (*model.User, error)
-	BulkRegister(users request.Users) ([]*model.User, error)
-	Login(user *model.User) (*model.User, error)
-	ValidateGiro(code string) (*model.Giro, error)
-	GetToken(clientKey, secretKey string) (key *request.OpenKey, err error)
+	Register(ctx context.Context, user request.User) (*model.User, error)
+	BulkRegister(ctx context.Context, users request.Users) ([]*model.User, error)
+	Login(ctx context.Context, login request.Login) (*model.User, *two_factor.Challenge, error)
+	ReadLoginAttempts(ctx context.Context, actorID int, q *query.Query) (*[]model.LoginAttempt, *query.Page, error)
+	Unlock(ctx context.Context, actorID, id int) (*model.User, error)
+	ValidateGiro(ctx context.Context, code string) (*model.Giro, error)
+	GetToken(ctx context.Context, clientKey, secretKey string) (key *request.OpenKey, err error)
+}
+
+// Challenger issues the challenge of the users logging in with a second factor, nil for
+// the others.
+type Challenger interface {
+	Challenge(ctx context.Context, u *model.User) (*two_factor.Challenge, error)
 }
 
 type usecase struct {
-	userRepository    user.Repository
-	giroRepository    giro.Repository
-	companyRepository company.Repository
-	roleRepository    role.Repository
+	userRepository         user.Repository
+	giroRepository         giro.Repository
+	companyRepository      company.Repository
+	roleRepository         role.Repository
+	loginAttemptRepository login_attempt.Repository
+	credential             credential.Service
+	lockout                credential.Lockout
+	challenger             Challenger
 }
 
-func NewUsecase(userRepository user.Repository, giroRepository giro.Repository, roleRepository role.Repository, companyRepository company.Repository) Usecase {
-	return &usecase{userRepository: userRepository, giroRepository: giroRepository, roleRepository: roleRepository, companyRepository: companyRepository}
+func NewUsecase(userRepository user.Repository, giroRepository giro.Repository, roleRepository role.Repository, companyRepository company.Repository, loginAttemptRepository login_attempt.Repository, credential credential.Service, lockout credential.Lockout, challenger Challenger) Usecase {
+	return &usecase{userRepository: userRepository, giroRepository: giroRepository, roleRepository: roleRepository, companyRepository: companyRepository, loginAttemptRepository: loginAttemptRepository, credential: credential, lockout: lockout, challenger: challenger}
 }
 
-func (e *usecase) Register(u request.User) (*model.User, error) {
-	helper.HashPassword(&u.Password)
+func (e *usecase) Register(ctx context.Context, u request.User) (*model.User, error) {
+	password, err := e.credential.Hash(u.Password)
+	if err != nil {
+		return nil, err
+	}
 
-	r, err := e.roleRepository.ReadByName(u.Role)
+	c, err := e.companyRepository.ReadById(ctx, u.CompanyID)
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		helper.Logger(ctx).Error(err)
 		return nil, err
 	}
 
-	c, err := e.companyRepository.ReadById(u.CompanyID)
+	r, err := e.companyRole(ctx, u.Role, c.ID)
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		helper.Logger(ctx).Error(err)
 		return nil, err
 	}
 
@@ -60,12 +84,12 @@ func (e *usecase) Register(u request.User) (*model.User, error) {
 		Name:              u.Name,
 		Email:             u.Email,
 		VerificationLevel: u.VerificationLevel,
-		Password:          u.Password,
+		Password:          password,
 	}
 
-	m, err := e.userRepository.Create(newUser)
+	m, err := e.userRepository.Create(ctx, newUser)
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		helper.Logger(ctx).Error(err)
 		return nil, err
 	}
 
@@ -76,21 +100,29 @@ func (e *usecase) Register(u request.User) (*model.User, error) {
 	return m, nil
 }
 
-func (e *usecase) BulkRegister(users request.Users) ([]*model.User, error) {
-	listUsers := make([]*model.User, 0)
-	for _, u := range users {
+func (e *usecase) BulkRegister(ctx context.Context, users request.Users) ([]*model.User, error) {
+	// every password is checked before any user is created
+	passwords := make([]string, len(users))
+	for i, u := range users {
+		password, err := e.credential.Hash(u.Password)
+		if err != nil {
+			return nil, indexed(err, i)
+		}
+		passwords[i] = password
+	}
 
-		helper.HashPassword(&u.Password)
+	listUsers := make([]*model.User, 0)
+	for i, u := range users {
 
-		r, err := e.roleRepository.ReadByName(u.Role)
+		c, err := e.companyRepository.ReadById(ctx, u.CompanyID)
 		if err != nil {
-			helper.CommonLogger().Error(err)
+			helper.Logger(ctx).Error(err)
 			return nil, err
 		}
 
-		c, err := e.companyRepository.ReadById(u.CompanyID)
+		r, err := e.companyRole(ctx, u.Role, c.ID)
 		if err != nil {
-			helper.CommonLogger().Error(err)
+			helper.Logger(ctx).Error(err)
 			return nil, err
 		}
 
@@ -100,12 +132,12 @@ func (e *usecase) BulkRegister(users request.Users) ([]*model.User, error) {
 			Name:              u.Name,
 			Email:             u.Email,
 			VerificationLevel: u.VerificationLevel,
-			Password:          u.Password,
+			Password:          passwords[i],
 		}
 
-		m, err := e.userRepository.Create(newUser)
+		m, err := e.userRepository.Create(ctx, newUser)
 		if err != nil {
-			helper.CommonLogger().Error(err)
+			helper.Logger(ctx).Error(err)
 			return nil, err
 		}
 
@@ -118,15 +150,157 @@ func (e *usecase) BulkRegister(users request.Users) ([]*model.User, error) {
 	return listUsers, nil
 }
 
-func (e *usecase) Login(user *model.User) (*model.User, error) {
-	return e.userRepository.ReadByEmail(user.Email)
+// Login checks the credentials of a user, throttling the accounts and IPs failing too
+// often. Unknown emails, wrong passwords and accounts waiting after failed logins fail
+// the same way, so the emails of accounts cannot be found out, and every attempt is
+// audited. Users logging in with a second factor get a challenge to answer instead of
+// succeeding.
+func (e *usecase) Login(ctx context.Context, login request.Login) (*model.User, *two_factor.Challenge, error) {
+	now := time.Now()
+	attempt := &model.LoginAttempt{Email: login.Email, IP: login.IP, UserAgent: login.UserAgent}
+
+	if e.lockout.IPFailures > 0 {
+		failures, err := e.loginAttemptRepository.CountFailures(ctx, map[string]interface{}{"ip": login.IP}, now.Add(-e.lockout.IPWindow))
+		if err != nil {
+			return nil, nil, err
+		}
+		if failures >= e.lockout.IPFailures {
+			e.audit(ctx, attempt, enum.LoginIPThrottled)
+			return nil, nil, throttled()
+		}
+	}
+
+	dbUser, err := e.userRepository.ReadByEmail(ctx, login.Email)
+	if apperror.IsNotFound(err) {
+		e.credential.Verify(ctx, nil, login.Password)
+		e.audit(ctx, attempt, enum.LoginUnknownEmail)
+		return nil, nil, invalidCredentials().Wrap(err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	attempt.UserID = dbUser.ID
+	attempt.CompanyID = dbUser.CompanyID
+
+	if dbUser.LockedUntil != nil && now.Before(*dbUser.LockedUntil) {
+		e.credential.Verify(ctx, nil, login.Password)
+		e.audit(ctx, attempt, enum.LoginLocked)
+		return nil, nil, invalidCredentials()
+	}
+
+	if !e.credential.Verify(ctx, dbUser, login.Password) {
+		failures, err := e.userRepository.FailLogin(ctx, dbUser.ID)
+		if wait := e.lockout.Wait(failures); err == nil && wait > 0 {
+			err = e.userRepository.Lock(ctx, dbUser.ID, now.Add(wait))
+		}
+		if err != nil {
+			helper.Logger(ctx).WithError(err).Error("[authUsecase.Login] failed counting failed login")
+		}
+		e.audit(ctx, attempt, enum.LoginWrongPassword)
+		return nil, nil, invalidCredentials()
+	}
+	if dbUser.FailedLogins > 0 || dbUser.LockedUntil != nil {
+		if err := e.userRepository.Unlock(ctx, dbUser.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// users of a company that onboarded itself wait for a superadmin to approve it
+	if dbUser.CompanyID != 0 {
+		c, err := e.companyRepository.ReadById(ctx, dbUser.CompanyID)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch c.Status {
+		case enum.CompanyPending:
+			e.audit(ctx, attempt, enum.LoginCompanyPending)
+			return nil, nil, apperror.Forbidden("company_pending", "company is waiting for approval")
+		case enum.CompanyRejected:
+			e.audit(ctx, attempt, enum.LoginCompanyRejected)
+			return nil, nil, apperror.Forbidden("company_rejected", "company was rejected")
+		}
+	}
+
+	challenge, err := e.challenger.Challenge(ctx, dbUser)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge != nil {
+		e.audit(ctx, attempt, enum.LoginTwoFactorPending)
+		return dbUser, challenge, nil
+	}
+
+	attempt.Success = true
+	e.audit(ctx, attempt, enum.LoginSucceeded)
+	return dbUser, nil, nil
+}
+
+// audit records a login attempt. Failing to is only logged, so users are not locked
+// out when the audit cannot be written.
+func (e *usecase) audit(ctx context.Context, attempt *model.LoginAttempt, result enum.LoginResult) {
+	attempt.Result = result
+	if _, err := e.loginAttemptRepository.Create(ctx, attempt); err != nil {
+		helper.Logger(ctx).WithError(err).Error("[authUsecase.Login] failed auditing login attempt")
+	}
+}
+
+func invalidCredentials() *apperror.Error {
+	return apperror.Unauthorized("invalid_credentials", "email or password is incorrect")
+}
+
+func throttled() *apperror.Error {
+	return apperror.RateLimited("login_throttled", "too many failed logins, try again later")
+}
+
+// ReadLoginAttempts finds the audited login attempts, those of the users of their own
+// company for admins other than superadmins.
+func (e *usecase) ReadLoginAttempts(ctx context.Context, actorID int, q *query.Query) (*[]model.LoginAttempt, *query.Page, error) {
+	a, err := actor.Load(ctx, e.userRepository, e.roleRepository, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !a.Superadmin {
+		q.Where("company_id", a.CompanyID)
+	}
+	return e.loginAttemptRepository.ReadAllBy(ctx, q)
+}
+
+// Unlock lets a user locked out by failed logins try again right away.
+func (e *usecase) Unlock(ctx context.Context, actorID, id int) (*model.User, error) {
+	a, err := actor.Load(ctx, e.userRepository, e.roleRepository, actorID)
+	if err != nil {
+		return nil, err
+	}
+	u, err := e.userRepository.ReadById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !a.Sees(u.CompanyID) {
+		return nil, apperror.NotFound("user_not_found", "user is not exists")
+	}
+	if err := e.userRepository.Unlock(ctx, id); err != nil {
+		return nil, err
+	}
+	return e.userRepository.ReadById(ctx, id)
 }
 
-func (e *usecase) ValidateGiro(code string) (*model.Giro, error) {
-	return e.giroRepository.ReadByCode(code)
+// ValidateGiro finds a giro account along with the company onboarded with it.
+func (e *usecase) ValidateGiro(ctx context.Context, code string) (*model.Giro, error) {
+	g, err := e.giroRepository.ReadByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if !g.IsActive {
+		return nil, apperror.Forbidden("giro_inactive", "giro is not active")
+	}
+	g.Company, err = e.companyRepository.ReadBy(ctx, map[string]interface{}{"giro_id": g.ID})
+	if err != nil && !apperror.IsNotFound(err) {
+		return nil, err
+	}
+	return g, nil
 }
 
-func (e *usecase) GetToken(clientKey, secretKey string) (key *request.OpenKey, err error) {
+func (e *usecase) GetToken(ctx context.Context, clientKey, secretKey string) (key *request.OpenKey, err error) {
 	var (
 		expduration = 15 //minutes
 		expTime     = (time.Now().Add(time.Minute * time.Duration(expduration))).Format("2006-01-02 15:04:05")
@@ -134,17 +308,16 @@ func (e *usecase) GetToken(clientKey, secretKey string) (key *request.OpenKey, e
 
 	// validation
 	if clientKey == "" || secretKey == "" {
-		return nil, errors.New("empty client or secret key")
+		return nil, apperror.Unauthorized("missing_client_credentials", "empty client or secret key")
 	}
 
 	if helper.ClientKey != clientKey || helper.SecretKey != secretKey {
-		return nil, errors.New("incorrect client or secret key")
+		return nil, apperror.Unauthorized("invalid_client_credentials", "incorrect client or secret key")
 	}
 
 	b1, err := helper.RsaEncrypt([]byte("PARI%" + expTime))
 	if err != nil {
-		helper.CommonLogger().Error(err)
-		panic(err)
+		return nil, apperror.Internal("token_error", "failed generating token").Wrap(err)
 	}
 
 	token := helper.Base64Enc(b1)
@@ -157,3 +330,21 @@ func (e *usecase) GetToken(clientKey, secretKey string) (key *request.OpenKey, e
 
 	return &mod, nil
 }
+
+// companyRole finds the role named name users of the company can hold: one shared by
+// all companies or one of the company.
+func (e *usecase) companyRole(ctx context.Context, name string, companyID int) (*model.Role, error) {
+	return e.roleRepository.ReadBy(ctx, map[string]interface{}{"name": name, "company_id": []int{0, companyID}})
+}
+
+// indexed prefixes the fields of the details of err with the index of the user they are
+// about, like the validation of the whole body does.
+func indexed(err error, i int) error {
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		for j := range appErr.Details {
+			appErr.Details[j].Field = fmt.Sprintf("[%d].%s", i, appErr.Details[j].Field)
+		}
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/credential"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	mock "bitbucket.org/bridce/ms-pari-web/internal/pkg/mock/repository"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/two_factor"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// attempts keeps login attempts in memory.
+type attempts []model.LoginAttempt
+
+func (a *attempts) Create(_ context.Context, attempt *model.LoginAttempt) (*model.LoginAttempt, error) {
+	attempt.CreatedAt = time.Now()
+	*a = append(*a, *attempt)
+	return attempt, nil
+}
+
+func (a *attempts) ReadAllBy(context.Context, *query.Query) (*[]model.LoginAttempt, *query.Page, error) {
+	return (*[]model.LoginAttempt)(a), &query.Page{}, nil
+}
+
+func (a *attempts) CountFailures(_ context.Context, criteria map[string]interface{}, since time.Time) (int, error) {
+	count := 0
+	for _, attempt := range *a {
+		if attempt.IP == criteria["ip"] && !attempt.Success && attempt.Result != enum.LoginTwoFactorPending && !attempt.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (a *attempts) results() []enum.LoginResult {
+	results := make([]enum.LoginResult, 0, len(*a))
+	for _, attempt := range *a {
+		results = append(results, attempt.Result)
+	}
+	return results
+}
+
+// challenger challenges the users whose id it holds.
+type challenger map[int]bool
+
+func (c challenger) Challenge(_ context.Context, u *model.User) (*two_factor.Challenge, error) {
+	if !c[u.ID] {
+		return nil, nil
+	}
+	return &two_factor.Challenge{Token: "challenge"}, nil
+}
+
+func setup(t *testing.T, lockout credential.Lockout) (*usecase, *mock.MockRepository, *attempts) {
+	users := mock.NewMockRepository(gomock.NewController(t))
+	c, err := credential.NewService(credential.DefaultPolicy, bcrypt.MinCost, users, nil)
+	require.NoError(t, err)
+	a := &attempts{}
+	return &usecase{userRepository: users, loginAttemptRepository: a, credential: c, lockout: lockout, challenger: challenger{}}, users, a
+}
+
+func requireCode(t *testing.T, err error, code string) {
+	var appErr *apperror.Error
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, code, appErr.Code)
+}
+
+func TestLoginGenericFailure(t *testing.T) {
+	e, users, a := setup(t, credential.Lockout{Free: 5, Threshold: 5})
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret-pass"), bcrypt.MinCost)
+	require.NoError(t, err)
+	u := &model.User{ID: 7, Email: "budi@example.com", Password: string(hash)}
+
+	users.EXPECT().ReadByEmail(gomock.Any(), "nobody@example.com").Return(nil, apperror.NotFound("user_not_found", "user is not exists"))
+	_, _, unknown := e.Login(context.Background(), request.Login{Email: "nobody@example.com", Password: "secret-pass", IP: "10.0.0.1"})
+
+	users.EXPECT().ReadByEmail(gomock.Any(), u.Email).Return(u, nil)
+	users.EXPECT().FailLogin(gomock.Any(), u.ID).Return(1, nil)
+	_, _, wrong := e.Login(context.Background(), request.Login{Email: u.Email, Password: "wrong-pass", IP: "10.0.0.1"})
+
+	var unknownErr, wrongErr *apperror.Error
+	require.ErrorAs(t, unknown, &unknownErr)
+	require.ErrorAs(t, wrong, &wrongErr)
+	require.Equal(t, unknownErr.Code, wrongErr.Code)
+	require.Equal(t, unknownErr.Message, wrongErr.Message)
+	requireCode(t, wrong, "invalid_credentials")
+	require.Equal(t, []enum.LoginResult{enum.LoginUnknownEmail, enum.LoginWrongPassword}, a.results())
+}
+
+func TestLoginLockout(t *testing.T) {
+	e, users, a := setup(t, credential.Lockout{Free: 1, Delay: time.Minute, Threshold: 3, Duration: time.Hour})
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret-pass"), bcrypt.MinCost)
+	require.NoError(t, err)
+	u := &model.User{ID: 7, Email: "budi@example.com", Password: string(hash)}
+	login := request.Login{Email: u.Email, Password: "wrong-pass", IP: "10.0.0.1"}
+
+	users.EXPECT().ReadByEmail(gomock.Any(), u.Email).Return(u, nil).AnyTimes()
+
+	// the first failure is free
+	users.EXPECT().FailLogin(gomock.Any(), u.ID).Return(1, nil)
+	_, _, err = e.Login(context.Background(), login)
+	requireCode(t, err, "invalid_credentials")
+
+	// the second waits
+	users.EXPECT().FailLogin(gomock.Any(), u.ID).Return(2, nil)
+	users.EXPECT().Lock(gomock.Any(), u.ID, gomock.Any()).DoAndReturn(func(_ context.Context, _ int, until time.Time) error {
+		require.WithinDuration(t, time.Now().Add(time.Minute), until, time.Second)
+		u.FailedLogins = 2
+		u.LockedUntil = &until
+		return nil
+	})
+	_, _, err = e.Login(context.Background(), login)
+	requireCode(t, err, "invalid_credentials")
+
+	// even the right password, failing like an unknown email
+	login.Password = "secret-pass"
+	_, _, err = e.Login(context.Background(), login)
+	requireCode(t, err, "invalid_credentials")
+
+	// until the wait is over, which forgets the failures
+	past := time.Now().Add(-time.Second)
+	u.LockedUntil = &past
+	users.EXPECT().Unlock(gomock.Any(), u.ID).Return(nil)
+	m, _, err := e.Login(context.Background(), login)
+	require.NoError(t, err)
+	require.Equal(t, u, m)
+
+	require.Equal(t, []enum.LoginResult{enum.LoginWrongPassword, enum.LoginWrongPassword, enum.LoginLocked, enum.LoginSucceeded}, a.results())
+	require.True(t, (*a)[3].Success)
+	require.Equal(t, u.ID, (*a)[3].UserID)
+}
+
+func TestLoginIPThrottled(t *testing.T) {
+	e, users, a := setup(t, credential.Lockout{Free: 10, Threshold: 10, IPFailures: 2, IPWindow: time.Minute})
+	users.EXPECT().ReadByEmail(gomock.Any(), gomock.Any()).Return(nil, apperror.NotFound("user_not_found", "user is not exists")).Times(2)
+
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		_, _, err := e.Login(context.Background(), request.Login{Email: email, Password: "secret-pass", IP: "10.0.0.1"})
+		requireCode(t, err, "invalid_credentials")
+	}
+	_, _, err := e.Login(context.Background(), request.Login{Email: "c@example.com", Password: "secret-pass", IP: "10.0.0.1"})
+	requireCode(t, err, "login_throttled")
+
+	// another IP is not
+	users.EXPECT().ReadByEmail(gomock.Any(), gomock.Any()).Return(nil, apperror.NotFound("user_not_found", "user is not exists"))
+	_, _, err = e.Login(context.Background(), request.Login{Email: "c@example.com", Password: "secret-pass", IP: "10.0.0.2"})
+	requireCode(t, err, "invalid_credentials")
+	require.Equal(t, enum.LoginIPThrottled, (*a)[2].Result)
+}
+
+func TestLoginTwoFactorPending(t *testing.T) {
+	e, users, a := setup(t, credential.Lockout{Free: 10, Threshold: 10, IPFailures: 1, IPWindow: time.Minute})
+	e.challenger = challenger{7: true}
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret-pass"), bcrypt.MinCost)
+	require.NoError(t, err)
+	u := &model.User{ID: 7, Email: "budi@example.com", Password: string(hash)}
+	login := request.Login{Email: u.Email, Password: "secret-pass", IP: "10.0.0.1"}
+
+	users.EXPECT().ReadByEmail(gomock.Any(), u.Email).Return(u, nil).Times(2)
+	m, challenge, err := e.Login(context.Background(), login)
+	require.NoError(t, err)
+	require.Equal(t, u, m)
+	require.Equal(t, "challenge", challenge.Token)
+
+	// a pending challenge is not a failure throttling the IP
+	_, challenge, err = e.Login(context.Background(), login)
+	require.NoError(t, err)
+	require.NotNil(t, challenge)
+	require.Equal(t, []enum.LoginResult{enum.LoginTwoFactorPending, enum.LoginTwoFactorPending}, a.results())
+	require.False(t, (*a)[0].Success)
+}
@@ -0,0 +1,78 @@
+package commodity
+
+import (
+	"context"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/commodity"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+)
+
+type Usecase interface {
+	Create(ctx context.Context, commodity *request.Commodity) (*model.Commodity, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Commodity, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.Commodity, error)
+	Update(ctx context.Context, id, version int, commodity *request.Commodity) (*model.Commodity, error)
+	Delete(ctx context.Context, id int) error
+}
+
+type usecase struct {
+	repository        commodity.Repository
+	productRepository product.Repository
+}
+
+func NewUsecase(repository commodity.Repository, productRepository product.Repository) Usecase {
+	return &usecase{repository, productRepository}
+}
+
+func (e *usecase) Create(ctx context.Context, commodity *request.Commodity) (*model.Commodity, error) {
+	return e.repository.Create(ctx, newCommodity(commodity))
+}
+
+func (e *usecase) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Commodity, *query.Page, error) {
+	return e.repository.ReadAllBy(ctx, q)
+}
+
+func (e *usecase) ReadById(ctx context.Context, id int) (*model.Commodity, error) {
+	return e.repository.ReadById(ctx, id)
+}
+
+// Update replaces a commodity. A version of 0 skips the If-Match check but the update
+// still fails if the commodity changes meanwhile.
+func (e *usecase) Update(ctx context.Context, id, version int, commodity *request.Commodity) (*model.Commodity, error) {
+	current, err := e.repository.ReadById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != current.Version {
+		return nil, apperror.VersionMismatch("commodity")
+	}
+	return e.repository.Update(ctx, id, current.Version, newCommodity(commodity))
+}
+
+// Delete removes a commodity no product refers to. Commodities in use can be
+// deactivated instead, which keeps them off new products.
+func (e *usecase) Delete(ctx context.Context, id int) error {
+	if _, err := e.repository.ReadById(ctx, id); err != nil {
+		return err
+	}
+	if e.productRepository.Count(ctx, map[string]interface{}{"commodity_id": id}) > 0 {
+		return apperror.Conflict("commodity_in_use", "commodity is used by products, deactivate it instead")
+	}
+	return e.repository.Delete(ctx, id)
+}
+
+func newCommodity(commodity *request.Commodity) *model.Commodity {
+	return &model.Commodity{
+		Code:         commodity.Code,
+		Name:         commodity.Name,
+		Category:     commodity.Category,
+		UnitQuantity: commodity.UnitQuantity,
+		UnitPrice:    commodity.UnitPrice,
+		PariCode:     commodity.PariCode,
+		IsActive:     commodity.IsActive,
+	}
+}
@@ -1,42 +1,125 @@
 package company
 
 import (
+	"context"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/patch"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/company"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/giro"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
 )
 
 type Usecase interface {
-	Create(company *model.Company) (*model.Company, error)
-	ReadAll() (*[]model.Company, error)
-	ReadById(id int) (*model.Company, error)
-	Update(id int, company *model.Company) (*model.Company, error)
-	Delete(id int) error
+	Create(ctx context.Context, company *request.Company) (*model.Company, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Company, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.Company, error)
+	Update(ctx context.Context, id int, company *request.Company) (*model.Company, error)
+	Patch(ctx context.Context, id, version int, doc patch.Document) (*model.Company, error)
+	Delete(ctx context.Context, id int) error
 }
 
 type usecase struct {
-	repository company.Repository
+	repository     company.Repository
+	giroRepository giro.Repository
+}
+
+func NewUsecase(repository company.Repository, giroRepository giro.Repository) Usecase {
+	return &usecase{repository, giroRepository}
+}
+
+func (e *usecase) Create(ctx context.Context, company *request.Company) (*model.Company, error) {
+	m := newCompany(company)
+	giroID, err := e.giroID(ctx, company.Giro, 0)
+	if err != nil {
+		return nil, err
+	}
+	m.GiroID = giroID
+	return e.repository.Create(ctx, m)
 }
 
-func NewUsecase(repository company.Repository) Usecase {
-	return &usecase{repository}
+func (e *usecase) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Company, *query.Page, error) {
+	return e.repository.ReadAllBy(ctx, q)
 }
 
-func (e *usecase) Create(company *model.Company) (*model.Company, error) {
-	return e.repository.Create(company)
+func (e *usecase) ReadById(ctx context.Context, id int) (*model.Company, error) {
+	return e.repository.ReadById(ctx, id)
 }
 
-func (e *usecase) ReadAll() (*[]model.Company, error) {
-	return e.repository.ReadAll()
+func (e *usecase) Update(ctx context.Context, id int, company *request.Company) (*model.Company, error) {
+	m := newCompany(company)
+	giroID, err := e.giroID(ctx, company.Giro, id)
+	if err != nil {
+		return nil, err
+	}
+	m.GiroID = giroID
+	return e.repository.Update(ctx, id, m)
+}
+
+func (e *usecase) Patch(ctx context.Context, id, version int, doc patch.Document) (*model.Company, error) {
+	current, err := e.repository.ReadById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != current.Version {
+		return nil, apperror.VersionMismatch("company")
+	}
+
+	dto := request.Company{
+		Name:    current.Name,
+		Code:    current.Code,
+		Address: current.Address,
+		Alias:   current.Alias,
+		Giro:    current.Giro,
+	}
+	fields, err := doc.Apply(&dto)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return current, nil
+	}
+	if _, ok := fields["giro"]; ok {
+		if fields["giro_id"], err = e.giroID(ctx, dto.Giro, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.repository.Patch(ctx, id, current.Version, fields)
 }
 
-func (e *usecase) ReadById(id int) (*model.Company, error) {
-	return e.repository.ReadById(id)
+func (e *usecase) Delete(ctx context.Context, id int) error {
+	return e.repository.Delete(ctx, id)
 }
 
-func (e *usecase) Update(id int, company *model.Company) (*model.Company, error) {
-	return e.repository.Update(id, company)
+// giroID is the id of the giro account with code, nil if there is none. A giro
+// account belongs to a single company, companyID being the one it is given to.
+func (e *usecase) giroID(ctx context.Context, code string, companyID int) (*int, error) {
+	g, err := e.giroRepository.ReadByCode(ctx, code)
+	if apperror.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	linked, err := e.repository.ReadBy(ctx, map[string]interface{}{"giro_id": g.ID})
+	if err != nil && !apperror.IsNotFound(err) {
+		return nil, err
+	}
+	if linked != nil && linked.ID != companyID {
+		return nil, apperror.Conflict("giro_in_use", "giro belongs to another company")
+	}
+	return &g.ID, nil
 }
 
-func (e *usecase) Delete(id int) error {
-	return e.repository.Delete(id)
+func newCompany(company *request.Company) *model.Company {
+	return &model.Company{
+		Name:    company.Name,
+		Code:    company.Code,
+		Address: company.Address,
+		Alias:   company.Alias,
+		Giro:    company.Giro,
+	}
 }
@@ -0,0 +1,134 @@
+package giro
+
+import (
+	"context"
+	"fmt"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/company"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/giro"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+)
+
+type Usecase interface {
+	Create(ctx context.Context, giro *request.Giro) (*model.Giro, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Giro, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.Giro, error)
+	Update(ctx context.Context, id, version int, giro *request.Giro) (*model.Giro, error)
+	Delete(ctx context.Context, id int) error
+	Import(ctx context.Context, giros request.Giros) (created, updated int, err error)
+}
+
+type usecase struct {
+	repository        giro.Repository
+	companyRepository company.Repository
+}
+
+func NewUsecase(repository giro.Repository, companyRepository company.Repository) Usecase {
+	return &usecase{repository, companyRepository}
+}
+
+func (e *usecase) Create(ctx context.Context, giro *request.Giro) (*model.Giro, error) {
+	return e.repository.Create(ctx, newGiro(giro))
+}
+
+func (e *usecase) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Giro, *query.Page, error) {
+	return e.repository.ReadAllBy(ctx, q)
+}
+
+// ReadById finds a giro along with the company onboarded with it.
+func (e *usecase) ReadById(ctx context.Context, id int) (*model.Giro, error) {
+	g, err := e.repository.ReadById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if g.Company, err = e.company(ctx, id); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Update replaces a giro. The code of a giro that onboarded a company stays as it is,
+// deactivating it keeps it from onboarding another one. A version of 0 skips the
+// If-Match check but the update still fails if the giro changes meanwhile.
+func (e *usecase) Update(ctx context.Context, id, version int, giro *request.Giro) (*model.Giro, error) {
+	current, err := e.repository.ReadById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != current.Version {
+		return nil, apperror.VersionMismatch("giro")
+	}
+	if giro.Code != current.Code {
+		c, err := e.company(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			return nil, apperror.Conflict("giro_in_use", fmt.Sprintf("giro onboarded company %d, its code cannot change", c.ID))
+		}
+	}
+	return e.repository.Update(ctx, id, current.Version, newGiro(giro))
+}
+
+// Delete removes a giro that onboarded no company. Giros in use can be deactivated
+// instead.
+func (e *usecase) Delete(ctx context.Context, id int) error {
+	if _, err := e.repository.ReadById(ctx, id); err != nil {
+		return err
+	}
+	c, err := e.company(ctx, id)
+	if err != nil {
+		return err
+	}
+	if c != nil {
+		return apperror.Conflict("giro_in_use", "giro onboarded a company, deactivate it instead")
+	}
+	return e.repository.Delete(ctx, id)
+}
+
+// Import creates the giros whose code is new and updates the others, all or none of
+// them. A code may appear once.
+func (e *usecase) Import(ctx context.Context, giros request.Giros) (created, updated int, err error) {
+	seen := make(map[string]int, len(giros))
+	var details []apperror.FieldError
+	models := make([]model.Giro, 0, len(giros))
+	for i := range giros {
+		if first, ok := seen[giros[i].Code]; ok {
+			details = append(details, apperror.FieldError{
+				Field:   fmt.Sprintf("[%d].code", i),
+				Message: fmt.Sprintf("is already at [%d]", first),
+			})
+			continue
+		}
+		seen[giros[i].Code] = i
+		models = append(models, *newGiro(&giros[i]))
+	}
+	if details != nil {
+		return 0, 0, apperror.Validation("validation_error", "request validation failed").WithDetails(details...)
+	}
+	return e.repository.Import(ctx, models)
+}
+
+// company is the company onboarded with the giro id, nil if there is none.
+func (e *usecase) company(ctx context.Context, id int) (*model.Company, error) {
+	c, err := e.companyRepository.ReadBy(ctx, map[string]interface{}{"giro_id": id})
+	if apperror.IsNotFound(err) {
+		return nil, nil
+	}
+	return c, err
+}
+
+func newGiro(giro *request.Giro) *model.Giro {
+	isActive := true
+	if giro.IsActive != nil {
+		isActive = *giro.IsActive
+	}
+	return &model.Giro{
+		Code:        giro.Code,
+		CompanyName: giro.CompanyName,
+		IsActive:    isActive,
+	}
+}
@@ -0,0 +1,249 @@
+package invitation
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/actor"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/credential"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/mailer"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/company"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/invitation"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+)
+
+type Usecase interface {
+	Invite(ctx context.Context, inviterID int, invitation *request.Invitation) (*model.Invitation, error)
+	ReadAllBy(ctx context.Context, inviterID int, q *query.Query) (*[]model.Invitation, *query.Page, error)
+	Revoke(ctx context.Context, inviterID, id int) (*model.Invitation, error)
+	ReadByToken(ctx context.Context, token string) (*model.Invitation, error)
+	Accept(ctx context.Context, acceptance request.InvitationAcceptance) (*model.User, error)
+}
+
+type usecase struct {
+	invitationRepository invitation.Repository
+	userRepository       user.Repository
+	roleRepository       role.Repository
+	companyRepository    company.Repository
+	credential           credential.Service
+	mailer               mailer.Mailer
+	ttl                  time.Duration
+	link                 string
+}
+
+// NewUsecase sends invitations through m, valid for ttl, linking to link with the
+// token as its token query parameter.
+func NewUsecase(invitationRepository invitation.Repository, userRepository user.Repository, roleRepository role.Repository, companyRepository company.Repository, credential credential.Service, m mailer.Mailer, ttl time.Duration, link string) Usecase {
+	return &usecase{invitationRepository, userRepository, roleRepository, companyRepository, credential, m, ttl, link}
+}
+
+// Invite sends a link to register with the role and company of the invitation. An
+// invitation still pending for the email is revoked.
+func (e *usecase) Invite(ctx context.Context, inviterID int, r *request.Invitation) (*model.Invitation, error) {
+	inviter, err := actor.Load(ctx, e.userRepository, e.roleRepository, inviterID)
+	if err != nil {
+		return nil, err
+	}
+	if !inviter.Sees(r.CompanyID) {
+		return nil, apperror.Forbidden("invitation_forbidden", "admins invite to their own company only")
+	}
+	ro, err := e.roleRepository.ReadById(ctx, r.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	if !inviter.Superadmin && ro.IsSuperadmin() {
+		return nil, apperror.Forbidden("invitation_forbidden", "only superadmins invite superadmins")
+	}
+	if !ro.AvailableTo(r.CompanyID) {
+		return nil, apperror.Validation("validation_error", "request validation failed").WithDetails(apperror.FieldError{
+			Field:   "role_id",
+			Message: "role belongs to another company",
+		})
+	}
+	c, err := e.companyRepository.ReadById(ctx, r.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.userRepository.ReadByEmail(ctx, r.Email); err == nil {
+		return nil, apperror.Conflict("email_registered", "email is already registered")
+	} else if !apperror.IsNotFound(err) {
+		return nil, err
+	}
+
+	if pending, err := e.invitationRepository.ReadBy(ctx, map[string]interface{}{"email": r.Email, "status": enum.InvitationPending}); err == nil {
+		if _, err := e.revoke(ctx, pending); err != nil {
+			return nil, err
+		}
+	} else if !apperror.IsNotFound(err) {
+		return nil, err
+	}
+
+	token, err := helper.NewSecretToken()
+	if err != nil {
+		return nil, apperror.Internal("token_error", "failed generating token").Wrap(err)
+	}
+	inv, err := e.invitationRepository.Create(ctx, &model.Invitation{
+		Email:             r.Email,
+		RoleID:            ro.ID,
+		CompanyID:         c.ID,
+		VerificationLevel: r.VerificationLevel,
+		TokenHash:         helper.HashToken(token),
+		Status:            enum.InvitationPending,
+		InvitedBy:         inviter.ID,
+		ExpiresAt:         time.Now().Add(e.ttl),
+	})
+	if err != nil {
+		return nil, err
+	}
+	inv.RoleName = ro.Name
+	inv.CompanyName = c.Name
+
+	if err := e.mailer.Send(ctx, e.message(inv, inviter.User, token)); err != nil {
+		helper.Logger(ctx).WithError(err).Error("[invitationUsecase.Invite] failed sending invitation")
+		if _, err := e.revoke(ctx, inv); err != nil {
+			helper.Logger(ctx).WithError(err).Error("[invitationUsecase.Invite] failed revoking unsent invitation")
+		}
+		return nil, apperror.Upstream("mail_failed", "failed sending the invitation email").Wrap(err)
+	}
+	return inv, nil
+}
+
+func (e *usecase) message(inv *model.Invitation, inviter *model.User, token string) mailer.Message {
+	link := e.link + "?token=" + url.QueryEscape(token)
+	return mailer.Message{
+		To:      inv.Email,
+		Subject: fmt.Sprintf("You are invited to join %s on PARI Korporat", inv.CompanyName),
+		Body: fmt.Sprintf("%s invited you to join %s on PARI Korporat as %s.\n\n"+
+			"Set your password to activate your account:\n%s\n\n"+
+			"The link can be used once and expires on %s.\n",
+			inviter.Name, inv.CompanyName, inv.RoleName, link, inv.ExpiresAt.Format("2 January 2006 15:04 MST")),
+	}
+}
+
+// ReadAllBy finds the invitations, those to their own company for admins other than
+// superadmins.
+func (e *usecase) ReadAllBy(ctx context.Context, inviterID int, q *query.Query) (*[]model.Invitation, *query.Page, error) {
+	inviter, err := actor.Load(ctx, e.userRepository, e.roleRepository, inviterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !inviter.Superadmin {
+		q.Where("company_id", inviter.CompanyID)
+	}
+	return e.invitationRepository.ReadAllBy(ctx, q)
+}
+
+// Revoke makes the link of a pending invitation unusable.
+func (e *usecase) Revoke(ctx context.Context, inviterID, id int) (*model.Invitation, error) {
+	inviter, err := actor.Load(ctx, e.userRepository, e.roleRepository, inviterID)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := e.invitationRepository.ReadById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !inviter.Sees(inv.CompanyID) {
+		return nil, apperror.NotFound("invitation_not_found", "invitation is not exists")
+	}
+	if inv.Status != enum.InvitationPending {
+		return nil, apperror.Conflict("invitation_"+string(inv.Status), fmt.Sprintf("invitation was already %s", inv.Status))
+	}
+	return e.revoke(ctx, inv)
+}
+
+func (e *usecase) revoke(ctx context.Context, inv *model.Invitation) (*model.Invitation, error) {
+	return e.invitationRepository.Patch(ctx, inv.ID, enum.InvitationPending, map[string]interface{}{
+		"status":     enum.InvitationRevoked,
+		"revoked_at": time.Now(),
+	})
+}
+
+// ReadByToken finds the invitation a link is for, as long as it can be accepted.
+func (e *usecase) ReadByToken(ctx context.Context, token string) (*model.Invitation, error) {
+	inv, err := e.usable(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	ro, err := e.roleRepository.ReadById(ctx, inv.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	c, err := e.companyRepository.ReadById(ctx, inv.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+	inv.RoleName = ro.Name
+	inv.CompanyName = c.Name
+	return inv, nil
+}
+
+// Accept registers the invitee with the password they chose. The user is returned with
+// their role name.
+func (e *usecase) Accept(ctx context.Context, a request.InvitationAcceptance) (*model.User, error) {
+	inv, err := e.ReadByToken(ctx, a.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := e.credential.Hash(a.Password)
+	if err != nil {
+		return nil, err
+	}
+	u, err := e.userRepository.Create(ctx, &model.User{
+		RoleID:            inv.RoleID,
+		CompanyID:         inv.CompanyID,
+		Name:              a.Name,
+		Email:             inv.Email,
+		VerificationLevel: inv.VerificationLevel,
+		Password:          password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the invitee chose the password, there is nothing to change
+	u, err = e.userRepository.Patch(ctx, u.ID, u.Version, map[string]interface{}{"must_change_password": false})
+	if err != nil {
+		return nil, err
+	}
+	_, err = e.invitationRepository.Patch(ctx, inv.ID, enum.InvitationPending, map[string]interface{}{
+		"status":      enum.InvitationAccepted,
+		"user_id":     u.ID,
+		"accepted_at": time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.Password = ""
+	u.RoleName = inv.RoleName
+	u.CompanyName = inv.CompanyName
+	return u, nil
+}
+
+// usable is the invitation of token if it is pending and not expired.
+func (e *usecase) usable(ctx context.Context, token string) (*model.Invitation, error) {
+	inv, err := e.invitationRepository.ReadBy(ctx, map[string]interface{}{"token_hash": helper.HashToken(token)})
+	if apperror.IsNotFound(err) {
+		return nil, apperror.NotFound("invitation_not_found", "invitation link is invalid").Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case inv.Status != enum.InvitationPending:
+		return nil, apperror.Conflict("invitation_"+string(inv.Status), fmt.Sprintf("invitation was already %s", inv.Status))
+	case inv.Expired(time.Now()):
+		return nil, apperror.Conflict("invitation_expired", "invitation expired, ask for a new one")
+	}
+	return inv, nil
+}
@@ -0,0 +1,29 @@
+package invitation
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessage(t *testing.T) {
+	e := &usecase{link: "https://pari.example.com/invitation"}
+	inv := &model.Invitation{
+		Email:       "alex@example.com",
+		RoleName:    "verificator",
+		CompanyName: "PT Tani Makmur",
+		ExpiresAt:   time.Date(2026, 1, 2, 15, 4, 0, 0, time.UTC),
+	}
+
+	m := e.message(inv, &model.User{Name: "Budi"}, "a+b/c")
+
+	require.Equal(t, "alex@example.com", m.To)
+	require.Equal(t, "You are invited to join PT Tani Makmur on PARI Korporat", m.Subject)
+	require.Contains(t, m.Body, "Budi invited you to join PT Tani Makmur on PARI Korporat as verificator.")
+	require.Contains(t, m.Body, "https://pari.example.com/invitation?token="+url.QueryEscape("a+b/c")+"\n")
+	require.True(t, strings.HasSuffix(m.Body, "expires on 2 January 2026 15:04 UTC.\n"))
+}
@@ -0,0 +1,172 @@
+package onboarding
+
+import (
+	"context"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/credential"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/company"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/giro"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/onboarding_step"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+)
+
+type Usecase interface {
+	Register(ctx context.Context, onboarding request.Onboarding) (*model.Company, *model.User, error)
+	Steps(ctx context.Context, companyID int, q *query.Query) (*[]model.OnboardingStep, *query.Page, error)
+	Approve(ctx context.Context, companyID, reviewerID int) (*model.Company, error)
+	Reject(ctx context.Context, companyID, reviewerID int, reason string) (*model.Company, error)
+}
+
+type usecase struct {
+	giroRepository           giro.Repository
+	companyRepository        company.Repository
+	userRepository           user.Repository
+	roleRepository           role.Repository
+	onboardingStepRepository onboarding_step.Repository
+	credential               credential.Service
+}
+
+func NewUsecase(giroRepository giro.Repository, companyRepository company.Repository, userRepository user.Repository, roleRepository role.Repository, onboardingStepRepository onboarding_step.Repository, credential credential.Service) Usecase {
+	return &usecase{giroRepository, companyRepository, userRepository, roleRepository, onboardingStepRepository, credential}
+}
+
+// Register signs up the company of a giro account. The company is created pending from
+// the giro the first time, and the first user registered for it becomes its admin.
+func (e *usecase) Register(ctx context.Context, o request.Onboarding) (*model.Company, *model.User, error) {
+	password, err := e.credential.Hash(o.Password)
+	if err != nil {
+		return nil, nil, err
+	}
+	g, err := e.giroRepository.ReadByCode(ctx, o.GiroCode)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !g.IsActive {
+		return nil, nil, apperror.Forbidden("giro_inactive", "giro is not active")
+	}
+
+	c, err := e.companyRepository.ReadBy(ctx, map[string]interface{}{"giro_id": g.ID})
+	if apperror.IsNotFound(err) {
+		c, err = e.createCompany(ctx, g)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.Status == enum.CompanyRejected {
+		return nil, nil, apperror.Conflict("company_rejected", "company of the giro was rejected")
+	}
+	if e.userRepository.Count(ctx, map[string]interface{}{"company_id": c.ID}) > 0 {
+		return nil, nil, apperror.Conflict("company_registered", "company of the giro is already registered, ask its admin for an account")
+	}
+
+	r, err := e.roleRepository.ReadByName(ctx, enum.RoleCompanyAdmin)
+	if err != nil {
+		return nil, nil, err
+	}
+	admin, err := e.userRepository.Create(ctx, &model.User{
+		RoleID:    r.ID,
+		CompanyID: c.ID,
+		Name:      o.Name,
+		Email:     o.Email,
+		Password:  password,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// the admin chose the password, there is nothing to change
+	admin, err = e.userRepository.Patch(ctx, admin.ID, admin.Version, map[string]interface{}{"must_change_password": false})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := e.track(ctx, c, enum.StepAdminRegistered, admin.ID, ""); err != nil {
+		return nil, nil, err
+	}
+
+	admin.Password = ""
+	admin.RoleName = r.Name
+	admin.CompanyName = c.Name
+	return c, admin, nil
+}
+
+func (e *usecase) createCompany(ctx context.Context, g *model.Giro) (*model.Company, error) {
+	c, err := e.companyRepository.Create(ctx, &model.Company{
+		Name:   g.CompanyName,
+		Code:   g.Code,
+		Giro:   g.Code,
+		GiroID: &g.ID,
+		Status: enum.CompanyPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := e.track(ctx, c, enum.StepGiroValidated, 0, g.Code); err != nil {
+		return nil, err
+	}
+	if err := e.track(ctx, c, enum.StepCompanyCreated, 0, ""); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Steps are the onboarding steps a company took.
+func (e *usecase) Steps(ctx context.Context, companyID int, q *query.Query) (*[]model.OnboardingStep, *query.Page, error) {
+	if _, err := e.companyRepository.ReadById(ctx, companyID); err != nil {
+		return nil, nil, err
+	}
+	q.Where("company_id", companyID)
+	return e.onboardingStepRepository.ReadAllBy(ctx, q)
+}
+
+// Approve lets the users of a pending company log in.
+func (e *usecase) Approve(ctx context.Context, companyID, reviewerID int) (*model.Company, error) {
+	return e.review(ctx, companyID, reviewerID, enum.CompanyApproved, "")
+}
+
+// Reject turns down a pending company, keeping the reason.
+func (e *usecase) Reject(ctx context.Context, companyID, reviewerID int, reason string) (*model.Company, error) {
+	return e.review(ctx, companyID, reviewerID, enum.CompanyRejected, reason)
+}
+
+func (e *usecase) review(ctx context.Context, companyID, reviewerID int, status enum.StatusCompany, reason string) (*model.Company, error) {
+	c, err := e.companyRepository.ReadById(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != enum.CompanyPending {
+		return nil, apperror.Conflict("company_reviewed", "company is not waiting for approval")
+	}
+
+	c, err = e.companyRepository.Patch(ctx, c.ID, c.Version, map[string]interface{}{
+		"status":        status,
+		"reject_reason": reason,
+		"reviewed_by":   reviewerID,
+		"reviewed_at":   time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	step := enum.StepCompanyApproved
+	if status == enum.CompanyRejected {
+		step = enum.StepCompanyRejected
+	}
+	if err := e.track(ctx, c, step, reviewerID, reason); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (e *usecase) track(ctx context.Context, c *model.Company, step enum.OnboardingStep, userID int, note string) error {
+	s := &model.OnboardingStep{CompanyID: c.ID, Step: step, UserID: userID, Note: note}
+	if c.GiroID != nil {
+		s.GiroID = *c.GiroID
+	}
+	_, err := e.onboardingStepRepository.Create(ctx, s)
+	return err
+}
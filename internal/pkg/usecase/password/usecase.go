@@ -0,0 +1,142 @@
+package password
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/credential"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/mailer"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/password_reset"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+)
+
+// maxResetsPerHour caps the links sent to one user, whoever asks for them.
+const maxResetsPerHour = 3
+
+type Usecase interface {
+	Forgot(ctx context.Context, email string)
+	Reset(ctx context.Context, reset request.PasswordReset) error
+}
+
+type usecase struct {
+	passwordResetRepository password_reset.Repository
+	userRepository          user.Repository
+	credential              credential.Service
+	mailer                  mailer.Mailer
+	ttl                     time.Duration
+	link                    string
+}
+
+// NewUsecase sends reset links through m, valid for ttl, linking to link with the token
+// as its token query parameter.
+func NewUsecase(passwordResetRepository password_reset.Repository, userRepository user.Repository, credential credential.Service, m mailer.Mailer, ttl time.Duration, link string) Usecase {
+	return &usecase{passwordResetRepository, userRepository, credential, m, ttl, link}
+}
+
+// Forgot sends a reset link to the user of email, if there is one. It returns right
+// away and the same way whether there is, so the caller cannot tell.
+func (e *usecase) Forgot(ctx context.Context, email string) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := e.forgot(ctx, email); err != nil {
+			helper.Logger(ctx).WithError(err).Error("[passwordUsecase.Forgot] failed sending reset link")
+		}
+	}()
+}
+
+func (e *usecase) forgot(ctx context.Context, email string) error {
+	u, err := e.userRepository.ReadByEmail(ctx, email)
+	// service accounts never get a password
+	if apperror.IsNotFound(err) || (err == nil && u.ServiceAccount) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	count, err := e.passwordResetRepository.CountSince(ctx, u.ID, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if count >= maxResetsPerHour {
+		helper.Logger(ctx).WithField("user_id", u.ID).Warn("[passwordUsecase.Forgot] too many reset links requested")
+		return nil
+	}
+
+	token, err := helper.NewSecretToken()
+	if err != nil {
+		return err
+	}
+	// only the latest link can be used
+	if err := e.passwordResetRepository.ExpireAll(ctx, u.ID); err != nil {
+		return err
+	}
+	reset, err := e.passwordResetRepository.Create(ctx, &model.PasswordReset{
+		UserID:    u.ID,
+		TokenHash: helper.HashToken(token),
+		ExpiresAt: now.Add(e.ttl),
+	})
+	if err != nil {
+		return err
+	}
+	return e.mailer.Send(ctx, e.message(u, reset, token))
+}
+
+func (e *usecase) message(u *model.User, reset *model.PasswordReset, token string) mailer.Message {
+	link := e.link + "?token=" + url.QueryEscape(token)
+	return mailer.Message{
+		To:      u.Email,
+		Subject: "Reset your PARI Korporat password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Someone asked to reset the password of your PARI Korporat account. Set a new one here:\n%s\n\n"+
+			"The link can be used once and expires on %s. If you did not ask for it, ignore this email.\n",
+			u.Name, link, reset.ExpiresAt.Format("2 January 2006 15:04 MST")),
+	}
+}
+
+// Reset sets the password of the user the token was sent to and revokes their sessions.
+// Every token that cannot be used fails the same way.
+func (e *usecase) Reset(ctx context.Context, r request.PasswordReset) error {
+	invalid := apperror.BadRequest("invalid_token", "reset link is invalid or expired")
+
+	reset, err := e.passwordResetRepository.ReadBy(ctx, map[string]interface{}{"token_hash": helper.HashToken(r.Token)})
+	if apperror.IsNotFound(err) {
+		return invalid.Wrap(err)
+	}
+	if err != nil {
+		return err
+	}
+	if reset.UsedAt != nil || reset.Expired(time.Now()) {
+		return invalid
+	}
+	u, err := e.userRepository.ReadById(ctx, reset.UserID)
+	if apperror.IsNotFound(err) {
+		return invalid.Wrap(err)
+	}
+	if err != nil {
+		return err
+	}
+	// a password the policy rejects leaves the token usable for another try
+	password, err := e.credential.Change(ctx, u, r.Password)
+	if err != nil {
+		return err
+	}
+	u.Password = password
+	u.MustChangePassword = false
+	// the token is used up with the password set, so a failed update leaves it usable
+	// for another try and concurrent resets with it set one password
+	if _, err := e.userRepository.ResetPassword(ctx, u, reset.ID); err != nil {
+		if apperror.Is(err, apperror.KindConflict) {
+			return invalid.Wrap(err)
+		}
+		return err
+	}
+	return e.passwordResetRepository.ExpireAll(ctx, u.ID)
+}
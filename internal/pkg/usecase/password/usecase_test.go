@@ -0,0 +1,193 @@
+package password
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/credential"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/mailer"
+	mock "bitbucket.org/bridce/ms-pari-web/internal/pkg/mock/repository"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// resets keeps password resets in memory.
+type resets struct {
+	rows []*model.PasswordReset
+}
+
+func (r *resets) Create(_ context.Context, reset *model.PasswordReset) (*model.PasswordReset, error) {
+	reset.ID = len(r.rows) + 1
+	reset.CreatedAt = time.Now()
+	r.rows = append(r.rows, reset)
+	return reset, nil
+}
+
+func (r *resets) ReadBy(_ context.Context, criteria map[string]interface{}) (*model.PasswordReset, error) {
+	for _, row := range r.rows {
+		if row.TokenHash == criteria["token_hash"] {
+			found := *row
+			return &found, nil
+		}
+	}
+	return nil, apperror.NotFound("password_reset_not_found", "password_reset is not exists")
+}
+
+func (r *resets) CountSince(_ context.Context, userID int, since time.Time) (int, error) {
+	count := 0
+	for _, row := range r.rows {
+		if row.UserID == userID && !row.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// resetPassword marks the reset used as users.ResetPassword does with the password set.
+func (r *resets) resetPassword(_ context.Context, u *model.User, resetID int) (*model.User, error) {
+	row := r.rows[resetID-1]
+	if row.UsedAt != nil {
+		return nil, apperror.Conflict("password_reset_used", "password reset was used meanwhile")
+	}
+	now := time.Now()
+	row.UsedAt = &now
+	return u, nil
+}
+
+func (r *resets) ExpireAll(_ context.Context, userID int) error {
+	now := time.Now()
+	for _, row := range r.rows {
+		if row.UserID == userID && row.UsedAt == nil && row.ExpiresAt.After(now) {
+			row.ExpiresAt = now
+		}
+	}
+	return nil
+}
+
+func setup(t *testing.T) (*usecase, *mock.MockRepository, *resets, *mailer.Memory) {
+	viper.Set("JWT_SECRET", "test-secret")
+	t.Cleanup(func() { viper.Set("JWT_SECRET", nil) })
+
+	users := mock.NewMockRepository(gomock.NewController(t))
+	c, err := credential.NewService(credential.Policy{MinLength: 8, MinClasses: 2}, bcrypt.MinCost, users, nil)
+	require.NoError(t, err)
+	r := &resets{}
+	m := &mailer.Memory{}
+	return &usecase{r, users, c, m, time.Hour, "https://pari.example.com/password/reset"}, users, r, m
+}
+
+func tokenOf(t *testing.T, m mailer.Message) string {
+	i := strings.Index(m.Body, "?token=")
+	require.NotEqual(t, -1, i)
+	token, err := url.QueryUnescape(strings.Fields(m.Body[i+len("?token="):])[0])
+	require.NoError(t, err)
+	return token
+}
+
+func TestForgotUnknownEmail(t *testing.T) {
+	e, users, r, m := setup(t)
+	users.EXPECT().ReadByEmail(gomock.Any(), "nobody@example.com").
+		Return(nil, apperror.NotFound("user_not_found", "user is not exists"))
+
+	require.NoError(t, e.forgot(context.Background(), "nobody@example.com"))
+	require.Empty(t, r.rows)
+	require.Empty(t, m.Messages())
+}
+
+func TestForgotLimitsLinks(t *testing.T) {
+	e, users, r, m := setup(t)
+	u := &model.User{ID: 7, Name: "Budi", Email: "budi@example.com"}
+	users.EXPECT().ReadByEmail(gomock.Any(), u.Email).Return(u, nil).Times(maxResetsPerHour + 1)
+
+	for i := 0; i <= maxResetsPerHour; i++ {
+		require.NoError(t, e.forgot(context.Background(), u.Email))
+	}
+	require.Len(t, r.rows, maxResetsPerHour)
+	require.Len(t, m.Messages(), maxResetsPerHour)
+	require.Equal(t, u.Email, m.Messages()[0].To)
+
+	// only the latest link is left usable
+	for _, row := range r.rows[:maxResetsPerHour-1] {
+		require.True(t, row.Expired(time.Now()))
+	}
+	require.False(t, r.rows[maxResetsPerHour-1].Expired(time.Now()))
+	require.Equal(t, helper.HashToken(tokenOf(t, m.Messages()[maxResetsPerHour-1])), r.rows[maxResetsPerHour-1].TokenHash)
+}
+
+func TestReset(t *testing.T) {
+	e, users, r, m := setup(t)
+	u := &model.User{ID: 7, Name: "Budi", Email: "budi@example.com", MustChangePassword: true}
+	users.EXPECT().ReadByEmail(gomock.Any(), u.Email).Return(u, nil)
+	users.EXPECT().ReadById(gomock.Any(), u.ID).Return(u, nil).Times(2)
+	users.EXPECT().ResetPassword(gomock.Any(), gomock.Any(), 1).DoAndReturn(func(ctx context.Context, updated *model.User, resetID int) (*model.User, error) {
+		require.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("n3w-password")))
+		require.False(t, updated.MustChangePassword)
+		return r.resetPassword(ctx, updated, resetID)
+	})
+
+	require.NoError(t, e.forgot(context.Background(), u.Email))
+	token := tokenOf(t, m.Messages()[0])
+
+	// a password the policy rejects does not use the token up
+	err := e.Reset(context.Background(), request.PasswordReset{Token: token, Password: "password"})
+	var appErr *apperror.Error
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, "weak_password", appErr.Code)
+
+	require.NoError(t, e.Reset(context.Background(), request.PasswordReset{Token: token, Password: "n3w-password"}))
+
+	// a used token fails like an unknown one
+	for _, token := range []string{token, "unknown"} {
+		err := e.Reset(context.Background(), request.PasswordReset{Token: token, Password: "n3w-password"})
+		var appErr *apperror.Error
+		require.ErrorAs(t, err, &appErr)
+		require.Equal(t, "invalid_token", appErr.Code)
+	}
+}
+
+func TestResetFailedUpdateKeepsToken(t *testing.T) {
+	e, users, r, m := setup(t)
+	u := &model.User{ID: 7, Name: "Budi", Email: "budi@example.com"}
+	users.EXPECT().ReadByEmail(gomock.Any(), u.Email).Return(u, nil)
+	users.EXPECT().ReadById(gomock.Any(), u.ID).Return(u, nil).Times(2)
+	gomock.InOrder(
+		users.EXPECT().ResetPassword(gomock.Any(), gomock.Any(), 1).Return(nil, apperror.Internal("user", "failed update data")),
+		users.EXPECT().ResetPassword(gomock.Any(), gomock.Any(), 1).DoAndReturn(r.resetPassword),
+	)
+
+	require.NoError(t, e.forgot(context.Background(), u.Email))
+	token := tokenOf(t, m.Messages()[0])
+
+	err := e.Reset(context.Background(), request.PasswordReset{Token: token, Password: "n3w-password"})
+	require.True(t, apperror.Is(err, apperror.KindInternal))
+	require.Nil(t, r.rows[0].UsedAt)
+
+	require.NoError(t, e.Reset(context.Background(), request.PasswordReset{Token: token, Password: "n3w-password"}))
+	require.NotNil(t, r.rows[0].UsedAt)
+}
+
+func TestResetUsedMeanwhile(t *testing.T) {
+	e, users, _, m := setup(t)
+	u := &model.User{ID: 7, Name: "Budi", Email: "budi@example.com"}
+	users.EXPECT().ReadByEmail(gomock.Any(), u.Email).Return(u, nil)
+	users.EXPECT().ReadById(gomock.Any(), u.ID).Return(u, nil)
+	// another reset with the token set its password first
+	users.EXPECT().ResetPassword(gomock.Any(), gomock.Any(), 1).Return(nil, apperror.Conflict("password_reset_used", "password reset was used meanwhile"))
+
+	require.NoError(t, e.forgot(context.Background(), u.Email))
+	token := tokenOf(t, m.Messages()[0])
+
+	err := e.Reset(context.Background(), request.PasswordReset{Token: token, Password: "n3w-password"})
+	var appErr *apperror.Error
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, "invalid_token", appErr.Code)
+}
@@ -0,0 +1,62 @@
+package product
+
+import (
+	"context"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+)
+
+// priceOf is the price p has.
+func priceOf(p *model.Product) *model.ProductPrice {
+	return &model.ProductPrice{
+		ProductID:        p.ID,
+		PriceAmount:      p.PriceAmount,
+		MinPriceAmount:   p.MinPriceAmount,
+		MaxPriceAmount:   p.MaxPriceAmount,
+		Currency:         p.Currency,
+		UnitPrice:        p.UnitPrice,
+		PricePerBaseUnit: p.PricePerBaseUnit,
+	}
+}
+
+func samePrice(a, b *model.ProductPrice) bool {
+	return a.PriceAmount == b.PriceAmount &&
+		a.MinPriceAmount == b.MinPriceAmount &&
+		a.MaxPriceAmount == b.MaxPriceAmount &&
+		a.Currency == b.Currency &&
+		a.UnitPrice == b.UnitPrice
+}
+
+// recordPrice adds the current price of p to its price history, as set by the approved
+// revision revisionID, 0 when the product was not on PARI yet.
+func (e *usecase) recordPrice(ctx context.Context, p *model.Product, revisionID int) error {
+	now := time.Now()
+	price := priceOf(p)
+	price.RevisionID = revisionID
+	price.EffectiveAt = &now
+	_, err := e.productPriceRepository.Create(ctx, price)
+	return err
+}
+
+// Prices is the price history of a product.
+func (e *usecase) Prices(ctx context.Context, id int, q *query.Query) (*[]model.ProductPrice, *query.Page, error) {
+	if _, err := e.productRepository.ReadById(ctx, id); err != nil {
+		return nil, nil, err
+	}
+	q.Where("product_id", id)
+	return e.productPriceRepository.ReadAllBy(ctx, q)
+}
+
+// pricePublished marks the price a product was published to PARI with as synced.
+func (e *usecase) pricePublished(ctx context.Context, productID int) {
+	price, err := e.productPriceRepository.ReadBy(ctx, map[string]interface{}{"product_id": productID})
+	if err == nil {
+		_, err = e.productPriceRepository.Patch(ctx, price.ID, map[string]interface{}{"synced_at": time.Now()})
+	}
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Warn("[productUsecase.Verification] failed marking the product price synced")
+	}
+}
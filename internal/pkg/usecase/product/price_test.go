@@ -0,0 +1,184 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/patch"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/commodity"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_price"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/search"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/validation"
+	"github.com/stretchr/testify/require"
+)
+
+// products keeps products in memory.
+type products struct {
+	product.Repository
+	rows map[int]*model.Product
+}
+
+func (r products) ReadById(_ context.Context, id int) (*model.Product, error) {
+	if row, ok := r.rows[id]; ok {
+		found := *row
+		return &found, nil
+	}
+	return nil, apperror.NotFound("product_not_found", "product is not exists")
+}
+
+func (r products) Create(ctx context.Context, m *model.Product) (*model.Product, error) {
+	m.ID = len(r.rows) + 1
+	saved := *m
+	r.rows[m.ID] = &saved
+	return r.ReadById(ctx, m.ID)
+}
+
+func (r products) Update(ctx context.Context, id int, m *model.Product) (*model.Product, error) {
+	m.ID = id
+	m.Status = r.rows[id].Status
+	saved := *m
+	r.rows[id] = &saved
+	return r.ReadById(ctx, id)
+}
+
+func (r products) Patch(ctx context.Context, id, _ int, fields map[string]interface{}) (*model.Product, error) {
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, r.rows[id]); err != nil {
+		return nil, err
+	}
+	return r.ReadById(ctx, id)
+}
+
+type commodities struct {
+	commodity.Repository
+}
+
+func (commodities) ReadById(_ context.Context, id int) (*model.Commodity, error) {
+	return &model.Commodity{ID: id, Name: "Beras", UnitQuantity: "kg", UnitPrice: "kg", IsActive: true}, nil
+}
+
+// prices keeps the price history in memory.
+type prices struct {
+	product_price.Repository
+	rows *[]model.ProductPrice
+}
+
+func (r prices) Create(_ context.Context, m *model.ProductPrice) (*model.ProductPrice, error) {
+	m.ID = len(*r.rows) + 1
+	*r.rows = append(*r.rows, *m)
+	return m, nil
+}
+
+func (r prices) ReadAllBy(_ context.Context, q *query.Query) (*[]model.ProductPrice, *query.Page, error) {
+	found := *r.rows
+	return &found, &query.Page{}, nil
+}
+
+func (r prices) ReadBy(_ context.Context, criteria map[string]interface{}) (*model.ProductPrice, error) {
+	for i := len(*r.rows) - 1; i >= 0; i-- {
+		if (*r.rows)[i].ProductID == criteria["product_id"] {
+			found := (*r.rows)[i]
+			return &found, nil
+		}
+	}
+	return nil, apperror.NotFound("product_price_not_found", "product price is not exists")
+}
+
+func (r prices) Patch(_ context.Context, id int, fields map[string]interface{}) (*model.ProductPrice, error) {
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	price := &(*r.rows)[id-1]
+	if err := json.Unmarshal(raw, price); err != nil {
+		return nil, err
+	}
+	found := *price
+	return &found, nil
+}
+
+func newPriceUsecase() (*usecase, *[]model.ProductPrice) {
+	rows := map[int]*model.Product{1: {
+		ID: 1, Name: "Beras", UnitQuantity: "kg", UnitPrice: "kg", PriceAmount: 1200000, Currency: "IDR",
+		CommodityID: 3, Commodity: "Beras", ProductCreatedAt: "2022-04-01", ExpiredAt: "2022-05-01",
+	}}
+	history := &[]model.ProductPrice{}
+	return &usecase{
+		productRepository:      products{rows: rows},
+		productPriceRepository: prices{rows: history},
+		commodityRepository:    commodities{},
+		searchIndex:            search.NewIndex(nil),
+	}, history
+}
+
+func TestUpdateRecordsPriceChanges(t *testing.T) {
+	uc, history := newPriceUsecase()
+	edit := request.UpdateProduct{
+		Name: "Beras Premium", Quantity: 10, PriceAmount: 1200000, Currency: "IDR",
+		ProductCreatedAt: "2022-04-01", ExpiredAt: "2022-05-01", CommodityID: 3,
+	}
+
+	_, err := uc.Update(context.Background(), 1, &edit)
+	require.NoError(t, err)
+	require.Empty(t, *history, "the price did not change")
+
+	edit.PriceAmount = 1500000
+	edit.UnitPrice = "g"
+	updated, err := uc.Update(context.Background(), 1, &edit)
+	require.NoError(t, err)
+	require.Len(t, *history, 1)
+	price := (*history)[0]
+	require.Equal(t, 1, price.ProductID)
+	require.Equal(t, int64(1500000), price.PriceAmount)
+	require.Equal(t, "g", price.UnitPrice)
+	require.Equal(t, updated.PricePerBaseUnit, price.PricePerBaseUnit)
+	require.NotNil(t, price.EffectiveAt)
+	require.Nil(t, price.SyncedAt)
+}
+
+func TestPatchRecordsPriceChanges(t *testing.T) {
+	validation.Register()
+	uc, history := newPriceUsecase()
+
+	_, err := uc.Patch(context.Background(), 1, 0, patch.Document{"name": "Beras Premium"})
+	require.NoError(t, err)
+	require.Empty(t, *history)
+
+	_, err = uc.Patch(context.Background(), 1, 0, patch.Document{"max_price_amount": json.Number("1600000")})
+	require.NoError(t, err)
+	_, err = uc.Patch(context.Background(), 1, 0, patch.Document{"price_amount": json.Number("1300000")})
+	require.NoError(t, err)
+	require.Len(t, *history, 2)
+	require.Equal(t, int64(1600000), (*history)[0].MaxPriceAmount)
+	require.Equal(t, int64(1300000), (*history)[1].PriceAmount)
+	require.Equal(t, int64(1600000), (*history)[1].MaxPriceAmount)
+
+	found, _, err := uc.Prices(context.Background(), 1, &query.Query{})
+	require.NoError(t, err)
+	require.Len(t, *found, 2)
+	_, _, err = uc.Prices(context.Background(), 2, &query.Query{})
+	require.True(t, apperror.IsNotFound(err))
+}
+
+func TestPricePublished(t *testing.T) {
+	uc, history := newPriceUsecase()
+	for _, amount := range []int64{1200000, 1300000} {
+		p, err := uc.productRepository.ReadById(context.Background(), 1)
+		require.NoError(t, err)
+		p.PriceAmount = amount
+		require.NoError(t, uc.recordPrice(context.Background(), p, 0))
+	}
+
+	uc.pricePublished(context.Background(), 1)
+	require.Nil(t, (*history)[0].SyncedAt)
+	require.NotNil(t, (*history)[1].SyncedAt, "the product is published with its latest price")
+}
@@ -0,0 +1,264 @@
+package product
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+)
+
+// revisedColumns are the product columns an edit of an approved product can change.
+var revisedColumns = []string{
+	"name", "description", "quantity", "unit_quantity", "price_amount", "unit_price", "currency",
+	"base_unit", "price_per_base_unit", "is_pre_order", "min_price_amount", "max_price_amount",
+	"product_created_at", "expired_at", "commodity_id", "commodity", "is_active",
+}
+
+// priceColumns are the product columns a price change is made of.
+var priceColumns = []string{"price_amount", "min_price_amount", "max_price_amount", "currency", "unit_price"}
+
+// Revisions are the edits proposed for a product since it was approved.
+func (e *usecase) Revisions(ctx context.Context, id int, q *query.Query) (*[]model.ProductRevision, *query.Page, error) {
+	if _, err := e.productRepository.ReadById(ctx, id); err != nil {
+		return nil, nil, err
+	}
+	q.Where("product_id", id)
+	return e.productRevisionRepository.ReadAllBy(ctx, q)
+}
+
+// pendingRevision is the revision of a product waiting for its verifiers, nil if none.
+func (e *usecase) pendingRevision(ctx context.Context, productID int) (*model.ProductRevision, error) {
+	revision, err := e.productRevisionRepository.ReadBy(ctx, map[string]interface{}{"product_id": productID, "status": enum.Processing})
+	if apperror.IsNotFound(err) {
+		return nil, nil
+	}
+	return revision, err
+}
+
+// proposed is live with the changes of its pending revision applied.
+func (e *usecase) proposed(ctx context.Context, live *model.Product) (*model.Product, error) {
+	next := *live
+	pending, err := e.pendingRevision(ctx, live.ID)
+	if err != nil || pending == nil {
+		return &next, err
+	}
+	if err := json.Unmarshal([]byte(pending.Changes), &next); err != nil {
+		return nil, apperror.Internal("product_revision_invalid", "invalid product revision").Wrap(err)
+	}
+	return &next, nil
+}
+
+// revise makes the columns next changes of the approved product live its pending
+// revision, replacing the previous one and its approvals. An edit back to the live
+// values withdraws the pending revision. live is returned with the revision.
+func (e *usecase) revise(ctx context.Context, live, next *model.Product) (*model.Product, error) {
+	changes, err := changedColumns(live, next)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := e.pendingRevision(ctx, live.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(changes) == 0 {
+		if pending != nil {
+			_, err := e.productRevisionRepository.Patch(ctx, pending.ID, enum.Processing, map[string]interface{}{
+				"status":        enum.Rejected,
+				"reject_reason": "withdrawn by a later edit",
+				"reviewed_at":   time.Now(),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return live, nil
+	}
+
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil {
+		live.PendingRevision, err = e.productRevisionRepository.Create(ctx, &model.ProductRevision{ProductID: live.ID, Changes: string(raw), Status: enum.Processing})
+		if err != nil {
+			return nil, err
+		}
+		return live, nil
+	}
+	if err := e.productRevisionUserRepository.DeleteBy(ctx, map[string]interface{}{"product_revision_id": pending.ID}); err != nil {
+		return nil, err
+	}
+	live.PendingRevision, err = e.productRevisionRepository.Patch(ctx, pending.ID, enum.Processing, map[string]interface{}{"changes": string(raw)})
+	if err != nil {
+		return nil, err
+	}
+	return live, nil
+}
+
+// changedColumns are the revised columns next has other values of than live.
+func changedColumns(live, next *model.Product) (map[string]interface{}, error) {
+	before, err := columnsOf(live)
+	if err != nil {
+		return nil, err
+	}
+	after, err := columnsOf(next)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(map[string]interface{})
+	for _, column := range revisedColumns {
+		if !reflect.DeepEqual(before[column], after[column]) {
+			changes[column] = after[column]
+		}
+	}
+	return changes, nil
+}
+
+func columnsOf(p *model.Product) (map[string]interface{}, error) {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&columns); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// revision loads a revision along with its product, which must be of company.
+func (e *usecase) revision(ctx context.Context, id, companyID int) (*model.ProductRevision, *model.Product, error) {
+	revision, err := e.productRevisionRepository.ReadById(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	productModel, err := e.productRepository.ReadById(ctx, revision.ProductID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if companyID == 0 || productModel.CompanyID != companyID {
+		return nil, nil, apperror.Forbidden("product_revision_forbidden", "product revision is not of your company")
+	}
+	return revision, productModel, nil
+}
+
+// VerificationRevision records that user userID of company companyID approved a
+// revision. The verifiers of the company are the users sharing the role of userID;
+// once every one of them approved it, the changes are applied to the product and sent
+// to PARI. Verifying an approved revision PARI did not take yet sends it again.
+func (e *usecase) VerificationRevision(ctx context.Context, userID, companyID int, request *request.ProductRevisionUser) (*model.ProductRevision, error) {
+	revision, productModel, err := e.revision(ctx, request.ProductRevisionID, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if revision.Status == enum.Approved && revision.SyncedAt == nil {
+		return e.syncRevision(ctx, productModel, revision)
+	}
+	if revision.Status != enum.Processing {
+		return nil, apperror.Conflict("product_revision_resolved", "product revision is not waiting for approval")
+	}
+
+	verifier, err := e.userRepository.ReadById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if e.productRevisionUserRepository.Count(ctx, map[string]interface{}{"product_revision_id": revision.ID, "user_id": userID}) == 0 {
+		pru := &model.ProductRevisionUser{ProductRevisionID: revision.ID, UserID: userID, CompanyID: companyID}
+		if _, err := e.productRevisionUserRepository.Create(ctx, pru); err != nil {
+			return nil, err
+		}
+	}
+
+	countApproval := e.productRevisionUserRepository.Count(ctx, map[string]interface{}{"product_revision_id": revision.ID, "company_id": companyID})
+	countUser := e.userRepository.Count(ctx, map[string]interface{}{"company_id": companyID, "role_id": verifier.RoleID})
+	if countApproval < countUser {
+		return revision, nil
+	}
+
+	fields, err := revision.Fields()
+	if err != nil {
+		return nil, apperror.Internal("product_revision_invalid", "invalid product revision").Wrap(err)
+	}
+	repriced := false
+	for _, column := range priceColumns {
+		if _, ok := fields[column]; ok {
+			repriced = true
+		}
+	}
+
+	updated, err := e.productRepository.Patch(ctx, productModel.ID, productModel.Version, fields)
+	if err != nil {
+		return nil, err
+	}
+	e.index(updated)
+	revision, err = e.productRevisionRepository.Patch(ctx, revision.ID, enum.Processing, map[string]interface{}{"status": enum.Approved, "reviewed_at": time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	if repriced {
+		if err := e.recordPrice(ctx, updated, revision.ID); err != nil {
+			return nil, err
+		}
+	}
+	return e.syncRevision(ctx, updated, revision)
+}
+
+// RejectionRevision records that user userID of company companyID rejected a
+// revision, leaving the product as it is.
+func (e *usecase) RejectionRevision(ctx context.Context, userID, companyID int, rejection *request.ProductRevisionRejection) (*model.ProductRevision, error) {
+	revision, _, err := e.revision(ctx, rejection.ProductRevisionID, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if revision.Status != enum.Processing {
+		return nil, apperror.Conflict("product_revision_resolved", "product revision is not waiting for approval")
+	}
+	return e.productRevisionRepository.Patch(ctx, revision.ID, enum.Processing, map[string]interface{}{
+		"status":        enum.Rejected,
+		"reject_reason": rejection.Reason,
+		"rejected_by":   userID,
+		"reviewed_at":   time.Now(),
+	})
+}
+
+// syncRevision sends the product of an approved revision to PARI and marks the
+// revision, and the price it set, synced.
+func (e *usecase) syncRevision(ctx context.Context, p *model.Product, revision *model.ProductRevision) (*model.ProductRevision, error) {
+	fields := e.pariFields(ctx, p)
+	fields["product_id"] = p.PariProductId
+	status := 0
+	if p.IsActive {
+		status = 1
+	}
+	fields["status"] = strconv.Itoa(status)
+	if err := postPari(ctx, enum.UpdateProduct, fields); err != nil {
+		return nil, err
+	}
+	helper.Logger(ctx).WithField("pari_product_id", p.PariProductId).Info("[productUsecase.syncRevision] product revision sent to PARI")
+
+	now := time.Now()
+	revision, err := e.productRevisionRepository.Patch(ctx, revision.ID, enum.Approved, map[string]interface{}{"synced_at": now})
+	if err != nil {
+		return nil, err
+	}
+	price, err := e.productPriceRepository.ReadBy(ctx, map[string]interface{}{"revision_id": revision.ID})
+	if err == nil {
+		_, err = e.productPriceRepository.Patch(ctx, price.ID, map[string]interface{}{"synced_at": now})
+	}
+	if err != nil && !apperror.IsNotFound(err) {
+		helper.Logger(ctx).WithError(err).Warn("[productUsecase.syncRevision] failed marking the product price synced")
+	}
+	return revision, nil
+}
@@ -0,0 +1,162 @@
+package product
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_revision"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_revision_user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"github.com/stretchr/testify/require"
+)
+
+// revisions keeps product revisions in memory.
+type revisions struct {
+	product_revision.Repository
+	rows map[int]*model.ProductRevision
+}
+
+func (r revisions) ReadById(_ context.Context, id int) (*model.ProductRevision, error) {
+	if row, ok := r.rows[id]; ok {
+		found := *row
+		return &found, nil
+	}
+	return nil, apperror.NotFound("product_revision_not_found", "product revision is not exists")
+}
+
+func (r revisions) Patch(ctx context.Context, id int, _ enum.StatusProduct, fields map[string]interface{}) (*model.ProductRevision, error) {
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, r.rows[id]); err != nil {
+		return nil, err
+	}
+	return r.ReadById(ctx, id)
+}
+
+// approvals keeps the approvals of revisions in memory.
+type approvals struct {
+	product_revision_user.Repository
+	rows *[]model.ProductRevisionUser
+}
+
+func (r approvals) Create(_ context.Context, m *model.ProductRevisionUser) (*model.ProductRevisionUser, error) {
+	*r.rows = append(*r.rows, *m)
+	return m, nil
+}
+
+func (r approvals) Count(_ context.Context, criteria map[string]interface{}) int {
+	count := 0
+	for _, row := range *r.rows {
+		if row.ProductRevisionID == criteria["product_revision_id"] && (criteria["user_id"] == nil || row.UserID == criteria["user_id"]) {
+			count++
+		}
+	}
+	return count
+}
+
+// verifiers are two users of company 7 sharing role 5.
+type verifiers struct {
+	user.Repository
+}
+
+func (verifiers) ReadById(_ context.Context, id int) (*model.User, error) {
+	return &model.User{ID: id, CompanyID: 7, RoleID: 5}, nil
+}
+
+func (verifiers) Count(_ context.Context, criteria map[string]interface{}) int {
+	if criteria["company_id"] == 7 && criteria["role_id"] == 5 {
+		return 2
+	}
+	return 0
+}
+
+func newRevisionUsecase() (*usecase, map[int]*model.ProductRevision, *[]model.ProductRevisionUser) {
+	uc, _ := newPriceUsecase()
+	uc.productRepository.(products).rows[1].CompanyID = 7
+	rows := map[int]*model.ProductRevision{1: {ID: 1, ProductID: 1, Changes: `{"name":"Beras Premium"}`, Status: enum.Processing}}
+	approved := &[]model.ProductRevisionUser{}
+	uc.productRevisionRepository = revisions{rows: rows}
+	uc.productRevisionUserRepository = approvals{rows: approved}
+	uc.userRepository = verifiers{}
+	return uc, rows, approved
+}
+
+func TestVerificationRevisionByCaller(t *testing.T) {
+	uc, _, approved := newRevisionUsecase()
+	revision, err := uc.VerificationRevision(context.Background(), 11, 7, &request.ProductRevisionUser{ProductRevisionID: 1})
+	require.NoError(t, err)
+	require.Equal(t, enum.Processing, revision.Status)
+	require.Equal(t, []model.ProductRevisionUser{{ProductRevisionID: 1, UserID: 11, CompanyID: 7}}, *approved)
+}
+
+func TestVerificationRevisionOfOtherCompany(t *testing.T) {
+	uc, _, approved := newRevisionUsecase()
+	_, err := uc.VerificationRevision(context.Background(), 11, 8, &request.ProductRevisionUser{ProductRevisionID: 1})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+	require.Empty(t, *approved)
+}
+
+func TestRejectionRevisionByCaller(t *testing.T) {
+	uc, _, _ := newRevisionUsecase()
+	revision, err := uc.RejectionRevision(context.Background(), 11, 7, &request.ProductRevisionRejection{ProductRevisionID: 1, Reason: "price too high"})
+	require.NoError(t, err)
+	require.Equal(t, enum.StatusProduct(enum.Rejected), revision.Status)
+	require.Equal(t, 11, revision.RejectedBy)
+}
+
+func TestRejectionRevisionOfOtherCompany(t *testing.T) {
+	uc, rows, _ := newRevisionUsecase()
+	_, err := uc.RejectionRevision(context.Background(), 11, 0, &request.ProductRevisionRejection{ProductRevisionID: 1, Reason: "price too high"})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+	require.Equal(t, enum.Processing, rows[1].Status)
+}
+
+func TestChangedColumns(t *testing.T) {
+	live := &model.Product{ID: 1, Name: "Beras", Quantity: 10, PriceAmount: 1250000, Currency: "IDR", IsActive: true, Version: 3}
+	next := *live
+	next.Name = "Beras Premium"
+	next.PriceAmount = 9007199254740993
+	next.IsActive = false
+	next.Version = 4
+
+	changes, err := changedColumns(live, &next)
+	require.NoError(t, err)
+	require.Equal(t, []string{"name", "price_amount", "is_active"}, keys(changes))
+
+	raw, err := json.Marshal(changes)
+	require.NoError(t, err)
+	fields, err := (&model.ProductRevision{Changes: string(raw)}).Fields()
+	require.NoError(t, err)
+	require.Equal(t, json.Number("9007199254740993"), fields["price_amount"])
+	require.Equal(t, false, fields["is_active"])
+
+	applied := *live
+	require.NoError(t, json.Unmarshal(raw, &applied))
+	require.Equal(t, "Beras Premium", applied.Name)
+	require.Equal(t, int64(9007199254740993), applied.PriceAmount)
+	require.Equal(t, 3, applied.Version)
+}
+
+func TestChangedColumnsNone(t *testing.T) {
+	live := &model.Product{ID: 1, Name: "Beras"}
+	changes, err := changedColumns(live, live)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+}
+
+func keys(m map[string]interface{}) []string {
+	result := make([]string, 0, len(m))
+	for _, column := range revisedColumns {
+		if _, ok := m[column]; ok {
+			result = append(result, column)
+		}
+	}
+	return result
+}
@@ -1,121 +1,165 @@
 package product
 
 import (
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_user"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/jinzhu/gorm"
-	"github.com/spf13/viper"
 	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"strconv"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/money"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/patch"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/commodity"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_price"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_revision"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_revision_user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/search"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/tracing"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/unit"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Usecase interface {
-	Create(product *request.Product) (*model.Product, error)
-	ReadAll() (*[]model.Product, error)
-	ReadAllBy(req request.ProductPaged) (*[]model.Product, error)
-	ReadById(id int) (*model.Product, error)
-	ReadByPariProductId(pariProductId string) (*model.Product, error)
-	ReadBy(req request.ProductDetail) (*helper.ProductResponse, error)
-	Update(id int, product *model.Product) (*model.Product, error)
-	Delete(id int) error
-	Count(req request.ProductPaged) int
-	Summary(companyId int) (interface{}, error)
-	Verification(productUser *request.ProductUser) (*helper.ProductResponse, error)
+	Create(ctx context.Context, product *request.Product) (*model.Product, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Product, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.Product, error)
+	ReadByPariProductId(ctx context.Context, pariProductId string) (*model.Product, error)
+	ReadBy(ctx context.Context, req request.ProductDetail) (*helper.ProductResponse, error)
+	Update(ctx context.Context, id int, product *request.UpdateProduct) (*model.Product, error)
+	Patch(ctx context.Context, id, version int, doc patch.Document) (*model.Product, error)
+	Purchase(ctx context.Context, transaction *request.ProductTransaction) (*model.Product, error)
+	Delete(ctx context.Context, id int) error
+	Summary(ctx context.Context, companyId int) (interface{}, error)
+	Verification(ctx context.Context, userID, companyID int, productUser *request.ProductUser) (*helper.ProductResponse, error)
+	Prices(ctx context.Context, id int, q *query.Query) (*[]model.ProductPrice, *query.Page, error)
+	Revisions(ctx context.Context, id int, q *query.Query) (*[]model.ProductRevision, *query.Page, error)
+	VerificationRevision(ctx context.Context, userID, companyID int, productRevisionUser *request.ProductRevisionUser) (*model.ProductRevision, error)
+	RejectionRevision(ctx context.Context, userID, companyID int, rejection *request.ProductRevisionRejection) (*model.ProductRevision, error)
+	Search(ctx context.Context, text string, q *query.Query) (*model.ProductSearch, *query.Page, error)
+	RebuildSearchIndex(ctx context.Context) error
 }
 
+// searchFacets are the columns search results are counted by.
+var searchFacets = []string{"commodity", "status"}
+
+// snippetWidth is the length of the description excerpt returned with search hits.
+const snippetWidth = 160
+
 type usecase struct {
-	productRepository     product.Repository
-	productUserRepository product_user.Repository
-	userRepository        user.Repository
-	roleRepository        role.Repository
+	productRepository             product.Repository
+	productUserRepository         product_user.Repository
+	productPriceRepository        product_price.Repository
+	productRevisionRepository     product_revision.Repository
+	productRevisionUserRepository product_revision_user.Repository
+	userRepository                user.Repository
+	commodityRepository           commodity.Repository
+	searchIndex                   *search.Index
 }
 
-func NewUsecase(productRepository product.Repository, productUserRepository product_user.Repository, userRepository user.Repository, roleRepository role.Repository) Usecase {
-	return &usecase{productRepository, productUserRepository, userRepository, roleRepository}
+func NewUsecase(productRepository product.Repository, productUserRepository product_user.Repository, productPriceRepository product_price.Repository, productRevisionRepository product_revision.Repository, productRevisionUserRepository product_revision_user.Repository, userRepository user.Repository, commodityRepository commodity.Repository, searchIndex *search.Index) Usecase {
+	return &usecase{productRepository, productUserRepository, productPriceRepository, productRevisionRepository, productRevisionUserRepository, userRepository, commodityRepository, searchIndex}
 }
 
-func (e *usecase) Create(product *request.Product) (*model.Product, error) {
+func (e *usecase) Create(ctx context.Context, product *request.Product) (*model.Product, error) {
 	//layout := "2006-01-02"
 	//startDateString := product.ProductCreatedAt.Format(layout)
 	//endDateString := product.ExpiredAt.Format(layout)
 	//t, _ := time.Parse(layout, product.ProductCreatedAt)
 	//t2, _ := time.Parse(layout, product.ExpiredAt)
 
+	if product.CompanyID == 0 {
+		return nil, apperror.Forbidden("company_required", "products can only be added by users of a company")
+	}
+
+	c, err := e.commodity(ctx, product.CommodityID, 0)
+	if err != nil {
+		return nil, err
+	}
+
 	p := &model.Product{
 		Image:            product.Image,
 		Name:             product.Name,
 		Description:      product.Description,
 		Quantity:         product.Quantity,
 		UnitQuantity:     product.UnitQuantity,
-		Price:            product.Price,
+		PriceAmount:      product.PriceAmount,
 		UnitPrice:        product.UnitPrice,
-		Status:           product.Status,
+		Currency:         product.Currency,
+		Status:           enum.Processing,
 		ProductCreatedAt: product.ProductCreatedAt,
 		ExpiredAt:        product.ExpiredAt,
-		Commodity:        product.Commodity,
+		CommodityID:      c.ID,
+		Commodity:        c.Name,
 		CompanyID:        product.CompanyID,
 		IsPreOrder:       product.IsPreOrder,
-		MinPrice:         product.MinPrice,
-		MaxPrice:         product.MaxPrice,
+		MinPriceAmount:   product.MinPriceAmount,
+		MaxPriceAmount:   product.MaxPriceAmount,
 		IsActive:         product.IsActive,
 		TmpImagePath:     product.TmpImagePath,
 	}
-
-	return e.productRepository.Create(p)
-}
-
-func (e *usecase) ReadAll() (*[]model.Product, error) {
-	return e.productRepository.ReadAll()
-}
-
-func (e *usecase) ReadAllBy(req request.ProductPaged) (*[]model.Product, error) {
-	criteria := make(map[string]interface{})
-	criteria["company_id"] = req.CompanyID
-
-	if req.Status != "" {
-		criteria["status"] = req.Status
+	if p.Currency == "" {
+		p.Currency = money.DefaultCurrency
 	}
-
-	if req.Commodity != "" {
-		criteria["commodity"] = req.Commodity
+	defaultUnits(p, c)
+	if err := pricePerBaseUnit(p); err != nil {
+		return nil, err
 	}
 
-	fmt.Println(req)
+	created, err := e.productRepository.Create(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	e.index(created)
+	if err := e.recordPrice(ctx, created, 0); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
 
-	return e.productRepository.ReadAllBy(criteria, req.Search, req.Page, req.Size)
+func (e *usecase) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.Product, *query.Page, error) {
+	return e.productRepository.ReadAllBy(ctx, q)
 }
 
-func (e *usecase) ReadById(id int) (*model.Product, error) {
-	return e.productRepository.ReadById(id)
+func (e *usecase) ReadById(ctx context.Context, id int) (*model.Product, error) {
+	return e.productRepository.ReadById(ctx, id)
 }
 
-func (e *usecase) ReadByPariProductId(pariProductId string) (*model.Product, error) {
-	return e.productRepository.ReadByPariProductId(pariProductId)
+func (e *usecase) ReadByPariProductId(ctx context.Context, pariProductId string) (*model.Product, error) {
+	return e.productRepository.ReadByPariProductId(ctx, pariProductId)
 }
 
-func (e *usecase) ReadBy(request request.ProductDetail) (*helper.ProductResponse, error) {
+func (e *usecase) ReadBy(ctx context.Context, request request.ProductDetail) (*helper.ProductResponse, error) {
 	var isVerifiedByUser bool
-	productModel, err := e.productRepository.ReadById(request.ID)
+	productModel, err := e.productRepository.ReadById(ctx, request.ID)
 
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		helper.Logger(ctx).Error(err)
+		return nil, err
+	}
+
+	productModel.PendingRevision, err = e.pendingRevision(ctx, productModel.ID)
+	if err != nil {
 		return nil, err
 	}
 
 	if request.UserID != 0 {
-		countProductUser := e.productUserRepository.Count(map[string]interface{}{"product_id": request.ID, "user_id": request.UserID})
+		countProductUser := e.productUserRepository.Count(ctx, map[string]interface{}{"product_id": request.ID, "user_id": request.UserID})
 		if countProductUser > 0 {
 			isVerifiedByUser = true
 		}
@@ -138,40 +182,35 @@ func (e *usecase) ReadBy(request request.ProductDetail) (*helper.ProductResponse
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", viper.Get("API_PARI_CORPORATE").(string)+enum.DetailProduct.String(), body)
+	req, err := http.NewRequestWithContext(ctx, "POST", viper.Get("API_PARI_CORPORATE").(string)+enum.DetailProduct.String(), body)
 	if err != nil {
-		fmt.Printf("http.NewRequest() error: %v\n", err)
-		return nil, err
+		return nil, apperror.Internal("pari_request_error", "failed building PARI request").Wrap(err)
 	}
 
 	req.Header.Add("Content-Type", writer.FormDataContentType())
 	req.Header.Add("Authorization", viper.Get("API_KEY_PARI_CORPORATE").(string))
 
-	c := &http.Client{}
+	c := tracing.NewHTTPClient()
 	resp, err := c.Do(req)
 	if err != nil {
-		fmt.Printf("http.Do() error: %v\n", err)
-		return nil, err
+		return nil, apperror.Upstream("pari_unavailable", "failed calling PARI").Wrap(err)
 	}
 
 	if resp.StatusCode != 200 {
 		b, _ := ioutil.ReadAll(resp.Body)
-		fmt.Printf("http.Do() error: %v\n", string(b))
-		return nil, fmt.Errorf(string(b))
+		return nil, apperror.Upstream("pari_error", fmt.Sprintf("PARI responded with status %d", resp.StatusCode)).Wrap(errors.New(string(b)))
 	}
 
-	fmt.Printf("http.Do() success: %v\n", resp.Body)
-
 	defer resp.Body.Close()
 
 	var response helper.ResponsePariDetail
 
 	err = json.NewDecoder(resp.Body).Decode(&response)
 	if err != nil {
-		return nil, err
+		return nil, apperror.Upstream("pari_invalid_response", "invalid response from PARI").Wrap(err)
 	}
 
-	fmt.Println(response.Data)
+	helper.Logger(ctx).WithField("pari_product_id", response.Data.ID).Debug("[productUsecase.ReadBy] PARI product detail received")
 
 	productModel.Name = response.Data.ProductName
 	productModel.Image = response.Data.Images
@@ -182,87 +221,398 @@ func (e *usecase) ReadBy(request request.ProductDetail) (*helper.ProductResponse
 	return result, nil
 }
 
-func (e *usecase) Update(id int, product *model.Product) (*model.Product, error) {
-	return e.productRepository.Update(id, product)
-}
+// Update replaces the editable fields of a product. The edit of an approved product
+// becomes its pending revision instead, and the product is returned as it is.
+func (e *usecase) Update(ctx context.Context, id int, product *request.UpdateProduct) (*model.Product, error) {
+	current, err := e.productRepository.ReadById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c, err := e.commodity(ctx, product.CommodityID, current.CommodityID)
+	if err != nil {
+		return nil, err
+	}
 
-func (e *usecase) Delete(id int) error {
-	return e.productRepository.Delete(id)
-}
+	m := &model.Product{}
+	applyUpdate(m, product, c)
+	defaultUnits(m, c)
+	if err := pricePerBaseUnit(m); err != nil {
+		return nil, err
+	}
+	if current.Status == enum.Approved {
+		return e.revise(ctx, current, m)
+	}
 
-func (e *usecase) Verification(request *request.ProductUser) (*helper.ProductResponse, error) {
+	updated, err := e.productRepository.Update(ctx, id, m)
+	if err != nil {
+		return nil, err
+	}
+	e.index(updated)
+	if !samePrice(priceOf(m), priceOf(current)) {
+		if err := e.recordPrice(ctx, updated, 0); err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
+}
 
-	productModel, err := e.productRepository.ReadById(request.ProductID)
+// Purchase takes the quantity bought on PARI out of the product stock.
+func (e *usecase) Purchase(ctx context.Context, transaction *request.ProductTransaction) (*model.Product, error) {
+	currentProduct, err := e.productRepository.ReadByPariProductId(ctx, transaction.PariProductId)
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		return nil, err
 	}
 
-	productUser, err := e.productUserRepository.ReadBy(map[string]interface{}{"product_id": request.ProductID, "user_id": request.UserID, "company_id": request.CompanyID})
-	if err != nil && !gorm.IsRecordNotFoundError(err) {
-		return nil, fmt.Errorf("failed finding product user: %v", err)
+	qty := currentProduct.Quantity - transaction.Quantity
+	if qty < 0 {
+		return nil, apperror.Conflict("insufficient_stock", "insufficient stock")
 	}
 
-	r, err := e.roleRepository.ReadById(request.RoleID)
+	return e.productRepository.Patch(ctx, currentProduct.ID, currentProduct.Version, map[string]interface{}{"quantity": qty})
+}
+
+// Patch applies a merge patch to the editable fields of a product. A version of 0 skips
+// the If-Match check but the update still fails if the product changes meanwhile. The
+// patch of an approved product applies on top of its pending revision, if any, and
+// replaces it.
+func (e *usecase) Patch(ctx context.Context, id, version int, doc patch.Document) (*model.Product, error) {
+	current, err := e.productRepository.ReadById(ctx, id)
 	if err != nil {
-		helper.CommonLogger().Error(err)
 		return nil, err
 	}
-
-	// checking whether productUser exists or not
-	if productUser == nil {
-		pu := &model.ProductUser{ProductID: request.ProductID, UserID: request.UserID, CompanyID: request.CompanyID}
-		_, err := e.productUserRepository.Create(pu)
-		if err != nil {
-			helper.CommonLogger().Error(err)
+	if version != 0 && version != current.Version {
+		return nil, apperror.VersionMismatch("product")
+	}
+	base := current
+	if current.Status == enum.Approved {
+		if base, err = e.proposed(ctx, current); err != nil {
 			return nil, err
 		}
 	}
 
-	// checking product has been approved by all user in company
-	countProductUser := e.productUserRepository.Count(map[string]interface{}{"company_id": request.CompanyID, "product_id": request.ProductID})
-	countUser := e.userRepository.Count(map[string]interface{}{"company_id": request.CompanyID, "role_id": r.ID})
-	if countUser == countProductUser {
-		var isPreOrder int
+	dto := request.UpdateProduct{
+		Name:             base.Name,
+		Description:      base.Description,
+		Quantity:         base.Quantity,
+		UnitQuantity:     base.UnitQuantity,
+		PriceAmount:      base.PriceAmount,
+		UnitPrice:        base.UnitPrice,
+		Currency:         base.Currency,
+		IsPreOrder:       base.IsPreOrder,
+		MinPriceAmount:   base.MinPriceAmount,
+		MaxPriceAmount:   base.MaxPriceAmount,
+		ProductCreatedAt: base.ProductCreatedAt,
+		ExpiredAt:        base.ExpiredAt,
+		CommodityID:      base.CommodityID,
+		IsActive:         base.IsActive,
+	}
+	fields, err := doc.Apply(&dto)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return current, nil
+	}
 
-		if productModel.IsPreOrder {
-			isPreOrder = 1
-		} else {
-			isPreOrder = 0
+	c, err := e.commodity(ctx, dto.CommodityID, current.CommodityID)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status == enum.Approved {
+		next := *base
+		applyUpdate(&next, &dto, c)
+		if repriced(fields) {
+			defaultUnits(&next, c)
+			if err := pricePerBaseUnit(&next); err != nil {
+				return nil, err
+			}
 		}
+		return e.revise(ctx, current, &next)
+	}
 
-		file, err := os.Open(productModel.Image)
-		if err != nil {
+	if _, ok := fields["commodity_id"]; ok {
+		fields["commodity"] = c.Name
+	}
+	if repriced(fields) {
+		p := &model.Product{UnitQuantity: dto.UnitQuantity, UnitPrice: dto.UnitPrice, PriceAmount: dto.PriceAmount}
+		defaultUnits(p, c)
+		if err := pricePerBaseUnit(p); err != nil {
 			return nil, err
 		}
+		fields["unit_quantity"] = p.UnitQuantity
+		fields["unit_price"] = p.UnitPrice
+		fields["base_unit"] = p.BaseUnit
+		fields["price_per_base_unit"] = p.PricePerBaseUnit
+	}
 
-		fileContents, err := ioutil.ReadAll(file)
-		if err != nil {
+	patched, err := e.productRepository.Patch(ctx, id, current.Version, fields)
+	if err != nil {
+		return nil, err
+	}
+	e.index(patched)
+	if !samePrice(priceOf(patched), priceOf(current)) {
+		if err := e.recordPrice(ctx, patched, 0); err != nil {
 			return nil, err
 		}
-		fi, err := file.Stat()
+	}
+	return patched, nil
+}
+
+func (e *usecase) Delete(ctx context.Context, id int) error {
+	if err := e.productRepository.Delete(ctx, id); err != nil {
+		return err
+	}
+	e.searchIndex.Remove(id)
+	return nil
+}
+
+// Search finds products by the words of text, tolerating typos and commodity synonyms,
+// and highlights the matched words in the name and a description excerpt.
+func (e *usecase) Search(ctx context.Context, text string, q *query.Query) (*model.ProductSearch, *query.Page, error) {
+	analyzed := e.searchIndex.Analyze(text)
+	if analyzed.Empty() {
+		return nil, nil, apperror.Validation("validation_error", "request validation failed").WithDetails(apperror.FieldError{
+			Field:   "q",
+			Message: fmt.Sprintf("must contain a word of at least %d characters", search.MinTokenSize),
+		})
+	}
+	match := analyzed.Boolean()
+
+	hits, page, err := e.productRepository.Search(ctx, match, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	facets, err := e.productRepository.Facets(ctx, match, q, searchFacets...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range *hits {
+		hit := &(*hits)[i]
+		hit.Highlights = map[string]string{
+			"name":        analyzed.Highlight(hit.Name),
+			"description": analyzed.Snippet(hit.Description, snippetWidth),
+		}
+	}
+	return &model.ProductSearch{Hits: *hits, Facets: facets}, page, nil
+}
+
+// RebuildSearchIndex loads the words of every product into the search index.
+func (e *usecase) RebuildSearchIndex(ctx context.Context) error {
+	products, err := e.productRepository.ReadAll(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range *products {
+		e.index(&(*products)[i])
+	}
+	return nil
+}
+
+func (e *usecase) index(p *model.Product) {
+	e.searchIndex.Put(p.ID, p.Name, p.Description, p.Commodity)
+}
+
+// commodity loads the commodity of a product. It must be active unless the product
+// already had it, so deactivating a commodity does not lock its products.
+func (e *usecase) commodity(ctx context.Context, id, currentID int) (*model.Commodity, error) {
+	c, err := e.commodityRepository.ReadById(ctx, id)
+	if apperror.IsNotFound(err) {
+		return nil, invalidField("commodity_id", "does not exist")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !c.IsActive && c.ID != currentID {
+		return nil, invalidField("commodity_id", "is not an active commodity")
+	}
+	return c, nil
+}
+
+func invalidField(field, message string) error {
+	return apperror.Validation("validation_error", "request validation failed").WithDetails(apperror.FieldError{
+		Field:   field,
+		Message: message,
+	})
+}
+
+// applyUpdate sets the editable fields of p to those of dto.
+func applyUpdate(p *model.Product, dto *request.UpdateProduct, c *model.Commodity) {
+	p.Name = dto.Name
+	p.Description = dto.Description
+	p.Quantity = dto.Quantity
+	p.UnitQuantity = dto.UnitQuantity
+	p.PriceAmount = dto.PriceAmount
+	p.UnitPrice = dto.UnitPrice
+	p.Currency = dto.Currency
+	p.IsPreOrder = dto.IsPreOrder
+	p.MinPriceAmount = dto.MinPriceAmount
+	p.MaxPriceAmount = dto.MaxPriceAmount
+	p.ProductCreatedAt = dto.ProductCreatedAt
+	p.ExpiredAt = dto.ExpiredAt
+	p.CommodityID = c.ID
+	p.Commodity = c.Name
+	p.IsActive = dto.IsActive
+}
+
+// defaultUnits fills the units a product was given none of with those of its commodity.
+func defaultUnits(p *model.Product, c *model.Commodity) {
+	if p.UnitQuantity == "" {
+		p.UnitQuantity = c.UnitQuantity
+	}
+	if p.UnitPrice == "" {
+		p.UnitPrice = c.UnitPrice
+	}
+}
+
+// pricePerBaseUnit prices p per base unit of the dimension of its units, after
+// checking that both units are known and measure the same.
+func pricePerBaseUnit(p *model.Product) error {
+	quantityUnit, ok := unit.Lookup(p.UnitQuantity)
+	if !ok {
+		return invalidField("unit_quantity", "is not a known unit")
+	}
+	priceUnit, ok := unit.Lookup(p.UnitPrice)
+	if !ok {
+		return invalidField("unit_price", "is not a known unit")
+	}
+	if quantityUnit.Dimension != priceUnit.Dimension {
+		return invalidField("unit_price", "must measure the same as unit_quantity")
+	}
+
+	base := priceUnit.Base()
+	amount, err := unit.PricePer(p.PriceAmount, priceUnit, base)
+	if err != nil {
+		return err
+	}
+	p.BaseUnit = base.Code
+	p.PricePerBaseUnit = amount
+	return nil
+}
+
+// repriced reports whether a patch changes what the price per base unit depends on.
+func repriced(fields map[string]interface{}) bool {
+	for _, column := range []string{"price_amount", "unit_quantity", "unit_price"} {
+		if _, ok := fields[column]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// postPari posts a form to a PARI endpoint, failing unless PARI accepts it.
+func postPari(ctx context.Context, endpoint enum.PARI, fields map[string]string) error {
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	for key, val := range fields {
+		_ = writer.WriteField(key, val)
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", viper.Get("API_PARI_CORPORATE").(string)+endpoint.String(), body)
+	if err != nil {
+		return apperror.Internal("pari_request_error", "failed building PARI request").Wrap(err)
+	}
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+	req.Header.Add("Authorization", viper.Get("API_KEY_PARI_CORPORATE").(string))
+
+	resp, err := tracing.NewHTTPClient().Do(req)
+	if err != nil {
+		return apperror.Upstream("pari_unavailable", "failed calling PARI").Wrap(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return apperror.Upstream("pari_error", fmt.Sprintf("PARI responded with status %d", resp.StatusCode)).Wrap(errors.New(string(b)))
+	}
+	return nil
+}
+
+// pariCommodity is the commodity of a product as PARI knows it.
+func (e *usecase) pariCommodity(ctx context.Context, p *model.Product) string {
+	c, err := e.commodityRepository.ReadById(ctx, p.CommodityID)
+	if err != nil {
+		helper.Logger(ctx).WithError(err).Warn("[productUsecase.Verification] commodity not found, sending its name")
+		return p.Commodity
+	}
+	if c.PariCode != "" {
+		return c.PariCode
+	}
+	return c.Name
+}
+
+// pariFields are the fields of p a PARI product is created or updated with.
+func (e *usecase) pariFields(ctx context.Context, p *model.Product) map[string]string {
+	isPreOrder := 0
+	if p.IsPreOrder {
+		isPreOrder = 1
+	}
+	return map[string]string{
+		"corporate_id":      strconv.Itoa(p.CompanyID),
+		"product_name":      p.Name,
+		"product_commodity": e.pariCommodity(ctx, p),
+		"date_production":   p.ProductCreatedAt,
+		"expires_date":      p.ExpiredAt,
+		"price":             strconv.FormatInt(money.Whole(p.PriceAmount, p.Currency), 10),
+		"minPrice":          strconv.FormatInt(money.Whole(p.MinPriceAmount, p.Currency), 10),
+		"maxPrice":          strconv.FormatInt(money.Whole(p.MaxPriceAmount, p.Currency), 10),
+		"isPreOrder":        strconv.Itoa(isPreOrder),
+		"description":       p.Description,
+		"quantity":          strconv.Itoa(p.Quantity),
+	}
+}
+
+// Verification records that user userID of company companyID approved a product. The
+// verifiers of the company are the users sharing the role of userID; once every one of
+// them approved it, the product is published to PARI.
+func (e *usecase) Verification(ctx context.Context, userID, companyID int, request *request.ProductUser) (*helper.ProductResponse, error) {
+
+	productModel, err := e.productRepository.ReadById(ctx, request.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	if companyID == 0 || productModel.CompanyID != companyID {
+		return nil, apperror.Forbidden("product_forbidden", "product is not of your company")
+	}
+
+	verifier, err := e.userRepository.ReadById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	productUser, err := e.productUserRepository.ReadBy(ctx, map[string]interface{}{"product_id": request.ProductID, "user_id": userID, "company_id": companyID})
+	if err != nil && !apperror.IsNotFound(err) {
+		return nil, err
+	}
+
+	// checking whether productUser exists or not
+	if productUser == nil {
+		pu := &model.ProductUser{ProductID: request.ProductID, UserID: userID, CompanyID: companyID}
+		_, err := e.productUserRepository.Create(ctx, pu)
 		if err != nil {
+			helper.Logger(ctx).Error(err)
 			return nil, err
 		}
-		file.Close()
-
-		extraFields := map[string]string{
-			"corporate_id":      strconv.Itoa(productModel.CompanyID),
-			"product_name":      productModel.Name,
-			"product_commodity": productModel.ProductCreatedAt,
-			"date_production":   productModel.ProductCreatedAt,
-			"expires_date":      productModel.ExpiredAt,
-			"price":             strconv.Itoa(int(productModel.Price)),
-			"minPrice":          strconv.Itoa(int(productModel.MinPrice)),
-			"maxPrice":          strconv.Itoa(int(productModel.MaxPrice)),
-			"isPreOrder":        strconv.Itoa(isPreOrder),
-			"status":            strconv.Itoa(1),
-			"description":       productModel.Description,
-			"quantity":          strconv.Itoa(productModel.Quantity),
+	}
+
+	// checking product has been approved by all user in company
+	countProductUser := e.productUserRepository.Count(ctx, map[string]interface{}{"company_id": companyID, "product_id": request.ProductID})
+	countUser := e.userRepository.Count(ctx, map[string]interface{}{"company_id": companyID, "role_id": verifier.RoleID})
+	if countUser == countProductUser {
+		fileName, fileContents, err := readImage(ctx, productModel.TmpImagePath)
+		if err != nil {
+			return nil, apperror.Internal("product_image_unavailable", "failed reading product image").Wrap(err)
 		}
 
+		extraFields := e.pariFields(ctx, productModel)
+		extraFields["status"] = strconv.Itoa(1)
+
 		body := new(bytes.Buffer)
 		writer := multipart.NewWriter(body)
-		part, err := writer.CreateFormFile("images", fi.Name())
+		part, err := writer.CreateFormFile("images", fileName)
 		if err != nil {
 			return nil, err
 		}
@@ -281,26 +631,23 @@ func (e *usecase) Verification(request *request.ProductUser) (*helper.ProductRes
 			return nil, err
 		}
 
-		req, err := http.NewRequest("POST", viper.Get("API_PARI_CORPORATE").(string)+enum.CreateProduct.String(), body)
+		req, err := http.NewRequestWithContext(ctx, "POST", viper.Get("API_PARI_CORPORATE").(string)+enum.CreateProduct.String(), body)
 		if err != nil {
-			fmt.Printf("http.NewRequest() error: %v\n", err)
-			return nil, err
+			return nil, apperror.Internal("pari_request_error", "failed building PARI request").Wrap(err)
 		}
 
 		req.Header.Add("Content-Type", writer.FormDataContentType())
 		req.Header.Add("Authorization", viper.Get("API_KEY_PARI_CORPORATE").(string))
 
-		c := &http.Client{}
+		c := tracing.NewHTTPClient()
 		resp, err := c.Do(req)
 		if err != nil {
-			fmt.Printf("http.Do() error: %v\n", err)
-			return nil, err
+			return nil, apperror.Upstream("pari_unavailable", "failed calling PARI").Wrap(err)
 		}
 
 		if resp.StatusCode != 200 {
 			b, _ := ioutil.ReadAll(resp.Body)
-			fmt.Printf("http.Do() error: %v\n", string(b))
-			return nil, fmt.Errorf(string(b))
+			return nil, apperror.Upstream("pari_error", fmt.Sprintf("PARI responded with status %d", resp.StatusCode)).Wrap(errors.New(string(b)))
 		}
 
 		defer resp.Body.Close()
@@ -309,26 +656,26 @@ func (e *usecase) Verification(request *request.ProductUser) (*helper.ProductRes
 
 		err = json.NewDecoder(resp.Body).Decode(&response)
 		if err != nil {
-			return nil, err
+			return nil, apperror.Upstream("pari_invalid_response", "invalid response from PARI").Wrap(err)
 		}
 
-		fmt.Println(response.Data)
+		helper.Logger(ctx).WithField("pari_product_id", response.Data.ID).Info("[productUsecase.Verification] product published to PARI")
 
+		tmpImagePath := productModel.TmpImagePath
 		productModel.Status = enum.Approved
 		productModel.PariProductId = response.Data.ID
 		productModel.Image = ""
 		productModel.TmpImagePath = ""
 
-		_, err = e.productRepository.Update(productModel.ID, productModel)
+		_, err = e.productRepository.Update(ctx, productModel.ID, productModel)
 		if err != nil {
-			helper.CommonLogger().Error(err)
 			return nil, err
 		}
+		e.pricePublished(ctx, productModel.ID)
 
-		err = os.Remove(productModel.TmpImagePath)
+		err = os.Remove(tmpImagePath)
 		if err != nil {
-			helper.CommonLogger().Error(err)
-			return nil, err
+			helper.Logger(ctx).WithError(err).Warn("[productUsecase.Verification] failed removing uploaded image")
 		}
 	}
 
@@ -336,32 +683,69 @@ func (e *usecase) Verification(request *request.ProductUser) (*helper.ProductRes
 	return result, nil
 }
 
-func (e *usecase) Count(req request.ProductPaged) int {
-	criteria := make(map[string]interface{})
-	criteria["company_id"] = req.CompanyID
+// readImage loads the product image that is uploaded to PARI once the product is approved.
+func readImage(ctx context.Context, path string) (string, []byte, error) {
+	_, span := tracing.Tracer().Start(ctx, "product.readImage", trace.WithAttributes(attribute.String("file.path", path)))
+	defer span.End()
 
-	if req.Status != "" {
-		criteria["status"] = req.Status
+	file, err := os.Open(path)
+	if err != nil {
+		span.RecordError(err)
+		return "", nil, err
 	}
+	defer file.Close()
 
-	if req.Commodity != "" {
-		criteria["commodity"] = req.Commodity
+	fileContents, err := ioutil.ReadAll(file)
+	if err != nil {
+		span.RecordError(err)
+		return "", nil, err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		span.RecordError(err)
+		return "", nil, err
 	}
 
-	return e.productRepository.Count(criteria)
+	span.SetAttributes(attribute.Int("file.size", len(fileContents)))
+	return fi.Name(), fileContents, nil
 }
 
-func (e *usecase) Summary(companyId int) (interface{}, error) {
+// Summary counts the products of a company by status and sums their stock by base
+// unit and its value by currency.
+func (e *usecase) Summary(ctx context.Context, companyId int) (interface{}, error) {
+	products, err := e.productRepository.ReadByCompany(ctx, companyId)
+	if err != nil {
+		return nil, err
+	}
+	stock := unit.Totals{}
+	stockValue := make(map[string]int64)
+	for _, p := range *products {
+		quantityUnit, known := unit.Lookup(p.UnitQuantity)
+		priceUnit, priceKnown := unit.Lookup(p.UnitPrice)
+		if !known || !priceKnown {
+			helper.Logger(ctx).WithField("product_id", p.ID).Warn("[productUsecase.Summary] product has unknown units, left out of the totals")
+			continue
+		}
+		value, err := unit.Total(p.PriceAmount, priceUnit, p.Quantity, quantityUnit)
+		if err != nil {
+			helper.Logger(ctx).WithField("product_id", p.ID).WithError(err).Warn("[productUsecase.Summary] product units do not match, left out of the totals")
+			continue
+		}
+		stock.Add(p.Quantity, quantityUnit)
+		stockValue[p.Currency] += value
+	}
 
-	allProduct := e.productRepository.Count(map[string]interface{}{"company_id": companyId})
-	processingProduct := e.productRepository.Count(map[string]interface{}{"company_id": companyId, "status": "processing"})
-	approvedProduct := e.productRepository.Count(map[string]interface{}{"company_id": companyId, "status": "approved"})
-	rejectedProduct := e.productRepository.Count(map[string]interface{}{"company_id": companyId, "status": "rejected"})
+	allProduct := e.productRepository.Count(ctx, map[string]interface{}{"company_id": companyId})
+	processingProduct := e.productRepository.Count(ctx, map[string]interface{}{"company_id": companyId, "status": "processing"})
+	approvedProduct := e.productRepository.Count(ctx, map[string]interface{}{"company_id": companyId, "status": "approved"})
+	rejectedProduct := e.productRepository.Count(ctx, map[string]interface{}{"company_id": companyId, "status": "rejected"})
 
 	return map[string]interface{}{
 		"all_product":        allProduct,
 		"processing_product": processingProduct,
 		"approved_product":   approvedProduct,
 		"rejected_product":   rejectedProduct,
+		"stock":              stock,
+		"stock_value_amount": stockValue,
 	}, nil
 }
@@ -0,0 +1,97 @@
+package product
+
+import (
+	"context"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product_user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateStartsProcessing(t *testing.T) {
+	uc, history := newPriceUsecase()
+	created, err := uc.Create(context.Background(), &request.Product{
+		Name: "Jagung", Quantity: 5, PriceAmount: 800000, ProductCreatedAt: "2022-04-01",
+		ExpiredAt: "2022-05-01", CompanyID: 7, CommodityID: 3,
+	})
+	require.NoError(t, err)
+	require.Equal(t, enum.Processing, created.Status)
+	require.Equal(t, 7, created.CompanyID)
+	require.Len(t, *history, 1)
+}
+
+func TestCreateRequiresCompany(t *testing.T) {
+	uc, _ := newPriceUsecase()
+	_, err := uc.Create(context.Background(), &request.Product{
+		Name: "Jagung", Quantity: 5, PriceAmount: 800000, ProductCreatedAt: "2022-04-01",
+		ExpiredAt: "2022-05-01", CommodityID: 3,
+	})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+}
+
+// productApprovals keeps the approvals of products in memory.
+type productApprovals struct {
+	product_user.Repository
+	rows *[]model.ProductUser
+}
+
+func (r productApprovals) ReadBy(_ context.Context, criteria map[string]interface{}) (*model.ProductUser, error) {
+	for _, row := range *r.rows {
+		if row.ProductID == criteria["product_id"] && row.UserID == criteria["user_id"] {
+			found := row
+			return &found, nil
+		}
+	}
+	return nil, apperror.NotFound("product_user_not_found", "product user is not exists")
+}
+
+func (r productApprovals) Create(_ context.Context, m *model.ProductUser) (*model.ProductUser, error) {
+	*r.rows = append(*r.rows, *m)
+	return m, nil
+}
+
+func (r productApprovals) Count(_ context.Context, criteria map[string]interface{}) int {
+	count := 0
+	for _, row := range *r.rows {
+		if row.ProductID == criteria["product_id"] && row.CompanyID == criteria["company_id"] {
+			count++
+		}
+	}
+	return count
+}
+
+func newVerificationUsecase() (*usecase, *[]model.ProductUser) {
+	uc, _ := newPriceUsecase()
+	uc.productRepository.(products).rows[1].CompanyID = 7
+	uc.productRepository.(products).rows[1].Status = enum.Processing
+	approved := &[]model.ProductUser{}
+	uc.productUserRepository = productApprovals{rows: approved}
+	uc.userRepository = verifiers{}
+	return uc, approved
+}
+
+func TestVerificationByCaller(t *testing.T) {
+	uc, approved := newVerificationUsecase()
+	result, err := uc.Verification(context.Background(), 11, 7, &request.ProductUser{ProductID: 1})
+	require.NoError(t, err)
+	require.True(t, result.IsVerifiedByUser)
+	require.Equal(t, enum.Processing, result.Product.Status, "one of the two verifiers approved it")
+	require.Equal(t, []model.ProductUser{{ProductID: 1, UserID: 11, CompanyID: 7}}, *approved)
+
+	_, err = uc.Verification(context.Background(), 11, 7, &request.ProductUser{ProductID: 1})
+	require.NoError(t, err)
+	require.Len(t, *approved, 1, "approving again is not counted twice")
+}
+
+func TestVerificationOfOtherCompany(t *testing.T) {
+	uc, approved := newVerificationUsecase()
+	for _, companyID := range []int{0, 8} {
+		_, err := uc.Verification(context.Background(), 11, companyID, &request.ProductUser{ProductID: 1})
+		require.True(t, apperror.Is(err, apperror.KindForbidden))
+	}
+	require.Empty(t, *approved)
+}
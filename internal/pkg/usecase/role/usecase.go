@@ -1,42 +1,268 @@
 package role
 
 import (
+	"context"
+	"fmt"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/actor"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/company"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"github.com/casbin/casbin"
 )
 
+// Usecase manages roles on behalf of a user. Superadmins manage every role; the other
+// users see the roles shared by all companies and those of their company, and manage
+// the latter only.
 type Usecase interface {
-	Create(role *model.Role) (*model.Role, error)
-	ReadAll() (*[]model.Role, error)
-	ReadById(id int) (*model.Role, error)
-	Update(id int, role *model.Role) (*model.Role, error)
-	Delete(id int) error
+	Create(ctx context.Context, actorID int, role *request.Role) (*model.Role, error)
+	ReadAllBy(ctx context.Context, actorID int, q *query.Query) (*[]model.Role, *query.Page, error)
+	ReadById(ctx context.Context, actorID, id int) (*model.Role, error)
+	Update(ctx context.Context, actorID, id int, role *request.Role) (*model.Role, error)
+	Delete(ctx context.Context, actorID, id int) error
+	Permissions(ctx context.Context, actorID, id int) (*model.Role, []permission.Permission, error)
+	SetPermissions(ctx context.Context, actorID, id int, permissions []string) (*model.Role, []permission.Permission, error)
 }
 
 type usecase struct {
-	repository role.Repository
+	repository        role.Repository
+	userRepository    user.Repository
+	companyRepository company.Repository
+	enforcer          *casbin.SyncedEnforcer
+}
+
+func NewUsecase(repository role.Repository, userRepository user.Repository, companyRepository company.Repository, enforcer *casbin.SyncedEnforcer) Usecase {
+	return &usecase{repository, userRepository, companyRepository, enforcer}
+}
+
+// Create adds a role granted the baseline permissions, in the company of the actor
+// unless a superadmin chose another one.
+func (e *usecase) Create(ctx context.Context, actorID int, role *request.Role) (*model.Role, error) {
+	a, err := actor.Load(ctx, e.userRepository, e.repository, actorID)
+	if err != nil {
+		return nil, err
+	}
+	companyID := role.CompanyID
+	if !a.Superadmin {
+		if companyID != 0 && companyID != a.CompanyID {
+			return nil, apperror.Forbidden("role_forbidden", "roles are created in your own company only")
+		}
+		companyID = a.CompanyID
+	} else if companyID != 0 {
+		if _, err := e.companyRepository.ReadById(ctx, companyID); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.available(ctx, role.Name, companyID); err != nil {
+		return nil, err
+	}
+
+	newRole, err := e.repository.Create(ctx, &model.Role{Name: role.Name, CompanyID: companyID})
+	if err != nil {
+		return nil, err
+	}
+	permission.Grant(e.enforcer, newRole.Name, permission.Domain(newRole.CompanyID), permission.Baseline...)
+	return newRole, nil
+}
+
+func (e *usecase) ReadAllBy(ctx context.Context, actorID int, q *query.Query) (*[]model.Role, *query.Page, error) {
+	a, err := actor.Load(ctx, e.userRepository, e.repository, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !a.Superadmin {
+		q.WhereIn("company_id", 0, a.CompanyID)
+	}
+	return e.repository.ReadAllBy(ctx, q)
+}
+
+func (e *usecase) ReadById(ctx context.Context, actorID, id int) (*model.Role, error) {
+	a, err := actor.Load(ctx, e.userRepository, e.repository, actorID)
+	if err != nil {
+		return nil, err
+	}
+	return e.visible(ctx, a, id)
+}
+
+// Update renames a role, along with its permissions and users. The built-in roles are
+// referred to by name and cannot be renamed.
+func (e *usecase) Update(ctx context.Context, actorID, id int, role *request.Role) (*model.Role, error) {
+	current, err := e.manageable(ctx, actorID, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Name == role.Name {
+		return current, nil
+	}
+	if builtIn(current) {
+		return nil, apperror.Forbidden("built_in_role", fmt.Sprintf("role %s is built in and cannot be renamed", current.Name))
+	}
+	if err := e.available(ctx, role.Name, current.CompanyID); err != nil {
+		return nil, err
+	}
+
+	updated, err := e.repository.Update(ctx, id, &model.Role{Name: role.Name})
+	if err != nil {
+		return nil, err
+	}
+	permission.Rename(e.enforcer, current.Name, role.Name, permission.Domain(current.CompanyID))
+	return updated, nil
+}
+
+// Delete removes a role no user holds, along with its permissions. The built-in roles
+// cannot be deleted.
+func (e *usecase) Delete(ctx context.Context, actorID, id int) error {
+	current, err := e.manageable(ctx, actorID, id)
+	if err != nil {
+		return err
+	}
+	if builtIn(current) {
+		return apperror.Forbidden("built_in_role", fmt.Sprintf("role %s is built in and cannot be deleted", current.Name))
+	}
+	if e.userRepository.Count(ctx, map[string]interface{}{"role_id": id, "deleted_at": nil}) > 0 {
+		return apperror.Conflict("role_in_use", "role is held by users, assign them another role first")
+	}
+
+	if err = e.repository.Delete(ctx, id); err != nil {
+		return err
+	}
+	permission.Remove(e.enforcer, current.Name, permission.Domain(current.CompanyID))
+	return nil
+}
+
+// Permissions is what the role is granted.
+func (e *usecase) Permissions(ctx context.Context, actorID, id int) (*model.Role, []permission.Permission, error) {
+	a, err := actor.Load(ctx, e.userRepository, e.repository, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := e.visible(ctx, a, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return current, permission.Of(e.enforcer, current.Name, permission.Domain(current.CompanyID)), nil
+}
+
+// SetPermissions grants the role exactly the given permissions of the catalog. Users
+// other than superadmins grant the permissions they are granted only, and the
+// superadmin is always granted the whole catalog.
+func (e *usecase) SetPermissions(ctx context.Context, actorID, id int, names []string) (*model.Role, []permission.Permission, error) {
+	a, err := actor.Load(ctx, e.userRepository, e.repository, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := e.visible(ctx, a, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := manage(a, current); err != nil {
+		return nil, nil, err
+	}
+	if current.IsSuperadmin() {
+		return nil, nil, apperror.Forbidden("built_in_role", "role superadmin is granted every permission")
+	}
+
+	permissions, err := parse(names)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !a.Superadmin {
+		_, granted := permission.Effective(e.enforcer, a.ID, permission.Domain(a.CompanyID))
+		held := make(map[permission.Permission]bool, len(granted))
+		for _, p := range granted {
+			held[p] = true
+		}
+		for _, p := range permissions {
+			if !held[p] {
+				return nil, nil, apperror.Forbidden("permission_forbidden", fmt.Sprintf("%s is not granted to you, so you cannot grant it", p))
+			}
+		}
+	}
+
+	domain := permission.Domain(current.CompanyID)
+	permission.Set(e.enforcer, current.Name, domain, permissions)
+	return current, permission.Of(e.enforcer, current.Name, domain), nil
 }
 
-func NewUsecase(repository role.Repository) Usecase {
-	return &usecase{repository}
+// visible finds a role the actor sees, as not found otherwise.
+func (e *usecase) visible(ctx context.Context, a *actor.Actor, id int) (*model.Role, error) {
+	r, err := e.repository.ReadById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !a.Superadmin && !r.AvailableTo(a.CompanyID) {
+		return nil, apperror.NotFound("role_not_found", "role is not exists")
+	}
+	return r, nil
 }
 
-func (e *usecase) Create(role *model.Role) (*model.Role, error) {
-	return e.repository.Create(role)
+// manageable finds a role the actor manages.
+func (e *usecase) manageable(ctx context.Context, actorID, id int) (*model.Role, error) {
+	a, err := actor.Load(ctx, e.userRepository, e.repository, actorID)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.visible(ctx, a, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := manage(a, r); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
-func (e *usecase) ReadAll() (*[]model.Role, error) {
-	return e.repository.ReadAll()
+func manage(a *actor.Actor, r *model.Role) error {
+	if !a.Manages(r) {
+		return apperror.Forbidden("role_forbidden", "roles shared by all companies are managed by superadmins only")
+	}
+	return nil
 }
 
-func (e *usecase) ReadById(id int) (*model.Role, error) {
-	return e.repository.ReadById(id)
+// available refuses a name already used by a role a user of the company could hold, or
+// by any role for a role shared by all companies.
+func (e *usecase) available(ctx context.Context, name string, companyID int) error {
+	criteria := map[string]interface{}{"name": name}
+	if companyID != 0 {
+		criteria["company_id"] = []int{0, companyID}
+	}
+	_, err := e.repository.ReadBy(ctx, criteria)
+	if err == nil {
+		return apperror.Conflict("role_exists", fmt.Sprintf("role %s already exists", name))
+	}
+	if !apperror.IsNotFound(err) {
+		return err
+	}
+	return nil
 }
 
-func (e *usecase) Update(id int, role *model.Role) (*model.Role, error) {
-	return e.repository.Update(id, role)
+// parse reads names as permissions of the catalog.
+func parse(names []string) ([]permission.Permission, error) {
+	var details []apperror.FieldError
+	result := make([]permission.Permission, 0, len(names))
+	for i, name := range names {
+		p, ok := permission.Lookup(name)
+		if !ok {
+			details = append(details, apperror.FieldError{
+				Field:   fmt.Sprintf("permissions[%d]", i),
+				Message: fmt.Sprintf("%s is not a permission of the catalog", name),
+			})
+			continue
+		}
+		result = append(result, p)
+	}
+	if len(details) > 0 {
+		return nil, apperror.Validation("validation_error", "request validation failed").WithDetails(details...)
+	}
+	return result, nil
 }
 
-func (e *usecase) Delete(id int) error {
-	return e.repository.Delete(id)
+func builtIn(r *model.Role) bool {
+	_, ok := permission.Defaults[r.Name]
+	return ok && r.CompanyID == 0
 }
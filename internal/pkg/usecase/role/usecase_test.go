@@ -0,0 +1,212 @@
+package role
+
+import (
+	"context"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	mock "bitbucket.org/bridce/ms-pari-web/internal/pkg/mock/repository"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/company"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"github.com/casbin/casbin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// roles keeps roles in memory.
+type roles struct {
+	role.Repository
+	rows map[int]*model.Role
+	next *int
+}
+
+func (r roles) Create(_ context.Context, m *model.Role) (*model.Role, error) {
+	*r.next++
+	m.ID = *r.next
+	saved := *m
+	r.rows[m.ID] = &saved
+	return m, nil
+}
+
+func (r roles) ReadById(_ context.Context, id int) (*model.Role, error) {
+	if row, ok := r.rows[id]; ok {
+		found := *row
+		return &found, nil
+	}
+	return nil, apperror.NotFound("role_not_found", "role is not exists")
+}
+
+func (r roles) ReadBy(_ context.Context, criteria map[string]interface{}) (*model.Role, error) {
+	for _, row := range r.rows {
+		if row.Name != criteria["name"] {
+			continue
+		}
+		companies, scoped := criteria["company_id"].([]int)
+		if !scoped || row.CompanyID == companies[0] || row.CompanyID == companies[1] {
+			found := *row
+			return &found, nil
+		}
+	}
+	return nil, apperror.NotFound("role_not_found", "role is not exists")
+}
+
+func (r roles) Update(ctx context.Context, id int, m *model.Role) (*model.Role, error) {
+	r.rows[id].Name = m.Name
+	return r.ReadById(ctx, id)
+}
+
+func (r roles) Delete(_ context.Context, id int) error {
+	delete(r.rows, id)
+	return nil
+}
+
+type companies struct {
+	company.Repository
+}
+
+func (companies) ReadById(_ context.Context, id int) (*model.Company, error) {
+	if id != 5 && id != 6 {
+		return nil, apperror.NotFound("company_not_found", "company is not exists")
+	}
+	return &model.Company{ID: id}, nil
+}
+
+// The superadmin is user 1, admins of companies 5 and 6 are users 2 and 3.
+const (
+	superadmin = 1
+	admin5     = 2
+	admin6     = 3
+)
+
+func newUsecase(t *testing.T) (Usecase, *mock.MockRepository, *casbin.SyncedEnforcer) {
+	users := mock.NewMockRepository(gomock.NewController(t))
+	for _, u := range []model.User{{ID: superadmin, RoleID: 1}, {ID: admin5, RoleID: 2, CompanyID: 5}, {ID: admin6, RoleID: 2, CompanyID: 6}} {
+		u := u
+		users.EXPECT().ReadById(gomock.Any(), u.ID).Return(&u, nil).AnyTimes()
+	}
+
+	enforcer := casbin.NewSyncedEnforcer("../../config/rbac_model.conf", false)
+	permission.Seed(enforcer)
+	permission.Assign(enforcer, admin5, "admin", "5")
+	permission.Assign(enforcer, admin6, "admin", "6")
+
+	next := 2
+	rows := map[int]*model.Role{1: {ID: 1, Name: "superadmin"}, 2: {ID: 2, Name: "admin"}}
+	return NewUsecase(roles{rows: rows, next: &next}, users, companies{}, enforcer), users, enforcer
+}
+
+func TestCreateGrantsBaseline(t *testing.T) {
+	uc, _, enforcer := newUsecase(t)
+
+	r, err := uc.Create(context.Background(), admin5, &request.Role{Name: "auditor"})
+	require.NoError(t, err)
+	require.Equal(t, 5, r.CompanyID)
+
+	_, permissions, err := uc.Permissions(context.Background(), admin5, r.ID)
+	require.NoError(t, err)
+	require.ElementsMatch(t, permission.Baseline, permissions)
+	require.Empty(t, permission.Of(enforcer, "auditor", permission.AllCompanies))
+}
+
+func TestCreateScopesRoleNames(t *testing.T) {
+	uc, _, _ := newUsecase(t)
+
+	_, err := uc.Create(context.Background(), admin5, &request.Role{Name: "auditor"})
+	require.NoError(t, err)
+	_, err = uc.Create(context.Background(), admin6, &request.Role{Name: "auditor"})
+	require.NoError(t, err, "companies define their roles independently")
+
+	_, err = uc.Create(context.Background(), admin5, &request.Role{Name: "auditor"})
+	require.True(t, apperror.Is(err, apperror.KindConflict))
+	_, err = uc.Create(context.Background(), admin5, &request.Role{Name: "admin"})
+	require.True(t, apperror.Is(err, apperror.KindConflict), "shared role names are taken in every company")
+	_, err = uc.Create(context.Background(), superadmin, &request.Role{Name: "auditor"})
+	require.True(t, apperror.Is(err, apperror.KindConflict))
+
+	_, err = uc.Create(context.Background(), admin5, &request.Role{Name: "clerk", CompanyID: 6})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+	r, err := uc.Create(context.Background(), superadmin, &request.Role{Name: "clerk", CompanyID: 6})
+	require.NoError(t, err)
+	require.Equal(t, 6, r.CompanyID)
+}
+
+func TestRolesOfAnotherCompany(t *testing.T) {
+	uc, _, _ := newUsecase(t)
+	r, err := uc.Create(context.Background(), admin5, &request.Role{Name: "auditor"})
+	require.NoError(t, err)
+
+	_, err = uc.ReadById(context.Background(), admin6, r.ID)
+	require.True(t, apperror.IsNotFound(err))
+	_, _, err = uc.SetPermissions(context.Background(), admin6, r.ID, []string{"user:read"})
+	require.True(t, apperror.IsNotFound(err))
+	err = uc.Delete(context.Background(), admin6, r.ID)
+	require.True(t, apperror.IsNotFound(err))
+
+	_, err = uc.ReadById(context.Background(), admin6, 2)
+	require.NoError(t, err, "shared roles are seen by every company")
+	_, _, err = uc.SetPermissions(context.Background(), admin6, 2, []string{"user:read"})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+}
+
+func TestSetPermissions(t *testing.T) {
+	uc, _, enforcer := newUsecase(t)
+	r, err := uc.Create(context.Background(), admin5, &request.Role{Name: "auditor"})
+	require.NoError(t, err)
+
+	_, permissions, err := uc.SetPermissions(context.Background(), admin5, r.ID, []string{"user:read", "login:manage"})
+	require.NoError(t, err)
+	require.Equal(t, []permission.Permission{permission.LoginManage, permission.UserRead}, permissions)
+	require.False(t, enforcer.HasPolicy("auditor", "5", "product", "read"))
+
+	_, _, err = uc.SetPermissions(context.Background(), admin5, r.ID, []string{"user:read", "report:read", "nonsense"})
+	var appErr *apperror.Error
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, apperror.KindValidation, appErr.Kind)
+	require.Len(t, appErr.Details, 2)
+	require.Equal(t, "permissions[1]", appErr.Details[0].Field)
+
+	_, _, err = uc.SetPermissions(context.Background(), admin5, r.ID, []string{"giro:manage"})
+	require.True(t, apperror.Is(err, apperror.KindForbidden), "admins grant what they are granted only")
+	_, _, err = uc.SetPermissions(context.Background(), superadmin, r.ID, []string{"giro:manage"})
+	require.NoError(t, err)
+
+	_, _, err = uc.SetPermissions(context.Background(), superadmin, 1, []string{"user:read"})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+	require.Len(t, enforcer.GetFilteredPolicy(0, "superadmin"), len(permission.Catalog))
+}
+
+func TestUpdateRenamesPolicies(t *testing.T) {
+	uc, _, enforcer := newUsecase(t)
+	r, err := uc.Create(context.Background(), admin5, &request.Role{Name: "auditor"})
+	require.NoError(t, err)
+	permission.Assign(enforcer, 7, "auditor", "5")
+
+	_, err = uc.Update(context.Background(), admin5, r.ID, &request.Role{Name: "reviewer"})
+	require.NoError(t, err)
+	roles, permissions := permission.Effective(enforcer, 7, "5")
+	require.Equal(t, []string{"reviewer"}, roles)
+	require.ElementsMatch(t, permission.Baseline, permissions)
+
+	_, err = uc.Update(context.Background(), superadmin, 2, &request.Role{Name: "owner"})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+}
+
+func TestDeleteRefusesRoleInUse(t *testing.T) {
+	uc, users, enforcer := newUsecase(t)
+	r, err := uc.Create(context.Background(), admin5, &request.Role{Name: "auditor"})
+	require.NoError(t, err)
+
+	users.EXPECT().Count(gomock.Any(), map[string]interface{}{"role_id": r.ID, "deleted_at": nil}).Return(1)
+	err = uc.Delete(context.Background(), admin5, r.ID)
+	require.True(t, apperror.Is(err, apperror.KindConflict))
+
+	users.EXPECT().Count(gomock.Any(), gomock.Any()).Return(0)
+	require.NoError(t, uc.Delete(context.Background(), admin5, r.ID))
+	require.Empty(t, enforcer.GetFilteredPolicy(0, "auditor"))
+
+	err = uc.Delete(context.Background(), superadmin, 2)
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+}
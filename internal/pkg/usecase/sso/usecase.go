@@ -0,0 +1,507 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/actor"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/oidc"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/company"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/login_attempt"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/sso"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/auth"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/two_factor"
+	"github.com/casbin/casbin"
+)
+
+// Authorization is where to send a user to log in with the provider of their company.
+type Authorization struct {
+	URL       string
+	ExpiresAt time.Time
+}
+
+type Usecase interface {
+	Provider(ctx context.Context, actorID, companyID int) (*model.SSOProvider, error)
+	SaveProvider(ctx context.Context, actorID, companyID int, r request.SSOProvider) (*model.SSOProvider, error)
+	DeleteProvider(ctx context.Context, actorID, companyID int) error
+	Authorize(ctx context.Context, companyID int) (*Authorization, error)
+	Login(ctx context.Context, login request.SSOLogin) (*model.User, *two_factor.Challenge, error)
+}
+
+type usecase struct {
+	ssoRepository          sso.Repository
+	userRepository         user.Repository
+	roleRepository         role.Repository
+	companyRepository      company.Repository
+	loginAttemptRepository login_attempt.Repository
+	client                 oidc.Client
+	challenger             auth.Challenger
+	enforcer               *casbin.SyncedEnforcer
+	redirectURL            string
+	loginTTL               time.Duration
+}
+
+// NewUsecase has providers redirect users back to redirectURL, which posts the code
+// and state to POST /login/sso, within loginTTL.
+func NewUsecase(ssoRepository sso.Repository, userRepository user.Repository, roleRepository role.Repository, companyRepository company.Repository, loginAttemptRepository login_attempt.Repository, client oidc.Client, challenger auth.Challenger, enforcer *casbin.SyncedEnforcer, redirectURL string, loginTTL time.Duration) Usecase {
+	return &usecase{ssoRepository, userRepository, roleRepository, companyRepository, loginAttemptRepository, client, challenger, enforcer, redirectURL, loginTTL}
+}
+
+// Provider is the provider of a company. Admins other than superadmins see the one of
+// their own company.
+func (e *usecase) Provider(ctx context.Context, actorID, companyID int) (*model.SSOProvider, error) {
+	if _, err := e.scope(ctx, actorID, companyID); err != nil {
+		return nil, err
+	}
+	return e.ssoRepository.ReadProviderBy(ctx, map[string]interface{}{"company_id": companyID})
+}
+
+// SaveProvider configures the provider of a company. The roles it maps users to have to
+// be available to the company and, unless the actor is a superadmin, grant no permission
+// the actor is not granted. An enabled provider has to be discoverable.
+func (e *usecase) SaveProvider(ctx context.Context, actorID, companyID int, r request.SSOProvider) (*model.SSOProvider, error) {
+	a, err := e.scope(ctx, actorID, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := e.companyRepository.ReadById(ctx, companyID); err != nil {
+		return nil, err
+	}
+	p, err := e.ssoRepository.ReadProviderBy(ctx, map[string]interface{}{"company_id": companyID})
+	if apperror.IsNotFound(err) {
+		p, err = &model.SSOProvider{CompanyID: companyID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var details []apperror.FieldError
+	if r.ClientSecret == "" && (p.ClientSecret == "" || r.ClientID != p.ClientID) {
+		details = append(details, apperror.FieldError{Field: "client_secret", Message: "client_secret is required"})
+	}
+	if len(r.RoleMapping) > 0 && r.RoleClaim == "" {
+		details = append(details, apperror.FieldError{Field: "role_claim", Message: "role_claim is required along with role_mapping"})
+	}
+	rules := make([]model.SSORoleRule, 0, len(r.RoleMapping))
+	for i, rule := range r.RoleMapping {
+		reason, err := e.mappable(ctx, a, companyID, rule.RoleID)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			details = append(details, apperror.FieldError{Field: fmt.Sprintf("role_mapping[%d].role_id", i), Message: reason})
+		}
+		rules = append(rules, model.SSORoleRule{Value: rule.Value, RoleID: rule.RoleID})
+	}
+	if r.DefaultRoleID != 0 {
+		reason, err := e.mappable(ctx, a, companyID, r.DefaultRoleID)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			details = append(details, apperror.FieldError{Field: "default_role_id", Message: reason})
+		}
+	}
+	if len(details) == 0 && *r.Enabled {
+		if _, err := e.client.Discover(ctx, r.Issuer); err != nil {
+			helper.Logger(ctx).WithError(err).Warn("[ssoUsecase.SaveProvider] failed discovering provider")
+			details = append(details, apperror.FieldError{Field: "issuer", Message: "issuer does not publish an OpenID Connect discovery document"})
+		}
+	}
+	if len(details) > 0 {
+		return nil, apperror.Validation("validation_error", "request validation failed").WithDetails(details...)
+	}
+
+	p.Issuer = r.Issuer
+	p.ClientID = r.ClientID
+	if r.ClientSecret != "" {
+		p.ClientSecret = r.ClientSecret
+	}
+	p.Scopes = strings.Join(r.Scopes, " ")
+	p.RoleClaim = r.RoleClaim
+	p.SetRules(rules)
+	p.DefaultRoleID = r.DefaultRoleID
+	p.Enabled = *r.Enabled
+	p.ConfiguredBy = actorID
+	return e.ssoRepository.SaveProvider(ctx, p)
+}
+
+// DeleteProvider stops the users of a company logging in with their provider, and
+// forgets the identities linked to it. The users are kept.
+func (e *usecase) DeleteProvider(ctx context.Context, actorID, companyID int) error {
+	if _, err := e.scope(ctx, actorID, companyID); err != nil {
+		return err
+	}
+	p, err := e.ssoRepository.ReadProviderBy(ctx, map[string]interface{}{"company_id": companyID})
+	if err != nil {
+		return err
+	}
+	return e.ssoRepository.DeleteProvider(ctx, p.ID)
+}
+
+// Authorize starts a login with the provider of a company, returning where to send the
+// user. Only the hash of the state is kept until the user comes back with it.
+func (e *usecase) Authorize(ctx context.Context, companyID int) (*Authorization, error) {
+	p, err := e.ssoRepository.ReadProviderBy(ctx, map[string]interface{}{"company_id": companyID})
+	if err == nil && !p.Enabled {
+		err = apperror.NotFound("sso_provider_not_found", "sso_provider is not exists")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := helper.NewSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := helper.NewSecretToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return nil, apperror.Internal("secret_error", "failed generating code verifier").Wrap(err)
+	}
+
+	authURL, err := e.client.AuthorizationURL(ctx, e.config(p), state, nonce, oidc.Challenge(verifier))
+	if err != nil {
+		return nil, unavailable().Wrap(err)
+	}
+	login, err := e.ssoRepository.CreateLogin(ctx, &model.SSOLogin{
+		ProviderID:   p.ID,
+		StateHash:    helper.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(e.loginTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Authorization{URL: authURL, ExpiresAt: login.ExpiresAt}, nil
+}
+
+// Login completes a login the user came back from with a code. The user is found by the
+// identity linked to them, else by the email the provider verified, which links them
+// when they are granted no more than the admin of the provider, else they are
+// provisioned into the company with the role their claims map to. The mapped role is
+// applied again at every login. Users logging in with a second factor get a challenge
+// to answer instead of succeeding, and every attempt is audited.
+func (e *usecase) Login(ctx context.Context, login request.SSOLogin) (*model.User, *two_factor.Challenge, error) {
+	l, err := e.ssoRepository.ReadLoginBy(ctx, map[string]interface{}{"state_hash": helper.HashToken(login.State)})
+	if apperror.IsNotFound(err) {
+		return nil, nil, invalidState().Wrap(err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if l.UsedAt != nil || l.Expired(time.Now()) {
+		return nil, nil, invalidState()
+	}
+	err = e.ssoRepository.UseLogin(ctx, l.ID)
+	if apperror.Is(err, apperror.KindConflict) {
+		return nil, nil, invalidState().Wrap(err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, err := e.ssoRepository.ReadProviderBy(ctx, map[string]interface{}{"id": l.ProviderID})
+	if apperror.IsNotFound(err) || (err == nil && !p.Enabled) {
+		return nil, nil, invalidState()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attempt := &model.LoginAttempt{CompanyID: p.CompanyID, IP: login.IP, UserAgent: login.UserAgent}
+	claims, err := e.client.Login(ctx, e.config(p), login.Code, l.CodeVerifier, l.Nonce)
+	if errors.Is(err, oidc.ErrRejected) {
+		e.audit(ctx, attempt, enum.LoginSSORejected)
+		return nil, nil, rejected().Wrap(err)
+	}
+	if err != nil {
+		return nil, nil, unavailable().Wrap(err)
+	}
+	attempt.Email = claims.Email
+
+	// users of a company that onboarded itself wait for a superadmin to approve it
+	c, err := e.companyRepository.ReadById(ctx, p.CompanyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch c.Status {
+	case enum.CompanyPending:
+		e.audit(ctx, attempt, enum.LoginCompanyPending)
+		return nil, nil, apperror.Forbidden("company_pending", "company is waiting for approval")
+	case enum.CompanyRejected:
+		e.audit(ctx, attempt, enum.LoginCompanyRejected)
+		return nil, nil, apperror.Forbidden("company_rejected", "company was rejected")
+	}
+
+	u, err := e.identify(ctx, p, claims)
+	if err != nil {
+		if apperror.Is(err, apperror.KindUnauthorized) || apperror.Is(err, apperror.KindConflict) || apperror.Is(err, apperror.KindForbidden) {
+			e.audit(ctx, attempt, enum.LoginSSORejected)
+		}
+		return nil, nil, err
+	}
+	attempt.Email = u.Email
+	attempt.UserID = u.ID
+
+	if u, err = e.syncRole(ctx, p, claims, u); err != nil {
+		return nil, nil, err
+	}
+
+	challenge, err := e.challenger.Challenge(ctx, u)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge != nil {
+		e.audit(ctx, attempt, enum.LoginTwoFactorPending)
+		return u, challenge, nil
+	}
+
+	attempt.Success = true
+	e.audit(ctx, attempt, enum.LoginSSOSucceeded)
+	return u, nil, nil
+}
+
+// identify finds the user of the claims, linking or provisioning them the first time.
+func (e *usecase) identify(ctx context.Context, p *model.SSOProvider, claims *oidc.Claims) (*model.User, error) {
+	identity, err := e.ssoRepository.ReadIdentityBy(ctx, map[string]interface{}{"provider_id": p.ID, "subject": claims.Subject})
+	if err == nil {
+		u, err := e.userRepository.ReadById(ctx, identity.UserID)
+		if apperror.IsNotFound(err) {
+			return nil, apperror.Unauthorized("sso_user_deleted", "user was deleted").Wrap(err)
+		}
+		return u, err
+	}
+	if !apperror.IsNotFound(err) {
+		return nil, err
+	}
+
+	// only an email the provider verified identifies a user it never logged in
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, apperror.Unauthorized("sso_email_unverified", "identity provider did not verify the email of the user")
+	}
+	u, err := e.userRepository.ReadByEmail(ctx, claims.Email)
+	if apperror.IsNotFound(err) {
+		u, err = e.provision(ctx, p, claims)
+	} else if err == nil {
+		err = e.linkable(ctx, p, u)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = e.ssoRepository.CreateIdentity(ctx, &model.UserIdentity{UserID: u.ID, ProviderID: p.ID, Subject: claims.Subject, Email: claims.Email})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// linkable tells whether an existing user may be linked to a subject of the provider:
+// they have to be of its company, not linked to another subject yet, and granted no
+// permission the admin who configured the provider is not. Superadmins never are.
+func (e *usecase) linkable(ctx context.Context, p *model.SSOProvider, u *model.User) error {
+	if u.CompanyID != p.CompanyID {
+		return apperror.Conflict("sso_email_taken", "email belongs to a user of another company")
+	}
+	_, err := e.ssoRepository.ReadIdentityBy(ctx, map[string]interface{}{"provider_id": p.ID, "user_id": u.ID})
+	if err == nil {
+		return apperror.Conflict("sso_email_taken", "user is linked to another identity")
+	}
+	if !apperror.IsNotFound(err) {
+		return err
+	}
+
+	unlinkable := apperror.Forbidden("sso_link_forbidden", "user is granted more than the admin of the provider, log in with your password")
+	ro, err := e.roleRepository.ReadById(ctx, u.RoleID)
+	if err != nil {
+		return err
+	}
+	if ro.IsSuperadmin() {
+		return unlinkable
+	}
+	admin, err := actor.Load(ctx, e.userRepository, e.roleRepository, p.ConfiguredBy)
+	if apperror.IsNotFound(err) {
+		return unlinkable.Wrap(err)
+	}
+	if err != nil {
+		return err
+	}
+	if admin.Superadmin {
+		return nil
+	}
+	_, granted := permission.Effective(e.enforcer, admin.ID, permission.Domain(admin.CompanyID))
+	_, held := permission.Effective(e.enforcer, u.ID, permission.Domain(u.CompanyID))
+	if len(permission.Missing(granted, held)) > 0 {
+		return unlinkable
+	}
+	return nil
+}
+
+// provision creates the user of the claims in the company of the provider. They have no
+// password, logging in with the provider only until they reset one.
+func (e *usecase) provision(ctx context.Context, p *model.SSOProvider, claims *oidc.Claims) (*model.User, error) {
+	roleID := p.Role(claims.Strings(p.RoleClaim))
+	if roleID == 0 {
+		roleID = p.DefaultRoleID
+	}
+	if roleID == 0 {
+		return nil, apperror.Forbidden("sso_role_unmapped", "no role is mapped to the user, ask the admin of the company")
+	}
+	reason, err := e.unmappable(ctx, p.CompanyID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		helper.Logger(ctx).WithField("role_id", roleID).WithField("reason", reason).Warn("[ssoUsecase.Login] mapped role is not available")
+		return nil, apperror.Forbidden("sso_role_unmapped", "no role is mapped to the user, ask the admin of the company")
+	}
+	r, err := e.roleRepository.ReadById(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+	u, err := e.userRepository.Create(ctx, &model.User{
+		Name:              name,
+		Email:             claims.Email,
+		VerificationLevel: enum.Level1,
+		RoleID:            r.ID,
+		CompanyID:         p.CompanyID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// there is no password to change
+	u, err = e.userRepository.Patch(ctx, u.ID, u.Version, map[string]interface{}{"must_change_password": false})
+	if err != nil {
+		return nil, err
+	}
+	permission.Assign(e.enforcer, u.ID, r.Name, permission.Domain(u.CompanyID))
+	return u, nil
+}
+
+// syncRole gives the user the role their claims map to, when a rule matches. Users
+// matching none keep their role, as do those mapped to a role that cannot be mapped
+// anymore.
+func (e *usecase) syncRole(ctx context.Context, p *model.SSOProvider, claims *oidc.Claims, u *model.User) (*model.User, error) {
+	roleID := p.Role(claims.Strings(p.RoleClaim))
+	if roleID == 0 || roleID == u.RoleID {
+		return u, nil
+	}
+	reason, err := e.unmappable(ctx, u.CompanyID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		helper.Logger(ctx).WithField("role_id", roleID).WithField("reason", reason).Warn("[ssoUsecase.Login] mapped role is not available")
+		return u, nil
+	}
+	r, err := e.roleRepository.ReadById(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := e.userRepository.Patch(ctx, u.ID, u.Version, map[string]interface{}{"role_id": r.ID})
+	if err != nil {
+		return nil, err
+	}
+	permission.Assign(e.enforcer, u.ID, r.Name, permission.Domain(u.CompanyID))
+	return updated, nil
+}
+
+// unmappable tells why users of the company may not be given the role through their
+// provider, empty when they may. Superadmins are never provisioned.
+func (e *usecase) unmappable(ctx context.Context, companyID, roleID int) (string, error) {
+	r, err := e.roleRepository.ReadById(ctx, roleID)
+	if apperror.IsNotFound(err) || (err == nil && !r.AvailableTo(companyID)) {
+		return "role is not exists", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if r.IsSuperadmin() {
+		return "superadmin role cannot be mapped", nil
+	}
+	return "", nil
+}
+
+// mappable tells why the actor may not map users of the company to the role: on top of
+// unmappable, admins other than superadmins map roles granting no permission they are
+// not granted.
+func (e *usecase) mappable(ctx context.Context, a *actor.Actor, companyID, roleID int) (string, error) {
+	reason, err := e.unmappable(ctx, companyID, roleID)
+	if err != nil || reason != "" || a.Superadmin {
+		return reason, err
+	}
+	r, err := e.roleRepository.ReadById(ctx, roleID)
+	if err != nil {
+		return "", err
+	}
+	_, granted := permission.Effective(e.enforcer, a.ID, permission.Domain(a.CompanyID))
+	if missing := permission.Missing(granted, permission.Grants(e.enforcer, r.Name, permission.Domain(companyID))); len(missing) > 0 {
+		return fmt.Sprintf("role grants %s, which is not granted to you", missing[0]), nil
+	}
+	return "", nil
+}
+
+// scope loads the actor, failing as not found when they may not configure the provider
+// of the company, admins other than superadmins configuring the one of their own company.
+func (e *usecase) scope(ctx context.Context, actorID, companyID int) (*actor.Actor, error) {
+	a, err := actor.Load(ctx, e.userRepository, e.roleRepository, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.Sees(companyID) {
+		return nil, apperror.NotFound("company_not_found", "company is not exists")
+	}
+	return a, nil
+}
+
+func (e *usecase) config(p *model.SSOProvider) oidc.Config {
+	return oidc.Config{
+		Issuer:       p.Issuer,
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  e.redirectURL,
+		Scopes:       strings.Fields(p.Scopes),
+	}
+}
+
+// audit records a login attempt. Failing to is only logged, so users are not locked
+// out when the audit cannot be written.
+func (e *usecase) audit(ctx context.Context, attempt *model.LoginAttempt, result enum.LoginResult) {
+	attempt.Result = result
+	if _, err := e.loginAttemptRepository.Create(ctx, attempt); err != nil {
+		helper.Logger(ctx).WithError(err).Error("[ssoUsecase.Login] failed auditing login attempt")
+	}
+}
+
+func invalidState() *apperror.Error {
+	return apperror.Unauthorized("sso_invalid_state", "sso login is unknown or expired, start again")
+}
+
+func rejected() *apperror.Error {
+	return apperror.Unauthorized("sso_rejected", "identity provider did not authenticate the user")
+}
+
+func unavailable() *apperror.Error {
+	return apperror.Upstream("sso_unavailable", "identity provider cannot be reached")
+}
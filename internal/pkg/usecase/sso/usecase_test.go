@@ -0,0 +1,594 @@
+package sso
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/oidc"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/oidc/oidctest"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/company"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/login_attempt"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/usecase/two_factor"
+	"github.com/casbin/casbin"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+// store keeps providers, logins and identities in memory.
+type store struct {
+	providers  []*model.SSOProvider
+	logins     []*model.SSOLogin
+	identities []*model.UserIdentity
+}
+
+func (s *store) ReadProviderBy(_ context.Context, criteria map[string]interface{}) (*model.SSOProvider, error) {
+	for _, p := range s.providers {
+		if p.ID == criteria["id"] || p.CompanyID == criteria["company_id"] {
+			found := *p
+			return &found, nil
+		}
+	}
+	return nil, apperror.NotFound("sso_provider_not_found", "sso_provider is not exists")
+}
+
+func (s *store) SaveProvider(_ context.Context, p *model.SSOProvider) (*model.SSOProvider, error) {
+	saved := *p
+	if p.ID == 0 {
+		p.ID = len(s.providers) + 1
+		saved.ID = p.ID
+		s.providers = append(s.providers, &saved)
+		return p, nil
+	}
+	s.providers[p.ID-1] = &saved
+	return p, nil
+}
+
+func (s *store) DeleteProvider(_ context.Context, id int) error {
+	s.providers[id-1] = &model.SSOProvider{}
+	identities := s.identities[:0]
+	for _, i := range s.identities {
+		if i.ProviderID != id {
+			identities = append(identities, i)
+		}
+	}
+	s.identities = identities
+	return nil
+}
+
+func (s *store) CreateLogin(_ context.Context, l *model.SSOLogin) (*model.SSOLogin, error) {
+	l.ID = len(s.logins) + 1
+	saved := *l
+	s.logins = append(s.logins, &saved)
+	return l, nil
+}
+
+func (s *store) ReadLoginBy(_ context.Context, criteria map[string]interface{}) (*model.SSOLogin, error) {
+	for _, l := range s.logins {
+		if l.StateHash == criteria["state_hash"] {
+			found := *l
+			return &found, nil
+		}
+	}
+	return nil, apperror.NotFound("sso_login_not_found", "sso_login is not exists")
+}
+
+func (s *store) UseLogin(_ context.Context, id int) error {
+	l := s.logins[id-1]
+	if l.UsedAt != nil {
+		return apperror.Conflict("sso_login_used", "sso login was completed meanwhile")
+	}
+	now := time.Now()
+	l.UsedAt = &now
+	return nil
+}
+
+func (s *store) ReadIdentityBy(_ context.Context, criteria map[string]interface{}) (*model.UserIdentity, error) {
+	for _, i := range s.identities {
+		if i.ProviderID == criteria["provider_id"] && (i.Subject == criteria["subject"] || i.UserID == criteria["user_id"]) {
+			found := *i
+			return &found, nil
+		}
+	}
+	return nil, apperror.NotFound("user_identity_not_found", "user_identity is not exists")
+}
+
+func (s *store) CreateIdentity(_ context.Context, i *model.UserIdentity) (*model.UserIdentity, error) {
+	i.ID = len(s.identities) + 1
+	saved := *i
+	s.identities = append(s.identities, &saved)
+	return i, nil
+}
+
+// users keeps users in memory, creating them with the defaults of the table.
+type users struct {
+	user.Repository
+	rows map[int]*model.User
+}
+
+func (r users) Create(_ context.Context, u *model.User) (*model.User, error) {
+	u.ID = len(r.rows) + 1
+	u.Version = 1
+	u.MustChangePassword = true
+	saved := *u
+	r.rows[u.ID] = &saved
+	return u, nil
+}
+
+func (r users) ReadById(_ context.Context, id int) (*model.User, error) {
+	if u, ok := r.rows[id]; ok {
+		found := *u
+		return &found, nil
+	}
+	return nil, apperror.NotFound("user_not_found", "user is not exists")
+}
+
+func (r users) ReadByEmail(_ context.Context, email string) (*model.User, error) {
+	for _, u := range r.rows {
+		if u.Email == email {
+			found := *u
+			return &found, nil
+		}
+	}
+	return nil, apperror.NotFound("user_not_found", "user is not exists")
+}
+
+func (r users) Patch(ctx context.Context, id, version int, fields map[string]interface{}) (*model.User, error) {
+	u := r.rows[id]
+	if u.Version != version {
+		return nil, apperror.VersionMismatch("user")
+	}
+	if v, ok := fields["must_change_password"]; ok {
+		u.MustChangePassword = v.(bool)
+	}
+	if v, ok := fields["role_id"]; ok {
+		u.RoleID = v.(int)
+	}
+	u.Version++
+	return r.ReadById(ctx, id)
+}
+
+type roles struct {
+	role.Repository
+}
+
+// The shared roles are superadmin 1, admin 2 and user 3; role 4 belongs to company 5
+// and role 6 to company 6.
+var roleRows = map[int]model.Role{
+	1: {ID: 1, Name: enum.RoleSuperadmin},
+	2: {ID: 2, Name: enum.RoleCompanyAdmin},
+	3: {ID: 3, Name: "user"},
+	4: {ID: 4, Name: "finance", CompanyID: 5},
+	6: {ID: 6, Name: "finance", CompanyID: 6},
+}
+
+func (roles) ReadById(_ context.Context, id int) (*model.Role, error) {
+	if r, ok := roleRows[id]; ok {
+		return &r, nil
+	}
+	return nil, apperror.NotFound("role_not_found", "role is not exists")
+}
+
+type companies struct {
+	company.Repository
+	status map[int]enum.StatusCompany
+}
+
+func (c companies) ReadById(_ context.Context, id int) (*model.Company, error) {
+	status, ok := c.status[id]
+	if !ok {
+		return nil, apperror.NotFound("company_not_found", "company is not exists")
+	}
+	return &model.Company{ID: id, Status: status}, nil
+}
+
+type attempts struct {
+	login_attempt.Repository
+	rows *[]model.LoginAttempt
+}
+
+func (a attempts) Create(_ context.Context, attempt *model.LoginAttempt) (*model.LoginAttempt, error) {
+	*a.rows = append(*a.rows, *attempt)
+	return attempt, nil
+}
+
+// challenger challenges the users of challenged.
+type challenger map[int]bool
+
+func (c challenger) Challenge(_ context.Context, u *model.User) (*two_factor.Challenge, error) {
+	if c[u.ID] {
+		return &two_factor.Challenge{Token: "challenge"}, nil
+	}
+	return nil, nil
+}
+
+// The superadmin is user 1, the admin of company 5 user 2.
+const (
+	superadmin = 1
+	admin5     = 2
+)
+
+type fixture struct {
+	uc         *usecase
+	idp        *oidctest.Provider
+	store      *store
+	users      users
+	attempts   *[]model.LoginAttempt
+	challenged challenger
+	enforcer   *casbin.SyncedEnforcer
+}
+
+func setup(t *testing.T) *fixture {
+	viper.Set("JWT_SECRET", "test-secret")
+	t.Cleanup(func() { viper.Set("JWT_SECRET", nil) })
+	idp := oidctest.NewProvider()
+	t.Cleanup(idp.Close)
+
+	enforcer := casbin.NewSyncedEnforcer("../../config/rbac_model.conf", false)
+	permission.Seed(enforcer)
+
+	f := &fixture{
+		idp:   idp,
+		store: &store{},
+		users: users{rows: map[int]*model.User{
+			superadmin: {ID: superadmin, Email: "root@pari.example.com", RoleID: 1, Version: 1},
+			admin5:     {ID: admin5, Email: "admin@five.example.com", RoleID: 2, CompanyID: 5, Version: 1},
+		}},
+		attempts:   &[]model.LoginAttempt{},
+		challenged: challenger{},
+		enforcer:   enforcer,
+	}
+	c := companies{status: map[int]enum.StatusCompany{5: enum.CompanyApproved, 6: enum.CompanyApproved, 7: enum.CompanyPending}}
+	f.uc = &usecase{f.store, f.users, roles{}, c, attempts{rows: f.attempts}, oidc.NewClient(http.DefaultClient), f.challenged, enforcer, "https://pari.example.com/sso/callback", 10 * time.Minute}
+	return f
+}
+
+func enabled() *bool {
+	b := true
+	return &b
+}
+
+// configure sets up the provider of a company: users of the finance group are given the
+// finance role of the company, if any, the others role 3.
+func (f *fixture) configure(t *testing.T, companyID int) *model.SSOProvider {
+	r := request.SSOProvider{
+		Issuer:        f.idp.Issuer(),
+		ClientID:      f.idp.ClientID,
+		ClientSecret:  f.idp.ClientSecret,
+		RoleClaim:     "groups",
+		DefaultRoleID: 3,
+		Enabled:       enabled(),
+	}
+	for _, role := range roleRows {
+		if role.Name == "finance" && role.CompanyID == companyID {
+			r.RoleMapping = []request.SSORoleRule{{Value: "finance", RoleID: role.ID}}
+		}
+	}
+	p, err := f.uc.SaveProvider(context.Background(), superadmin, companyID, r)
+	require.NoError(t, err)
+	return p
+}
+
+// login logs the user of claims in with the provider of the company.
+func (f *fixture) login(t *testing.T, companyID int, claims map[string]interface{}) (*model.User, *two_factor.Challenge, error) {
+	a, err := f.uc.Authorize(context.Background(), companyID)
+	require.NoError(t, err)
+	code, state, err := f.idp.Authorize(a.URL, claims)
+	require.NoError(t, err)
+	return f.uc.Login(context.Background(), request.SSOLogin{State: state, Code: code, IP: "10.0.0.1"})
+}
+
+func (f *fixture) lastResult() enum.LoginResult {
+	return (*f.attempts)[len(*f.attempts)-1].Result
+}
+
+func TestLoginProvisions(t *testing.T) {
+	f := setup(t)
+	f.configure(t, 5)
+
+	u, challenge, err := f.login(t, 5, map[string]interface{}{
+		"sub": "alex", "email": "alex@five.example.com", "email_verified": true, "name": "Alex", "groups": []string{"finance"},
+	})
+	require.NoError(t, err)
+	require.Nil(t, challenge)
+	require.Equal(t, "Alex", u.Name)
+	require.Equal(t, 5, u.CompanyID)
+	require.Equal(t, 4, u.RoleID)
+	require.False(t, u.MustChangePassword)
+	require.Empty(t, u.Password)
+	require.Equal(t, enum.LoginSSOSucceeded, f.lastResult())
+	require.True(t, (*f.attempts)[0].Success)
+	require.Equal(t, u.ID, (*f.attempts)[0].UserID)
+	roles, _ := permission.Effective(f.enforcer, u.ID, "5")
+	require.Equal(t, []string{"finance"}, roles)
+
+	// the same subject logs in as the same user, whatever email it has now
+	again, _, err := f.login(t, 5, map[string]interface{}{"sub": "alex", "email": "alex@new.example.com"})
+	require.NoError(t, err)
+	require.Equal(t, u.ID, again.ID)
+	require.Equal(t, 4, again.RoleID, "users matching no rule keep their role")
+	require.Len(t, f.users.rows, 3)
+	require.Len(t, f.store.identities, 1)
+}
+
+func TestLoginSyncsMappedRole(t *testing.T) {
+	f := setup(t)
+	f.configure(t, 5)
+
+	u, _, err := f.login(t, 5, map[string]interface{}{"sub": "alex", "email": "alex@five.example.com", "email_verified": true})
+	require.NoError(t, err)
+	require.Equal(t, 3, u.RoleID, "users matching no rule are given the default role")
+
+	u, _, err = f.login(t, 5, map[string]interface{}{"sub": "alex", "groups": "finance"})
+	require.NoError(t, err)
+	require.Equal(t, 4, u.RoleID)
+	roles, _ := permission.Effective(f.enforcer, u.ID, "5")
+	require.Equal(t, []string{"finance"}, roles)
+}
+
+func TestLoginLinksVerifiedEmail(t *testing.T) {
+	f := setup(t)
+	f.configure(t, 5)
+	f.users.rows[3] = &model.User{ID: 3, Email: "budi@five.example.com", RoleID: 2, CompanyID: 5, Version: 1}
+	f.users.rows[4] = &model.User{ID: 4, Email: "budi@six.example.com", RoleID: 3, CompanyID: 6, Version: 1}
+
+	_, _, err := f.login(t, 5, map[string]interface{}{"sub": "budi", "email": "budi@five.example.com"})
+	require.True(t, apperror.Is(err, apperror.KindUnauthorized), "an unverified email links no one")
+	require.Equal(t, enum.LoginSSORejected, f.lastResult())
+
+	_, _, err = f.login(t, 5, map[string]interface{}{"sub": "budi", "email": "budi@six.example.com", "email_verified": true})
+	require.True(t, apperror.Is(err, apperror.KindConflict), "users of another company are not linked")
+
+	u, _, err := f.login(t, 5, map[string]interface{}{"sub": "budi", "email": "budi@five.example.com", "email_verified": true})
+	require.NoError(t, err)
+	require.Equal(t, 3, u.ID)
+	require.Equal(t, 2, u.RoleID)
+	require.Equal(t, []*model.UserIdentity{{ID: 1, UserID: 3, ProviderID: 1, Subject: "budi", Email: "budi@five.example.com"}}, f.store.identities)
+
+	// a linked user is not linked to another subject
+	_, _, err = f.login(t, 5, map[string]interface{}{"sub": "budi-2", "email": "budi@five.example.com", "email_verified": true})
+	require.True(t, apperror.Is(err, apperror.KindConflict))
+}
+
+func TestLoginLinksNoOneGrantedMore(t *testing.T) {
+	f := setup(t)
+	p := f.configure(t, 5)
+	require.Equal(t, superadmin, p.ConfiguredBy)
+	p.ConfiguredBy = admin5
+	_, err := f.store.SaveProvider(context.Background(), p)
+	require.NoError(t, err)
+	permission.Assign(f.enforcer, admin5, enum.RoleCompanyAdmin, "5")
+	permission.Grant(f.enforcer, "finance", "5", permission.ProductVerify)
+	f.users.rows[3] = &model.User{ID: 3, Email: "budi@five.example.com", RoleID: 4, CompanyID: 5, Version: 1}
+	f.users.rows[4] = &model.User{ID: 4, Email: "root@five.example.com", RoleID: 1, CompanyID: 5, Version: 1}
+	f.users.rows[5] = &model.User{ID: 5, Email: "cici@five.example.com", RoleID: 3, CompanyID: 5, Version: 1}
+	permission.Assign(f.enforcer, 3, "finance", "5")
+	permission.Assign(f.enforcer, 5, "user", "5")
+
+	// finance verifies products, which the admin of the provider does not
+	_, _, err = f.login(t, 5, map[string]interface{}{"sub": "budi", "email": "budi@five.example.com", "email_verified": true})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+	require.Equal(t, enum.LoginSSORejected, f.lastResult())
+
+	_, _, err = f.login(t, 5, map[string]interface{}{"sub": "root", "email": "root@five.example.com", "email_verified": true})
+	require.True(t, apperror.Is(err, apperror.KindForbidden), "superadmins are never linked")
+	require.Empty(t, f.store.identities)
+
+	u, _, err := f.login(t, 5, map[string]interface{}{"sub": "cici", "email": "cici@five.example.com", "email_verified": true})
+	require.NoError(t, err)
+	require.Equal(t, 5, u.ID)
+}
+
+func TestLoginIgnoresUnmappableRoles(t *testing.T) {
+	f := setup(t)
+	p := f.configure(t, 5)
+	// rules saved before the roles changed
+	p.SetRules([]model.SSORoleRule{{Value: "root", RoleID: 1}, {Value: "six", RoleID: 6}})
+	_, err := f.store.SaveProvider(context.Background(), p)
+	require.NoError(t, err)
+
+	_, _, err = f.login(t, 5, map[string]interface{}{"sub": "eve", "email": "eve@five.example.com", "email_verified": true, "groups": "root"})
+	require.True(t, apperror.Is(err, apperror.KindForbidden), "no one is provisioned superadmin")
+
+	u, _, err := f.login(t, 5, map[string]interface{}{"sub": "alex", "email": "alex@five.example.com", "email_verified": true})
+	require.NoError(t, err)
+	require.Equal(t, 3, u.RoleID)
+	for _, group := range []string{"root", "six"} {
+		u, _, err = f.login(t, 5, map[string]interface{}{"sub": "alex", "groups": group})
+		require.NoError(t, err)
+		require.Equal(t, 3, u.RoleID)
+	}
+}
+
+func TestLoginWithoutRole(t *testing.T) {
+	f := setup(t)
+	p := f.configure(t, 5)
+	p.DefaultRoleID = 0
+	_, err := f.store.SaveProvider(context.Background(), p)
+	require.NoError(t, err)
+
+	_, _, err = f.login(t, 5, map[string]interface{}{"sub": "alex", "email": "alex@five.example.com", "email_verified": true})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+	require.Len(t, f.users.rows, 2)
+	require.Empty(t, f.store.identities)
+}
+
+func TestLoginState(t *testing.T) {
+	f := setup(t)
+	f.configure(t, 5)
+	claims := map[string]interface{}{"sub": "alex", "email": "alex@five.example.com", "email_verified": true}
+
+	_, _, err := f.uc.Login(context.Background(), request.SSOLogin{State: "forged", Code: "code"})
+	require.True(t, apperror.Is(err, apperror.KindUnauthorized))
+
+	a, err := f.uc.Authorize(context.Background(), 5)
+	require.NoError(t, err)
+	code, state, err := f.idp.Authorize(a.URL, claims)
+	require.NoError(t, err)
+	_, _, err = f.uc.Login(context.Background(), request.SSOLogin{State: state, Code: code})
+	require.NoError(t, err)
+	_, _, err = f.uc.Login(context.Background(), request.SSOLogin{State: state, Code: code})
+	require.True(t, apperror.Is(err, apperror.KindUnauthorized), "a login is completed once")
+
+	a, err = f.uc.Authorize(context.Background(), 5)
+	require.NoError(t, err)
+	code, state, err = f.idp.Authorize(a.URL, claims)
+	require.NoError(t, err)
+	f.store.logins[len(f.store.logins)-1].ExpiresAt = time.Now()
+	_, _, err = f.uc.Login(context.Background(), request.SSOLogin{State: state, Code: code})
+	require.True(t, apperror.Is(err, apperror.KindUnauthorized), "an expired login is not completed")
+}
+
+func TestLoginRejected(t *testing.T) {
+	f := setup(t)
+	f.configure(t, 5)
+
+	a, err := f.uc.Authorize(context.Background(), 5)
+	require.NoError(t, err)
+	_, state, err := f.idp.Authorize(a.URL, map[string]interface{}{"sub": "alex"})
+	require.NoError(t, err)
+	_, _, err = f.uc.Login(context.Background(), request.SSOLogin{State: state, Code: "guessed"})
+	require.True(t, apperror.Is(err, apperror.KindUnauthorized))
+	require.Equal(t, enum.LoginSSORejected, f.lastResult())
+	require.False(t, (*f.attempts)[0].Success)
+}
+
+func TestLoginPendingCompany(t *testing.T) {
+	f := setup(t)
+	f.configure(t, 7)
+
+	_, _, err := f.login(t, 7, map[string]interface{}{"sub": "alex", "email": "alex@seven.example.com", "email_verified": true})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+	require.Equal(t, enum.LoginCompanyPending, f.lastResult())
+	require.Len(t, f.users.rows, 2)
+}
+
+func TestLoginChallenged(t *testing.T) {
+	f := setup(t)
+	f.configure(t, 5)
+	f.challenged[admin5] = true
+	claims := map[string]interface{}{"sub": "admin", "email": "admin@five.example.com", "email_verified": true}
+
+	u, challenge, err := f.login(t, 5, claims)
+	require.NoError(t, err)
+	require.Equal(t, admin5, u.ID)
+	require.Equal(t, "challenge", challenge.Token)
+	require.Equal(t, enum.LoginTwoFactorPending, f.lastResult())
+}
+
+func TestAuthorize(t *testing.T) {
+	f := setup(t)
+
+	_, err := f.uc.Authorize(context.Background(), 5)
+	require.True(t, apperror.IsNotFound(err))
+
+	p := f.configure(t, 5)
+	a, err := f.uc.Authorize(context.Background(), 5)
+	require.NoError(t, err)
+	require.Contains(t, a.URL, f.idp.Issuer()+"/authorize?")
+	require.WithinDuration(t, time.Now().Add(10*time.Minute), a.ExpiresAt, time.Minute)
+	require.NotContains(t, a.URL, f.store.logins[0].CodeVerifier, "only the challenge of the verifier is sent")
+
+	p.Enabled = false
+	_, err = f.store.SaveProvider(context.Background(), p)
+	require.NoError(t, err)
+	_, err = f.uc.Authorize(context.Background(), 5)
+	require.True(t, apperror.IsNotFound(err))
+}
+
+func TestSaveProvider(t *testing.T) {
+	f := setup(t)
+	valid := func() request.SSOProvider {
+		return request.SSOProvider{Issuer: f.idp.Issuer(), ClientID: "pari", ClientSecret: "secret", Enabled: enabled()}
+	}
+
+	_, err := f.uc.SaveProvider(context.Background(), admin5, 6, valid())
+	require.True(t, apperror.IsNotFound(err), "admins configure the provider of their own company")
+	p, err := f.uc.SaveProvider(context.Background(), admin5, 5, valid())
+	require.NoError(t, err)
+	require.Equal(t, 5, p.CompanyID)
+
+	// the secret is kept when left out
+	r := valid()
+	r.ClientSecret = ""
+	r.Scopes = []string{"groups"}
+	p, err = f.uc.SaveProvider(context.Background(), admin5, 5, r)
+	require.NoError(t, err)
+	require.Equal(t, "secret", p.ClientSecret)
+	require.Equal(t, "groups", p.Scopes)
+	require.Len(t, f.store.providers, 1)
+
+	r = valid()
+	r.ClientID = "another"
+	r.ClientSecret = ""
+	r.RoleMapping = []request.SSORoleRule{{Value: "root", RoleID: 1}, {Value: "finance", RoleID: 6}, {Value: "finance", RoleID: 4}}
+	r.DefaultRoleID = 99
+	_, err = f.uc.SaveProvider(context.Background(), admin5, 5, r)
+	require.True(t, apperror.Is(err, apperror.KindValidation))
+	var fields []string
+	for _, d := range apperror.From(err).Details {
+		fields = append(fields, d.Field)
+	}
+	require.Equal(t, []string{"client_secret", "role_claim", "role_mapping[0].role_id", "role_mapping[1].role_id", "default_role_id"}, fields)
+
+	// an enabled provider has to be discoverable, a disabled one may be set up ahead
+	gone := oidctest.NewProvider()
+	gone.Close()
+	r = valid()
+	r.Issuer = gone.Issuer()
+	_, err = f.uc.SaveProvider(context.Background(), admin5, 5, r)
+	require.True(t, apperror.Is(err, apperror.KindValidation))
+	r.Enabled = new(bool)
+	_, err = f.uc.SaveProvider(context.Background(), admin5, 5, r)
+	require.NoError(t, err)
+}
+
+func TestSaveProviderMapsNoRoleGrantingMore(t *testing.T) {
+	f := setup(t)
+	permission.Assign(f.enforcer, admin5, enum.RoleCompanyAdmin, "5")
+	permission.Grant(f.enforcer, "finance", "5", permission.ProductVerify)
+	r := request.SSOProvider{Issuer: f.idp.Issuer(), ClientID: "pari", ClientSecret: "secret", Enabled: enabled(), RoleClaim: "groups"}
+
+	// finance verifies products, which the admin does not
+	r.RoleMapping = []request.SSORoleRule{{Value: "finance", RoleID: 4}, {Value: "staff", RoleID: 3}}
+	r.DefaultRoleID = 4
+	_, err := f.uc.SaveProvider(context.Background(), admin5, 5, r)
+	require.True(t, apperror.Is(err, apperror.KindValidation))
+	var fields []string
+	for _, d := range apperror.From(err).Details {
+		fields = append(fields, d.Field)
+	}
+	require.Equal(t, []string{"role_mapping[0].role_id", "default_role_id"}, fields)
+	require.Empty(t, f.store.providers)
+
+	r.RoleMapping = r.RoleMapping[1:]
+	r.DefaultRoleID = 3
+	_, err = f.uc.SaveProvider(context.Background(), admin5, 5, r)
+	require.NoError(t, err)
+
+	r.RoleMapping = []request.SSORoleRule{{Value: "finance", RoleID: 4}}
+	p, err := f.uc.SaveProvider(context.Background(), superadmin, 5, r)
+	require.NoError(t, err)
+	require.Equal(t, 4, p.Rules()[0].RoleID)
+}
+
+func TestDeleteProvider(t *testing.T) {
+	f := setup(t)
+	f.configure(t, 5)
+	_, _, err := f.login(t, 5, map[string]interface{}{"sub": "alex", "email": "alex@five.example.com", "email_verified": true})
+	require.NoError(t, err)
+
+	require.NoError(t, f.uc.DeleteProvider(context.Background(), admin5, 5))
+	require.Empty(t, f.store.identities)
+	require.Len(t, f.users.rows, 3, "provisioned users are kept")
+	_, err = f.uc.Provider(context.Background(), admin5, 5)
+	require.True(t, apperror.IsNotFound(err))
+}
@@ -1,44 +1,63 @@
 package transaction_pre_order
 
 import (
-	"fmt"
+	"context"
 
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
-	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/money"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/patch"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/transaction_pre_order"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/transaction_pre_order_user"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
-	"github.com/jinzhu/gorm"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/unit"
 )
 
 type Usecase interface {
-	Create(transactionPreOrder *request.TransactionPreOrder) (*model.TransactionPreOrder, error)
-	ReadAll() (*[]model.TransactionPreOrder, error)
-	ReadAllBy(req request.TransactionPreOrderPaged) (*[]model.TransactionPreOrder, error)
-	ReadById(id int) (*model.TransactionPreOrder, error)
-	ReadBy(req request.TransactionPreOrderDetail) (*helper.TransactionPreOrderResponse, error)
-	Update(id int, transactionPreOrder *model.TransactionPreOrder) (*model.TransactionPreOrder, error)
-	Delete(id int) error
-	Count(req request.TransactionPreOrderPaged) int
-	Summary(companyId int) (interface{}, error)
-	Verification(transactionPreOrderUser *request.TransactionPreOrderUser) (*helper.TransactionPreOrderResponse, error)
+	Create(ctx context.Context, transactionPreOrder *request.TransactionPreOrder) (*model.TransactionPreOrder, error)
+	ReadAllBy(ctx context.Context, q *query.Query) (*[]model.TransactionPreOrder, *query.Page, error)
+	ReadById(ctx context.Context, id int) (*model.TransactionPreOrder, error)
+	ReadBy(ctx context.Context, req request.TransactionPreOrderDetail) (*helper.TransactionPreOrderResponse, error)
+	Update(ctx context.Context, id int, transactionPreOrder *request.UpdateTransactionPreOrder) (*model.TransactionPreOrder, error)
+	Patch(ctx context.Context, id, version int, doc patch.Document) (*model.TransactionPreOrder, error)
+	Delete(ctx context.Context, id int) error
+	Summary(ctx context.Context, companyId int) (interface{}, error)
+	Verification(ctx context.Context, userID, companyID int, transactionPreOrderUser *request.TransactionPreOrderUser) (*helper.TransactionPreOrderResponse, error)
 }
 
 type usecase struct {
 	transactionPreOrderRepository     transaction_pre_order.Repository
 	transactionPreOrderUserRepository transaction_pre_order_user.Repository
 	userRepository                    user.Repository
-	roleRepository                    role.Repository
+	productRepository                 product.Repository
 }
 
-func NewUsecase(transactionPreOrderRepository transaction_pre_order.Repository, transactionPreOrderUserRepository transaction_pre_order_user.Repository, userRepository user.Repository, roleRepository role.Repository) Usecase {
-	return &usecase{transactionPreOrderRepository, transactionPreOrderUserRepository, userRepository, roleRepository}
+func NewUsecase(transactionPreOrderRepository transaction_pre_order.Repository, transactionPreOrderUserRepository transaction_pre_order_user.Repository, userRepository user.Repository, productRepository product.Repository) Usecase {
+	return &usecase{transactionPreOrderRepository, transactionPreOrderUserRepository, userRepository, productRepository}
 }
 
-func (e *usecase) Create(transactionPreOrder *request.TransactionPreOrder) (*model.TransactionPreOrder, error) {
+func (e *usecase) Create(ctx context.Context, transactionPreOrder *request.TransactionPreOrder) (*model.TransactionPreOrder, error) {
+	p, err := e.productRepository.ReadById(ctx, transactionPreOrder.ProductID)
+	if apperror.IsNotFound(err) {
+		return nil, apperror.Validation("validation_error", "request validation failed").WithDetails(apperror.FieldError{
+			Field:   "product_id",
+			Message: "does not exist",
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	actualPrice := money.FromMajor(transactionPreOrder.ActualPrice, p.Currency)
+	total, err := totalAmount(p, transactionPreOrder.Quantity, actualPrice)
+	if err != nil {
+		return nil, err
+	}
 
 	m := &model.TransactionPreOrder{
 		PariProductID:     transactionPreOrder.PariProductId,
@@ -47,105 +66,172 @@ func (e *usecase) Create(transactionPreOrder *request.TransactionPreOrder) (*mod
 		CompanyID:         transactionPreOrder.CompanyID,
 		Quantity:          transactionPreOrder.Quantity,
 		Status:            transactionPreOrder.Status,
-		ActualPrice:       transactionPreOrder.ActualPrice,
+		ActualPriceAmount: actualPrice,
+		Currency:          p.Currency,
+		TotalAmount:       total,
 		BuyerName:         transactionPreOrder.BuyerName,
 		BuyerAddress:      transactionPreOrder.BuyerAddress,
 		BuyerContact:      transactionPreOrder.BuyerContact,
 	}
 
-	return e.transactionPreOrderRepository.Create(m)
+	return e.transactionPreOrderRepository.Create(ctx, m)
 }
 
-func (e *usecase) ReadAll() (*[]model.TransactionPreOrder, error) {
-	return e.transactionPreOrderRepository.ReadAll()
+func (e *usecase) ReadAllBy(ctx context.Context, q *query.Query) (*[]model.TransactionPreOrder, *query.Page, error) {
+	return e.transactionPreOrderRepository.ReadAllBy(ctx, q)
 }
 
-func (e *usecase) ReadAllBy(req request.TransactionPreOrderPaged) (*[]model.TransactionPreOrder, error) {
-	criteria := make(map[string]interface{})
-	criteria["company_id"] = req.CompanyID
+func (e *usecase) ReadById(ctx context.Context, id int) (*model.TransactionPreOrder, error) {
+	return e.transactionPreOrderRepository.ReadById(ctx, id)
+}
 
-	if req.Status != "" {
-		criteria["status"] = req.Status
+func (e *usecase) ReadBy(ctx context.Context, req request.TransactionPreOrderDetail) (*helper.TransactionPreOrderResponse, error) {
+	var isVerifiedByUser bool
+	productModel, err := e.transactionPreOrderRepository.ReadById(ctx, req.ID)
+
+	if err != nil {
+		helper.Logger(ctx).Error(err)
+		return nil, err
 	}
 
-	if req.Commodity != "" {
-		criteria["commodity"] = req.Commodity
+	if req.UserID != 0 {
+		countTransactionPreOrderUser := e.transactionPreOrderUserRepository.Count(ctx, map[string]interface{}{"transaction_pre_order_id": req.ID, "user_id": req.UserID})
+		if countTransactionPreOrderUser > 0 {
+			isVerifiedByUser = true
+		}
 	}
 
-	fmt.Println(req)
+	result := &helper.TransactionPreOrderResponse{TransactionPreOrder: productModel, IsVerifiedByUser: isVerifiedByUser}
 
-	return e.transactionPreOrderRepository.ReadAllBy(criteria, req.Search, req.Page, req.Size)
+	return result, nil
 }
 
-func (e *usecase) ReadById(id int) (*model.TransactionPreOrder, error) {
-	return e.transactionPreOrderRepository.ReadById(id)
+func (e *usecase) Update(ctx context.Context, id int, transactionPreOrder *request.UpdateTransactionPreOrder) (*model.TransactionPreOrder, error) {
+	current, err := e.transactionPreOrderRepository.ReadById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	total, err := e.total(ctx, current.ProductID, transactionPreOrder.Quantity, transactionPreOrder.ActualPriceAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &model.TransactionPreOrder{
+		Quantity:          transactionPreOrder.Quantity,
+		BuyerName:         transactionPreOrder.BuyerName,
+		BuyerAddress:      transactionPreOrder.BuyerAddress,
+		BuyerContact:      transactionPreOrder.BuyerContact,
+		ActualPriceAmount: transactionPreOrder.ActualPriceAmount,
+		TotalAmount:       total,
+	}
+
+	return e.transactionPreOrderRepository.Update(ctx, id, m)
 }
 
-func (e *usecase) ReadBy(req request.TransactionPreOrderDetail) (*helper.TransactionPreOrderResponse, error) {
-	var isVerifiedByUser bool
-	productModel, err := e.transactionPreOrderRepository.ReadById(req.ID)
+func (e *usecase) Patch(ctx context.Context, id, version int, doc patch.Document) (*model.TransactionPreOrder, error) {
+	current, err := e.transactionPreOrderRepository.ReadById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != current.Version {
+		return nil, apperror.VersionMismatch("transaction pre order")
+	}
 
+	dto := request.UpdateTransactionPreOrder{
+		Quantity:          current.Quantity,
+		BuyerName:         current.BuyerName,
+		BuyerAddress:      current.BuyerAddress,
+		BuyerContact:      current.BuyerContact,
+		ActualPriceAmount: current.ActualPriceAmount,
+	}
+	fields, err := doc.Apply(&dto)
 	if err != nil {
-		helper.CommonLogger().Error(err)
 		return nil, err
 	}
+	if len(fields) == 0 {
+		return current, nil
+	}
 
-	if req.UserID != 0 {
-		countTransactionPreOrderUser := e.transactionPreOrderUserRepository.Count(map[string]interface{}{"transaction_pre_order_id": req.ID, "user_id": req.UserID})
-		if countTransactionPreOrderUser > 0 {
-			isVerifiedByUser = true
+	_, quantity := fields["quantity"]
+	_, actualPrice := fields["actual_price_amount"]
+	if quantity || actualPrice {
+		total, err := e.total(ctx, current.ProductID, dto.Quantity, dto.ActualPriceAmount)
+		if err != nil {
+			return nil, err
 		}
+		fields["total_amount"] = total
 	}
 
-	result := &helper.TransactionPreOrderResponse{TransactionPreOrder: productModel, IsVerifiedByUser: isVerifiedByUser}
+	return e.transactionPreOrderRepository.Patch(ctx, id, current.Version, fields)
+}
 
-	return result, nil
+func (e *usecase) total(ctx context.Context, productID, quantity int, actualPrice int64) (int64, error) {
+	p, err := e.productRepository.ReadById(ctx, productID)
+	if err != nil {
+		return 0, err
+	}
+	return totalAmount(p, quantity, actualPrice)
 }
 
-func (e *usecase) Update(id int, transactionPreOrder *model.TransactionPreOrder) (*model.TransactionPreOrder, error) {
-	return e.transactionPreOrderRepository.Update(id, transactionPreOrder)
+// totalAmount prices quantity, in the quantity unit of p, at actualPrice per its price unit.
+func totalAmount(p *model.Product, quantity int, actualPrice int64) (int64, error) {
+	quantityUnit, known := unit.Lookup(p.UnitQuantity)
+	priceUnit, priceKnown := unit.Lookup(p.UnitPrice)
+	if !known || !priceKnown {
+		return 0, apperror.Conflict("unknown_product_units", "the units of the product are not known, update the product first")
+	}
+	total, err := unit.Total(actualPrice, priceUnit, quantity, quantityUnit)
+	if err != nil {
+		return 0, apperror.Conflict("unknown_product_units", "the units of the product do not measure the same, update the product first").Wrap(err)
+	}
+	return total, nil
 }
 
-func (e *usecase) Delete(id int) error {
-	return e.transactionPreOrderRepository.Delete(id)
+func (e *usecase) Delete(ctx context.Context, id int) error {
+	return e.transactionPreOrderRepository.Delete(ctx, id)
 }
 
-func (e *usecase) Verification(request *request.TransactionPreOrderUser) (*helper.TransactionPreOrderResponse, error) {
+// Verification records that user userID of company companyID approved a pre-order. The
+// verifiers of the company are the users sharing the role of userID; once every one of
+// them approved it, the pre-order is approved.
+func (e *usecase) Verification(ctx context.Context, userID, companyID int, request *request.TransactionPreOrderUser) (*helper.TransactionPreOrderResponse, error) {
 
-	productModel, err := e.transactionPreOrderRepository.ReadById(request.TransactionPreOrderID)
+	productModel, err := e.transactionPreOrderRepository.ReadById(ctx, request.TransactionPreOrderID)
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		return nil, err
 	}
-
-	productUser, err := e.transactionPreOrderUserRepository.ReadBy(map[string]interface{}{"transaction_pre_order_id": request.TransactionPreOrderID, "user_id": request.UserID, "company_id": request.CompanyID})
-	if err != nil && !gorm.IsRecordNotFoundError(err) {
-		return nil, fmt.Errorf("failed finding transaction pre order user: ", err)
+	if companyID == 0 || productModel.CompanyID != companyID {
+		return nil, apperror.Forbidden("transaction_pre_order_forbidden", "transaction pre-order is not of your company")
 	}
 
-	r, err := e.roleRepository.ReadById(request.RoleID)
+	verifier, err := e.userRepository.ReadById(ctx, userID)
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		return nil, err
+	}
+
+	productUser, err := e.transactionPreOrderUserRepository.ReadBy(ctx, map[string]interface{}{"transaction_pre_order_id": request.TransactionPreOrderID, "user_id": userID, "company_id": companyID})
+	if err != nil && !apperror.IsNotFound(err) {
 		return nil, err
 	}
 
 	// checking whether transactionPreOrderUser exists or not
 	if productUser == nil {
-		pu := &model.TransactionPreOrderUser{TransactionPreOrderID: request.TransactionPreOrderID, UserID: request.UserID, CompanyID: request.CompanyID}
-		_, err := e.transactionPreOrderUserRepository.Create(pu)
+		pu := &model.TransactionPreOrderUser{TransactionPreOrderID: request.TransactionPreOrderID, UserID: userID, CompanyID: companyID}
+		_, err := e.transactionPreOrderUserRepository.Create(ctx, pu)
 		if err != nil {
-			helper.CommonLogger().Error(err)
+			helper.Logger(ctx).Error(err)
 			return nil, err
 		}
 	}
 
 	// checking product has been approved by all user in company
-	countTransactionPreOrderUser := e.transactionPreOrderUserRepository.Count(map[string]interface{}{"company_id": request.CompanyID, "transaction_pre_order_id": request.TransactionPreOrderID})
-	countUser := e.userRepository.Count(map[string]interface{}{"company_id": request.CompanyID, "role_id": r.ID})
+	countTransactionPreOrderUser := e.transactionPreOrderUserRepository.Count(ctx, map[string]interface{}{"company_id": companyID, "transaction_pre_order_id": request.TransactionPreOrderID})
+	countUser := e.userRepository.Count(ctx, map[string]interface{}{"company_id": companyID, "role_id": verifier.RoleID})
 	if countUser == countTransactionPreOrderUser {
 		productModel.Status = enum.Approved
-		_, err := e.transactionPreOrderRepository.Update(productModel.ID, productModel)
+		_, err := e.transactionPreOrderRepository.Update(ctx, productModel.ID, productModel)
 		if err != nil {
-			helper.CommonLogger().Error(err)
+			helper.Logger(ctx).Error(err)
 			return nil, err
 		}
 	}
@@ -154,32 +240,35 @@ func (e *usecase) Verification(request *request.TransactionPreOrderUser) (*helpe
 	return result, nil
 }
 
-func (e *usecase) Count(req request.TransactionPreOrderPaged) int {
-	criteria := make(map[string]interface{})
-	criteria["company_id"] = req.CompanyID
-
-	if req.Status != "" {
-		criteria["status"] = req.Status
+// Summary counts the pre-orders of a company by status and sums their quantity by base
+// unit and their total by currency.
+func (e *usecase) Summary(ctx context.Context, companyId int) (interface{}, error) {
+	totals, err := e.transactionPreOrderRepository.Totals(ctx, companyId)
+	if err != nil {
+		return nil, err
 	}
-
-	if req.Commodity != "" {
-		criteria["commodity"] = req.Commodity
+	quantity := unit.Totals{}
+	amounts := make(map[string]int64)
+	for _, t := range *totals {
+		amounts[t.Currency] += t.TotalAmount
+		if u, ok := unit.Lookup(t.UnitQuantity); ok {
+			quantity.Add(t.Quantity, u)
+		} else {
+			helper.Logger(ctx).WithField("unit", t.UnitQuantity).Warn("[transactionPreOrderUsecase.Summary] unknown unit left out of the quantity")
+		}
 	}
 
-	return e.transactionPreOrderRepository.Count(criteria)
-}
-
-func (e *usecase) Summary(companyId int) (interface{}, error) {
-
-	allTransactionPreOrder := e.transactionPreOrderRepository.Count(map[string]interface{}{"company_id": companyId})
-	processingTransactionPreOrder := e.transactionPreOrderRepository.Count(map[string]interface{}{"company_id": companyId, "status": "processing"})
-	approvedTransactionPreOrder := e.transactionPreOrderRepository.Count(map[string]interface{}{"company_id": companyId, "status": "approved"})
-	rejectedTransactionPreOrder := e.transactionPreOrderRepository.Count(map[string]interface{}{"company_id": companyId, "status": "rejected"})
+	allTransactionPreOrder := e.transactionPreOrderRepository.Count(ctx, map[string]interface{}{"company_id": companyId})
+	processingTransactionPreOrder := e.transactionPreOrderRepository.Count(ctx, map[string]interface{}{"company_id": companyId, "status": "processing"})
+	approvedTransactionPreOrder := e.transactionPreOrderRepository.Count(ctx, map[string]interface{}{"company_id": companyId, "status": "approved"})
+	rejectedTransactionPreOrder := e.transactionPreOrderRepository.Count(ctx, map[string]interface{}{"company_id": companyId, "status": "rejected"})
 
 	return map[string]interface{}{
 		"all_product":        allTransactionPreOrder,
 		"processing_product": processingTransactionPreOrder,
 		"approved_product":   approvedTransactionPreOrder,
 		"rejected_product":   rejectedTransactionPreOrder,
+		"quantity":           quantity,
+		"total_amount":       amounts,
 	}, nil
 }
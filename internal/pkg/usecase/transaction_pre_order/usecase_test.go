@@ -0,0 +1,114 @@
+package transaction_pre_order
+
+import (
+	"context"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/transaction_pre_order"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/transaction_pre_order_user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"github.com/stretchr/testify/require"
+)
+
+// preOrders keeps pre-orders in memory.
+type preOrders struct {
+	transaction_pre_order.Repository
+	rows map[int]*model.TransactionPreOrder
+}
+
+func (r preOrders) ReadById(_ context.Context, id int) (*model.TransactionPreOrder, error) {
+	if row, ok := r.rows[id]; ok {
+		found := *row
+		return &found, nil
+	}
+	return nil, apperror.NotFound("transaction_pre_order_not_found", "transaction pre-order is not exists")
+}
+
+func (r preOrders) Update(ctx context.Context, id int, m *model.TransactionPreOrder) (*model.TransactionPreOrder, error) {
+	*r.rows[id] = *m
+	return r.ReadById(ctx, id)
+}
+
+// approvals keeps the approvals of pre-orders in memory.
+type approvals struct {
+	transaction_pre_order_user.Repository
+	rows *[]model.TransactionPreOrderUser
+}
+
+func (r approvals) ReadBy(_ context.Context, criteria map[string]interface{}) (*model.TransactionPreOrderUser, error) {
+	for _, row := range *r.rows {
+		if row.TransactionPreOrderID == criteria["transaction_pre_order_id"] && row.UserID == criteria["user_id"] {
+			found := row
+			return &found, nil
+		}
+	}
+	return nil, apperror.NotFound("transaction_pre_order_user_not_found", "transaction pre-order user is not exists")
+}
+
+func (r approvals) Create(_ context.Context, m *model.TransactionPreOrderUser) (*model.TransactionPreOrderUser, error) {
+	*r.rows = append(*r.rows, *m)
+	return m, nil
+}
+
+func (r approvals) Count(_ context.Context, criteria map[string]interface{}) int {
+	count := 0
+	for _, row := range *r.rows {
+		if row.TransactionPreOrderID == criteria["transaction_pre_order_id"] && row.CompanyID == criteria["company_id"] {
+			count++
+		}
+	}
+	return count
+}
+
+// verifiers are two users of company 7 sharing role 5.
+type verifiers struct {
+	user.Repository
+}
+
+func (verifiers) ReadById(_ context.Context, id int) (*model.User, error) {
+	return &model.User{ID: id, CompanyID: 7, RoleID: 5}, nil
+}
+
+func (verifiers) Count(_ context.Context, criteria map[string]interface{}) int {
+	if criteria["company_id"] == 7 && criteria["role_id"] == 5 {
+		return 2
+	}
+	return 0
+}
+
+func newVerificationUsecase() (*usecase, map[int]*model.TransactionPreOrder, *[]model.TransactionPreOrderUser) {
+	rows := map[int]*model.TransactionPreOrder{1: {ID: 1, CompanyID: 7, Status: enum.Processing}}
+	approved := &[]model.TransactionPreOrderUser{}
+	return &usecase{
+		transactionPreOrderRepository:     preOrders{rows: rows},
+		transactionPreOrderUserRepository: approvals{rows: approved},
+		userRepository:                    verifiers{},
+	}, rows, approved
+}
+
+func TestVerificationByCaller(t *testing.T) {
+	uc, rows, approved := newVerificationUsecase()
+	result, err := uc.Verification(context.Background(), 11, 7, &request.TransactionPreOrderUser{TransactionPreOrderID: 1})
+	require.NoError(t, err)
+	require.True(t, result.IsVerifiedByUser)
+	require.Equal(t, enum.Processing, rows[1].Status, "one of the two verifiers approved it")
+	require.Equal(t, []model.TransactionPreOrderUser{{TransactionPreOrderID: 1, UserID: 11, CompanyID: 7}}, *approved)
+
+	_, err = uc.Verification(context.Background(), 12, 7, &request.TransactionPreOrderUser{TransactionPreOrderID: 1})
+	require.NoError(t, err)
+	require.Equal(t, enum.StatusProduct(enum.Approved), rows[1].Status)
+}
+
+func TestVerificationOfOtherCompany(t *testing.T) {
+	uc, rows, approved := newVerificationUsecase()
+	for _, companyID := range []int{0, 8} {
+		_, err := uc.Verification(context.Background(), 11, companyID, &request.TransactionPreOrderUser{TransactionPreOrderID: 1})
+		require.True(t, apperror.Is(err, apperror.KindForbidden))
+	}
+	require.Empty(t, *approved)
+	require.Equal(t, enum.Processing, rows[1].Status)
+}
@@ -0,0 +1,106 @@
+package trash
+
+import (
+	"context"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/product"
+	trashRepository "bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/trash"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/search"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/trash"
+)
+
+type Usecase interface {
+	ReadAllBy(ctx context.Context, r *trash.Resource, q *query.Query, out interface{}) (*query.Page, error)
+	Restore(ctx context.Context, r *trash.Resource, id int) error
+	Purge(ctx context.Context, r *trash.Resource, id int) error
+	PurgeExpired(ctx context.Context) (map[string]int, error)
+	Schedule(ctx context.Context, interval time.Duration)
+}
+
+type usecase struct {
+	trashRepository   trashRepository.Repository
+	productRepository product.Repository
+	searchIndex       *search.Index
+	retention         time.Duration
+}
+
+// NewUsecase manages the trash, purging records that were deleted longer than
+// retention ago.
+func NewUsecase(trashRepository trashRepository.Repository, productRepository product.Repository, searchIndex *search.Index, retention time.Duration) Usecase {
+	return &usecase{trashRepository, productRepository, searchIndex, retention}
+}
+
+func (e *usecase) ReadAllBy(ctx context.Context, r *trash.Resource, q *query.Query, out interface{}) (*query.Page, error) {
+	return e.trashRepository.ReadAllBy(ctx, r, q, out)
+}
+
+// Restore brings a record back from the trash and puts the restored products back in
+// the search index.
+func (e *usecase) Restore(ctx context.Context, r *trash.Resource, id int) error {
+	if err := e.trashRepository.Restore(ctx, r, id); err != nil {
+		return err
+	}
+
+	var products []model.Product
+	switch r {
+	case trash.Products:
+		p, err := e.productRepository.ReadById(ctx, id)
+		if err != nil {
+			return err
+		}
+		products = append(products, *p)
+	case trash.Companies:
+		ps, err := e.productRepository.ReadByCompany(ctx, id)
+		if err != nil {
+			return err
+		}
+		products = *ps
+	}
+	for _, p := range products {
+		e.searchIndex.Put(p.ID, p.Name, p.Description, p.Commodity)
+	}
+	return nil
+}
+
+func (e *usecase) Purge(ctx context.Context, r *trash.Resource, id int) error {
+	return e.trashRepository.Purge(ctx, r, id)
+}
+
+// PurgeExpired purges the records deleted longer than the retention ago and counts
+// them by resource.
+func (e *usecase) PurgeExpired(ctx context.Context) (map[string]int, error) {
+	before := time.Now().Add(-e.retention)
+	purged := make(map[string]int, len(trash.Resources))
+	for _, r := range trash.Resources {
+		count, err := e.trashRepository.PurgeDeletedBefore(ctx, r, before)
+		if err != nil {
+			return purged, err
+		}
+		purged[r.Name] = count
+	}
+	return purged, nil
+}
+
+// Schedule purges the expired records every interval until ctx is done.
+func (e *usecase) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := e.PurgeExpired(ctx)
+		if err != nil {
+			helper.Logger(ctx).WithError(err).Error("[trashUsecase.Schedule] failed purging the trash")
+		} else {
+			helper.Logger(ctx).WithField("purged", purged).Info("[trashUsecase.Schedule] trash purged")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package two_factor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/actor"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/credential"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/company"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/login_attempt"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/two_factor"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/totp"
+	"github.com/casbin/casbin"
+)
+
+// recoveryCodes is how many recovery codes a user gets at once.
+const recoveryCodes = 10
+
+// Status tells whether a user logs in with a TOTP code, whether they have to, and how
+// many recovery codes they have left.
+type Status struct {
+	Enabled       bool
+	Required      bool
+	RecoveryCodes int
+}
+
+// Enrollment is a pending secret, to be added to an authenticator app by hand or
+// through URI, which QR codes encode.
+type Enrollment struct {
+	Secret string
+	URI    string
+}
+
+// Challenge is what a user whose password was right still has to answer: a TOTP code,
+// or the confirmation of a secret enrolled first if Enroll.
+type Challenge struct {
+	Token  string
+	Enroll bool
+}
+
+type Usecase interface {
+	Status(ctx context.Context, userID int) (*Status, error)
+	Enroll(ctx context.Context, userID int) (*Enrollment, error)
+	Confirm(ctx context.Context, userID int, code string) ([]string, error)
+	Disable(ctx context.Context, userID int, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error)
+	Reset(ctx context.Context, actorID, userID int) error
+	Challenge(ctx context.Context, u *model.User) (*Challenge, error)
+	EnrollChallenge(ctx context.Context, challengeToken string) (*Enrollment, error)
+	Complete(ctx context.Context, login request.TwoFactorLogin) (*model.User, []string, error)
+	SetCompanyPolicy(ctx context.Context, actorID, companyID int, require bool) (*model.Company, error)
+	SetRolePolicy(ctx context.Context, actorID, roleID int, require bool) (*model.Role, error)
+}
+
+type usecase struct {
+	twoFactorRepository    two_factor.Repository
+	userRepository         user.Repository
+	roleRepository         role.Repository
+	companyRepository      company.Repository
+	loginAttemptRepository login_attempt.Repository
+	enforcer               *casbin.SyncedEnforcer
+	lockout                credential.Lockout
+	issuer                 string
+	challengeTTL           time.Duration
+}
+
+// NewUsecase names issuer in the authenticator apps of the users, and lets them answer
+// a challenge within challengeTTL.
+func NewUsecase(twoFactorRepository two_factor.Repository, userRepository user.Repository, roleRepository role.Repository, companyRepository company.Repository, loginAttemptRepository login_attempt.Repository, enforcer *casbin.SyncedEnforcer, lockout credential.Lockout, issuer string, challengeTTL time.Duration) Usecase {
+	return &usecase{twoFactorRepository, userRepository, roleRepository, companyRepository, loginAttemptRepository, enforcer, lockout, issuer, challengeTTL}
+}
+
+func (e *usecase) Status(ctx context.Context, userID int) (*Status, error) {
+	u, err := e.userRepository.ReadById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := e.required(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{Required: required}
+
+	tf, err := e.twoFactorRepository.ReadByUserId(ctx, userID)
+	if apperror.IsNotFound(err) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	status.Enabled = tf.Enabled
+	if tf.Enabled {
+		status.RecoveryCodes, err = e.twoFactorRepository.CountRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Enroll creates a new pending secret for the user, replacing any previous one they did
+// not confirm.
+func (e *usecase) Enroll(ctx context.Context, userID int) (*Enrollment, error) {
+	u, err := e.userRepository.ReadById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return e.enroll(ctx, u)
+}
+
+func (e *usecase) enroll(ctx context.Context, u *model.User) (*Enrollment, error) {
+	tf, err := e.twoFactorRepository.ReadByUserId(ctx, u.ID)
+	if apperror.IsNotFound(err) {
+		tf, err = &model.TwoFactor{UserID: u.ID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, apperror.Conflict("two_factor_enabled", "two-factor authentication is already enabled")
+	}
+
+	tf.Secret, err = totp.NewSecret()
+	if err != nil {
+		return nil, apperror.Internal("secret_error", "failed generating secret").Wrap(err)
+	}
+	if _, err := e.twoFactorRepository.Save(ctx, tf); err != nil {
+		return nil, err
+	}
+	return &Enrollment{Secret: tf.Secret, URI: totp.URI(e.issuer, u.Email, tf.Secret)}, nil
+}
+
+// Confirm enables the pending secret of the user once they prove with code that their
+// app has it, and returns their recovery codes, which are not shown again.
+func (e *usecase) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	tf, err := e.twoFactorRepository.ReadByUserId(ctx, userID)
+	if apperror.IsNotFound(err) {
+		return nil, apperror.Conflict("two_factor_not_enrolled", "enroll first").Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, apperror.Conflict("two_factor_enabled", "two-factor authentication is already enabled")
+	}
+	return e.confirm(ctx, tf, code)
+}
+
+func (e *usecase) confirm(ctx context.Context, tf *model.TwoFactor, code string) ([]string, error) {
+	counter, ok := totp.Verify(tf.Secret, code, time.Now())
+	if !ok {
+		return nil, invalidCode()
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := e.twoFactorRepository.Enable(ctx, tf.ID, counter, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns two-factor authentication off for the user, unless their role or company
+// requires it.
+func (e *usecase) Disable(ctx context.Context, userID int, code string) error {
+	u, err := e.userRepository.ReadById(ctx, userID)
+	if err != nil {
+		return err
+	}
+	required, err := e.required(ctx, u)
+	if err != nil {
+		return err
+	}
+	if required {
+		return apperror.Forbidden("two_factor_required", "two-factor authentication is required for this user")
+	}
+	tf, err := e.enabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := e.verify(ctx, tf, code); err != nil {
+		return err
+	}
+	return e.twoFactorRepository.Delete(ctx, userID)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, used or not.
+func (e *usecase) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	tf, err := e.enabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.verify(ctx, tf, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := e.twoFactorRepository.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Reset forgets the secret and recovery codes of a user who lost both, so they log in
+// with their password alone, or enroll again at their next login if they have to.
+// Admins other than superadmins reset the users of their own company who are granted
+// no permission the admin is not.
+func (e *usecase) Reset(ctx context.Context, actorID, userID int) error {
+	a, err := actor.Load(ctx, e.userRepository, e.roleRepository, actorID)
+	if err != nil {
+		return err
+	}
+	u, err := e.userRepository.ReadById(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !a.Sees(u.CompanyID) {
+		return apperror.NotFound("user_not_found", "user is not exists")
+	}
+	if !a.Superadmin {
+		_, granted := permission.Effective(e.enforcer, a.ID, permission.Domain(a.CompanyID))
+		_, held := permission.Effective(e.enforcer, u.ID, permission.Domain(u.CompanyID))
+		if missing := permission.Missing(granted, held); len(missing) > 0 {
+			return apperror.Forbidden("two_factor_reset_forbidden", fmt.Sprintf("user is granted %s, which is not granted to you", missing[0]))
+		}
+	}
+	if err := e.twoFactorRepository.Delete(ctx, userID); err != nil {
+		return err
+	}
+	helper.Logger(ctx).WithField("actor_id", actorID).WithField("user_id", userID).Info("[twoFactorUsecase.Reset] two-factor authentication reset")
+	return nil
+}
+
+// Challenge is nil when u logs in with their password alone, that is when they did not
+// enable two-factor authentication and are not required to.
+func (e *usecase) Challenge(ctx context.Context, u *model.User) (*Challenge, error) {
+	tf, err := e.twoFactorRepository.ReadByUserId(ctx, u.ID)
+	if err != nil && !apperror.IsNotFound(err) {
+		return nil, err
+	}
+	if tf != nil && tf.Enabled {
+		return &Challenge{Token: helper.GenerateChallengeToken(u, e.challengeTTL)}, nil
+	}
+	required, err := e.required(ctx, u)
+	if err != nil || !required {
+		return nil, err
+	}
+	return &Challenge{Token: helper.GenerateChallengeToken(u, e.challengeTTL), Enroll: true}, nil
+}
+
+// EnrollChallenge enrolls the user of a challenge who has to enroll before logging in.
+func (e *usecase) EnrollChallenge(ctx context.Context, challengeToken string) (*Enrollment, error) {
+	u, err := e.challenged(ctx, challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return e.enroll(ctx, u)
+}
+
+// Complete answers the challenge of a user with a TOTP code or a recovery code, or
+// confirms the secret they enrolled at login, returning their recovery codes then.
+// Wrong codes count as failed logins of the user, and every attempt is audited.
+func (e *usecase) Complete(ctx context.Context, login request.TwoFactorLogin) (*model.User, []string, error) {
+	u, err := e.challenged(ctx, login.ChallengeToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	attempt := &model.LoginAttempt{Email: u.Email, UserID: u.ID, CompanyID: u.CompanyID, IP: login.IP, UserAgent: login.UserAgent}
+	if u.LockedUntil != nil && now.Before(*u.LockedUntil) {
+		e.audit(ctx, attempt, enum.LoginLocked)
+		return nil, nil, apperror.RateLimited("login_throttled", "too many failed logins, try again later")
+	}
+
+	tf, err := e.twoFactorRepository.ReadByUserId(ctx, u.ID)
+	if apperror.IsNotFound(err) {
+		return nil, nil, apperror.Conflict("two_factor_not_enrolled", "enroll first").Wrap(err)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var codes []string
+	if tf.Enabled {
+		err = e.verify(ctx, tf, login.Code)
+	} else {
+		codes, err = e.confirm(ctx, tf, login.Code)
+	}
+	if apperror.Is(err, apperror.KindUnauthorized) {
+		failures, err := e.userRepository.FailLogin(ctx, u.ID)
+		if wait := e.lockout.Wait(failures); err == nil && wait > 0 {
+			err = e.userRepository.Lock(ctx, u.ID, now.Add(wait))
+		}
+		if err != nil {
+			helper.Logger(ctx).WithError(err).Error("[twoFactorUsecase.Complete] failed counting failed login")
+		}
+		e.audit(ctx, attempt, enum.LoginTwoFactorFailed)
+		return nil, nil, invalidCode()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.FailedLogins > 0 || u.LockedUntil != nil {
+		if err := e.userRepository.Unlock(ctx, u.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	attempt.Success = true
+	e.audit(ctx, attempt, enum.LoginSucceeded)
+	return u, codes, nil
+}
+
+// SetCompanyPolicy requires the users of a company of verification level 2 and above to
+// log in with a TOTP code, or stops requiring it. Admins other than superadmins set the
+// policy of their own company.
+func (e *usecase) SetCompanyPolicy(ctx context.Context, actorID, companyID int, require bool) (*model.Company, error) {
+	a, err := actor.Load(ctx, e.userRepository, e.roleRepository, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.Sees(companyID) {
+		return nil, apperror.NotFound("company_not_found", "company is not exists")
+	}
+	c, err := e.companyRepository.ReadById(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	return e.companyRepository.Patch(ctx, c.ID, c.Version, map[string]interface{}{"require_two_factor": require})
+}
+
+// SetRolePolicy requires every user of a role to log in with a TOTP code, or stops
+// requiring it. Admins set the policy of the roles they manage: superadmins of every
+// role, other admins of the roles of their company, not of those shared by all.
+func (e *usecase) SetRolePolicy(ctx context.Context, actorID, roleID int, require bool) (*model.Role, error) {
+	a, err := actor.Load(ctx, e.userRepository, e.roleRepository, actorID)
+	if err != nil {
+		return nil, err
+	}
+	ro, err := e.roleRepository.ReadById(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.Superadmin && !ro.AvailableTo(a.CompanyID) {
+		return nil, apperror.NotFound("role_not_found", "role is not exists")
+	}
+	if !a.Manages(ro) {
+		return nil, apperror.Forbidden("role_forbidden", "roles shared by all companies are managed by superadmins only")
+	}
+	return e.roleRepository.Patch(ctx, roleID, map[string]interface{}{"require_two_factor": require})
+}
+
+// required tells whether u has to log in with a TOTP code, because of their role, or
+// because of their company and their verification level.
+func (e *usecase) required(ctx context.Context, u *model.User) (bool, error) {
+	ro, err := e.roleRepository.ReadById(ctx, u.RoleID)
+	if err != nil && !apperror.IsNotFound(err) {
+		return false, err
+	}
+	if ro != nil && ro.RequireTwoFactor {
+		return true, nil
+	}
+	if u.CompanyID == 0 || u.VerificationLevel < enum.Level2 {
+		return false, nil
+	}
+	c, err := e.companyRepository.ReadById(ctx, u.CompanyID)
+	if apperror.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return c.RequireTwoFactor, nil
+}
+
+// challenged loads the user of a challenge token, which is refused once their session
+// version changed, e.g. after a password reset.
+func (e *usecase) challenged(ctx context.Context, challengeToken string) (*model.User, error) {
+	userID, ver, err := helper.ValidateChallengeToken(challengeToken)
+	if err != nil {
+		return nil, invalidChallenge().Wrap(err)
+	}
+	u, err := e.userRepository.ReadById(ctx, userID)
+	if apperror.IsNotFound(err) {
+		return nil, invalidChallenge().Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if u.SessionVersion != ver {
+		return nil, invalidChallenge()
+	}
+	return u, nil
+}
+
+func (e *usecase) enabled(ctx context.Context, userID int) (*model.TwoFactor, error) {
+	tf, err := e.twoFactorRepository.ReadByUserId(ctx, userID)
+	if apperror.IsNotFound(err) || (err == nil && !tf.Enabled) {
+		return nil, apperror.Conflict("two_factor_disabled", "two-factor authentication is not enabled")
+	}
+	return tf, err
+}
+
+// verify accepts a TOTP code of tf not accepted before, or an unused recovery code of
+// its user, using it up.
+func (e *usecase) verify(ctx context.Context, tf *model.TwoFactor, code string) error {
+	if counter, ok := totp.Verify(tf.Secret, code, time.Now()); ok {
+		err := e.twoFactorRepository.Advance(ctx, tf.ID, counter)
+		if apperror.Is(err, apperror.KindConflict) {
+			return invalidCode().Wrap(err)
+		}
+		return err
+	}
+	err := e.twoFactorRepository.UseRecoveryCode(ctx, tf.UserID, helper.HashToken(normalize(code)))
+	if apperror.IsNotFound(err) {
+		return invalidCode().Wrap(err)
+	}
+	return err
+}
+
+// audit records a login attempt. Failing to is only logged, so users are not locked
+// out when the audit cannot be written.
+func (e *usecase) audit(ctx context.Context, attempt *model.LoginAttempt, result enum.LoginResult) {
+	attempt.Result = result
+	if _, err := e.loginAttemptRepository.Create(ctx, attempt); err != nil {
+		helper.Logger(ctx).WithError(err).Error("[twoFactorUsecase.Complete] failed auditing login attempt")
+	}
+}
+
+// newRecoveryCodes generates recovery codes such as 7KQ2-M4XD-P9TA-3BWE, along with the
+// hashes that are kept of them.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodes)
+	hashes := make([]string, recoveryCodes)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, apperror.Internal("secret_error", "failed generating recovery codes").Wrap(err)
+		}
+		s := base32.StdEncoding.EncodeToString(b)
+		codes[i] = s[0:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:16]
+		hashes[i] = helper.HashToken(normalize(codes[i]))
+	}
+	return codes, hashes, nil
+}
+
+// normalize lets recovery codes be typed without dashes or in lower case.
+func normalize(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func invalidCode() *apperror.Error {
+	return apperror.Unauthorized("invalid_code", "code is incorrect")
+}
+
+func invalidChallenge() *apperror.Error {
+	return apperror.Unauthorized("invalid_challenge", "challenge is invalid or expired, log in again")
+}
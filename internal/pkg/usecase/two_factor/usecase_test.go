@@ -0,0 +1,366 @@
+package two_factor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/credential"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
+	mock "bitbucket.org/bridce/ms-pari-web/internal/pkg/mock/repository"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/company"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/totp"
+	"github.com/casbin/casbin"
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+// secrets keeps two-factor secrets and recovery codes in memory.
+type secrets struct {
+	rows  map[int]*model.TwoFactor
+	codes map[string]*model.RecoveryCode
+}
+
+func (s *secrets) ReadByUserId(_ context.Context, userID int) (*model.TwoFactor, error) {
+	row, ok := s.rows[userID]
+	if !ok {
+		return nil, apperror.NotFound("two_factor_not_found", "two_factor is not exists")
+	}
+	found := *row
+	return &found, nil
+}
+
+func (s *secrets) Save(_ context.Context, tf *model.TwoFactor) (*model.TwoFactor, error) {
+	if tf.ID == 0 {
+		tf.ID = tf.UserID
+	}
+	saved := *tf
+	s.rows[tf.UserID] = &saved
+	return tf, nil
+}
+
+func (s *secrets) Enable(ctx context.Context, id int, counter int64, codeHashes []string) error {
+	row := s.rows[id]
+	row.Enabled = true
+	row.LastCounter = counter
+	return s.ReplaceRecoveryCodes(ctx, row.UserID, codeHashes)
+}
+
+func (s *secrets) Advance(_ context.Context, id int, counter int64) error {
+	row := s.rows[id]
+	if row.LastCounter >= counter {
+		return apperror.Conflict("code_used", "code was already used")
+	}
+	row.LastCounter = counter
+	return nil
+}
+
+func (s *secrets) Delete(_ context.Context, userID int) error {
+	delete(s.rows, userID)
+	for hash, code := range s.codes {
+		if code.UserID == userID {
+			delete(s.codes, hash)
+		}
+	}
+	return nil
+}
+
+func (s *secrets) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	for hash, code := range s.codes {
+		if code.UserID == userID {
+			delete(s.codes, hash)
+		}
+	}
+	for _, hash := range codeHashes {
+		s.codes[hash] = &model.RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	return nil
+}
+
+func (s *secrets) UseRecoveryCode(_ context.Context, userID int, codeHash string) error {
+	code, ok := s.codes[codeHash]
+	if !ok || code.UserID != userID || code.UsedAt != nil {
+		return apperror.NotFound("recovery_code_not_found", "recovery code is not exists")
+	}
+	now := time.Now()
+	code.UsedAt = &now
+	return nil
+}
+
+func (s *secrets) CountRecoveryCodes(_ context.Context, userID int) (int, error) {
+	count := 0
+	for _, code := range s.codes {
+		if code.UserID == userID && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// roles and companies only read rows by id, and roles set their policy, which is all
+// the usecase needs of them.
+type roles struct {
+	role.Repository
+	rows map[int]*model.Role
+}
+
+func (r roles) ReadById(_ context.Context, id int) (*model.Role, error) {
+	if row, ok := r.rows[id]; ok {
+		return row, nil
+	}
+	return nil, apperror.NotFound("role_not_found", "role is not exists")
+}
+
+func (r roles) Patch(ctx context.Context, id int, fields map[string]interface{}) (*model.Role, error) {
+	r.rows[id].RequireTwoFactor = fields["require_two_factor"].(bool)
+	return r.ReadById(ctx, id)
+}
+
+type companies struct {
+	company.Repository
+	rows map[int]*model.Company
+}
+
+func (c companies) ReadById(_ context.Context, id int) (*model.Company, error) {
+	if row, ok := c.rows[id]; ok {
+		return row, nil
+	}
+	return nil, apperror.NotFound("company_not_found", "company is not exists")
+}
+
+// attempts keeps login attempts in memory.
+type attempts []model.LoginAttempt
+
+func (a *attempts) Create(_ context.Context, attempt *model.LoginAttempt) (*model.LoginAttempt, error) {
+	*a = append(*a, *attempt)
+	return attempt, nil
+}
+
+func (a *attempts) ReadAllBy(context.Context, *query.Query) (*[]model.LoginAttempt, *query.Page, error) {
+	return (*[]model.LoginAttempt)(a), &query.Page{}, nil
+}
+
+func (a *attempts) CountFailures(context.Context, map[string]interface{}, time.Time) (int, error) {
+	return 0, nil
+}
+
+func setup(t *testing.T) (*usecase, *mock.MockRepository, *secrets, *attempts) {
+	viper.Set("JWT_SECRET", "test-secret")
+	t.Cleanup(func() { viper.Set("JWT_SECRET", nil) })
+
+	users := mock.NewMockRepository(gomock.NewController(t))
+	s := &secrets{rows: map[int]*model.TwoFactor{}, codes: map[string]*model.RecoveryCode{}}
+	r := roles{rows: map[int]*model.Role{
+		1: {ID: 1, Name: enum.RoleSuperadmin},
+		2: {ID: 2, Name: "verificator", RequireTwoFactor: true},
+		3: {ID: 3, Name: "user"},
+		4: {ID: 4, Name: enum.RoleCompanyAdmin},
+		5: {ID: 5, Name: "kasir", CompanyID: 2},
+		6: {ID: 6, Name: "kasir", CompanyID: 1},
+	}}
+	c := companies{rows: map[int]*model.Company{
+		1: {ID: 1, RequireTwoFactor: true},
+		2: {ID: 2},
+	}}
+	a := &attempts{}
+	enforcer := casbin.NewSyncedEnforcer("../../config/rbac_model.conf", false)
+	permission.Seed(enforcer)
+	permission.Assign(enforcer, 20, enum.RoleCompanyAdmin, "2")
+	permission.Assign(enforcer, 21, "user", "2")
+	permission.Assign(enforcer, 22, "verificator", "2")
+	lockout := credential.Lockout{Free: 1, Delay: time.Minute, Threshold: 10, Duration: time.Hour}
+	return &usecase{s, users, r, c, a, enforcer, lockout, "PARI Korporat", time.Minute}, users, s, a
+}
+
+func requireCode(t *testing.T, err error, code string) {
+	var appErr *apperror.Error
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, code, appErr.Code)
+}
+
+func TestRequired(t *testing.T) {
+	e, _, _, _ := setup(t)
+	for _, tt := range []struct {
+		name     string
+		user     model.User
+		required bool
+	}{
+		{"role requiring it", model.User{RoleID: 2, CompanyID: 2}, true},
+		{"company requiring it, level 2", model.User{RoleID: 3, CompanyID: 1, VerificationLevel: enum.Level2}, true},
+		{"company requiring it, level 1", model.User{RoleID: 3, CompanyID: 1, VerificationLevel: enum.Level1}, false},
+		{"neither", model.User{RoleID: 3, CompanyID: 2, VerificationLevel: enum.Level3}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			required, err := e.required(context.Background(), &tt.user)
+			require.NoError(t, err)
+			require.Equal(t, tt.required, required)
+		})
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	e, _, s, _ := setup(t)
+
+	// neither enabled nor required
+	challenge, err := e.Challenge(context.Background(), &model.User{ID: 7, RoleID: 3, CompanyID: 2})
+	require.NoError(t, err)
+	require.Nil(t, challenge)
+
+	// required but not enrolled
+	challenge, err = e.Challenge(context.Background(), &model.User{ID: 7, RoleID: 2, CompanyID: 2})
+	require.NoError(t, err)
+	require.True(t, challenge.Enroll)
+
+	// enabled
+	s.rows[7] = &model.TwoFactor{ID: 7, UserID: 7, Enabled: true}
+	challenge, err = e.Challenge(context.Background(), &model.User{ID: 7, RoleID: 3, CompanyID: 2})
+	require.NoError(t, err)
+	require.False(t, challenge.Enroll)
+	userID, _, err := helper.ValidateChallengeToken(challenge.Token)
+	require.NoError(t, err)
+	require.Equal(t, 7, userID)
+}
+
+func TestCompleteEnrollmentAtLogin(t *testing.T) {
+	e, users, s, a := setup(t)
+	u := &model.User{ID: 7, Email: "budi@example.com", RoleID: 2}
+	users.EXPECT().ReadById(gomock.Any(), u.ID).Return(u, nil).AnyTimes()
+	challenge := helper.GenerateChallengeToken(u, time.Minute)
+
+	enrollment, err := e.EnrollChallenge(context.Background(), challenge)
+	require.NoError(t, err)
+	require.Contains(t, enrollment.URI, "otpauth://totp/")
+	require.False(t, s.rows[7].Enabled)
+
+	code, err := totp.Code(enrollment.Secret, totp.Counter(time.Now()))
+	require.NoError(t, err)
+	m, codes, err := e.Complete(context.Background(), request.TwoFactorLogin{ChallengeToken: challenge, Code: code})
+	require.NoError(t, err)
+	require.Equal(t, u, m)
+	require.Len(t, codes, recoveryCodes)
+	require.True(t, s.rows[7].Enabled)
+	require.Equal(t, enum.LoginSucceeded, (*a)[0].Result)
+
+	// enabled secrets are not enrolled again
+	_, err = e.EnrollChallenge(context.Background(), challenge)
+	requireCode(t, err, "two_factor_enabled")
+}
+
+func TestCompleteRefusesReplayedCodes(t *testing.T) {
+	e, users, s, a := setup(t)
+	secret, err := totp.NewSecret()
+	require.NoError(t, err)
+	s.rows[7] = &model.TwoFactor{ID: 7, UserID: 7, Secret: secret, Enabled: true}
+	u := &model.User{ID: 7, Email: "budi@example.com", RoleID: 3}
+	users.EXPECT().ReadById(gomock.Any(), u.ID).Return(u, nil).AnyTimes()
+	challenge := helper.GenerateChallengeToken(u, time.Minute)
+
+	code, err := totp.Code(secret, totp.Counter(time.Now()))
+	require.NoError(t, err)
+	_, _, err = e.Complete(context.Background(), request.TwoFactorLogin{ChallengeToken: challenge, Code: code})
+	require.NoError(t, err)
+
+	// the same code again counts as a failed login
+	users.EXPECT().FailLogin(gomock.Any(), u.ID).Return(1, nil)
+	_, _, err = e.Complete(context.Background(), request.TwoFactorLogin{ChallengeToken: challenge, Code: code})
+	requireCode(t, err, "invalid_code")
+	require.Equal(t, enum.LoginTwoFactorFailed, (*a)[1].Result)
+	require.Equal(t, u.ID, (*a)[1].UserID)
+}
+
+func TestCompleteWithRecoveryCode(t *testing.T) {
+	e, users, s, _ := setup(t)
+	codes, hashes, err := newRecoveryCodes()
+	require.NoError(t, err)
+	s.rows[7] = &model.TwoFactor{ID: 7, UserID: 7, Enabled: true}
+	require.NoError(t, s.ReplaceRecoveryCodes(context.Background(), 7, hashes))
+	u := &model.User{ID: 7, Email: "budi@example.com", RoleID: 3}
+	users.EXPECT().ReadById(gomock.Any(), u.ID).Return(u, nil).AnyTimes()
+	challenge := helper.GenerateChallengeToken(u, time.Minute)
+
+	// typed in lower case without dashes
+	_, _, err = e.Complete(context.Background(), request.TwoFactorLogin{ChallengeToken: challenge, Code: strings.ToLower(strings.ReplaceAll(codes[0], "-", ""))})
+	require.NoError(t, err)
+	left, err := s.CountRecoveryCodes(context.Background(), 7)
+	require.NoError(t, err)
+	require.Equal(t, recoveryCodes-1, left)
+
+	// recovery codes are used once
+	users.EXPECT().FailLogin(gomock.Any(), u.ID).Return(1, nil)
+	_, _, err = e.Complete(context.Background(), request.TwoFactorLogin{ChallengeToken: challenge, Code: codes[0]})
+	requireCode(t, err, "invalid_code")
+}
+
+func TestCompleteRefusesRevokedChallenge(t *testing.T) {
+	e, users, _, _ := setup(t)
+	u := &model.User{ID: 7, RoleID: 3}
+	challenge := helper.GenerateChallengeToken(u, time.Minute)
+
+	// the password was reset meanwhile
+	users.EXPECT().ReadById(gomock.Any(), u.ID).Return(&model.User{ID: 7, RoleID: 3, SessionVersion: 1}, nil)
+	_, _, err := e.Complete(context.Background(), request.TwoFactorLogin{ChallengeToken: challenge, Code: "123456"})
+	requireCode(t, err, "invalid_challenge")
+
+	_, _, err = e.Complete(context.Background(), request.TwoFactorLogin{ChallengeToken: helper.GenerateToken(u), Code: "123456"})
+	requireCode(t, err, "invalid_challenge")
+}
+
+func TestDisableWhenRequired(t *testing.T) {
+	e, users, s, _ := setup(t)
+	s.rows[7] = &model.TwoFactor{ID: 7, UserID: 7, Enabled: true}
+	users.EXPECT().ReadById(gomock.Any(), 7).Return(&model.User{ID: 7, RoleID: 2}, nil)
+
+	err := e.Disable(context.Background(), 7, "123456")
+	requireCode(t, err, "two_factor_required")
+	require.NotNil(t, s.rows[7])
+}
+
+func TestResetRefusesUsersGrantedMore(t *testing.T) {
+	e, users, s, _ := setup(t)
+	users.EXPECT().ReadById(gomock.Any(), 1).Return(&model.User{ID: 1, RoleID: 1}, nil).AnyTimes()
+	users.EXPECT().ReadById(gomock.Any(), 20).Return(&model.User{ID: 20, RoleID: 4, CompanyID: 2}, nil).AnyTimes()
+	users.EXPECT().ReadById(gomock.Any(), 21).Return(&model.User{ID: 21, RoleID: 3, CompanyID: 2}, nil).AnyTimes()
+	users.EXPECT().ReadById(gomock.Any(), 22).Return(&model.User{ID: 22, RoleID: 2, CompanyID: 2}, nil).AnyTimes()
+	for _, id := range []int{21, 22} {
+		s.rows[id] = &model.TwoFactor{ID: id, UserID: id, Enabled: true}
+	}
+
+	require.NoError(t, e.Reset(context.Background(), 20, 21))
+	require.Nil(t, s.rows[21])
+
+	// verificators verify products, which admins do not
+	err := e.Reset(context.Background(), 20, 22)
+	requireCode(t, err, "two_factor_reset_forbidden")
+	require.NotNil(t, s.rows[22])
+
+	require.NoError(t, e.Reset(context.Background(), 1, 22))
+	require.Nil(t, s.rows[22])
+}
+
+func TestSetRolePolicy(t *testing.T) {
+	e, users, _, _ := setup(t)
+	users.EXPECT().ReadById(gomock.Any(), 1).Return(&model.User{ID: 1, RoleID: 1}, nil).AnyTimes()
+	users.EXPECT().ReadById(gomock.Any(), 20).Return(&model.User{ID: 20, RoleID: 4, CompanyID: 2}, nil).AnyTimes()
+
+	ro, err := e.SetRolePolicy(context.Background(), 20, 5, true)
+	require.NoError(t, err)
+	require.True(t, ro.RequireTwoFactor)
+
+	_, err = e.SetRolePolicy(context.Background(), 20, 3, true)
+	requireCode(t, err, "role_forbidden")
+	_, err = e.SetRolePolicy(context.Background(), 20, 6, true)
+	requireCode(t, err, "role_not_found")
+
+	ro, err = e.SetRolePolicy(context.Background(), 1, 3, true)
+	require.NoError(t, err)
+	require.True(t, ro.RequireTwoFactor)
+}
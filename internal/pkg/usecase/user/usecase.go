@@ -7,58 +7,354 @@
 package user
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/actor"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/credential"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/helper"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/patch"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/query"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
 	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"github.com/casbin/casbin"
 )
 
+// Usecase manages users on behalf of an admin. Superadmins manage every user; the other
+// admins see the users of their company, and manage those granted no permission the
+// admin is not, giving them roles granting no such permission either.
 type Usecase interface {
-	Create(user *model.User) (*model.User, error)
-	ReadAll() (*[]model.User, error)
-	ReadById(id int) (*model.User, error)
-	Update(id int, user *model.User) (*model.User, error)
-	ChangePassword(user request.ChangePassword) (*model.User, error)
-	Delete(id int) error
+	Create(ctx context.Context, actorID int, user *request.CreateUser) (*model.User, error)
+	CreateServiceAccount(ctx context.Context, actorID int, account *request.ServiceAccount) (*model.User, error)
+	ReadAllBy(ctx context.Context, actorID int, q *query.Query) (*[]model.User, *query.Page, error)
+	ReadById(ctx context.Context, actorID, id int) (*model.User, error)
+	Update(ctx context.Context, actorID, id int, user *request.UpdateUser) (*model.User, error)
+	Patch(ctx context.Context, actorID, id, version int, doc patch.Document) (*model.User, error)
+	ChangePassword(ctx context.Context, user request.ChangePassword) (*model.User, error)
+	Delete(ctx context.Context, actorID, id int) error
+	AssignRole(ctx context.Context, actorID, id, roleID int) (*model.User, error)
+	RevokeRole(ctx context.Context, actorID, id int) (*model.User, error)
+	Permissions(ctx context.Context, actorID, id int) ([]string, []permission.Permission, error)
+	SyncRoles(ctx context.Context) error
 }
 
+// serviceAccountDomain is the domain of the emails service accounts are given, which
+// cannot receive any.
+const serviceAccountDomain = "service-account.invalid"
+
 type usecase struct {
-	repository user.Repository
+	repository     user.Repository
+	roleRepository role.Repository
+	credential     credential.Service
+	enforcer       *casbin.SyncedEnforcer
+}
+
+func NewUsecase(repository user.Repository, roleRepository role.Repository, credential credential.Service, enforcer *casbin.SyncedEnforcer) Usecase {
+	return &usecase{repository, roleRepository, credential, enforcer}
+}
+
+func (e *usecase) Create(ctx context.Context, actorID int, user *request.CreateUser) (*model.User, error) {
+	r, err := e.creatable(ctx, actorID, user.CompanyID, user.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	password, err := e.credential.Hash(user.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &model.User{
+		Name:              user.Name,
+		Email:             user.Email,
+		Password:          password,
+		RoleID:            user.RoleID,
+		CompanyID:         user.CompanyID,
+		VerificationLevel: user.VerificationLevel,
+	}
+
+	newUser, err := e.repository.Create(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	permission.Assign(e.enforcer, newUser.ID, r.Name, permission.Domain(newUser.CompanyID))
+	newUser.RoleName = r.Name
+	return newUser, nil
 }
 
-func NewUsecase(repository user.Repository) Usecase {
-	return &usecase{repository}
+// CreateServiceAccount creates a user scripts authenticate as with access tokens only.
+// It has no password and an email nobody receives, so it can neither log in nor reset a
+// password.
+func (e *usecase) CreateServiceAccount(ctx context.Context, actorID int, account *request.ServiceAccount) (*model.User, error) {
+	r, err := e.creatable(ctx, actorID, account.CompanyID, account.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, apperror.Internal("secret_error", "failed generating service account email").Wrap(err)
+	}
+
+	newUser, err := e.repository.Create(ctx, &model.User{
+		Name:           account.Name,
+		Email:          "service-" + hex.EncodeToString(suffix) + "@" + serviceAccountDomain,
+		RoleID:         r.ID,
+		CompanyID:      account.CompanyID,
+		ServiceAccount: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// there is no password to change
+	newUser, err = e.repository.Patch(ctx, newUser.ID, newUser.Version, map[string]interface{}{"must_change_password": false})
+	if err != nil {
+		return nil, err
+	}
+	permission.Assign(e.enforcer, newUser.ID, r.Name, permission.Domain(newUser.CompanyID))
+	newUser.RoleName = r.Name
+	return newUser, nil
 }
 
-func (e *usecase) Create(user *model.User) (*model.User, error) {
-	return e.repository.Create(user)
+func (e *usecase) ReadAllBy(ctx context.Context, actorID int, q *query.Query) (*[]model.User, *query.Page, error) {
+	a, err := actor.Load(ctx, e.repository, e.roleRepository, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !a.Superadmin {
+		q.Where("company_id", a.CompanyID)
+	}
+	return e.repository.ReadAllBy(ctx, q)
 }
 
-func (e *usecase) ReadAll() (*[]model.User, error) {
-	return e.repository.ReadAll()
+func (e *usecase) ReadById(ctx context.Context, actorID, id int) (*model.User, error) {
+	a, err := actor.Load(ctx, e.repository, e.roleRepository, actorID)
+	if err != nil {
+		return nil, err
+	}
+	return e.visible(ctx, a, id)
 }
 
-func (e *usecase) ReadById(id int) (*model.User, error) {
-	return e.repository.ReadById(id)
+func (e *usecase) Update(ctx context.Context, actorID, id int, user *request.UpdateUser) (*model.User, error) {
+	if _, _, err := e.manageable(ctx, actorID, id); err != nil {
+		return nil, err
+	}
+	return e.repository.Update(ctx, id, &model.User{Name: user.Name, Email: user.Email})
 }
 
-func (e *usecase) Update(id int, user *model.User) (*model.User, error) {
-	return e.repository.Update(id, user)
+func (e *usecase) Patch(ctx context.Context, actorID, id, version int, doc patch.Document) (*model.User, error) {
+	_, current, err := e.manageable(ctx, actorID, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != current.Version {
+		return nil, apperror.VersionMismatch("user")
+	}
+
+	dto := request.UpdateUser{Name: current.Name, Email: current.Email}
+	fields, err := doc.Apply(&dto)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return current, nil
+	}
+
+	return e.repository.Patch(ctx, id, current.Version, fields)
 }
 
-func (e *usecase) ChangePassword(changePassword request.ChangePassword) (*model.User, error) {
-	userModel, err := e.repository.ReadById(changePassword.UserID)
+func (e *usecase) ChangePassword(ctx context.Context, changePassword request.ChangePassword) (*model.User, error) {
+	userModel, err := e.repository.ReadById(ctx, changePassword.UserID)
 	if err != nil {
-		helper.CommonLogger().Error(err)
+		helper.Logger(ctx).Error(err)
 		return nil, err
 	}
 
-	userModel.Password = changePassword.Password
+	password, err := e.credential.Change(ctx, userModel, changePassword.Password)
+	if err != nil {
+		return nil, err
+	}
+	userModel.Password = password
 	userModel.MustChangePassword = false
 
-	return e.repository.UpdatePasswordLogin(userModel)
+	return e.repository.UpdatePasswordLogin(ctx, userModel)
+}
+
+// Delete moves the user to the trash. They keep their role, so restoring them gives it
+// back, while their tokens stop working.
+func (e *usecase) Delete(ctx context.Context, actorID, id int) error {
+	if _, _, err := e.manageable(ctx, actorID, id); err != nil {
+		return err
+	}
+	return e.repository.Delete(ctx, id)
+}
+
+// AssignRole makes the role the only role of the user, in users.role_id and casbin alike.
+// The role is either shared by all companies or one of the company of the user.
+func (e *usecase) AssignRole(ctx context.Context, actorID, id, roleID int) (*model.User, error) {
+	a, current, err := e.manageable(ctx, actorID, id)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.grantable(ctx, a, current.CompanyID, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := e.repository.Patch(ctx, id, current.Version, map[string]interface{}{"role_id": r.ID})
+	if err != nil {
+		return nil, err
+	}
+	permission.Assign(e.enforcer, id, r.Name, permission.Domain(current.CompanyID))
+	updated.RoleName = r.Name
+	return updated, nil
+}
+
+// RevokeRole leaves the user without any role, so they are granted nothing. Their
+// casbin grouping is removed first, so they are granted nothing while users.role_id is
+// updated, and given back if the update fails.
+func (e *usecase) RevokeRole(ctx context.Context, actorID, id int) (*model.User, error) {
+	_, current, err := e.manageable(ctx, actorID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	domain := permission.Domain(current.CompanyID)
+	roles, _ := permission.Effective(e.enforcer, id, domain)
+	permission.Assign(e.enforcer, id, "", domain)
+	updated, err := e.repository.Patch(ctx, id, current.Version, map[string]interface{}{"role_id": 0})
+	if err != nil {
+		// users have one role at most
+		for _, r := range roles {
+			permission.Assign(e.enforcer, id, r, domain)
+		}
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Permissions is what the user is granted in their company through their roles, along
+// with the roles.
+func (e *usecase) Permissions(ctx context.Context, actorID, id int) ([]string, []permission.Permission, error) {
+	a, err := actor.Load(ctx, e.repository, e.roleRepository, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := e.visible(ctx, a, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	roles, permissions := permission.Effective(e.enforcer, id, permission.Domain(current.CompanyID))
+	return roles, permissions, nil
+}
+
+// SyncRoles groups every user into the role of their users.role_id in casbin, in the
+// domain of their company, fixing the groupings left stale by the role changes made
+// before they were kept in sync. Roles of another company are not held.
+func (e *usecase) SyncRoles(ctx context.Context) error {
+	roles, err := e.roleRepository.ReadAll(ctx)
+	if err != nil {
+		return err
+	}
+	byID := make(map[int]model.Role, len(*roles))
+	for _, r := range *roles {
+		byID[r.ID] = r
+	}
+
+	users, err := e.repository.ReadAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, u := range *users {
+		r, ok := byID[u.RoleID]
+		if !ok || !r.AvailableTo(u.CompanyID) {
+			r.Name = ""
+		}
+		permission.Assign(e.enforcer, u.ID, r.Name, permission.Domain(u.CompanyID))
+	}
+	return nil
+}
+
+// visible finds a user the actor sees, as not found otherwise.
+func (e *usecase) visible(ctx context.Context, a *actor.Actor, id int) (*model.User, error) {
+	u, err := e.repository.ReadById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !a.Sees(u.CompanyID) {
+		return nil, apperror.NotFound("user_not_found", "user is not exists")
+	}
+	return u, nil
+}
+
+// manageable finds a user the actor manages, along with the actor.
+func (e *usecase) manageable(ctx context.Context, actorID, id int) (*actor.Actor, *model.User, error) {
+	a, err := actor.Load(ctx, e.repository, e.roleRepository, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	u, err := e.visible(ctx, a, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !a.Superadmin {
+		_, held := permission.Effective(e.enforcer, u.ID, permission.Domain(u.CompanyID))
+		if missing := permission.Missing(e.granted(a), held); len(missing) > 0 {
+			return nil, nil, apperror.Forbidden("user_forbidden", fmt.Sprintf("user is granted %s, which is not granted to you", missing[0]))
+		}
+	}
+	return a, u, nil
+}
+
+// creatable checks that the actor may add a user with the role to the company.
+func (e *usecase) creatable(ctx context.Context, actorID, companyID, roleID int) (*model.Role, error) {
+	a, err := actor.Load(ctx, e.repository, e.roleRepository, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !a.Sees(companyID) {
+		return nil, apperror.Forbidden("user_forbidden", "users are added to your own company only")
+	}
+	return e.grantable(ctx, a, companyID, roleID)
+}
+
+// grantable finds a role the actor may give users of the company: superadmins any role
+// available to it, other admins those granting no permission they are not, so never
+// the superadmin role.
+func (e *usecase) grantable(ctx context.Context, a *actor.Actor, companyID, roleID int) (*model.Role, error) {
+	r, err := e.roleRepository.ReadById(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if !r.AvailableTo(companyID) {
+		return nil, roleOfAnotherCompany()
+	}
+	if a.Superadmin {
+		return r, nil
+	}
+	if r.IsSuperadmin() {
+		return nil, apperror.Forbidden("role_forbidden", "role superadmin is given by superadmins only")
+	}
+	grants := permission.Grants(e.enforcer, r.Name, permission.Domain(companyID))
+	if missing := permission.Missing(e.granted(a), grants); len(missing) > 0 {
+		return nil, apperror.Forbidden("role_forbidden", fmt.Sprintf("role grants %s, which is not granted to you", missing[0]))
+	}
+	return r, nil
+}
+
+// granted is what the actor is granted in their company.
+func (e *usecase) granted(a *actor.Actor) []permission.Permission {
+	_, granted := permission.Effective(e.enforcer, a.ID, permission.Domain(a.CompanyID))
+	return granted
 }
 
-func (e *usecase) Delete(id int) error {
-	return e.repository.Delete(id)
+func roleOfAnotherCompany() error {
+	return apperror.Validation("validation_error", "request validation failed").WithDetails(apperror.FieldError{
+		Field:   "role_id",
+		Message: "role belongs to another company",
+	})
 }
@@ -0,0 +1,233 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/enum"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/model"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/patch"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/permission"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/role"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/repository/user"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"github.com/casbin/casbin"
+	"github.com/stretchr/testify/require"
+)
+
+// users keeps users in memory. Patching fails while failing is set.
+type users struct {
+	user.Repository
+	rows    map[int]*model.User
+	failing *bool
+}
+
+func (r users) Create(_ context.Context, m *model.User) (*model.User, error) {
+	m.ID = len(r.rows) + 1
+	m.Version = 1
+	saved := *m
+	r.rows[m.ID] = &saved
+	return m, nil
+}
+
+func (r users) ReadById(_ context.Context, id int) (*model.User, error) {
+	if row, ok := r.rows[id]; ok {
+		found := *row
+		return &found, nil
+	}
+	return nil, apperror.NotFound("user_not_found", "user is not exists")
+}
+
+func (r users) Patch(ctx context.Context, id, version int, fields map[string]interface{}) (*model.User, error) {
+	if *r.failing {
+		return nil, apperror.Internal("user", "failed update data")
+	}
+	row := r.rows[id]
+	if row.Version != version {
+		return nil, apperror.VersionMismatch("user")
+	}
+	if roleID, ok := fields["role_id"]; ok {
+		row.RoleID = roleID.(int)
+	}
+	row.Version++
+	return r.ReadById(ctx, id)
+}
+
+// roles keeps roles in memory.
+type roles struct {
+	role.Repository
+	rows map[int]*model.Role
+}
+
+func (r roles) ReadById(_ context.Context, id int) (*model.Role, error) {
+	if row, ok := r.rows[id]; ok {
+		found := *row
+		return &found, nil
+	}
+	return nil, apperror.NotFound("role_not_found", "role is not exists")
+}
+
+// The superadmin is user 1, admins of companies 5 and 6 are users 2 and 3. Clerk is a
+// user of company 5 and verifier one holding finance, granting more than admins.
+const (
+	superadmin = 1
+	admin5     = 2
+	admin6     = 3
+	clerk      = 4
+	verifier   = 5
+)
+
+func newUsecase(t *testing.T) (*usecase, map[int]*model.User, *bool) {
+	rows := map[int]*model.User{
+		superadmin: {ID: superadmin, RoleID: 1, Version: 1},
+		admin5:     {ID: admin5, RoleID: 2, CompanyID: 5, Version: 1},
+		admin6:     {ID: admin6, RoleID: 2, CompanyID: 6, Version: 1},
+		clerk:      {ID: clerk, RoleID: 3, CompanyID: 5, Version: 1},
+		verifier:   {ID: verifier, RoleID: 4, CompanyID: 5, Version: 1},
+	}
+	failing := new(bool)
+
+	enforcer := casbin.NewSyncedEnforcer("../../config/rbac_model.conf", false)
+	permission.Seed(enforcer)
+	permission.Assign(enforcer, superadmin, enum.RoleSuperadmin, permission.AllCompanies)
+	permission.Assign(enforcer, admin5, enum.RoleCompanyAdmin, "5")
+	permission.Assign(enforcer, admin6, enum.RoleCompanyAdmin, "6")
+	permission.Assign(enforcer, clerk, "user", "5")
+	permission.Grant(enforcer, "finance", "5", permission.ProductVerify)
+	permission.Assign(enforcer, verifier, "finance", "5")
+
+	return &usecase{
+		repository: users{rows: rows, failing: failing},
+		roleRepository: roles{rows: map[int]*model.Role{
+			1: {ID: 1, Name: enum.RoleSuperadmin}, 2: {ID: 2, Name: enum.RoleCompanyAdmin}, 3: {ID: 3, Name: "user"},
+			4: {ID: 4, Name: "finance", CompanyID: 5}, 5: {ID: 5, Name: "finance", CompanyID: 6},
+		}},
+		enforcer: enforcer,
+	}, rows, failing
+}
+
+func TestAssignRole(t *testing.T) {
+	uc, rows, _ := newUsecase(t)
+
+	updated, err := uc.AssignRole(context.Background(), superadmin, clerk, 4)
+	require.NoError(t, err)
+	require.Equal(t, 4, updated.RoleID)
+	require.Equal(t, "finance", updated.RoleName)
+	held, _ := permission.Effective(uc.enforcer, clerk, "5")
+	require.Equal(t, []string{"finance"}, held)
+
+	_, err = uc.AssignRole(context.Background(), superadmin, clerk, 5)
+	require.True(t, apperror.Is(err, apperror.KindValidation))
+	require.Equal(t, 4, rows[clerk].RoleID)
+	held, _ = permission.Effective(uc.enforcer, clerk, "5")
+	require.Equal(t, []string{"finance"}, held)
+}
+
+func TestRevokeRole(t *testing.T) {
+	uc, rows, _ := newUsecase(t)
+
+	updated, err := uc.RevokeRole(context.Background(), admin5, clerk)
+	require.NoError(t, err)
+	require.Zero(t, updated.RoleID)
+	require.Zero(t, rows[clerk].RoleID)
+	held, granted := permission.Effective(uc.enforcer, clerk, "5")
+	require.Empty(t, held)
+	require.Empty(t, granted)
+}
+
+func TestRevokeRoleFailedUpdateKeepsRole(t *testing.T) {
+	uc, rows, failing := newUsecase(t)
+	*failing = true
+
+	_, err := uc.RevokeRole(context.Background(), admin5, clerk)
+	require.True(t, apperror.Is(err, apperror.KindInternal))
+	require.Equal(t, 3, rows[clerk].RoleID)
+	held, _ := permission.Effective(uc.enforcer, clerk, "5")
+	require.Equal(t, []string{"user"}, held)
+}
+
+func TestUsersOfAnotherCompany(t *testing.T) {
+	uc, rows, _ := newUsecase(t)
+	ctx := context.Background()
+
+	_, err := uc.ReadById(ctx, admin6, clerk)
+	require.True(t, apperror.IsNotFound(err))
+	_, _, err = uc.Permissions(ctx, admin6, clerk)
+	require.True(t, apperror.IsNotFound(err))
+	_, err = uc.Update(ctx, admin6, clerk, &request.UpdateUser{Name: "Budi", Email: "budi@example.com"})
+	require.True(t, apperror.IsNotFound(err))
+	doc, err := patch.Parse(strings.NewReader(`{"name":"Budi"}`))
+	require.NoError(t, err)
+	_, err = uc.Patch(ctx, admin6, clerk, 0, doc)
+	require.True(t, apperror.IsNotFound(err))
+	require.True(t, apperror.IsNotFound(uc.Delete(ctx, admin6, clerk)))
+	_, err = uc.AssignRole(ctx, admin6, clerk, 3)
+	require.True(t, apperror.IsNotFound(err))
+	_, err = uc.RevokeRole(ctx, admin6, clerk)
+	require.True(t, apperror.IsNotFound(err))
+	require.Equal(t, 3, rows[clerk].RoleID)
+
+	_, err = uc.CreateServiceAccount(ctx, admin6, &request.ServiceAccount{Name: "sync", RoleID: 3, CompanyID: 5})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+	_, err = uc.Create(ctx, admin6, &request.CreateUser{Name: "Budi", Email: "budi@example.com", Password: "n3w-password", RoleID: 3, CompanyID: 5})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+	require.Len(t, rows, 5)
+
+	u, err := uc.ReadById(ctx, admin5, clerk)
+	require.NoError(t, err)
+	require.Equal(t, clerk, u.ID)
+	u, err = uc.ReadById(ctx, superadmin, clerk)
+	require.NoError(t, err)
+	require.Equal(t, clerk, u.ID)
+}
+
+func TestUsersGrantedMoreThanAdmin(t *testing.T) {
+	uc, rows, _ := newUsecase(t)
+	ctx := context.Background()
+
+	// verifier holds finance, granting product:verify admins are not granted
+	_, err := uc.Update(ctx, admin5, verifier, &request.UpdateUser{Name: "Budi", Email: "budi@example.com"})
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+	require.True(t, apperror.Is(uc.Delete(ctx, admin5, verifier), apperror.KindForbidden))
+	_, err = uc.RevokeRole(ctx, admin5, verifier)
+	require.True(t, apperror.Is(err, apperror.KindForbidden))
+	require.Equal(t, 4, rows[verifier].RoleID)
+	_, err = uc.AssignRole(ctx, admin5, superadmin, 3)
+	require.True(t, apperror.IsNotFound(err), "superadmins belong to no company")
+
+	// they still see them
+	_, held, err := uc.Permissions(ctx, admin5, verifier)
+	require.NoError(t, err)
+	require.Contains(t, held, permission.ProductVerify)
+
+	_, err = uc.RevokeRole(ctx, superadmin, verifier)
+	require.NoError(t, err)
+	require.Zero(t, rows[verifier].RoleID)
+}
+
+func TestRolesGrantingMoreThanAdmin(t *testing.T) {
+	uc, rows, _ := newUsecase(t)
+	ctx := context.Background()
+
+	for _, roleID := range []int{1, 4} {
+		_, err := uc.AssignRole(ctx, admin5, clerk, roleID)
+		require.True(t, apperror.Is(err, apperror.KindForbidden))
+		_, err = uc.CreateServiceAccount(ctx, admin5, &request.ServiceAccount{Name: "sync", RoleID: roleID, CompanyID: 5})
+		require.True(t, apperror.Is(err, apperror.KindForbidden))
+		_, err = uc.Create(ctx, admin5, &request.CreateUser{Name: "Budi", Email: "budi@example.com", Password: "n3w-password", RoleID: roleID, CompanyID: 5})
+		require.True(t, apperror.Is(err, apperror.KindForbidden))
+	}
+	require.Equal(t, 3, rows[clerk].RoleID)
+	require.Len(t, rows, 5)
+
+	account, err := uc.CreateServiceAccount(ctx, admin5, &request.ServiceAccount{Name: "sync", RoleID: 3, CompanyID: 5})
+	require.NoError(t, err)
+	held, _ := permission.Effective(uc.enforcer, account.ID, "5")
+	require.Equal(t, []string{"user"}, held)
+
+	updated, err := uc.AssignRole(ctx, superadmin, clerk, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, updated.RoleID)
+}
@@ -0,0 +1,149 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/unit"
+	"github.com/go-playground/validator/v10"
+)
+
+// SliceError holds the validation error of every invalid item of a slice, by index.
+type SliceError map[int]error
+
+func (e SliceError) Error() string {
+	indexes := e.indexes()
+	messages := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		messages = append(messages, fmt.Sprintf("[%d]: %s", i, e[i]))
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e SliceError) indexes() []int {
+	indexes := make([]int, 0, len(e))
+	for i := range e {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// FromBind turns an error returned by gin's ShouldBind* into a domain error: failed
+// validation rules become a 422 listing every invalid field, anything else (malformed
+// JSON, wrong types) is a 400.
+func FromBind(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	details := fieldErrors("", err)
+	if details == nil {
+		return apperror.BadRequest("invalid_request", "invalid request").Wrap(err)
+	}
+	return apperror.Validation("validation_error", "request validation failed").WithDetails(details...).Wrap(err)
+}
+
+func fieldErrors(prefix string, err error) []apperror.FieldError {
+	var sliceErr SliceError
+	if errors.As(err, &sliceErr) {
+		var details []apperror.FieldError
+		for _, i := range sliceErr.indexes() {
+			itemDetails := fieldErrors(fmt.Sprintf("%s[%d].", prefix, i), sliceErr[i])
+			if itemDetails == nil {
+				return nil
+			}
+			details = append(details, itemDetails...)
+		}
+		return details
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return nil
+	}
+
+	details := make([]apperror.FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		details = append(details, apperror.FieldError{
+			Field:   prefix + fieldPath(fe),
+			Message: message(fe),
+		})
+	}
+	return details
+}
+
+// fieldPath drops the struct name from the namespace, e.g. "Product.expired_at" -> "expired_at".
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+func message(fe validator.FieldError) string {
+	param := fe.Param()
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if isString(fe) {
+			return fmt.Sprintf("must be at least %s characters", param)
+		}
+		return fmt.Sprintf("must be at least %s", param)
+	case "max":
+		if isString(fe) {
+			return fmt.Sprintf("must be at most %s characters", param)
+		}
+		return fmt.Sprintf("must be at most %s", param)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", param)
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", param)
+	case "lt":
+		return fmt.Sprintf("must be less than %s", param)
+	case "lte":
+		return fmt.Sprintf("must be less than or equal to %s", param)
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(param), ", "))
+	case "gtefield":
+		return fmt.Sprintf("must be greater than or equal to %s", snakeCase(param))
+	case "ltefield":
+		return fmt.Sprintf("must be less than or equal to %s", snakeCase(param))
+	case "unit":
+		return fmt.Sprintf("must be one of: %s", strings.Join(unit.Codes(), ", "))
+	case "unit_dimension":
+		return fmt.Sprintf("must measure the same as %s", param)
+	case "currency":
+		return "must be a supported ISO 4217 currency code"
+	case "giro":
+		return "must be a 15 digit giro number"
+	case "date":
+		return "must be a date formatted as YYYY-MM-DD"
+	case "date_gtefield":
+		return fmt.Sprintf("must be a date formatted as YYYY-MM-DD, not before %s", snakeCase(param))
+	case "price_range":
+		return "must be between min_price_amount and max_price_amount"
+	default:
+		return fmt.Sprintf("failed on the %q rule", fe.Tag())
+	}
+}
+
+func isString(fe validator.FieldError) bool {
+	_, ok := fe.Value().(string)
+	return ok
+}
+
+var upperPattern = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// snakeCase converts a Go field name used as a rule param into its json name.
+func snakeCase(s string) string {
+	return strings.ToLower(upperPattern.ReplaceAllString(s, "${1}_${2}"))
+}
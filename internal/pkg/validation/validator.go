@@ -0,0 +1,186 @@
+package validation
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/money"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/unit"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// DateLayout is the format of every date field accepted by the API.
+const DateLayout = "2006-01-02"
+
+var giroPattern = regexp.MustCompile(`^[0-9]{15}$`)
+
+// Validator is the gin binding validator of the API. Besides the built in tags it
+// understands "unit", "currency", "giro", "date" and "date_gtefield=Field", reports fields
+// by their json/form name and keeps the index of every invalid item of a slice.
+type Validator struct {
+	once     sync.Once
+	validate *validator.Validate
+}
+
+var _ binding.StructValidator = &Validator{}
+
+// Register installs the API validator as gin's binding validator.
+func Register() {
+	binding.Validator = &Validator{}
+}
+
+// ValidateStruct validates a struct, a pointer to a struct or a slice of them.
+func (v *Validator) ValidateStruct(obj interface{}) error {
+	if obj == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(obj)
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		return v.ValidateStruct(value.Elem().Interface())
+	case reflect.Struct:
+		v.lazyinit()
+		return v.validate.Struct(obj)
+	case reflect.Slice, reflect.Array:
+		errs := make(SliceError)
+		for i := 0; i < value.Len(); i++ {
+			if err := v.ValidateStruct(value.Index(i).Interface()); err != nil {
+				errs[i] = err
+			}
+		}
+		if len(errs) == 0 {
+			return nil
+		}
+		return errs
+	default:
+		return nil
+	}
+}
+
+// Engine returns the underlying validator.
+func (v *Validator) Engine() interface{} {
+	v.lazyinit()
+	return v.validate
+}
+
+func (v *Validator) lazyinit() {
+	v.once.Do(func() {
+		v.validate = validator.New()
+		v.validate.SetTagName("binding")
+		v.validate.RegisterTagNameFunc(fieldName)
+
+		_ = v.validate.RegisterValidation("unit", func(fl validator.FieldLevel) bool {
+			_, ok := unit.Lookup(fl.Field().String())
+			return ok
+		})
+		_ = v.validate.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+			return money.IsCurrency(fl.Field().String())
+		})
+		_ = v.validate.RegisterValidation("giro", func(fl validator.FieldLevel) bool {
+			return giroPattern.MatchString(fl.Field().String())
+		})
+		_ = v.validate.RegisterValidation("date", func(fl validator.FieldLevel) bool {
+			_, err := time.Parse(DateLayout, fl.Field().String())
+			return err == nil
+		})
+		_ = v.validate.RegisterValidation("date_gtefield", dateGteField)
+
+		// a type has a single struct level validation, so product rules are chained
+		v.validate.RegisterStructValidation(func(sl validator.StructLevel) {
+			productPriceRange(sl)
+			sameDimension(sl)
+		}, request.Product{}, request.UpdateProduct{})
+		v.validate.RegisterStructValidation(sameDimension, request.Commodity{})
+	})
+}
+
+// dateGteField checks that a date is not before the date in the field named by the param.
+// An unparsable other field is left to its own "date" rule.
+func dateGteField(fl validator.FieldLevel) bool {
+	date, err := time.Parse(DateLayout, fl.Field().String())
+	if err != nil {
+		return false
+	}
+
+	other, _, _, ok := fl.GetStructFieldOKAdvanced2(fl.Parent(), fl.Param())
+	if !ok || other.Kind() != reflect.String {
+		return false
+	}
+	start, err := time.Parse(DateLayout, other.String())
+	if err != nil {
+		return true
+	}
+	return !date.Before(start)
+}
+
+// productPriceRange checks min_price_amount <= price_amount <= max_price_amount when
+// a maximum is given.
+func productPriceRange(sl validator.StructLevel) {
+	var price, minPrice, maxPrice int64
+	switch p := sl.Current().Interface().(type) {
+	case request.Product:
+		price, minPrice, maxPrice = p.PriceAmount, p.MinPriceAmount, p.MaxPriceAmount
+	case request.UpdateProduct:
+		price, minPrice, maxPrice = p.PriceAmount, p.MinPriceAmount, p.MaxPriceAmount
+	default:
+		return
+	}
+
+	if maxPrice == 0 {
+		return
+	}
+	if maxPrice < minPrice {
+		sl.ReportError(maxPrice, "max_price_amount", "MaxPriceAmount", "gtefield", "MinPriceAmount")
+	}
+	if price < minPrice || price > maxPrice {
+		sl.ReportError(price, "price_amount", "PriceAmount", "price_range", "")
+	}
+}
+
+// sameDimension checks that the quantity and the price are in units of the same
+// dimension, so a price per kg is not given for a quantity in liters. Empty or unknown
+// units are left to their own rules.
+func sameDimension(sl validator.StructLevel) {
+	var quantityUnit, priceUnit string
+	switch p := sl.Current().Interface().(type) {
+	case request.Product:
+		quantityUnit, priceUnit = p.UnitQuantity, p.UnitPrice
+	case request.UpdateProduct:
+		quantityUnit, priceUnit = p.UnitQuantity, p.UnitPrice
+	case request.Commodity:
+		quantityUnit, priceUnit = p.UnitQuantity, p.UnitPrice
+	default:
+		return
+	}
+
+	q, ok := unit.Lookup(quantityUnit)
+	if !ok {
+		return
+	}
+	if p, ok := unit.Lookup(priceUnit); ok && p.Dimension != q.Dimension {
+		sl.ReportError(priceUnit, "unit_price", "UnitPrice", "unit_dimension", "unit_quantity")
+	}
+}
+
+// fieldName names a field as the client sent it: json name first, then form, then uri.
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			continue
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
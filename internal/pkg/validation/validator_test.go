@@ -0,0 +1,124 @@
+package validation
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"testing"
+
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/apperror"
+	"bitbucket.org/bridce/ms-pari-web/internal/pkg/request"
+	"github.com/stretchr/testify/require"
+)
+
+func validProduct() request.Product {
+	return request.Product{
+		Name:             "Gabah Kering Panen",
+		Quantity:         10,
+		UnitQuantity:     "kg",
+		PriceAmount:      550000,
+		UnitPrice:        "kg",
+		MinPriceAmount:   500000,
+		MaxPriceAmount:   600000,
+		ProductCreatedAt: "2022-06-01",
+		ExpiredAt:        "2022-06-30",
+		CompanyID:        1,
+		CommodityID:      1,
+		File:             &multipart.FileHeader{Filename: "gabah.jpg"},
+	}
+}
+
+func fields(t *testing.T, err error) map[string]string {
+	t.Helper()
+	appErr := apperror.From(FromBind(err))
+	require.Equal(t, http.StatusUnprocessableEntity, appErr.StatusCode())
+
+	result := make(map[string]string, len(appErr.Details))
+	for _, d := range appErr.Details {
+		result[d.Field] = d.Message
+	}
+	return result
+}
+
+func TestValidProduct(t *testing.T) {
+	p := validProduct()
+	require.NoError(t, (&Validator{}).ValidateStruct(&p))
+}
+
+func TestProductFieldErrors(t *testing.T) {
+	p := validProduct()
+	p.Name = ""
+	p.Quantity = -1
+	p.CommodityID = 0
+	p.ProductCreatedAt = "2022-06-30"
+	p.ExpiredAt = "2022-06-01"
+	p.PriceAmount = 700000
+	p.File = nil
+
+	got := fields(t, (&Validator{}).ValidateStruct(&p))
+	require.Equal(t, map[string]string{
+		"name":         "is required",
+		"quantity":     "must be greater than 0",
+		"commodity_id": "is required",
+		"expired_at":   "must be a date formatted as YYYY-MM-DD, not before product_created_at",
+		"price_amount": "must be between min_price_amount and max_price_amount",
+		"file":         "is required",
+	}, got)
+}
+
+func TestProductUnits(t *testing.T) {
+	p := validProduct()
+	p.UnitQuantity = "karung"
+	p.Currency = "idr"
+
+	got := fields(t, (&Validator{}).ValidateStruct(&p))
+	require.Equal(t, map[string]string{
+		"unit_quantity": "must be one of: g, kg, kuintal, ton, ml, liter, pcs, ekor, lusin, kodi",
+		"currency":      "must be a supported ISO 4217 currency code",
+	}, got)
+
+	p = validProduct()
+	p.UnitQuantity = "ton"
+	p.UnitPrice = "liter"
+	got = fields(t, (&Validator{}).ValidateStruct(&p))
+	require.Equal(t, map[string]string{"unit_price": "must measure the same as unit_quantity"}, got)
+
+	p.UnitPrice = ""
+	require.NoError(t, (&Validator{}).ValidateStruct(&p), "empty units default to those of the commodity")
+}
+
+func TestProductInvalidDate(t *testing.T) {
+	p := validProduct()
+	p.ProductCreatedAt = "01/06/2022"
+
+	got := fields(t, (&Validator{}).ValidateStruct(&p))
+	require.Equal(t, map[string]string{"product_created_at": "must be a date formatted as YYYY-MM-DD"}, got)
+}
+
+func TestSliceErrorsKeepIndex(t *testing.T) {
+	users := request.Users{
+		{Name: "Budi", Email: "budi@example.com", Password: "rahasia123", Role: "user", CompanyID: 1},
+		{Name: "Sari", Email: "not-an-email", Password: "short", Role: "user", CompanyID: 1},
+	}
+
+	got := fields(t, (&Validator{}).ValidateStruct(users))
+	require.Equal(t, map[string]string{
+		"[1].email":    "must be a valid email address",
+		"[1].password": "must be at least 8 characters",
+	}, got)
+}
+
+func TestCompanyGiro(t *testing.T) {
+	c := request.Company{Name: "KUD Makmur", Code: "KUD01", Giro: "12-34"}
+
+	got := fields(t, (&Validator{}).ValidateStruct(&c))
+	require.Equal(t, map[string]string{"giro": "must be a 15 digit giro number"}, got)
+
+	c.Giro = "020601000123305"
+	require.NoError(t, (&Validator{}).ValidateStruct(&c))
+}
+
+func TestFromBindMalformedBody(t *testing.T) {
+	err := FromBind(errors.New("unexpected EOF"))
+	require.True(t, apperror.Is(err, apperror.KindBadRequest))
+}